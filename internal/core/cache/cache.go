@@ -21,6 +21,12 @@ const (
 	KeyLoginLock     = "login:lock:"
 	KeyBlacklist     = "blacklist:"
 	KeyResetToken    = "reset_token:"
+	KeyVerifyToken   = "verify_token:"
+	KeyVerifyResend  = "verify:resend:"
+	KeyMFAChallenge  = "mfa:challenge:"
+	KeyMFAUsedStep   = "mfa:used:"
+	KeyOIDCState     = "oidc:state:"
+	KeyRolePerms     = "rbac:role:"
 	KeyUser          = "user:"
 )
 
@@ -33,9 +39,9 @@ type CacheRedis interface {
 	BlockUser(ctx context.Context, userID string, duration time.Duration) error
 	IsUserBlocked(ctx context.Context, userID string) (bool, error)
 
-	StoreSession(ctx context.Context, userID string, session SessionData, ttl time.Duration) error
-	GetSession(ctx context.Context, userID string) (*SessionData, error)
-	DeleteSession(ctx context.Context, userID string) error
+	StoreSession(ctx context.Context, sessionID string, session SessionData, ttl time.Duration) error
+	GetSession(ctx context.Context, sessionID string) (*SessionData, error)
+	DeleteSession(ctx context.Context, sessionID string) error
 
 	IsTokenBlacklisted(ctx context.Context, jti string) (bool, error)
 	BlacklistToken(ctx context.Context, jti string, ttl time.Duration) error
@@ -44,6 +50,23 @@ type CacheRedis interface {
 	GetResetToken(ctx context.Context, token string) (string, error)
 	DeleteResetToken(ctx context.Context, token string) error
 
+	StoreVerificationToken(ctx context.Context, nonce, userID string, ttl time.Duration) error
+	GetVerificationToken(ctx context.Context, nonce string) (string, error)
+	DeleteVerificationToken(ctx context.Context, nonce string) error
+	IncrementVerificationResend(ctx context.Context, userID string, window time.Duration) (int64, error)
+
+	StoreMFAChallenge(ctx context.Context, token string, challenge MFAChallenge, ttl time.Duration) error
+	GetMFAChallenge(ctx context.Context, token string) (*MFAChallenge, error)
+	DeleteMFAChallenge(ctx context.Context, token string) error
+	MarkTOTPStepUsed(ctx context.Context, userID string, step int64, ttl time.Duration) (bool, error)
+
+	StoreOIDCState(ctx context.Context, state string, data OIDCState, ttl time.Duration) error
+	TakeOIDCState(ctx context.Context, state string) (*OIDCState, error)
+
+	SetRolePermissions(ctx context.Context, roleName string, perms []string, ttl time.Duration) error
+	GetRolePermissions(ctx context.Context, roleName string) ([]string, error)
+	DeleteRolePermissions(ctx context.Context, roleName string) error
+
 	Ping(ctx context.Context) error
 }
 
@@ -54,7 +77,9 @@ type cacheRedis struct {
 	Logger *zap.SugaredLogger
 }
 
+// SessionData is the cached state of one login, keyed by the session ID carried in the JWT.
 type SessionData struct {
+	SessionID    string            `json:"session_id"`
 	UserID       string            `json:"user_id"`
 	Email        string            `json:"email"`
 	GlobalRole   string            `json:"global_role"`
@@ -63,6 +88,47 @@ type SessionData struct {
 	AccessToken  string    `json:"access_token,omitempty"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+	MFA          bool      `json:"mfa"` // connexion validée par un second facteur
+
+	APIKey *APIKeyScope `json:"api_key,omitempty"` // requête authentifiée par une clé API
+}
+
+// APIKeyScope narrows what a request made with an API key may do, on top of the user's roles.
+// An empty list does not restrict.
+type APIKeyScope struct {
+	KeyID      string   `json:"key_id"`
+	Scopes     []string `json:"scopes,omitempty"`
+	ProjectIDs []string `json:"project_ids,omitempty"`
+}
+
+// Allows tells whether the key may use perm on the project.
+func (k *APIKeyScope) Allows(projectID, perm string) bool {
+	return (len(k.Scopes) == 0 || contains(k.Scopes, perm)) &&
+		(len(k.ProjectIDs) == 0 || contains(k.ProjectIDs, projectID))
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAChallenge is a password login waiting for its second factor.
+type MFAChallenge struct {
+	UserID    string `json:"user_id"`
+	UserAgent string `json:"user_agent"`
+	ClientIP  string `json:"client_ip"`
+}
+
+// OIDCState keeps what the callback needs to finish an authorization-code flow.
+type OIDCState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // code_verifier PKCE
 }
 
 func NewcacheRedis(client *redis.Client, logger *zap.SugaredLogger) *cacheRedis {
@@ -129,13 +195,13 @@ func (r *cacheRedis) IsUserBlocked(ctx context.Context, userID string) (bool, er
 	return exists > 0, nil
 }
 
-func (r *cacheRedis) StoreSession(ctx context.Context, userID string, session SessionData, ttl time.Duration) error {
-	key := r.getKey(KeySession, userID)
+func (r *cacheRedis) StoreSession(ctx context.Context, sessionID string, session SessionData, ttl time.Duration) error {
+	key := r.getKey(KeySession, sessionID)
 	return r.setJSON(ctx, key, session, ttl)
 }
 
-func (r *cacheRedis) GetSession(ctx context.Context, userID string) (*SessionData, error) {
-	key := r.getKey(KeySession, userID)
+func (r *cacheRedis) GetSession(ctx context.Context, sessionID string) (*SessionData, error) {
+	key := r.getKey(KeySession, sessionID)
 	var session SessionData
 	if err := r.getJSON(ctx, key, &session); err != nil {
 		return nil, err
@@ -143,8 +209,8 @@ func (r *cacheRedis) GetSession(ctx context.Context, userID string) (*SessionDat
 	return &session, nil
 }
 
-func (r *cacheRedis) DeleteSession(ctx context.Context, userID string) error {
-	key := r.getKey(KeySession, userID)
+func (r *cacheRedis) DeleteSession(ctx context.Context, sessionID string) error {
+	key := r.getKey(KeySession, sessionID)
 	return r.delete(ctx, key)
 }
 
@@ -178,6 +244,105 @@ func (r *cacheRedis) DeleteResetToken(ctx context.Context, token string) error {
 	return r.delete(ctx, key)
 }
 
+func (r *cacheRedis) StoreVerificationToken(ctx context.Context, nonce, userID string, ttl time.Duration) error {
+	key := r.getKey(KeyVerifyToken, nonce)
+	return r.Client.Set(ctx, key, userID, ttl).Err()
+}
+
+func (r *cacheRedis) GetVerificationToken(ctx context.Context, nonce string) (string, error) {
+	key := r.getKey(KeyVerifyToken, nonce)
+	val, err := r.Client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return val, err
+}
+
+func (r *cacheRedis) DeleteVerificationToken(ctx context.Context, nonce string) error {
+	key := r.getKey(KeyVerifyToken, nonce)
+	return r.delete(ctx, key)
+}
+
+// IncrementVerificationResend counts the verification emails sent to a user in the current window.
+func (r *cacheRedis) IncrementVerificationResend(ctx context.Context, userID string, window time.Duration) (int64, error) {
+	key := r.getKey(KeyVerifyResend, userID)
+
+	count, err := r.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, betoerrors.Wrap(err, betoerrors.CodeCacheError, "failed to increment verification resend counter")
+	}
+
+	if count == 1 {
+		if _, err := r.Client.Expire(ctx, key, window).Result(); err != nil {
+			r.Logger.Errorw("Failed to expire resend counter", "key", key, "error", err)
+		}
+	}
+	return count, nil
+}
+
+func (r *cacheRedis) StoreMFAChallenge(ctx context.Context, token string, challenge MFAChallenge, ttl time.Duration) error {
+	return r.setJSON(ctx, r.getKey(KeyMFAChallenge, token), challenge, ttl)
+}
+
+func (r *cacheRedis) GetMFAChallenge(ctx context.Context, token string) (*MFAChallenge, error) {
+	var challenge MFAChallenge
+	if err := r.getJSON(ctx, r.getKey(KeyMFAChallenge, token), &challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (r *cacheRedis) DeleteMFAChallenge(ctx context.Context, token string) error {
+	return r.delete(ctx, r.getKey(KeyMFAChallenge, token))
+}
+
+// MarkTOTPStepUsed records that a code was accepted for this time step. It returns false when
+// the step was already used, i.e. the code is being replayed.
+func (r *cacheRedis) MarkTOTPStepUsed(ctx context.Context, userID string, step int64, ttl time.Duration) (bool, error) {
+	key := r.getKey(KeyMFAUsedStep, fmt.Sprintf("%s:%d", userID, step))
+	ok, err := r.Client.SetNX(ctx, key, "1", ttl).Result()
+	if err != nil {
+		return false, betoerrors.Wrap(err, betoerrors.CodeCacheError, "failed to record totp usage")
+	}
+	return ok, nil
+}
+
+func (r *cacheRedis) StoreOIDCState(ctx context.Context, state string, data OIDCState, ttl time.Duration) error {
+	return r.setJSON(ctx, r.getKey(KeyOIDCState, state), data, ttl)
+}
+
+// TakeOIDCState reads and deletes the state in one step, a callback can only be used once.
+func (r *cacheRedis) TakeOIDCState(ctx context.Context, state string) (*OIDCState, error) {
+	raw, err := r.Client.GetDel(ctx, r.getKey(KeyOIDCState, state)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrCacheMiss
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeCacheError, "failed to get oidc state")
+	}
+	var data OIDCState
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeCacheError, "failed to decode oidc state")
+	}
+	return &data, nil
+}
+
+func (r *cacheRedis) SetRolePermissions(ctx context.Context, roleName string, perms []string, ttl time.Duration) error {
+	return r.setJSON(ctx, r.getKey(KeyRolePerms, roleName), perms, ttl)
+}
+
+func (r *cacheRedis) GetRolePermissions(ctx context.Context, roleName string) ([]string, error) {
+	var perms []string
+	if err := r.getJSON(ctx, r.getKey(KeyRolePerms, roleName), &perms); err != nil {
+		return nil, err
+	}
+	return perms, nil
+}
+
+func (r *cacheRedis) DeleteRolePermissions(ctx context.Context, roleName string) error {
+	return r.delete(ctx, r.getKey(KeyRolePerms, roleName))
+}
+
 func (r *cacheRedis) setJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	data, err := json.Marshal(value)
 	if err != nil {
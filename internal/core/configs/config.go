@@ -21,11 +21,17 @@ type GlobalConfig struct {
 	Tracing     TracingConfig    `mapstructure:"tracing"`
 	Temporal    TemporalConfig   `mapstructure:"temporal"`
 	Mail        MailConfig       `mapstructure:"mail"`
+	Verify      VerifyConfig     `mapstructure:"verification"`
+	MFA         MFAConfig        `mapstructure:"mfa"`
+	Secrets     SecretsConfig    `mapstructure:"secrets"`
+	APIKeys     APIKeyConfig     `mapstructure:"api_keys"`
+	OIDC        OIDCConfig       `mapstructure:"oidc"`
 	Swagger     SwaggerConfig    `mapstructure:"swagger"`
 	JWT         JWTConfig        `mapstructure:"jwt"`
 	Frontend    FrontendConfig   `mapstructure:"frontend"`
 	Roles       RolesConfig      `mapstructure:"roles"`
 	Kubernetes  KubernetesConfig `mapstructure:"kubernetes"`
+	Build       BuildConfig      `mapstructure:"build"`
 	Vps         Vps              `mapstructure:"vps"`
 }
 
@@ -46,6 +52,58 @@ type MailConfig struct {
 	Enabled      bool   `mapstructure:"enabled"`
 }
 
+// VerifyConfig gates accounts on email verification. Disabled by default so that accounts
+// created before the flow existed keep working.
+type VerifyConfig struct {
+	Required             bool          `mapstructure:"required"`
+	AllowUnverifiedLogin bool          `mapstructure:"allow_unverified_login"` // connexion permise, création de projets/clés API bloquée
+	TokenTTL             time.Duration `mapstructure:"token_ttl"`
+	ResendLimit          int           `mapstructure:"resend_limit"` // renvois autorisés par fenêtre
+	ResendWindow         time.Duration `mapstructure:"resend_window"`
+}
+
+type MFAConfig struct {
+	Issuer        string        `mapstructure:"issuer"`         // nom affiché par l'application d'authentification
+	EncryptionKey string        `mapstructure:"encryption_key"` // chiffre les secrets TOTP, jwt.secret à défaut
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`
+}
+
+type SecretsConfig struct {
+	EncryptionKey string `mapstructure:"encryption_key"` // chiffre les tokens git des builds et des sources, jwt.secret à défaut
+}
+
+// APIKeyConfig tunes the API key lifecycle, zero values fall back to the auth module defaults.
+type APIKeyConfig struct {
+	RotationGrace      time.Duration `mapstructure:"rotation_grace"`       // ancien secret encore accepté après une rotation
+	ExpiryNotice       time.Duration `mapstructure:"expiry_notice"`        // préavis de l'email avant expiration
+	UsageFlushInterval time.Duration `mapstructure:"usage_flush_interval"` // écriture groupée des compteurs d'usage
+}
+
+// OIDCConfig lists the identity providers usable for single sign-on, keyed by the name used
+// in /auth/oidc/:provider.
+type OIDCConfig struct {
+	Providers            map[string]OIDCProviderConfig `mapstructure:"providers"`
+	DisablePasswordLogin bool                          `mapstructure:"disable_password_login"` // connexion et inscription locales refusées
+}
+
+type OIDCProviderConfig struct {
+	Issuer         string         `mapstructure:"issuer"`
+	ClientID       string         `mapstructure:"client_id"`
+	ClientSecret   string         `mapstructure:"client_secret"`
+	RedirectURL    string         `mapstructure:"redirect_url"` // .../auth/oidc/<provider>/callback
+	Scopes         []string       `mapstructure:"scopes"`       // openid, email et profile par défaut
+	GroupsClaim    string         `mapstructure:"groups_claim"` // "groups" par défaut
+	AllowedDomains []string       `mapstructure:"allowed_domains"`
+	RoleRules      []OIDCRoleRule `mapstructure:"role_rules"`
+}
+
+// OIDCRoleRule grants a project role to the members of an IdP group.
+type OIDCRoleRule struct {
+	Group     string `mapstructure:"group"`
+	ProjectID string `mapstructure:"project_id"`
+	Role      string `mapstructure:"role"`
+}
+
 type ServerConfig struct {
 	GRPCPort        int           `mapstructure:"grpc_port"`
 	HTTPPort        int           `mapstructure:"http_port"`
@@ -113,6 +171,23 @@ type RolesConfig struct {
 
 type KubernetesConfig struct {
 	KubeConfigPath string `mapstructure:"kubeconfig_path"`
+	SnapshotClass  string `mapstructure:"snapshot_class"` // VolumeSnapshotClass CSI, vide = classe par défaut
+}
+
+// BuildConfig is the registry the in-cluster builds push to. The registry delegates its
+// authentication to kubemanager (token auth, realm /kmanager/v1/registry/token): the login
+// written in a project namespace only pulls the images of the project, the one of a build
+// only pushes its image while it runs.
+type BuildConfig struct {
+	Registry      string        `mapstructure:"registry"`        // préfixe des images, ex: registry.example.com/kubemanager
+	TokenKeyFile  string        `mapstructure:"token_key_file"`  // clé privée PEM (RSA ou P-256) signant les jetons du registre
+	TokenCertFile string        `mapstructure:"token_cert_file"` // son certificat, à mettre dans auth.token.rootcertbundle
+	TokenService  string        `mapstructure:"token_service"`   // auth.token.service du registre, son hôte à défaut
+	Insecure      bool          `mapstructure:"insecure"`        // registre servi en HTTP
+	KanikoImage   string        `mapstructure:"kaniko_image"`
+	CPU           string        `mapstructure:"cpu"` // request = limit du pod de build, compté dans le quota du projet
+	Memory        string        `mapstructure:"memory"`
+	Timeout       time.Duration `mapstructure:"timeout"`
 }
 
 var AppConfig GlobalConfig
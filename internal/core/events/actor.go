@@ -0,0 +1,33 @@
+package events
+
+import "context"
+
+// types d'auteur d'un événement
+const (
+	ActorUser           = "user"
+	ActorServiceAccount = "service_account"
+)
+
+// Actor is who caused an event, read from the request context by Record.
+type Actor struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Email    string `json:"email,omitempty"`
+	APIKeyID string `json:"api_key_id,omitempty"` // requête authentifiée par une clé API
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the caller, set once per request by the auth middleware.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the caller stored by WithActor.
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+)
+
+// Broker publishes one message, it returns once the broker has taken it in charge.
+type Broker interface {
+	Publish(ctx context.Context, routingKey, messageID string, body []byte) error
+	Close() error
+}
+
+var _ Broker = (*amqpBroker)(nil)
+
+// amqpBroker publishes on a durable topic exchange with publisher confirms.
+// The connection is opened lazily and reopened after a failure.
+type amqpBroker struct {
+	cfg    configs.RabbitConfig
+	logger *zap.SugaredLogger
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewAMQPBroker(cfg configs.RabbitConfig, logger *zap.SugaredLogger) Broker {
+	return &amqpBroker{cfg: cfg, logger: logger}
+}
+
+func (b *amqpBroker) Publish(ctx context.Context, routingKey, messageID string, body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch, err := b.ensureChannel()
+	if err != nil {
+		return err
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, b.cfg.Exchange, routingKey, true, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Timestamp:    time.Now(),
+		AppId:        Source,
+		Body:         body,
+	})
+	if err != nil {
+		b.reset()
+		return fmt.Errorf("publish %s: %w", routingKey, err)
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		b.reset()
+		return fmt.Errorf("confirm %s: %w", routingKey, err)
+	}
+	if !acked {
+		return fmt.Errorf("broker refused %s", routingKey)
+	}
+	return nil
+}
+
+func (b *amqpBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	return nil
+}
+
+// ensureChannel declares the exchange and, if configured, a durable queue bound to every
+// routing key so that events are kept until a consumer reads them.
+func (b *amqpBroker) ensureChannel() (*amqp.Channel, error) {
+	if b.channel != nil && !b.channel.IsClosed() {
+		return b.channel, nil
+	}
+	b.reset()
+
+	conn, err := amqp.Dial(b.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq connection: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("rabbitmq channel: %w", err)
+	}
+
+	err = errors.Join(
+		ch.Confirm(false),
+		ch.ExchangeDeclare(b.cfg.Exchange, amqp.ExchangeTopic, true, false, false, false, nil),
+	)
+	if err == nil && b.cfg.Queue != "" {
+		if _, err = ch.QueueDeclare(b.cfg.Queue, true, false, false, false, nil); err == nil {
+			err = ch.QueueBind(b.cfg.Queue, "#", b.cfg.Exchange, false, nil)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("rabbitmq topology: %w", err)
+	}
+
+	b.conn, b.channel = conn, ch
+	b.logger.Infow("connected to rabbitmq", "exchange", b.cfg.Exchange, "queue", b.cfg.Queue)
+	return ch, nil
+}
+
+func (b *amqpBroker) reset() {
+	if b.conn != nil {
+		_ = b.conn.Close()
+	}
+	b.conn, b.channel = nil, nil
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Source is set on every event, consumers sharing the exchange filter on it.
+const Source = "kubemanager"
+
+// Event is the message published on the exchange, its type is the routing key.
+type Event struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Source     string                 `json:"source"`
+	ProjectID  string                 `json:"project_id,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Actor      *Actor                 `json:"actor,omitempty"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+func New(eventType, projectID string, data map[string]interface{}) Event {
+	return Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		Source:     Source,
+		ProjectID:  projectID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// OutboxEvent is an event waiting in Postgres to be published by the relay.
+type OutboxEvent struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key"`
+	Type        string     `gorm:"type:varchar(50);not null"`
+	Payload     string     `gorm:"type:text;not null"`
+	Attempts    int        `gorm:"default:0"`
+	LastError   string     `gorm:"type:text"`
+	CreatedAt   time.Time  `gorm:"index"`
+	PublishedAt *time.Time `gorm:"index"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "event_outbox"
+}
+
+// Record writes the events in the outbox with tx, the transaction of the change they describe:
+// either both are committed or none, the relay then publishes them even if the broker was down.
+// Events without an actor get the caller carried by the transaction context, if any.
+func Record(tx *gorm.DB, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	actor, hasActor := ActorFrom(tx.Statement.Context)
+
+	rows := make([]OutboxEvent, 0, len(events))
+	for _, e := range events {
+		if e.Actor == nil && hasActor {
+			a := actor
+			e.Actor = &a
+		}
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, OutboxEvent{
+			ID:        uuid.MustParse(e.ID),
+			Type:      e.Type,
+			Payload:   string(payload),
+			CreatedAt: e.OccurredAt,
+		})
+	}
+	return tx.Create(&rows).Error
+}
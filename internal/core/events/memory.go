@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is a message received by the MemoryBroker.
+type Message struct {
+	RoutingKey string
+	MessageID  string
+	Body       []byte
+}
+
+var _ Broker = (*MemoryBroker)(nil)
+
+// MemoryBroker stands in for RabbitMQ in tests and local runs. SetErr simulates a broker
+// that is down: the relay keeps the events in the outbox until it is cleared.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	err      error
+	messages []Message
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(_ context.Context, routingKey, messageID string, body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	b.messages = append(b.messages, Message{RoutingKey: routingKey, MessageID: messageID, Body: body})
+	return nil
+}
+
+func (b *MemoryBroker) SetErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+// Messages returns a copy of the messages published so far.
+func (b *MemoryBroker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
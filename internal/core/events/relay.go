@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultRelayInterval = 2 * time.Second
+	defaultRelayBatch    = 100
+	// les événements publiés sont gardés une semaine pour le diagnostic
+	defaultRetention = 7 * 24 * time.Hour
+	purgeInterval    = time.Hour
+)
+
+// Relay publishes the outbox on the broker. An event is marked as published only once the
+// broker confirmed it, so a crash between the two publishes it again: delivery is at-least-once
+// and consumers deduplicate on the message ID (the event ID).
+type Relay struct {
+	DB        *gorm.DB
+	Broker    Broker
+	Logger    *zap.SugaredLogger
+	Interval  time.Duration
+	BatchSize int
+	Retention time.Duration
+}
+
+func NewRelay(db *gorm.DB, broker Broker, logger *zap.SugaredLogger) *Relay {
+	return &Relay{
+		DB:        db,
+		Broker:    broker,
+		Logger:    logger.With("component", "EventRelay"),
+		Interval:  defaultRelayInterval,
+		BatchSize: defaultRelayBatch,
+		Retention: defaultRetention,
+	}
+}
+
+// Run polls the outbox until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	lastPurge := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// on vide l'outbox par lots tant qu'ils sont pleins
+		for {
+			n, err := r.RelayBatch(ctx)
+			if err != nil {
+				r.Logger.Warnw("event relay failed", "error", err)
+				break
+			}
+			if n < r.BatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) > purgeInterval {
+			lastPurge = time.Now()
+			if err := r.purge(ctx); err != nil {
+				r.Logger.Warnw("event outbox purge failed", "error", err)
+			}
+		}
+	}
+}
+
+// RelayBatch publishes the oldest pending events in order and returns how many were published.
+// It stops at the first failure so that a broker outage does not reorder the events; the rows
+// are locked with SKIP LOCKED, several instances can relay the same outbox.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	published := 0
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pending []OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("created_at ASC, id ASC").
+			Limit(r.BatchSize).
+			Find(&pending).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range pending {
+			e := &pending[i]
+			if err := r.Broker.Publish(ctx, e.Type, e.ID.String(), []byte(e.Payload)); err != nil {
+				r.Logger.Warnw("event not published, will retry", "event_id", e.ID, "type", e.Type, "attempts", e.Attempts+1, "error", err)
+				return tx.Model(e).Updates(map[string]interface{}{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": err.Error(),
+				}).Error
+			}
+
+			now := time.Now()
+			if err := tx.Model(e).Update("published_at", &now).Error; err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
+
+func (r *Relay) purge(ctx context.Context) error {
+	return r.DB.WithContext(ctx).
+		Where("published_at IS NOT NULL AND published_at < ?", time.Now().Add(-r.Retention)).
+		Delete(&OutboxEvent{}).Error
+}
@@ -0,0 +1,246 @@
+//go:build cgo
+
+// Le pilote SQLite de GORM (mattn/go-sqlite3) est en cgo : sans compilateur C, ces tests sont
+// ignorés plutôt que de casser « CGO_ENABLED=0 go test ./... ».
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// le relais ne dépend que de l'outbox : SQLite suffit, le verrouillage SKIP LOCKED y est ignoré
+func newOutboxDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "outbox.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open outbox db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&OutboxEvent{}); err != nil {
+		t.Fatalf("migrate outbox: %v", err)
+	}
+	return db
+}
+
+func newTestRelay(db *gorm.DB, broker Broker) *Relay {
+	return NewRelay(db, broker, zap.NewNop().Sugar())
+}
+
+// recordEvents writes one event per type, a millisecond apart so that the outbox order is fixed.
+func recordEvents(t *testing.T, db *gorm.DB, types ...string) []Event {
+	t.Helper()
+	var out []Event
+	for _, typ := range types {
+		e := New(typ, "project-1", map[string]interface{}{"type": typ})
+		if err := Record(db, e); err != nil {
+			t.Fatalf("record %s: %v", typ, err)
+		}
+		out = append(out, e)
+		time.Sleep(time.Millisecond)
+	}
+	return out
+}
+
+func pendingEvents(t *testing.T, db *gorm.DB) []OutboxEvent {
+	t.Helper()
+	var rows []OutboxEvent
+	if err := db.Where("published_at IS NULL").Order("created_at ASC").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func assertPublished(t *testing.T, broker *MemoryBroker, want []Event) {
+	t.Helper()
+	got := broker.Messages()
+	if len(got) != len(want) {
+		t.Fatalf("published %d messages, want %d", len(got), len(want))
+	}
+	for i, msg := range got {
+		if msg.MessageID != want[i].ID || msg.RoutingKey != want[i].Type {
+			t.Errorf("message %d = %s/%s, want %s/%s", i, msg.RoutingKey, msg.MessageID, want[i].Type, want[i].ID)
+		}
+	}
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	db := newOutboxDB(t)
+	broker := NewMemoryBroker()
+	relay := newTestRelay(db, broker)
+	events := recordEvents(t, db, "project.created", "workload.deployed", "member.added")
+
+	n, err := relay.RelayBatch(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("RelayBatch = %d, %v; want 3, nil", n, err)
+	}
+	assertPublished(t, broker, events)
+
+	var body Event
+	if err := json.Unmarshal(broker.Messages()[1].Body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.ID != events[1].ID || body.Source != Source || body.ProjectID != "project-1" {
+		t.Errorf("body = %+v, want the recorded event", body)
+	}
+
+	if rows := pendingEvents(t, db); len(rows) != 0 {
+		t.Errorf("%d events still pending after publication", len(rows))
+	}
+	if n, err := relay.RelayBatch(context.Background()); err != nil || n != 0 {
+		t.Errorf("second RelayBatch = %d, %v; want nothing to publish", n, err)
+	}
+	if len(broker.Messages()) != 3 {
+		t.Errorf("events published twice")
+	}
+}
+
+func TestRelayKeepsEventsWhileBrokerIsDown(t *testing.T) {
+	db := newOutboxDB(t)
+	broker := NewMemoryBroker()
+	relay := newTestRelay(db, broker)
+	events := recordEvents(t, db, "project.created", "project.deleted")
+
+	broker.SetErr(errors.New("connection refused"))
+	for i := 0; i < 2; i++ {
+		if n, err := relay.RelayBatch(context.Background()); err != nil || n != 0 {
+			t.Fatalf("RelayBatch with broker down = %d, %v; want 0, nil", n, err)
+		}
+	}
+
+	rows := pendingEvents(t, db)
+	if len(rows) != 2 {
+		t.Fatalf("%d pending events, want 2", len(rows))
+	}
+	// seul le premier est tenté, les suivants attendent pour ne pas être réordonnés
+	if rows[0].Attempts != 2 || rows[0].LastError != "connection refused" {
+		t.Errorf("first event attempts = %d, error = %q", rows[0].Attempts, rows[0].LastError)
+	}
+	if rows[1].Attempts != 0 {
+		t.Errorf("second event attempted %d times while the first was pending", rows[1].Attempts)
+	}
+
+	broker.SetErr(nil)
+	if n, err := relay.RelayBatch(context.Background()); err != nil || n != 2 {
+		t.Fatalf("RelayBatch after recovery = %d, %v; want 2, nil", n, err)
+	}
+	assertPublished(t, broker, events)
+}
+
+// flakyBroker refuses one message ID, the others go to the memory broker.
+type flakyBroker struct {
+	*MemoryBroker
+	refuse string
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, routingKey, messageID string, body []byte) error {
+	if messageID == b.refuse {
+		return errors.New("nack")
+	}
+	return b.MemoryBroker.Publish(ctx, routingKey, messageID, body)
+}
+
+func TestRelayStopsAtFirstFailure(t *testing.T) {
+	db := newOutboxDB(t)
+	events := recordEvents(t, db, "a", "b", "c")
+	broker := &flakyBroker{MemoryBroker: NewMemoryBroker(), refuse: events[1].ID}
+	relay := newTestRelay(db, broker)
+
+	if n, err := relay.RelayBatch(context.Background()); err != nil || n != 1 {
+		t.Fatalf("RelayBatch = %d, %v; want 1, nil", n, err)
+	}
+	assertPublished(t, broker.MemoryBroker, events[:1])
+
+	broker.refuse = ""
+	if n, err := relay.RelayBatch(context.Background()); err != nil || n != 2 {
+		t.Fatalf("RelayBatch = %d, %v; want 2, nil", n, err)
+	}
+	assertPublished(t, broker.MemoryBroker, events)
+}
+
+func TestRelayBatchSize(t *testing.T) {
+	db := newOutboxDB(t)
+	broker := NewMemoryBroker()
+	relay := newTestRelay(db, broker)
+	relay.BatchSize = 2
+	events := recordEvents(t, db, "a", "b", "c")
+
+	if n, _ := relay.RelayBatch(context.Background()); n != 2 {
+		t.Fatalf("first batch = %d, want 2", n)
+	}
+	if n, _ := relay.RelayBatch(context.Background()); n != 1 {
+		t.Fatalf("second batch = %d, want 1", n)
+	}
+	assertPublished(t, broker, events)
+}
+
+func TestRecordFollowsTheTransaction(t *testing.T) {
+	db := newOutboxDB(t)
+	ctx := WithActor(context.Background(), Actor{Type: ActorUser, ID: "user-1", Email: "a@example.com"})
+
+	// le changement échoue : l'événement ne doit jamais être publié
+	_ = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := Record(tx, New("project.created", "p", nil)); err != nil {
+			t.Fatal(err)
+		}
+		return errors.New("rollback")
+	})
+	if rows := pendingEvents(t, db); len(rows) != 0 {
+		t.Fatalf("%d events recorded by a rolled back transaction", len(rows))
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return Record(tx, New("project.created", "p", nil))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := pendingEvents(t, db)
+	if len(rows) != 1 {
+		t.Fatalf("%d events recorded, want 1", len(rows))
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(rows[0].Payload), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Actor == nil || e.Actor.ID != "user-1" {
+		t.Errorf("actor = %+v, want the caller of the context", e.Actor)
+	}
+}
+
+func TestRelayPurgesOldPublishedEvents(t *testing.T) {
+	db := newOutboxDB(t)
+	relay := newTestRelay(db, NewMemoryBroker())
+	recordEvents(t, db, "old", "recent", "pending")
+
+	old := time.Now().Add(-2 * relay.Retention)
+	recent := time.Now()
+	db.Model(&OutboxEvent{}).Where("type = ?", "old").Update("published_at", &old)
+	db.Model(&OutboxEvent{}).Where("type = ?", "recent").Update("published_at", &recent)
+
+	if err := relay.purge(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	db.Model(&OutboxEvent{}).Order("created_at ASC").Pluck("type", &left)
+	if len(left) != 2 || left[0] != "recent" || left[1] != "pending" {
+		t.Errorf("outbox after purge = %v, want [recent pending]", left)
+	}
+}
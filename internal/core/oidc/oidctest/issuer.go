@@ -0,0 +1,210 @@
+// Package oidctest runs a local OpenID Connect issuer for tests: discovery, JWKS and an
+// authorization-code token endpoint checking PKCE, with ID tokens signed by an RSA key.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer is the mock identity provider. Mutate, when set, edits the claims of every ID token
+// before it is signed, to produce tokens the relying party must refuse.
+type Issuer struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	Mutate       func(claims jwt.MapClaims)
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	kid    string
+	grants map[string]grant
+}
+
+// grant is an authorization code waiting for the token request.
+type grant struct {
+	claims    jwt.MapClaims
+	nonce     string
+	challenge string
+}
+
+// New starts the issuer, it is stopped at the end of the test.
+func New(t testing.TB, clientID, clientSecret string) *Issuer {
+	t.Helper()
+	i := &Issuer{ClientID: clientID, ClientSecret: clientSecret, grants: map[string]grant{}}
+	if err := i.RotateKey(); err != nil {
+		t.Fatalf("oidctest: generate key: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", i.discovery)
+	mux.HandleFunc("/jwks", i.jwks)
+	mux.HandleFunc("/token", i.token)
+	i.Server = httptest.NewServer(mux)
+	t.Cleanup(i.Server.Close)
+	return i
+}
+
+// URL is the issuer identifier, to put in the provider configuration.
+func (i *Issuer) URL() string {
+	return i.Server.URL
+}
+
+// RotateKey replaces the signing key, the previous one disappears from the JWKS.
+func (i *Issuer) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.key = key
+	i.kid = randomID()
+	return nil
+}
+
+// Authorize plays the browser and the login page: it reads the authorization request built
+// by the relying party and returns the code and state of the redirection to its callback.
+// The claims are those of the authenticated user (sub, email, groups, amr...).
+func (i *Issuer) Authorize(authURL string, claims map[string]interface{}) (code, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	switch {
+	case q.Get("client_id") != i.ClientID:
+		return "", "", errors.New("oidctest: unknown client_id")
+	case q.Get("response_type") != "code":
+		return "", "", errors.New("oidctest: response_type must be code")
+	case q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "":
+		return "", "", errors.New("oidctest: PKCE S256 challenge required")
+	case q.Get("nonce") == "" || q.Get("state") == "":
+		return "", "", errors.New("oidctest: nonce and state required")
+	}
+
+	code = randomID()
+	i.mu.Lock()
+	i.grants[code] = grant{claims: jwt.MapClaims(claims), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	i.mu.Unlock()
+	return code, q.Get("state"), nil
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.URL(),
+		"authorization_endpoint": i.URL() + "/authorize",
+		"token_endpoint":         i.URL() + "/token",
+		"jwks_uri":               i.URL() + "/jwks",
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, _ *http.Request) {
+	i.mu.Lock()
+	pub, kid := i.key.PublicKey, i.kid
+	i.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != i.ClientID || secret != i.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	// un code ne sert qu'une fois
+	i.mu.Lock()
+	g, ok := i.grants[r.PostForm.Get("code")]
+	delete(i.grants, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	if !ok || s256(r.PostForm.Get("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := i.sign(g)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomID(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (i *Issuer) sign(g grant) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   i.URL(),
+		"aud":   i.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": g.nonce,
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+	if i.Mutate != nil {
+		i.Mutate(claims)
+	}
+
+	i.mu.Lock()
+	key, kid := i.key, i.kid
+	i.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,232 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+)
+
+var ErrUnknownProvider = errors.New("unknown oidc provider")
+
+// Claims is the part of the ID token used to provision the user.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified *bool // nil quand l'IdP n'émet pas le claim
+	Name          string
+	Groups        []string
+	AMR           []string
+}
+
+// Registry resolves the configured providers. Discovery is done on first use so that an
+// unreachable IdP does not prevent the API from starting.
+type Registry struct {
+	cfg        configs.OIDCConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*Provider
+}
+
+func NewRegistry(cfg configs.OIDCConfig, httpClient *http.Client) *Registry {
+	return &Registry{
+		cfg:        cfg,
+		httpClient: httpClient,
+		providers:  make(map[string]*Provider),
+	}
+}
+
+// Config returns the configuration of a provider.
+func (r *Registry) Config(name string) (configs.OIDCProviderConfig, bool) {
+	cfg, ok := r.cfg.Providers[name]
+	return cfg, ok
+}
+
+func (r *Registry) Get(ctx context.Context, name string) (*Provider, error) {
+	cfg, ok := r.cfg.Providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.providers[name]; ok {
+		return p, nil
+	}
+
+	p, err := discover(ctx, name, cfg, r.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	r.providers[name] = p
+	return p, nil
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is one discovered identity provider.
+type Provider struct {
+	Name       string
+	cfg        configs.OIDCProviderConfig
+	oauth      *oauth2.Config
+	issuer     string
+	httpClient *http.Client
+	keys       *keySet
+}
+
+func discover(ctx context.Context, name string, cfg configs.OIDCProviderConfig, httpClient *http.Client) (*Provider, error) {
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var doc discoveryDocument
+	if err := getJSON(ctx, httpClient, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", name, err)
+	}
+
+	// l'émetteur annoncé doit être celui configuré (OpenID Connect Discovery §4.3)
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc discovery for %s: issuer mismatch, got %q", name, doc.Issuer)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		Name: name,
+		cfg:  cfg,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  doc.AuthorizationEndpoint,
+				TokenURL: doc.TokenEndpoint,
+			},
+		},
+		issuer:     doc.Issuer,
+		httpClient: httpClient,
+		keys:       newKeySet(doc.JWKSURI, httpClient),
+	}, nil
+}
+
+// AuthCodeURL builds the authorization request, with the PKCE challenge derived from verifier.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+}
+
+// Exchange redeems the authorization code and returns the verified ID token claims.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, rawIDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	mapClaims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, mapClaims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.keys.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if got, _ := mapClaims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+
+	claims := &Claims{
+		Subject: stringClaim(mapClaims, "sub"),
+		Email:   strings.ToLower(stringClaim(mapClaims, "email")),
+		Name:    stringClaim(mapClaims, "name"),
+		AMR:     stringsClaim(mapClaims, "amr"),
+	}
+	if v, ok := mapClaims["email_verified"].(bool); ok {
+		claims.EmailVerified = &v
+	}
+
+	groupsClaim := p.cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	claims.Groups = stringsClaim(mapClaims, groupsClaim)
+
+	return claims, nil
+}
+
+func stringClaim(c jwt.MapClaims, name string) string {
+	v, _ := c[name].(string)
+	return v
+}
+
+// stringsClaim accepts a JSON array or a single string, both are found in the wild.
+func stringsClaim(c jwt.MapClaims, name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
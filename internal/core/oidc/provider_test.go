@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/core/oidc/oidctest"
+)
+
+func newTestProvider(t *testing.T, issuer *oidctest.Issuer, groupsClaim string) *Provider {
+	t.Helper()
+	registry := NewRegistry(configs.OIDCConfig{Providers: map[string]configs.OIDCProviderConfig{
+		"corp": {
+			Issuer:       issuer.URL() + "/",
+			ClientID:     issuer.ClientID,
+			ClientSecret: issuer.ClientSecret,
+			RedirectURL:  "https://kubemanager.test/kmanager/v1/auth/oidc/corp/callback",
+			GroupsClaim:  groupsClaim,
+		},
+	}}, issuer.Server.Client())
+
+	p, err := registry.Get(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+	return p
+}
+
+// login runs the browser part of the flow and redeems the code like the callback does.
+func login(t *testing.T, p *Provider, issuer *oidctest.Issuer, claims map[string]interface{}) (*Claims, error) {
+	t.Helper()
+	verifier := oauth2.GenerateVerifier()
+	code, state, err := issuer.Authorize(p.AuthCodeURL("state-1", "nonce-1", verifier), claims)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+	return p.Exchange(context.Background(), code, verifier, "nonce-1")
+}
+
+func TestExchangeReturnsVerifiedClaims(t *testing.T) {
+	issuer := oidctest.New(t, "kubemanager", "secret")
+	p := newTestProvider(t, issuer, "roles")
+
+	claims, err := login(t, p, issuer, map[string]interface{}{
+		"sub":            "user-42",
+		"email":          "Zoe@Example.com",
+		"email_verified": true,
+		"name":           "Zoé",
+		"roles":          []string{"platform", "ops"},
+		"amr":            "mfa",
+	})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "user-42" || claims.Email != "zoe@example.com" || claims.Name != "Zoé" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Errorf("email_verified = %v, want true", claims.EmailVerified)
+	}
+	if strings.Join(claims.Groups, ",") != "platform,ops" {
+		t.Errorf("groups = %v, read from the configured claim", claims.Groups)
+	}
+	if len(claims.AMR) != 1 || claims.AMR[0] != "mfa" {
+		t.Errorf("amr = %v", claims.AMR)
+	}
+}
+
+func TestExchangeWithoutEmailVerifiedClaim(t *testing.T) {
+	issuer := oidctest.New(t, "kubemanager", "secret")
+	p := newTestProvider(t, issuer, "")
+
+	claims, err := login(t, p, issuer, map[string]interface{}{"sub": "user-42", "email": "zoe@example.com"})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.EmailVerified != nil {
+		t.Errorf("email_verified = %v, want nil when the claim is absent", *claims.EmailVerified)
+	}
+}
+
+func TestExchangeRejectsInvalidTokens(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		want   string
+	}{
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "another-client" }, "audience"},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, "issuer"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "expired"},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, "exp"},
+		{"replayed nonce", func(c jwt.MapClaims) { c["nonce"] = "nonce-of-another-login" }, "nonce"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			issuer := oidctest.New(t, "kubemanager", "secret")
+			issuer.Mutate = tc.mutate
+			p := newTestProvider(t, issuer, "")
+
+			_, err := login(t, p, issuer, map[string]interface{}{"sub": "user-42", "email": "zoe@example.com"})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestExchangeChecksPKCEAndSingleUseCodes(t *testing.T) {
+	issuer := oidctest.New(t, "kubemanager", "secret")
+	p := newTestProvider(t, issuer, "")
+
+	verifier := oauth2.GenerateVerifier()
+	code, _, err := issuer.Authorize(p.AuthCodeURL("s", "n", verifier), map[string]interface{}{"sub": "u", "email": "u@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(context.Background(), code, oauth2.GenerateVerifier(), "n"); err == nil {
+		t.Error("code redeemed with another PKCE verifier")
+	}
+
+	code, _, _ = issuer.Authorize(p.AuthCodeURL("s", "n", verifier), map[string]interface{}{"sub": "u", "email": "u@example.com"})
+	if _, err := p.Exchange(context.Background(), code, verifier, "n"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), code, verifier, "n"); err == nil {
+		t.Error("code redeemed twice")
+	}
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	issuer := oidctest.New(t, "kubemanager", "secret")
+	p := newTestProvider(t, issuer, "")
+	claims := map[string]interface{}{"sub": "user-42", "email": "zoe@example.com"}
+
+	if _, err := login(t, p, issuer, claims); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if err := issuer.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	// le JWKS n'est pas rechargé plus d'une fois par minute sur un kid inconnu
+	if _, err := login(t, p, issuer, claims); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("err = %v, want an unknown signing key right after the previous refresh", err)
+	}
+
+	p.keys.mu.Lock()
+	p.keys.lastRefresh = time.Now().Add(-2 * jwksRefreshInterval)
+	p.keys.mu.Unlock()
+	if _, err := login(t, p, issuer, claims); err != nil {
+		t.Fatalf("login after rotation: %v", err)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":         "https://idp.example.com",
+			"token_endpoint": "https://idp.example.com/token",
+			"jwks_uri":       "https://idp.example.com/jwks",
+		})
+	}))
+	defer srv.Close()
+
+	registry := NewRegistry(configs.OIDCConfig{Providers: map[string]configs.OIDCProviderConfig{
+		"corp": {Issuer: srv.URL, ClientID: "kubemanager"},
+	}}, srv.Client())
+	if _, err := registry.Get(context.Background(), "corp"); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Errorf("err = %v, want an issuer mismatch", err)
+	}
+	if _, err := registry.Get(context.Background(), "other"); err != ErrUnknownProvider {
+		t.Errorf("err = %v, want ErrUnknownProvider", err)
+	}
+}
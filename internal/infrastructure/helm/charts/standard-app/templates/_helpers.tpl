@@ -0,0 +1,139 @@
+{{/* kind du workload : service | worker | job | cronjob */}}
+{{- define "standard-app.kind" -}}
+{{- .Values.kind | default "service" -}}
+{{- end }}
+
+{{/* conteneur commun au Deployment, au Job et au CronJob */}}
+{{- define "standard-app.container" -}}
+- name: {{ .Release.Name }}
+  {{- if .Values.image.digest }}
+  image: "{{ .Values.image.repository }}@{{ .Values.image.digest }}"
+  {{- else }}
+  image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+  {{- end }}
+  imagePullPolicy: {{ .Values.image.pullPolicy }}
+
+  {{- if eq (include "standard-app.kind" .) "service" }}
+  ports:
+    - containerPort: {{ .Values.service.targetPort | default 8080 }}
+  {{- end }}
+
+  #security container
+  securityContext:
+    allowPrivilegeEscalation: false
+    runAsNonRoot: true
+    runAsUser: 1000
+
+  # environment variables from a Secret
+  envFrom:
+    - secretRef:
+        name: {{ .Values.envSecretName }}
+    # secrets de connexion des add-ons liés, optionnels si l'add-on est supprimé
+    {{- range .Values.envSecrets }}
+    - secretRef:
+        name: {{ .name }}
+        optional: true
+      {{- if .prefix }}
+      prefix: {{ .prefix | quote }}
+      {{- end }}
+    {{- end }}
+
+  # environnement directes
+  {{- if .Values.envVars }}
+  env:
+    {{- range $key, $val := .Values.envVars }}
+    - name: {{ $key }}
+      value: {{ $val | quote }}
+    {{- end }}
+  {{- end }}
+
+  # volumes (persistants, emptyDir, tmpfs)
+  {{- with .Values.volumes }}
+  volumeMounts:
+    {{- range . }}
+    - name: {{ .name }}
+      mountPath: {{ .mountPath }}
+    {{- end }}
+  {{- end }}
+
+  # Ressources CPU / Mémoire
+  resources:
+    requests:
+      cpu: {{ .Values.resources.requests.cpu | default "100m" }}
+      memory: {{ .Values.resources.requests.memory | default "128Mi" }}
+    limits:
+      cpu: {{ .Values.resources.limits.cpu | default "200m" }}
+      memory: {{ .Values.resources.limits.memory | default "256Mi" }}
+
+  {{- if eq (include "standard-app.kind" .) "service" }}
+
+  # Liveness Probe
+  livenessProbe:
+    httpGet:
+      path: /health
+      port: {{ .Values.service.targetPort | default 8080 }}
+    initialDelaySeconds: 5
+    periodSeconds: 10
+
+  # Readiness Probe
+  readinessProbe:
+    httpGet:
+      path: /health
+      port: {{ .Values.service.targetPort | default 8080 }}
+    initialDelaySeconds: 2
+    periodSeconds: 5
+  {{- end }}
+{{- end }}
+
+{{/* secrets de pull du registre des builds */}}
+{{- define "standard-app.imagePullSecrets" -}}
+{{- with .Values.imagePullSecrets }}
+imagePullSecrets:
+  {{- range . }}
+  - name: {{ . }}
+  {{- end }}
+{{- end }}
+{{- end }}
+
+{{/* statement des volumes du pod */}}
+{{- define "standard-app.volumes" -}}
+{{- with .Values.volumes }}
+volumes:
+  {{- range . }}
+  - name: {{ .name }}
+    {{- if eq .type "persistent" }}
+    persistentVolumeClaim:
+      claimName: {{ .claimName }}
+    {{- else if or (eq .type "tmpfs") .size }}
+    emptyDir:
+      {{- if eq .type "tmpfs" }}
+      medium: Memory
+      {{- end }}
+      {{- if .size }}
+      sizeLimit: {{ .size | quote }}
+      {{- end }}
+    {{- else }}
+    emptyDir: {}
+    {{- end }}
+  {{- end }}
+{{- end }}
+{{- end }}
+
+{{/* spec du Job, partagée avec le jobTemplate du CronJob */}}
+{{- define "standard-app.jobSpec" -}}
+parallelism: {{ .Values.replicaCount }}
+backoffLimit: {{ .Values.batch.backoffLimit }}
+{{- if .Values.batch.ttlSecondsAfterFinished }}
+ttlSecondsAfterFinished: {{ .Values.batch.ttlSecondsAfterFinished }}
+{{- end }}
+template:
+  metadata:
+    labels:
+      app: {{ .Release.Name }}
+  spec:
+    restartPolicy: {{ .Values.batch.restartPolicy | default "Never" }}
+    {{- include "standard-app.imagePullSecrets" . | nindent 4 }}
+    containers:
+      {{- include "standard-app.container" . | nindent 6 }}
+    {{- include "standard-app.volumes" . | nindent 4 }}
+{{- end }}
@@ -62,6 +62,12 @@ func (a *App) Run(ctx context.Context) error {
 
 	a.Temporal.Worker = workerManager.Start()
 
+	stopRelay := a.startEventRelay(ctx)
+	defer stopRelay()
+
+	stopAPIKeyJobs := a.startAPIKeyJobs(ctx)
+	defer stopAPIKeyJobs()
+
 	a.startHTTPServer()
 
 	a.gracefulShutdown(a.Servers.GRPC, a.Servers.HTTP, a.Config.Server.ShutdownTimeout)
@@ -1,43 +1,98 @@
 package router
 
 import (
+	"github.com/thekrauss/kubemanager/internal/modules/addons"
+	addonRepos "github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+	addonSvc "github.com/thekrauss/kubemanager/internal/modules/addons/service"
 	controller "github.com/thekrauss/kubemanager/internal/modules/auth"
 	authRepos "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
 	authSvc "github.com/thekrauss/kubemanager/internal/modules/auth/service"
+	"github.com/thekrauss/kubemanager/internal/modules/builds"
+	buildRepos "github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	buildSvc "github.com/thekrauss/kubemanager/internal/modules/builds/service"
+	"github.com/thekrauss/kubemanager/internal/modules/executions"
+	executionSvc "github.com/thekrauss/kubemanager/internal/modules/executions/service"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync"
+	gitSyncRepos "github.com/thekrauss/kubemanager/internal/modules/gitsync/repository"
+	gitSyncSvc "github.com/thekrauss/kubemanager/internal/modules/gitsync/service"
+	mailSvc "github.com/thekrauss/kubemanager/internal/modules/mailer/service"
 	"github.com/thekrauss/kubemanager/internal/modules/projects"
 	projectRepos "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
 	projectSvc "github.com/thekrauss/kubemanager/internal/modules/projects/service"
+	"github.com/thekrauss/kubemanager/internal/modules/templates"
+	templateRepos "github.com/thekrauss/kubemanager/internal/modules/templates/repository"
+	templateSvc "github.com/thekrauss/kubemanager/internal/modules/templates/service"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks"
+	webhookRepos "github.com/thekrauss/kubemanager/internal/modules/webhooks/repository"
+	webhookSvc "github.com/thekrauss/kubemanager/internal/modules/webhooks/service"
 	workload "github.com/thekrauss/kubemanager/internal/modules/workloads"
 	workloadsRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
 	workloadsSvc "github.com/thekrauss/kubemanager/internal/modules/workloads/service"
 )
 
 type RepositoryContainer struct {
-	Auth     authRepos.AuthRepository
-	Project  projectRepos.ProjectRepository
-	Workload workloadsRepo.WorkloadRepository
+	Auth          authRepos.AuthRepository
+	Project       projectRepos.ProjectRepository
+	NetworkPolicy projectRepos.NetworkPolicyRepository
+	Workload      workloadsRepo.WorkloadRepository
+	Snapshot      workloadsRepo.SnapshotRepository
+	Addon         addonRepos.AddonRepository
+	Template      templateRepos.TemplateRepository
+	GitSource     gitSyncRepos.GitSourceRepository
+	Build         buildRepos.BuildRepository
+	Webhook       webhookRepos.WebhookRepository
 }
 
 type ServiceContainer struct {
-	Auth     authSvc.IAuthService
-	RBAC     authSvc.IRBACService
-	APIKey   authSvc.IAPIKeyService
-	Project  projectSvc.IProjectService
-	Workload *workloadsSvc.WorkloadService
+	Auth      authSvc.IAuthService
+	RBAC      authSvc.IRBACService
+	APIKey    authSvc.IAPIKeyService
+	Project   projectSvc.IProjectService
+	Manifest  projectSvc.IManifestService
+	Workload  *workloadsSvc.WorkloadService
+	Execution executionSvc.IExecutionService
+	Addon     addonSvc.IAddonService
+	Template  templateSvc.ITemplateService
+	GitSync   gitSyncSvc.IGitSyncService
+	Build     buildSvc.IBuildService
+	Webhook   webhookSvc.IWebhookService
+	Mail      mailSvc.IMailService
 }
 
 type ControllerContainer struct {
-	Auth     controller.IAuthController
-	APIKey   controller.IAPIKeyController
-	RBAC     controller.IRBACController
-	Project  projects.IProjectController
-	Workload workload.IWorkloadController
+	Auth      controller.IAuthController
+	APIKey    controller.IAPIKeyController
+	Session   controller.ISessionController
+	MFA       controller.IMFAController
+	OIDC      controller.IOIDCController
+	Accounts  controller.IServiceAccountController
+	Invites   controller.IInvitationController
+	RBAC      controller.IRBACController
+	Project   projects.IProjectController
+	Workload  workload.IWorkloadController
+	Execution executions.IExecutionController
+	Addon     addons.IAddonController
+	Template  templates.ITemplateController
+	GitSync   gitsync.IGitSyncController
+	Build     builds.IBuildController
+	Webhook   webhooks.IWebhookController
 }
 
 func AddAllRoutes(a *App) {
 	addAuthRoutes(a)
 	addRBACRoutes(a)
 	addAPIKeyRoutes(a)
+	addSessionRoutes(a)
+	addMFARoutes(a)
+	addOIDCRoutes(a)
 	addProjectRoutes(a)
+	addServiceAccountRoutes(a)
+	addInvitationRoutes(a)
 	addWorkloadRoutes(a)
+	addWorkflowRoutes(a)
+	addAddonRoutes(a)
+	addTemplateRoutes(a)
+	addGitSyncRoutes(a)
+	addBuildRoutes(a)
+	addWebhookRoutes(a)
 }
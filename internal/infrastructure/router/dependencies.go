@@ -11,11 +11,23 @@ import (
 	"go.temporal.io/sdk/client"
 
 	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/events"
 	"github.com/thekrauss/kubemanager/internal/infrastructure/database"
 	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	addondomain "github.com/thekrauss/kubemanager/internal/modules/addons/domain"
+	addonRepos "github.com/thekrauss/kubemanager/internal/modules/addons/repository"
 	authdomain "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	builddomain "github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	buildRepos "github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	gitdomain "github.com/thekrauss/kubemanager/internal/modules/gitsync/domain"
+	gitSyncRepos "github.com/thekrauss/kubemanager/internal/modules/gitsync/repository"
+	projectdomain "github.com/thekrauss/kubemanager/internal/modules/projects/domain"
 	projectRepos "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	tpldomain "github.com/thekrauss/kubemanager/internal/modules/templates/domain"
+	templateRepos "github.com/thekrauss/kubemanager/internal/modules/templates/repository"
+	webhookdomain "github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+	webhookRepos "github.com/thekrauss/kubemanager/internal/modules/webhooks/repository"
 	wkldomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
 	workloadsRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
 )
@@ -90,11 +102,25 @@ func (a *App) initDatabase() error {
 		&authdomain.User{},
 		&authdomain.Project{},
 		&authdomain.UserSession{},
+		&authdomain.MFARecoveryCode{},
+		&authdomain.UserIdentity{},
 		&authdomain.ProjectMember{},
+		&authdomain.ProjectInvitation{},
+		&projectdomain.NetworkPolicy{},
 		&authdomain.Role{},
 		&authdomain.Permission{},
 		&authdomain.APIKey{},
 		&wkldomain.Workload{},
+		&wkldomain.WorkloadVolume{},
+		&wkldomain.VolumeSnapshot{},
+		&addondomain.Addon{},
+		&addondomain.AddonBinding{},
+		&tpldomain.WorkloadTemplate{},
+		&gitdomain.GitSource{},
+		&builddomain.Build{},
+		&webhookdomain.Webhook{},
+		&webhookdomain.WebhookDelivery{},
+		&events.OutboxEvent{},
 	)
 	if err != nil {
 		return fmt.Errorf("auto-migration failed: %w", err)
@@ -131,12 +157,26 @@ func (a *App) initRepositories() {
 	a.Logger.Info("itializing repositories...")
 	authRepo := repository.NewAuthRepository(a.DB)
 	projectRepo := projectRepos.NewProjectRepository(a.DB)
+	netPolRepo := projectRepos.NewNetworkPolicyRepository(a.DB)
 	workloadRepo := workloadsRepo.NewWorkloadRepository(a.DB)
+	snapshotRepo := workloadsRepo.NewSnapshotRepository(a.DB)
+	addonRepo := addonRepos.NewAddonRepository(a.DB)
+	templateRepo := templateRepos.NewTemplateRepository(a.DB)
+	gitSourceRepo := gitSyncRepos.NewGitSourceRepository(a.DB)
+	buildRepo := buildRepos.NewBuildRepository(a.DB)
+	webhookRepo := webhookRepos.NewWebhookRepository(a.DB)
 
 	a.Repos = &RepositoryContainer{
-		Auth:     authRepo,
-		Project:  projectRepo,
-		Workload: workloadRepo,
+		Auth:          authRepo,
+		Project:       projectRepo,
+		NetworkPolicy: netPolRepo,
+		Workload:      workloadRepo,
+		Snapshot:      snapshotRepo,
+		Addon:         addonRepo,
+		Template:      templateRepo,
+		GitSource:     gitSourceRepo,
+		Build:         buildRepo,
+		Webhook:       webhookRepo,
 	}
 }
 
@@ -1,11 +1,29 @@
 package router
 
 import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/core/oidc"
 	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	addonCtrl "github.com/thekrauss/kubemanager/internal/modules/addons"
+	addonSvc "github.com/thekrauss/kubemanager/internal/modules/addons/service"
 	authCtrl "github.com/thekrauss/kubemanager/internal/modules/auth"
 	authSvc "github.com/thekrauss/kubemanager/internal/modules/auth/service"
+	buildCtrl "github.com/thekrauss/kubemanager/internal/modules/builds"
+	buildSvc "github.com/thekrauss/kubemanager/internal/modules/builds/service"
+	executionCtrl "github.com/thekrauss/kubemanager/internal/modules/executions"
+	executionSvc "github.com/thekrauss/kubemanager/internal/modules/executions/service"
+	gitSyncCtrl "github.com/thekrauss/kubemanager/internal/modules/gitsync"
+	gitSyncSvc "github.com/thekrauss/kubemanager/internal/modules/gitsync/service"
+	mailSvc "github.com/thekrauss/kubemanager/internal/modules/mailer/service"
 	projectCtrl "github.com/thekrauss/kubemanager/internal/modules/projects"
 	projectSvc "github.com/thekrauss/kubemanager/internal/modules/projects/service"
+	templateCtrl "github.com/thekrauss/kubemanager/internal/modules/templates"
+	templateSvc "github.com/thekrauss/kubemanager/internal/modules/templates/service"
+	webhookCtrl "github.com/thekrauss/kubemanager/internal/modules/webhooks"
+	webhookSvc "github.com/thekrauss/kubemanager/internal/modules/webhooks/service"
 	workloadsCtrl "github.com/thekrauss/kubemanager/internal/modules/workloads"
 	workloadsSvc "github.com/thekrauss/kubemanager/internal/modules/workloads/service"
 )
@@ -14,33 +32,80 @@ func (a *App) initDomainLayers() error {
 	a.Logger.Info("Initializing domain layers...")
 
 	hasher := security.NewPasswordHasher()
-	apiKeyService := authSvc.NewAPIKeyService(a.Repos.Auth)
-	rbacService := authSvc.NewRBACService(a.Repos.Auth, a.Logger)
-	authService := authSvc.NewAuthService(a.Config, a.Repos.Auth, a.Security.JWTManager, a.Cache, a.Logger, hasher)
+	// le RBAC émet member.added vers les webhooks du projet
+	webhookService := webhookSvc.NewWebhookService(a.Temporal.Client, a.Config, a.Logger, a.Repos.Webhook, a.Repos.Project)
+	rbacService := authSvc.NewRBACService(a.Repos.Auth, a.Cache, a.Logger, webhookService)
+	mailService := mailSvc.NewMailService(a.Temporal.Client, a.Config, a.Logger)
+	apiKeyService := authSvc.NewAPIKeyService(a.Repos.Auth, a.Config, mailService, a.Logger)
+	authService := authSvc.NewAuthService(a.Config, a.Repos.Auth, a.Security.JWTManager, a.Cache, a.Logger, hasher, mailService)
+	oidcProviders := oidc.NewRegistry(a.Config.OIDC, &http.Client{Timeout: 10 * time.Second})
+	oidcService := authSvc.NewOIDCService(authService, rbacService, oidcProviders, a.Logger)
+	serviceAccountService := authSvc.NewServiceAccountService(a.Repos.Auth, apiKeyService, a.Logger)
+	invitationService := authSvc.NewInvitationService(a.Repos.Auth, a.Config, mailService, webhookService, a.Logger)
 
 	projectService := projectSvc.NewProjectService(a.Temporal.Client, a.Config, a.Logger, a.Repos.Project, a.K8sProvider.Client)
-	workloadService := workloadsSvc.NewWorkloadService(a.Temporal.Client, a.Repos.Workload, a.Repos.Project)
+	manifestService := projectSvc.NewManifestService(a.Temporal.Client, a.Config, a.Logger, a.Repos.Project, a.Repos.NetworkPolicy, a.Repos.Auth, a.Repos.Workload, a.Repos.Addon)
+	workloadService := workloadsSvc.NewWorkloadService(a.Temporal.Client, a.Config, a.Repos.Workload, a.Repos.Project, a.Repos.Snapshot, a.Repos.Addon)
+	executionService := executionSvc.NewExecutionService(a.Temporal.Client, a.Logger)
+	addonService := addonSvc.NewAddonService(a.Temporal.Client, a.Config, a.Logger, a.Repos.Addon, a.Repos.Project, a.Repos.Workload)
+	templateService := templateSvc.NewTemplateService(a.Config, a.Logger, a.Repos.Template, a.Repos.Project, workloadService)
+	gitSyncService := gitSyncSvc.NewGitSyncService(a.Temporal.Client, a.Config, a.Logger, a.Repos.GitSource, a.Repos.Project)
+	registryTokens, err := security.NewRegistryTokenSigner(a.Config.Build)
+	if err != nil {
+		return fmt.Errorf("failed to load the registry token key: %w", err)
+	}
+	buildService := buildSvc.NewBuildService(a.Temporal.Client, a.Config, a.Logger, a.Repos.Build, a.Repos.Workload, a.K8sProvider.Client, registryTokens)
 
 	authController := authCtrl.NewAuthController(authService, rbacService)
-	rbacController := authCtrl.NewRBACController(rbacService)
+	rbacController := authCtrl.NewRBACController(rbacService, a.Config)
 	apiKeyController := authCtrl.NewAPIKeyController(apiKeyService)
-	projectController := projectCtrl.NewProjectHandlers(projectService)
+	sessionController := authCtrl.NewSessionController(authService)
+	mfaController := authCtrl.NewMFAController(authService)
+	oidcController := authCtrl.NewOIDCController(oidcService)
+	serviceAccountController := authCtrl.NewServiceAccountController(serviceAccountService)
+	invitationController := authCtrl.NewInvitationController(invitationService)
+	projectController := projectCtrl.NewProjectHandlers(projectService, manifestService)
 	workloadController := workloadsCtrl.NewWorkloadHandler(workloadService)
+	executionController := executionCtrl.NewExecutionHandler(executionService)
+	addonController := addonCtrl.NewAddonHandler(addonService)
+	templateController := templateCtrl.NewTemplateHandler(templateService)
+	gitSyncController := gitSyncCtrl.NewGitSyncHandler(gitSyncService)
+	buildController := buildCtrl.NewBuildHandler(buildService)
+	webhookController := webhookCtrl.NewWebhookHandler(webhookService)
 
 	a.Services = &ServiceContainer{
-		Auth:     authService,
-		RBAC:     rbacService,
-		APIKey:   apiKeyService,
-		Project:  projectService,
-		Workload: workloadService,
+		Auth:      authService,
+		RBAC:      rbacService,
+		APIKey:    apiKeyService,
+		Project:   projectService,
+		Manifest:  manifestService,
+		Workload:  workloadService,
+		Execution: executionService,
+		Addon:     addonService,
+		Template:  templateService,
+		GitSync:   gitSyncService,
+		Build:     buildService,
+		Webhook:   webhookService,
+		Mail:      mailService,
 	}
 
 	a.Controllers = &ControllerContainer{
-		Auth:     authController,
-		RBAC:     rbacController,
-		APIKey:   apiKeyController,
-		Project:  projectController,
-		Workload: workloadController,
+		Auth:      authController,
+		RBAC:      rbacController,
+		APIKey:    apiKeyController,
+		Session:   sessionController,
+		MFA:       mfaController,
+		OIDC:      oidcController,
+		Accounts:  serviceAccountController,
+		Invites:   invitationController,
+		Project:   projectController,
+		Workload:  workloadController,
+		Execution: executionController,
+		Addon:     addonController,
+		Template:  templateController,
+		GitSync:   gitSyncController,
+		Build:     buildController,
+		Webhook:   webhookController,
 	}
 
 	a.Logger.Info("Domain layers successfully initialized.")
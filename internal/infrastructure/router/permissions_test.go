@@ -0,0 +1,374 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wI2L/fizz"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	addonCtrl "github.com/thekrauss/kubemanager/internal/modules/addons"
+	authCtrl "github.com/thekrauss/kubemanager/internal/modules/auth"
+	authDomain "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	authRepos "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	buildCtrl "github.com/thekrauss/kubemanager/internal/modules/builds"
+	executionCtrl "github.com/thekrauss/kubemanager/internal/modules/executions"
+	gitSyncCtrl "github.com/thekrauss/kubemanager/internal/modules/gitsync"
+	projectCtrl "github.com/thekrauss/kubemanager/internal/modules/projects"
+	projectRepos "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	templateCtrl "github.com/thekrauss/kubemanager/internal/modules/templates"
+	webhookCtrl "github.com/thekrauss/kubemanager/internal/modules/webhooks"
+	workloadsCtrl "github.com/thekrauss/kubemanager/internal/modules/workloads"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	workloadsRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+)
+
+const platformAdmin = "PLATFORM_ADMIN"
+
+var (
+	matrixProject  = uuid.New()
+	matrixWorkload = uuid.New()
+	matrixUser     = uuid.New()
+
+	registerOnce sync.Once
+	matrixEngine *gin.Engine
+)
+
+// matrixAuthRepo gives the user the role under test in matrixProject and nothing elsewhere.
+type matrixAuthRepo struct {
+	authRepos.AuthRepository
+	role string
+}
+
+func (r *matrixAuthRepo) GetProjectRoleName(_ context.Context, projectID, userID uuid.UUID) (string, error) {
+	if r.role == "" || projectID != matrixProject || userID != matrixUser {
+		return "", gorm.ErrRecordNotFound
+	}
+	return r.role, nil
+}
+
+func (r *matrixAuthRepo) GetRoleByName(_ context.Context, name string) (*authDomain.Role, error) {
+	for role, perms := range authDomain.DefaultRolePermissions() {
+		if role.String() != name {
+			continue
+		}
+		out := &authDomain.Role{Name: name, IsSystem: true}
+		for _, p := range perms {
+			out.Permissions = append(out.Permissions, authDomain.Permission{Slug: p.String()})
+		}
+		return out, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *matrixAuthRepo) GetProjectMFAPolicy(context.Context, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+// matrixCache never holds role permissions, so each request reads them from the repository.
+type matrixCache struct {
+	cache.CacheRedis
+}
+
+func (matrixCache) GetRolePermissions(context.Context, string) ([]string, error) {
+	return nil, errors.New("miss")
+}
+
+func (matrixCache) SetRolePermissions(context.Context, string, []string, time.Duration) error {
+	return nil
+}
+
+type matrixWorkloads struct {
+	workloadsRepo.WorkloadRepository
+}
+
+func (matrixWorkloads) GetByID(_ context.Context, id uuid.UUID) (*workloadDomain.Workload, error) {
+	if id != matrixWorkload {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &workloadDomain.Workload{ID: id, ProjectID: matrixProject}, nil
+}
+
+type matrixProjects struct {
+	projectRepos.ProjectRepository
+}
+
+func (matrixProjects) GetProjectByID(_ context.Context, id string) (*authDomain.Project, error) {
+	if id != matrixProject.String() {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &authDomain.Project{ID: matrixProject}, nil
+}
+
+// permission attendue pour chaque route protégée, écrite à la main pour qu'un AddRight
+// modifié par erreur fasse échouer le test
+var expectedRights = map[string]authDomain.PermissionType{
+	"POST /rbac/assign-role":                                                  rights.MembersManage,
+	"GET /rbac/:projectID/members":                                            rights.ProjectView,
+	"DELETE /rbac/:projectID/members/:userID":                                 rights.MembersManage,
+	"GET /projects/:id/status":                                                rights.ProjectView,
+	"GET /projects/:id/metrics":                                               rights.ProjectView,
+	"DELETE /projects/:id":                                                    rights.ProjectDelete,
+	"PUT /projects/:id/mfa-policy":                                            rights.MembersManage,
+	"POST /projects/:id/retry":                                                rights.ProjectEdit,
+	"POST /projects/:id/apply":                                                rights.ProjectEdit,
+	"GET /projects/:id/service-accounts":                                      rights.ProjectView,
+	"POST /projects/:id/service-accounts":                                     rights.MembersManage,
+	"PUT /projects/:id/service-accounts/:accountID":                           rights.MembersManage,
+	"DELETE /projects/:id/service-accounts/:accountID":                        rights.MembersManage,
+	"GET /projects/:id/service-accounts/:accountID/api-keys":                  rights.MembersManage,
+	"POST /projects/:id/service-accounts/:accountID/api-keys":                 rights.MembersManage,
+	"POST /projects/:id/service-accounts/:accountID/api-keys/:keyID/rotate":   rights.MembersManage,
+	"DELETE /projects/:id/service-accounts/:accountID/api-keys/:keyID":        rights.MembersManage,
+	"POST /projects/:id/invitations":                                          rights.MembersManage,
+	"GET /projects/:id/invitations":                                           rights.MembersManage,
+	"DELETE /projects/:id/invitations/:invitationID":                          rights.MembersManage,
+	"POST /projects/:id/addons":                                               rights.WorkloadCreate,
+	"GET /projects/:id/addons":                                                rights.ProjectView,
+	"GET /projects/:id/addons/:addonID":                                       rights.ProjectView,
+	"DELETE /projects/:id/addons/:addonID":                                    rights.WorkloadDelete,
+	"POST /projects/:id/addons/:addonID/bindings":                             rights.WorkloadCreate,
+	"DELETE /projects/:id/addons/:addonID/bindings/:workloadID":               rights.WorkloadCreate,
+	"GET /projects/:id/templates":                                             rights.ProjectView,
+	"POST /projects/:id/templates":                                            rights.ProjectEdit,
+	"GET /projects/:id/templates/:name":                                       rights.ProjectView,
+	"PUT /projects/:id/templates/:name":                                       rights.ProjectEdit,
+	"DELETE /projects/:id/templates/:name":                                    rights.ProjectEdit,
+	"PUT /projects/:id/git-source":                                            rights.ProjectEdit,
+	"GET /projects/:id/git-source":                                            rights.ProjectView,
+	"DELETE /projects/:id/git-source":                                         rights.ProjectEdit,
+	"POST /projects/:id/git-source/sync":                                      rights.WorkloadCreate,
+	"POST /projects/:id/webhooks":                                             rights.ProjectEdit,
+	"GET /projects/:id/webhooks":                                              rights.ProjectView,
+	"PUT /projects/:id/webhooks/:webhookID":                                   rights.ProjectEdit,
+	"DELETE /projects/:id/webhooks/:webhookID":                                rights.ProjectEdit,
+	"GET /projects/:id/webhooks/:webhookID/deliveries":                        rights.ProjectView,
+	"POST /projects/:id/webhooks/:webhookID/deliveries/:deliveryID/redeliver": rights.ProjectEdit,
+	"GET /workloads/:id":                                                      rights.ProjectView,
+	"POST /workloads":                                                         rights.WorkloadCreate,
+	"POST /workloads/:id/cancel":                                              rights.WorkloadCreate,
+	"POST /workloads/:id/retry":                                               rights.WorkloadCreate,
+	"POST /workloads/:id/run":                                                 rights.WorkloadCreate,
+	"POST /workloads/:id/volume/resize":                                       rights.WorkloadCreate,
+	"POST /workloads/:id/snapshots":                                           rights.WorkloadCreate,
+	"GET /workloads/:id/snapshots":                                            rights.ProjectView,
+	"PUT /workloads/:id/snapshots/schedule":                                   rights.WorkloadCreate,
+	"DELETE /workloads/:id/snapshots/:snapshotID":                             rights.WorkloadDelete,
+	"POST /workloads/:id/snapshots/:snapshotID/restore":                       rights.WorkloadCreate,
+	"DELETE /workloads/:id":                                                   rights.WorkloadDelete,
+	"POST /workloads/from-template":                                           rights.WorkloadCreate,
+	"POST /workloads/:id/builds":                                              rights.WorkloadCreate,
+	"GET /workloads/:id/builds":                                               rights.ProjectView,
+	"GET /workloads/:id/builds/:buildID":                                      rights.ProjectView,
+	"GET /workloads/:id/builds/:buildID/logs":                                 rights.LogsView,
+	"GET /workflows/:id":                                                      rights.ProjectView,
+}
+
+// matrixRoute is a route guarded by a project permission, with its absolute path.
+type matrixRoute struct {
+	group *RouteGroup
+	route *Route
+	path  string
+}
+
+func guardedRoutes() []matrixRoute {
+	var out []matrixRoute
+	var walk func(g *RouteGroup, prefix string)
+	walk = func(g *RouteGroup, prefix string) {
+		for _, r := range g.Routes {
+			if r.Right != "" {
+				out = append(out, matrixRoute{group: g, route: r, path: prefix + r.Path})
+			}
+		}
+		for _, child := range g.Groups {
+			walk(child, prefix+child.Path)
+		}
+	}
+	for _, g := range AllGroups {
+		walk(g, g.Path)
+	}
+	return out
+}
+
+// setupMatrix registers every route once with a stub handler, the session of the request is
+// read from the X-Test-Role header.
+func setupMatrix(t *testing.T) *gin.Engine {
+	t.Helper()
+	registerOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		cfg := &configs.GlobalConfig{Roles: configs.RolesConfig{PlatformAdmin: platformAdmin}}
+		app := &App{
+			Config: cfg,
+			Logger: zap.NewNop().Sugar(),
+			Repos: &RepositoryContainer{
+				Workload: matrixWorkloads{},
+				Project:  matrixProjects{},
+			},
+			Controllers: &ControllerContainer{
+				Auth:      authCtrl.NewAuthController(nil, nil),
+				APIKey:    authCtrl.NewAPIKeyController(nil),
+				Session:   authCtrl.NewSessionController(nil),
+				MFA:       authCtrl.NewMFAController(nil),
+				OIDC:      authCtrl.NewOIDCController(nil),
+				Accounts:  authCtrl.NewServiceAccountController(nil),
+				Invites:   authCtrl.NewInvitationController(nil),
+				RBAC:      authCtrl.NewRBACController(nil, cfg),
+				Project:   projectCtrl.NewProjectHandlers(nil, nil),
+				Workload:  workloadsCtrl.NewWorkloadHandler(nil),
+				Execution: executionCtrl.NewExecutionHandler(nil),
+				Addon:     addonCtrl.NewAddonHandler(nil),
+				Template:  templateCtrl.NewTemplateHandler(nil),
+				GitSync:   gitSyncCtrl.NewGitSyncHandler(nil),
+				Build:     buildCtrl.NewBuildHandler(nil),
+				Webhook:   webhookCtrl.NewWebhookHandler(nil),
+			},
+		}
+		AddAllRoutes(app)
+
+		// seul le contrôle d'accès est testé, les handlers répondent 200 s'ils sont atteints
+		for _, r := range guardedRoutes() {
+			r.route.Handler = func(c *gin.Context) { c.Status(http.StatusOK) }
+		}
+
+		repo := &matrixAuthRepo{}
+		mw := &security.MiddlewareManager{
+			Config:    cfg,
+			CacheRepo: matrixCache{},
+			Logger:    app.Logger,
+			AuthRepo:  repo,
+		}
+
+		engine := gin.New()
+		engine.Use(func(c *gin.Context) {
+			role := c.GetHeader("X-Test-Role")
+			session := &cache.SessionData{UserID: matrixUser.String()}
+			if role == platformAdmin {
+				session.GlobalRole = platformAdmin
+			} else {
+				repo.role = role
+			}
+			c.Set(security.UserSessionKey, session)
+			c.Set(security.UserIDKey, session.UserID)
+			c.Next()
+		})
+		if err := RegisterRoutes(fizz.NewFromEngine(engine), mw); err != nil {
+			t.Fatalf("register routes: %v", err)
+		}
+		matrixEngine = engine
+	})
+	return matrixEngine
+}
+
+// requestPath fills the path parameters so that the resolver of the route finds matrixProject.
+func requestPath(r matrixRoute, project uuid.UUID) string {
+	parts := strings.Split(r.path, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, ":") {
+			continue
+		}
+		switch {
+		case part == ":projectID", part == ":id" && r.group == ProjectGroup:
+			parts[i] = project.String()
+		case part == ":id" && r.group == WorkloadGroup:
+			parts[i] = matrixWorkload.String()
+		case part == ":id" && r.group == WorkflowGroup:
+			parts[i] = "project-delete-" + project.String()
+		default:
+			parts[i] = uuid.NewString()
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func doMatrixRequest(engine *gin.Engine, r matrixRoute, role string, project uuid.UUID) int {
+	body := fmt.Sprintf(`{"project_id":%q}`, project)
+	req := httptest.NewRequest(r.route.Method, requestPath(r, project), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		req.Header.Set("X-Test-Role", role)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestRoutePermissionMatrix checks every guarded route against every built-in role: the
+// handler is reached only when the role holds the permission declared by the route.
+func TestRoutePermissionMatrix(t *testing.T) {
+	engine := setupMatrix(t)
+	routes := guardedRoutes()
+	if len(routes) == 0 {
+		t.Fatal("no guarded route registered")
+	}
+
+	seen := make(map[string]bool)
+	roles := authDomain.DefaultRolePermissions()
+	for _, r := range routes {
+		key := r.route.Method + " " + strings.TrimPrefix(r.path, PathAPIRoot)
+		perm, ok := expectedRights[key]
+		if !ok {
+			t.Errorf("%s: guarded route missing from the matrix", key)
+			continue
+		}
+		seen[key] = true
+		if r.route.Right != perm.String() {
+			t.Errorf("%s: declares %s, want %s", key, r.route.Right, perm)
+		}
+
+		for role, perms := range roles {
+			want := http.StatusForbidden
+			for _, p := range perms {
+				if p == perm {
+					want = http.StatusOK
+				}
+			}
+			if got := doMatrixRequest(engine, r, role.String(), matrixProject); got != want {
+				t.Errorf("%s %s as %s: got %d, want %d", r.route.Method, r.path, role, got, want)
+			}
+		}
+
+		if got := doMatrixRequest(engine, r, "", matrixProject); got != http.StatusForbidden {
+			t.Errorf("%s %s as non-member: got %d, want %d", r.route.Method, r.path, got, http.StatusForbidden)
+		}
+		if got := doMatrixRequest(engine, r, platformAdmin, matrixProject); got != http.StatusOK {
+			t.Errorf("%s %s as platform admin: got %d, want %d", r.route.Method, r.path, got, http.StatusOK)
+		}
+	}
+
+	for key := range expectedRights {
+		if !seen[key] {
+			t.Errorf("%s: expected a guarded route", key)
+		}
+	}
+}
+
+// TestRoutePermissionOtherProject checks that an owner of one project gets nothing on another.
+func TestRoutePermissionOtherProject(t *testing.T) {
+	engine := setupMatrix(t)
+	other := uuid.New()
+	owner := authDomain.RoleTypes.Owner.String()
+
+	for _, r := range guardedRoutes() {
+		// les routes /workloads/:id résolvent le projet depuis le workload, pas depuis la requête
+		if r.group == WorkloadGroup && strings.Contains(r.path, ":id") {
+			continue
+		}
+		got := doMatrixRequest(engine, r, owner, other)
+		if got != http.StatusForbidden && got != http.StatusNotFound {
+			t.Errorf("%s %s on another project: got %d, want 403 or 404", r.route.Method, r.path, got)
+		}
+	}
+}
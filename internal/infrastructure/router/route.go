@@ -1,17 +1,37 @@
 package router
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/loopfz/gadgeto/tonic"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	authDomain "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/projects"
 )
 
+// permissions exigées par les routes (AddRight)
+var rights = authDomain.PermissionTypes
+
 var (
 	AuthGroup     = RootGroup.NewGroup("/auth", "Gestion de l'authentification et des tokens")
 	RBACGroup     = RootGroup.NewGroup("/rbac", "Configuration globale des rôles")
-	ProjectGroup  = RootGroup.NewGroup("/projects", "Gestion des projets et de leurs membres")
+	ProjectGroup  = RootGroup.NewGroup("/projects", "Gestion des projets et de leurs membres").ResolveProject(security.ProjectFromParam("id"))
 	APIKeyGroup   = RootGroup.NewGroup("/users/api-keys", "Gestion des clés API utilisateur")
+	SessionGroup  = RootGroup.NewGroup("/users/me/sessions", "Sessions de connexion de l'utilisateur")
+	AdminGroup    = RootGroup.NewGroup("/admin/users", "Administration des comptes (admins plateforme)")
 	WorkloadGroup = RootGroup.NewGroup("/workloads", "Gestion des déploiements Helm (Workloads)")
+	WorkflowGroup = RootGroup.NewGroup("/workflows", "Suivi des workflows Temporal")
+	AddonGroup    = RootGroup.NewGroup("/addons", "Catalogue des add-ons managés")
+	TemplateGroup = RootGroup.NewGroup("/templates", "Templates de workloads globaux (édition réservée aux admins)")
+	GitSyncGroup  = RootGroup.NewGroup("/git-sync", "Webhooks de synchronisation Git (signés HMAC)")
+	InviteGroup   = RootGroup.NewGroup("/invitations", "Réponse aux invitations de projet (utilisateur connecté)")
+	RegistryGroup = RootGroup.NewGroup("/registry", "Authentification du registre d'images des builds (token auth)")
 )
 
 func addAuthRoutes(app *App) {
@@ -20,45 +40,266 @@ func addAuthRoutes(app *App) {
 	AuthGroup.AddRoute("/login", http.MethodPost, "Connexion", tonic.Handler(r.Login, http.StatusOK))
 	AuthGroup.AddRoute("/validate", http.MethodPost, "Validation", tonic.Handler(r.ValidateToken, http.StatusOK))
 	AuthGroup.AddRoute("/refresh", http.MethodPost, "Refresh Token", tonic.Handler(r.RefreshToken, http.StatusOK))
+	AuthGroup.AddRoute("/logout", http.MethodPost, "Déconnexion de la session courante", tonic.Handler(r.Logout, http.StatusNoContent))
 	AuthGroup.AddRoute("/forgot-password", http.MethodPost, "Forgot Password", tonic.Handler(r.ForgotPassword, http.StatusOK))
 	AuthGroup.AddRoute("/reset-password", http.MethodPost, "Reset Password", tonic.Handler(r.ResetPassword, http.StatusOK))
+	AuthGroup.AddRoute("/verify-email", http.MethodPost, "Vérifier l'adresse email", tonic.Handler(r.VerifyEmail, http.StatusOK))
+	AuthGroup.AddRoute("/resend-verification", http.MethodPost, "Renvoyer l'email de vérification", tonic.Handler(r.ResendVerification, http.StatusOK))
 
 }
 
 func addRBACRoutes(app *App) {
 	r := app.Controllers.RBAC
 	RBACGroup.AddRoute("/roles", http.MethodGet, "Lister tous les rôles disponibles", tonic.Handler(r.ListAllRoles, http.StatusOK))
-	RBACGroup.AddRoute("/assign-role", http.MethodPost, "Assigner un rôle à un utilisateur", tonic.Handler(r.AssignRole, http.StatusOK))
-	RBACGroup.AddRoute("/:projectID/members", http.MethodGet, "Lister les membres d'un projet", tonic.Handler(r.ListProjectMembers, http.StatusOK))
-	RBACGroup.AddRoute("/:projectID/members/:userID", http.MethodDelete, "Révoquer l'accès d'un membre", tonic.Handler(r.RevokeProjectAccess, http.StatusOK))
+	RBACGroup.AddRoute("/roles", http.MethodPost, "Créer un rôle personnalisé", tonic.Handler(r.CreateRole, http.StatusCreated))
+	RBACGroup.AddRoute("/roles/:id", http.MethodPut, "Modifier les permissions d'un rôle personnalisé", tonic.Handler(r.UpdateRole, http.StatusOK))
+	RBACGroup.AddRoute("/roles/:id", http.MethodDelete, "Supprimer un rôle personnalisé inutilisé", tonic.Handler(r.DeleteRole, http.StatusOK))
+	RBACGroup.AddRoute("/permissions", http.MethodGet, "Lister les permissions assignables à un rôle", tonic.Handler(r.ListPermissions, http.StatusOK))
+	RBACGroup.AddRoute("/assign-role", http.MethodPost, "Assigner un rôle à un utilisateur", tonic.Handler(r.AssignRole, http.StatusOK)).
+		AddRight(rights.MembersManage).
+		ResolveProject(security.ProjectFromBody("project_id"))
+	RBACGroup.AddRoute("/:projectID/members", http.MethodGet, "Lister les membres d'un projet", tonic.Handler(r.ListProjectMembers, http.StatusOK)).
+		AddRight(rights.ProjectView).
+		ResolveProject(security.ProjectFromParam("projectID"))
+	RBACGroup.AddRoute("/:projectID/members/:userID", http.MethodDelete, "Révoquer l'accès d'un membre", tonic.Handler(r.RevokeProjectAccess, http.StatusOK)).
+		AddRight(rights.MembersManage).
+		ResolveProject(security.ProjectFromParam("projectID"))
 }
 
 func addAPIKeyRoutes(app *App) {
 	r := app.Controllers.APIKey
 	APIKeyGroup.AddRoute("", http.MethodGet, "Lister les clés API", tonic.Handler(r.ListKeys, http.StatusOK))
-	APIKeyGroup.AddRoute("", http.MethodPost, "Créer une nouvelle clé API", tonic.Handler(r.CreateKey, http.StatusCreated))
+	APIKeyGroup.AddRoute("", http.MethodPost, "Créer une nouvelle clé API", tonic.Handler(r.CreateKey, http.StatusCreated)).
+		RequireVerified()
 	APIKeyGroup.AddRoute("/:id", http.MethodDelete, "Révoquer une clé API", tonic.Handler(r.RevokeKey, http.StatusOK))
+	APIKeyGroup.AddRoute("/:id/rotate", http.MethodPost, "Générer un nouveau secret (l'ancien reste valide pendant la période de grâce)", tonic.Handler(r.RotateKey, http.StatusOK))
+	APIKeyGroup.AddRoute("/:id/usage", http.MethodGet, "Usage d'une clé API (nombre de requêtes, dernière IP)", tonic.Handler(r.GetKeyUsage, http.StatusOK))
+}
+
+func addMFARoutes(app *App) {
+	r := app.Controllers.MFA
+	AuthGroup.AddRoute("/mfa/verify", http.MethodPost, "Valider le second facteur d'une connexion", tonic.Handler(r.Verify, http.StatusOK))
+	AuthGroup.AddRoute("/mfa/enroll", http.MethodPost, "Générer un secret TOTP", tonic.Handler(r.Enroll, http.StatusOK))
+	AuthGroup.AddRoute("/mfa/confirm", http.MethodPost, "Activer le MFA avec un premier code", tonic.Handler(r.Confirm, http.StatusOK))
+	AuthGroup.AddRoute("/mfa/disable", http.MethodPost, "Désactiver le MFA", tonic.Handler(r.Disable, http.StatusOK))
+	AuthGroup.AddRoute("/mfa/recovery-codes", http.MethodPost, "Régénérer les codes de secours", tonic.Handler(r.RegenerateRecoveryCodes, http.StatusOK))
+}
+
+func addOIDCRoutes(app *App) {
+	r := app.Controllers.OIDC
+	AuthGroup.AddRoute("/oidc/:provider/login", http.MethodGet, "Connexion via un fournisseur d'identité (SSO)", r.Login)
+	AuthGroup.AddRoute("/oidc/:provider/callback", http.MethodGet, "Retour du fournisseur d'identité", tonic.Handler(r.Callback, http.StatusOK))
+}
+
+func addSessionRoutes(app *App) {
+	r := app.Controllers.Session
+	SessionGroup.AddRoute("", http.MethodGet, "Lister mes sessions actives", tonic.Handler(r.ListMySessions, http.StatusOK))
+	SessionGroup.AddRoute("", http.MethodDelete, "Déconnecter toutes les autres sessions", tonic.Handler(r.RevokeMyOtherSessions, http.StatusOK))
+	SessionGroup.AddRoute("/:id", http.MethodDelete, "Révoquer une de mes sessions", tonic.Handler(r.RevokeMySession, http.StatusOK))
+
+	AdminGroup.AddRoute("/:id/sessions", http.MethodGet, "Lister les sessions d'un utilisateur", tonic.Handler(r.ListUserSessions, http.StatusOK))
+	AdminGroup.AddRoute("/:id/sessions", http.MethodDelete, "Déconnecter toutes les sessions d'un utilisateur", tonic.Handler(r.RevokeUserSessions, http.StatusOK))
+	AdminGroup.AddRoute("/:id/sessions/:sessionID", http.MethodDelete, "Révoquer une session d'un utilisateur", tonic.Handler(r.RevokeUserSession, http.StatusOK))
 }
 
 func addProjectRoutes(app *App) {
 	r := app.Controllers.Project
 	//ProjectGroup.AddRoute("", http.MethodGet, "Lister mes projets", tonic.Handler(r.ListProjects, http.StatusOK))
-	ProjectGroup.AddRoute("", http.MethodPost, "Créer un projet", tonic.Handler(r.CreateProject, http.StatusCreated))
+	// aucun projet n'existe encore, pas de droit à vérifier; le handler refuse les clés API
+	ProjectGroup.AddRoute("", http.MethodPost, "Créer un projet", tonic.Handler(r.CreateProject, http.StatusCreated)).
+		RequireVerified().
+		SkipRight()
 	//ProjectGroup.AddRoute("/:id", http.MethodGet, "Détails d'un projet", tonic.Handler(r.GetProject, http.StatusOK))
-	ProjectGroup.AddRoute("/:id/status", http.MethodGet, "Statut K8s d'un projet", tonic.Handler(r.GetProjectStatus, http.StatusOK))
-	ProjectGroup.AddRoute("/:id/metrics", http.MethodGet, "Métriques de consommation", tonic.Handler(r.GetProjectMetrics, http.StatusOK))
-	ProjectGroup.AddRoute("/:id", http.MethodDelete, "Supprimer un projet", tonic.Handler(r.DeleteProject, http.StatusAccepted))
+	ProjectGroup.AddRoute("/:id/status", http.MethodGet, "Statut K8s d'un projet", tonic.Handler(r.GetProjectStatus, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/metrics", http.MethodGet, "Métriques de consommation", tonic.Handler(r.GetProjectMetrics, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id", http.MethodDelete, "Supprimer un projet", tonic.Handler(r.DeleteProject, http.StatusAccepted)).
+		AddRight(rights.ProjectDelete)
+	ProjectGroup.AddRoute("/:id/mfa-policy", http.MethodPut, "Exiger le MFA des propriétaires et membres autorisés au shell", tonic.Handler(r.SetMFAPolicy, http.StatusOK)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/retry", http.MethodPost, "Relancer le provisioning d'un projet", tonic.Handler(r.RetryProject, http.StatusAccepted)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/apply", http.MethodPost, "Appliquer un manifeste YAML/JSON (plan à blanc sans ?apply=true)", tonic.Handler(r.ApplyManifest, http.StatusOK)).
+		AddPayload(projects.ApplyManifestRequest{}).
+		AddRight(rights.ProjectEdit)
+}
+
+func addServiceAccountRoutes(app *App) {
+	r := app.Controllers.Accounts
+	ProjectGroup.AddRoute("/:id/service-accounts", http.MethodGet, "Lister les comptes de service du projet", tonic.Handler(r.List, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/service-accounts", http.MethodPost, "Créer un compte de service (CI)", tonic.Handler(r.Create, http.StatusCreated)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/service-accounts/:accountID", http.MethodPut, "Changer le rôle d'un compte de service", tonic.Handler(r.Update, http.StatusOK)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/service-accounts/:accountID", http.MethodDelete, "Supprimer un compte de service et ses clés API", tonic.Handler(r.Delete, http.StatusNoContent)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/service-accounts/:accountID/api-keys", http.MethodGet, "Lister les clés API d'un compte de service", tonic.Handler(r.ListKeys, http.StatusOK)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/service-accounts/:accountID/api-keys", http.MethodPost, "Créer une clé API pour un compte de service", tonic.Handler(r.CreateKey, http.StatusCreated)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/service-accounts/:accountID/api-keys/:keyID/rotate", http.MethodPost, "Faire tourner une clé API d'un compte de service", tonic.Handler(r.RotateKey, http.StatusOK)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/service-accounts/:accountID/api-keys/:keyID", http.MethodDelete, "Révoquer une clé API d'un compte de service", tonic.Handler(r.RevokeKey, http.StatusNoContent)).
+		AddRight(rights.MembersManage)
+}
+
+func addInvitationRoutes(app *App) {
+	r := app.Controllers.Invites
+	ProjectGroup.AddRoute("/:id/invitations", http.MethodPost, "Inviter une personne par email (met à jour le rôle d'un membre existant)", tonic.Handler(r.Invite, http.StatusCreated)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/invitations", http.MethodGet, "Lister les invitations en attente", tonic.Handler(r.List, http.StatusOK)).
+		AddRight(rights.MembersManage)
+	ProjectGroup.AddRoute("/:id/invitations/:invitationID", http.MethodDelete, "Révoquer une invitation en attente", tonic.Handler(r.Revoke, http.StatusNoContent)).
+		AddRight(rights.MembersManage)
+
+	InviteGroup.AddRoute("/accept", http.MethodPost, "Accepter une invitation à un projet", tonic.Handler(r.Accept, http.StatusOK))
+	InviteGroup.AddRoute("/decline", http.MethodPost, "Décliner une invitation à un projet", tonic.Handler(r.Decline, http.StatusNoContent))
 }
 
 func addWorkloadRoutes(app *App) {
 	r := app.Controllers.Workload
+	WorkloadGroup.ResolveProject(security.ProjectFromResource("id", workloadProject(app)))
 
 	//WorkloadGroup.AddRoute("", http.MethodGet, "Lister tous les workloads", tonic.Handler(r.ListWorkloads, http.StatusOK))
-	WorkloadGroup.AddRoute("/:id", http.MethodGet, "Statut détaillé d'un workload", tonic.Handler(r.GetWorkloadStatus, http.StatusOK))
+	WorkloadGroup.AddRoute("/:id", http.MethodGet, "Statut détaillé d'un workload", tonic.Handler(r.GetWorkloadStatus, http.StatusOK)).
+		AddRight(rights.ProjectView)
 
-	WorkloadGroup.AddRoute("", http.MethodPost, "Déployer un nouveau workload", tonic.Handler(r.CreateWorkload, http.StatusAccepted))
+	WorkloadGroup.AddRoute("", http.MethodPost, "Déployer un nouveau workload", tonic.Handler(r.CreateWorkload, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate).
+		ResolveProject(security.ProjectFromBody("project_id"))
+	WorkloadGroup.AddRoute("/:id/cancel", http.MethodPost, "Annuler un déploiement en cours", tonic.Handler(r.CancelWorkload, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/retry", http.MethodPost, "Relancer un déploiement échoué", tonic.Handler(r.RetryWorkload, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/run", http.MethodPost, "Déclencher manuellement un cronjob", tonic.Handler(r.RunWorkload, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/volume/resize", http.MethodPost, "Agrandir le volume persistant", tonic.Handler(r.ResizeVolume, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/snapshots", http.MethodPost, "Créer un snapshot du volume", tonic.Handler(r.CreateSnapshot, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/snapshots", http.MethodGet, "Lister les snapshots du volume", tonic.Handler(r.ListSnapshots, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	WorkloadGroup.AddRoute("/:id/snapshots/schedule", http.MethodPut, "Planifier les snapshots et leur rétention", tonic.Handler(r.SetSnapshotSchedule, http.StatusOK)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/snapshots/:snapshotID", http.MethodDelete, "Supprimer un snapshot", tonic.Handler(r.DeleteSnapshot, http.StatusAccepted)).
+		AddRight(rights.WorkloadDelete)
+	WorkloadGroup.AddRoute("/:id/snapshots/:snapshotID/restore", http.MethodPost, "Restaurer un snapshot", tonic.Handler(r.RestoreSnapshot, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
 	//WorkloadGroup.AddRoute("/:id", http.MethodPut, "Mettre à jour un workload (Scaling/Image)", tonic.Handler(r.UpdateWorkload, http.StatusAccepted))
-	//WorkloadGroup.AddRoute("/:id", http.MethodDelete, "Supprimer et désinstaller un workload", tonic.Handler(r.DeleteWorkload, http.StatusAccepted))
+	WorkloadGroup.AddRoute("/:id", http.MethodDelete, "Supprimer et désinstaller un workload", tonic.Handler(r.DeleteWorkload, http.StatusAccepted)).
+		AddRight(rights.WorkloadDelete)
 
 	//WorkloadGroup.AddRoute("/:id/logs", http.MethodGet, "Récupérer les logs des pods", tonic.Handler(r.GetWorkloadLogs, http.StatusOK))
 }
+
+// workloadProject finds the project of a workload, the routes under /workloads/:id do not carry it.
+func workloadProject(app *App) func(c *gin.Context, id uuid.UUID) (uuid.UUID, error) {
+	return func(c *gin.Context, id uuid.UUID) (uuid.UUID, error) {
+		workload, err := app.Repos.Workload.GetByID(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return uuid.Nil, security.ErrResourceNotFound
+			}
+			return uuid.Nil, fmt.Errorf("failed to resolve the workload project")
+		}
+		return workload.ProjectID, nil
+	}
+}
+
+func addAddonRoutes(app *App) {
+	r := app.Controllers.Addon
+	AddonGroup.AddRoute("/catalog", http.MethodGet, "Catalogue des add-ons (types, versions, tailles)", tonic.Handler(r.GetCatalog, http.StatusOK))
+
+	ProjectGroup.AddRoute("/:id/addons", http.MethodPost, "Provisionner un add-on (Postgres/Redis)", tonic.Handler(r.CreateAddon, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	ProjectGroup.AddRoute("/:id/addons", http.MethodGet, "Lister les add-ons du projet", tonic.Handler(r.ListAddons, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/addons/:addonID", http.MethodGet, "Détails d'un add-on", tonic.Handler(r.GetAddon, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/addons/:addonID", http.MethodDelete, "Supprimer un add-on (snapshot final optionnel)", tonic.Handler(r.DeleteAddon, http.StatusAccepted)).
+		AddRight(rights.WorkloadDelete)
+	ProjectGroup.AddRoute("/:id/addons/:addonID/bindings", http.MethodPost, "Lier un add-on à un workload", tonic.Handler(r.BindAddon, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	ProjectGroup.AddRoute("/:id/addons/:addonID/bindings/:workloadID", http.MethodDelete, "Délier un add-on d'un workload", tonic.Handler(r.UnbindAddon, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+}
+
+func addTemplateRoutes(app *App) {
+	r := app.Controllers.Template
+	TemplateGroup.AddRoute("", http.MethodGet, "Lister les templates globaux", tonic.Handler(r.ListOrgTemplates, http.StatusOK))
+	TemplateGroup.AddRoute("", http.MethodPost, "Créer un template global (admin)", tonic.Handler(r.CreateOrgTemplate, http.StatusCreated))
+	TemplateGroup.AddRoute("/:name", http.MethodGet, "Détails d'un template global (version optionnelle)", tonic.Handler(r.GetOrgTemplate, http.StatusOK))
+	TemplateGroup.AddRoute("/:name", http.MethodPut, "Publier une nouvelle version d'un template global (admin)", tonic.Handler(r.UpdateOrgTemplate, http.StatusCreated))
+	TemplateGroup.AddRoute("/:name", http.MethodDelete, "Supprimer un template global et ses versions (admin)", tonic.Handler(r.DeleteOrgTemplate, http.StatusOK))
+
+	ProjectGroup.AddRoute("/:id/templates", http.MethodGet, "Lister les templates du projet et les templates globaux", tonic.Handler(r.ListProjectTemplates, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/templates", http.MethodPost, "Créer un template de workload", tonic.Handler(r.CreateProjectTemplate, http.StatusCreated)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/templates/:name", http.MethodGet, "Détails d'un template (version optionnelle)", tonic.Handler(r.GetProjectTemplate, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/templates/:name", http.MethodPut, "Publier une nouvelle version d'un template", tonic.Handler(r.UpdateProjectTemplate, http.StatusCreated)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/templates/:name", http.MethodDelete, "Supprimer un template et ses versions", tonic.Handler(r.DeleteProjectTemplate, http.StatusOK)).
+		AddRight(rights.ProjectEdit)
+
+	WorkloadGroup.AddRoute("/from-template", http.MethodPost, "Déployer un workload depuis un template", tonic.Handler(r.DeployFromTemplate, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate).
+		ResolveProject(security.ProjectFromBody("project_id"))
+}
+
+func addGitSyncRoutes(app *App) {
+	r := app.Controllers.GitSync
+	ProjectGroup.AddRoute("/:id/git-source", http.MethodPut, "Configurer le dépôt Git suivi par le projet", tonic.Handler(r.ConfigureSource, http.StatusOK)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/git-source", http.MethodGet, "Statut de synchronisation du dépôt Git", tonic.Handler(r.GetSource, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/git-source", http.MethodDelete, "Arrêter le suivi du dépôt Git", tonic.Handler(r.DeleteSource, http.StatusNoContent)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/git-source/sync", http.MethodPost, "Forcer une synchronisation du dépôt Git", tonic.Handler(r.TriggerSync, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+
+	// appelée par la forge, authentifiée par la signature HMAC du corps
+	GitSyncGroup.AddRoute("/webhooks/:sourceID", http.MethodPost, "Webhook de push (X-Hub-Signature-256)", tonic.Handler(r.Webhook, http.StatusAccepted))
+}
+
+func addBuildRoutes(app *App) {
+	r := app.Controllers.Build
+	WorkloadGroup.AddRoute("/:id/builds", http.MethodPost, "Construire l'image depuis un dépôt Git et la déployer", tonic.Handler(r.StartBuild, http.StatusAccepted)).
+		AddRight(rights.WorkloadCreate)
+	WorkloadGroup.AddRoute("/:id/builds", http.MethodGet, "Historique des builds du workload", tonic.Handler(r.ListBuilds, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	WorkloadGroup.AddRoute("/:id/builds/:buildID", http.MethodGet, "Statut d'un build", tonic.Handler(r.GetBuild, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	// texte brut, ?follow=true garde la réponse ouverte jusqu'à la fin du build
+	WorkloadGroup.AddRoute("/:id/builds/:buildID/logs", http.MethodGet, "Logs du build (Kaniko)", r.StreamBuildLogs).
+		AddRight(rights.LogsView)
+
+	// appelée par le registre pour le kubelet et Kaniko, authentifiée par les logins écrits dans les namespaces
+	RegistryGroup.AddRoute("/token", http.MethodGet, "Jeton du registre (pull du projet, push du build en cours)", r.RegistryToken)
+}
+
+func addWebhookRoutes(app *App) {
+	r := app.Controllers.Webhook
+	ProjectGroup.AddRoute("/:id/webhooks", http.MethodPost, "Abonner un endpoint aux événements du projet", tonic.Handler(r.CreateWebhook, http.StatusCreated)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/webhooks", http.MethodGet, "Lister les webhooks du projet", tonic.Handler(r.ListWebhooks, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/webhooks/:webhookID", http.MethodPut, "Modifier un webhook", tonic.Handler(r.UpdateWebhook, http.StatusOK)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/webhooks/:webhookID", http.MethodDelete, "Supprimer un webhook", tonic.Handler(r.DeleteWebhook, http.StatusNoContent)).
+		AddRight(rights.ProjectEdit)
+	ProjectGroup.AddRoute("/:id/webhooks/:webhookID/deliveries", http.MethodGet, "Journal des livraisons du webhook", tonic.Handler(r.ListDeliveries, http.StatusOK)).
+		AddRight(rights.ProjectView)
+	ProjectGroup.AddRoute("/:id/webhooks/:webhookID/deliveries/:deliveryID/redeliver", http.MethodPost, "Renvoyer une livraison", tonic.Handler(r.Redeliver, http.StatusAccepted)).
+		AddRight(rights.ProjectEdit)
+}
+
+func addWorkflowRoutes(app *App) {
+	r := app.Controllers.Execution
+	WorkflowGroup.AddRoute("/:id", http.MethodGet, "Progression et historique d'un workflow", tonic.Handler(r.GetWorkflow, http.StatusOK)).
+		AddRight(rights.ProjectView).
+		ResolveProject(workflowProject(app))
+}
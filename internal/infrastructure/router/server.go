@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/thekrauss/beto-shared/pkg/redis"
+	"github.com/thekrauss/kubemanager/internal/core/events"
 	"github.com/thekrauss/kubemanager/internal/middleware/security"
 	"github.com/wI2L/fizz"
 	"github.com/wI2L/fizz/openapi"
@@ -46,6 +48,54 @@ type Observability struct {
 	TracerShutdown func()
 }
 
+// startEventRelay publishes the event outbox on RabbitMQ. Without a broker URL the events
+// stay in the outbox and are published once the relay runs with one.
+func (a *App) startEventRelay(ctx context.Context) func() {
+	if a.Config.RabbitMQ.URL == "" {
+		a.Logger.Warn("rabbitmq url not configured, domain events are kept in the outbox")
+		return func() {}
+	}
+
+	broker := events.NewAMQPBroker(a.Config.RabbitMQ, a.Logger)
+	relay := events.NewRelay(a.DB, broker, a.Logger)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		if err := broker.Close(); err != nil {
+			log.Printf("rabbitmq close error: %v", err)
+		}
+	}
+}
+
+// startAPIKeyJobs runs the batched usage recorder of the API key middleware and the expiry
+// notices. The returned func waits for the last usage batch to be written.
+func (a *App) startAPIKeyJobs(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a.Security.Middleware.Usage.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		a.Services.APIKey.RunExpiryNotifications(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
 func (a *App) startHTTPServer() {
 
 	engine := gin.New()
@@ -0,0 +1,142 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+)
+
+// workflowOwner finds the project of the resource a workflow ID was built from.
+type workflowOwner func(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error)
+
+// préfixes des IDs de workflow posés par les services, du plus long au plus court pour que
+// "workload-snapshot-delete-" passe avant "workload-snapshot-"
+var workflowOwners = []struct {
+	prefix string
+	owner  workflowOwner
+}{
+	{"project-delete-", projectOwner},
+	{"project-apply-", projectOwner},
+	{"workload-snapshot-schedule-", workloadOwner},
+	{"workload-snapshot-delete-", snapshotOwner},
+	{"workload-snapshot-", snapshotOwner},
+	{"workload-build-deploy-", buildOwner},
+	{"workload-build-", buildOwner},
+	{"workload-bindings-", workloadOwner},
+	{"workload-deploy-", workloadOwner},
+	{"workload-update-", workloadOwner},
+	{"workload-resize-", workloadOwner},
+	{"workload-restore-", workloadOwner},
+	{"workload-delete-", workloadOwner},
+	{"workload-run-", workloadOwner},
+	{"addon-provision-", addonOwner},
+	{"addon-delete-", addonOwner},
+	{"git-sync-manual-", gitSourceOwner},
+	{"git-sync-", gitSourceOwner},
+	{"webhook-delivery-", deliveryOwner},
+}
+
+// workflowProject resolves the project owning a workflow, for the /workflows/:id route. Workflows
+// not tied to a project (emails) are only visible to platform admins.
+func workflowProject(app *App) security.ProjectResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		ctx := c.Request.Context()
+		workflowID := c.Param("id")
+
+		// l'ID de création est aléatoire, seule la ligne du projet le connaît
+		if strings.HasPrefix(workflowID, "project-create-") {
+			project, err := app.Repos.Project.GetProjectByWorkflowID(ctx, workflowID)
+			if err != nil {
+				return uuid.Nil, ownerError(err, "project")
+			}
+			return project.ID, nil
+		}
+
+		for _, o := range workflowOwners {
+			rest, found := strings.CutPrefix(workflowID, o.prefix)
+			if !found {
+				continue
+			}
+			// les runs et bindings ajoutent un suffixe après l'UUID de la ressource
+			if len(rest) > 36 {
+				rest = rest[:36]
+			}
+			id, err := uuid.Parse(rest)
+			if err != nil {
+				return uuid.Nil, security.ErrResourceNotFound
+			}
+			return o.owner(ctx, app, id)
+		}
+		return uuid.Nil, security.ErrResourceNotFound
+	}
+}
+
+func projectOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	project, err := app.Repos.Project.GetProjectByID(ctx, id.String())
+	if err != nil {
+		return uuid.Nil, ownerError(err, "project")
+	}
+	return project.ID, nil
+}
+
+func workloadOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	workload, err := app.Repos.Workload.GetByID(ctx, id)
+	if err != nil {
+		return uuid.Nil, ownerError(err, "workload")
+	}
+	return workload.ProjectID, nil
+}
+
+func snapshotOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	snapshot, err := app.Repos.Snapshot.GetByID(ctx, id)
+	if err != nil {
+		return uuid.Nil, ownerError(err, "snapshot")
+	}
+	return workloadOwner(ctx, app, snapshot.WorkloadID)
+}
+
+func buildOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	build, err := app.Repos.Build.GetByID(ctx, id)
+	if err != nil {
+		return uuid.Nil, ownerError(err, "build")
+	}
+	return build.ProjectID, nil
+}
+
+func addonOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	addon, err := app.Repos.Addon.GetByID(ctx, id)
+	if err != nil {
+		return uuid.Nil, ownerError(err, "add-on")
+	}
+	return addon.ProjectID, nil
+}
+
+func gitSourceOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	source, err := app.Repos.GitSource.GetByID(ctx, id)
+	if err != nil {
+		return uuid.Nil, ownerError(err, "git source")
+	}
+	return source.ProjectID, nil
+}
+
+func deliveryOwner(ctx context.Context, app *App, id uuid.UUID) (uuid.UUID, error) {
+	delivery, err := app.Repos.Webhook.GetDelivery(ctx, id)
+	if err != nil {
+		return uuid.Nil, ownerError(err, "webhook delivery")
+	}
+	return delivery.ProjectID, nil
+}
+
+func ownerError(err error, resource string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return security.ErrResourceNotFound
+	}
+	return fmt.Errorf("failed to resolve the %s project", resource)
+}
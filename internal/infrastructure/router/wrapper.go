@@ -29,6 +29,9 @@ type Route struct {
 	Handler     gin.HandlerFunc
 	ID          string
 	Right       string
+	Resolver    security.ProjectResolver
+	SkipsRight  bool
+	Verified    bool
 	Payload     interface{}
 	Query       interface{}
 	Responses   map[int]interface{}
@@ -40,6 +43,8 @@ type RouteGroup struct {
 	Routes      []*Route
 	Groups      []*RouteGroup
 	Middlewares []gin.HandlerFunc
+	// résout le projet ciblé par les routes du groupe, chacune doit alors déclarer une permission
+	Resolver security.ProjectResolver
 }
 
 var (
@@ -59,6 +64,13 @@ func (g *RouteGroup) NewGroup(path string, desc string) *RouteGroup {
 	return child
 }
 
+// ResolveProject sets how the routes of the group find their project. Registering a route of
+// such a group without AddRight or SkipRight fails at startup.
+func (g *RouteGroup) ResolveProject(resolver security.ProjectResolver) *RouteGroup {
+	g.Resolver = resolver
+	return g
+}
+
 func (g *RouteGroup) AddRoute(path string, method string, desc string, handler gin.HandlerFunc) *Route {
 	r := &Route{
 		Method:      method,
@@ -159,13 +171,24 @@ func registerGroup(fg *fizz.RouterGroup, group *RouteGroup, absPath string, mw *
 
 		handlers := []gin.HandlerFunc{r.Handler}
 
+		resolver := r.Resolver
+		if resolver == nil {
+			resolver = group.Resolver
+		}
+		if group.Resolver != nil && r.Right == "" && !r.SkipsRight {
+			panic(fmt.Sprintf("Route %s %s%s declares no permission", r.Method, absPath, r.Path))
+		}
+
 		if r.Right != "" {
 			perm, err := domain.PermissionTypes.NewFromString(context.Background(), r.Right)
 			if err != nil {
 				panic(fmt.Sprintf("Invalid permission defined in route: %s", r.Right))
 			}
+			if resolver == nil {
+				panic(fmt.Sprintf("Route %s %s%s has a permission but no project resolver", r.Method, absPath, r.Path))
+			}
 
-			permissionMiddleware := mw.RequireProjectPermission(perm)
+			permissionMiddleware := mw.RequireProjectPermission(perm, resolver)
 			handlers = append([]gin.HandlerFunc{permissionMiddleware}, handlers...)
 
 			options = append(options, fizz.Security(&openapi.SecurityRequirement{
@@ -173,6 +196,10 @@ func registerGroup(fg *fizz.RouterGroup, group *RouteGroup, absPath string, mw *
 			}))
 		}
 
+		if r.Verified {
+			handlers = append([]gin.HandlerFunc{mw.RequireVerifiedEmail()}, handlers...)
+		}
+
 		g.Handle(r.Path, r.Method, options, handlers...)
 	}
 
@@ -186,12 +213,31 @@ func (r *Route) AddID(id string) *Route {
 	return r
 }
 
-func (r *Route) AddRight(right string) *Route {
-	r.Right = right
+// AddRight requires the permission in the project found by the group or route resolver.
+func (r *Route) AddRight(right domain.PermissionType) *Route {
+	r.Right = right.String()
 	RouteMap[r.ID] = r
 	return r
 }
 
+// ResolveProject overrides the group resolver, e.g. for a route carrying the project in its body.
+func (r *Route) ResolveProject(resolver security.ProjectResolver) *Route {
+	r.Resolver = resolver
+	return r
+}
+
+// SkipRight marks a route of a project group that deliberately checks no project permission.
+func (r *Route) SkipRight() *Route {
+	r.SkipsRight = true
+	return r
+}
+
+// RequireVerified restricts the route to accounts with a verified email address.
+func (r *Route) RequireVerified() *Route {
+	r.Verified = true
+	return r
+}
+
 func (r *Route) AddPayload(dto interface{}) *Route {
 	r.Payload = dto
 	return r
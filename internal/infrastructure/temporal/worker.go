@@ -1,20 +1,44 @@
 package temporal
 
 import (
+	"time"
+
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	metricsv1 "k8s.io/metrics/pkg/client/clientset/versioned"
 
 	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	addonActivities "github.com/thekrauss/kubemanager/internal/modules/addons/activities"
+	addonRepo "github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+	addonWorkflows "github.com/thekrauss/kubemanager/internal/modules/addons/workflows"
+	authRepo "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
 	authSvc "github.com/thekrauss/kubemanager/internal/modules/auth/service"
+	buildActivities "github.com/thekrauss/kubemanager/internal/modules/builds/activities"
+	buildRepo "github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	buildWorkflows "github.com/thekrauss/kubemanager/internal/modules/builds/workflows"
+	gitSyncActivities "github.com/thekrauss/kubemanager/internal/modules/gitsync/activities"
+	gitSyncRepo "github.com/thekrauss/kubemanager/internal/modules/gitsync/repository"
+	gitSyncWorkflows "github.com/thekrauss/kubemanager/internal/modules/gitsync/workflows"
+	mailActivities "github.com/thekrauss/kubemanager/internal/modules/mailer/activities"
+	mailTransport "github.com/thekrauss/kubemanager/internal/modules/mailer/transport"
+	mailWorkflows "github.com/thekrauss/kubemanager/internal/modules/mailer/workflows"
 	projectActivities "github.com/thekrauss/kubemanager/internal/modules/projects/activities"
 	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	projectSvc "github.com/thekrauss/kubemanager/internal/modules/projects/service"
 	projectWorkflows "github.com/thekrauss/kubemanager/internal/modules/projects/workflows"
+	webhookActivities "github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+	webhookRepo "github.com/thekrauss/kubemanager/internal/modules/webhooks/repository"
+	webhookSvc "github.com/thekrauss/kubemanager/internal/modules/webhooks/service"
+	webhookWorkflows "github.com/thekrauss/kubemanager/internal/modules/webhooks/workflows"
 	workloadActivities "github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	workloadRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+	workloadSvc "github.com/thekrauss/kubemanager/internal/modules/workloads/service"
 	workloadWorkflows "github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
 )
 
@@ -40,6 +64,17 @@ func (m *WorkerConfig) Start() worker.Worker {
 		m.Logger.Fatalf("Impossible de créer le client metrics: %v", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(m.K8sConfig)
+	if err != nil {
+		m.Logger.Fatalf("Impossible de créer le client dynamique: %v", err)
+	}
+
+	// tokens git chiffrés en base
+	secrets, err := security.SecretBoxFromConfig(m.Config)
+	if err != nil {
+		m.Logger.Fatalf("Impossible d'initialiser le chiffrement des secrets: %v", err)
+	}
+
 	projDBActs := &projectActivities.ProjectDBActivities{
 		Repo:   projectRepo.NewProjectRepository(m.DB),
 		Logger: m.Logger,
@@ -53,17 +88,103 @@ func (m *WorkerConfig) Start() worker.Worker {
 	}
 
 	workloadDBActs := &workloadActivities.WorkloadDBActivities{
-		DB:     m.DB,
-		Logger: m.Logger,
+		DB:           m.DB,
+		Logger:       m.Logger,
+		Repo:         workloadRepo.NewWorkloadRepository(m.DB),
+		ProjectRepo:  projectRepo.NewProjectRepository(m.DB),
+		SnapshotRepo: workloadRepo.NewSnapshotRepository(m.DB),
+		AddonRepo:    addonRepo.NewAddonRepository(m.DB),
 	}
 
 	helmActs := &workloadActivities.WorkloadActivities{
-		K8sConfig: m.K8sConfig,
+		K8sConfig:     m.K8sConfig,
+		K8sClient:     m.K8sClient,
+		DynamicClient: dynamicClient,
+	}
+
+	addonDBActs := &addonActivities.AddonDBActivities{
+		Repo:   addonRepo.NewAddonRepository(m.DB),
+		Logger: m.Logger,
+	}
+
+	addonK8sActs := &addonActivities.AddonActivities{
+		K8sClient:    m.K8sClient,
+		Repo:         addonRepo.NewAddonRepository(m.DB),
+		WorkloadRepo: workloadRepo.NewWorkloadRepository(m.DB),
+	}
+
+	// les workloads d'un manifeste passent par le même service que l'API REST
+	manifestActs := &projectActivities.ManifestActivities{
+		K8sClient:         m.K8sClient,
+		Logger:            m.Logger,
+		Rbac:              m.RBACSvc,
+		ProjectRepo:       projectRepo.NewProjectRepository(m.DB),
+		NetworkPolicyRepo: projectRepo.NewNetworkPolicyRepository(m.DB),
+		Workloads: workloadSvc.NewWorkloadService(
+			m.Client,
+			m.Config,
+			workloadRepo.NewWorkloadRepository(m.DB),
+			projectRepo.NewProjectRepository(m.DB),
+			workloadRepo.NewSnapshotRepository(m.DB),
+			addonRepo.NewAddonRepository(m.DB),
+		),
+	}
+
+	gitSyncActs := &gitSyncActivities.GitSyncActivities{
+		Repo:    gitSyncRepo.NewGitSourceRepository(m.DB),
+		Secrets: secrets,
+		Logger:  m.Logger,
+		Manifests: projectSvc.NewManifestService(
+			m.Client,
+			m.Config,
+			m.Logger,
+			projectRepo.NewProjectRepository(m.DB),
+			projectRepo.NewNetworkPolicyRepository(m.DB),
+			authRepo.NewAuthRepository(m.DB),
+			workloadRepo.NewWorkloadRepository(m.DB),
+			addonRepo.NewAddonRepository(m.DB),
+		),
+	}
+
+	// les dépôts Git sont clonés par le worker : mêmes adresses interdites que les webhooks
+	gitSyncActivities.GuardGitTransport(webhookActivities.NewDeliveryClient(2 * time.Minute))
+
+	buildActs := &buildActivities.BuildActivities{
 		K8sClient: m.K8sClient,
+		Config:    m.Config,
+		Repo:      buildRepo.NewBuildRepository(m.DB),
+		Logger:    m.Logger,
+		Workloads: manifestActs.Workloads,
+		Secrets:   secrets,
+		Registry:  security.RegistryCredentialsFromConfig(m.Config),
+	}
+
+	eventActs := &webhookActivities.EventActivities{
+		Emitter: webhookSvc.NewWebhookService(
+			m.Client,
+			m.Config,
+			m.Logger,
+			webhookRepo.NewWebhookRepository(m.DB),
+			projectRepo.NewProjectRepository(m.DB),
+		),
+	}
+
+	deliveryActs := &webhookActivities.DeliveryActivities{
+		Repo:       webhookRepo.NewWebhookRepository(m.DB),
+		HTTPClient: webhookActivities.NewDeliveryClient(15 * time.Second),
+		Logger:     m.Logger,
+	}
+
+	mailActs := &mailActivities.MailActivities{
+		Transport:   mailTransport.New(m.Config.Mail, m.Logger),
+		Config:      m.Config,
+		AuthRepo:    authRepo.NewAuthRepository(m.DB),
+		ProjectRepo: projectRepo.NewProjectRepository(m.DB),
+		Logger:      m.Logger,
 	}
 
 	m.registerWorkflows(w)
-	m.registerActivities(w, projDBActs, projK8sActs, workloadDBActs, helmActs)
+	m.registerActivities(w, projDBActs, projK8sActs, workloadDBActs, helmActs, addonDBActs, addonK8sActs, manifestActs, gitSyncActs, buildActs, eventActs, deliveryActs, mailActs)
 
 	go m.run(w)
 
@@ -72,7 +193,22 @@ func (m *WorkerConfig) Start() worker.Worker {
 
 func (m *WorkerConfig) registerWorkflows(w worker.Worker) {
 	w.RegisterWorkflow(projectWorkflows.CreateProjectWorkflow)
+	w.RegisterWorkflow(projectWorkflows.ApplyManifestWorkflow)
 	w.RegisterWorkflow(workloadWorkflows.DeployWorkloadWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.ResizeVolumeWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.RunCronJobWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.DeleteWorkloadWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.CreateSnapshotWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.DeleteSnapshotWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.ScheduledSnapshotWorkflow)
+	w.RegisterWorkflow(workloadWorkflows.RestoreSnapshotWorkflow)
+	w.RegisterWorkflow(addonWorkflows.ProvisionAddonWorkflow)
+	w.RegisterWorkflow(addonWorkflows.DeleteAddonWorkflow)
+	w.RegisterWorkflow(addonWorkflows.SyncBindingsWorkflow)
+	w.RegisterWorkflow(gitSyncWorkflows.SyncGitSourceWorkflow)
+	w.RegisterWorkflow(buildWorkflows.BuildWorkloadWorkflow)
+	w.RegisterWorkflow(webhookWorkflows.DeliverWebhookWorkflow)
+	w.RegisterWorkflow(mailWorkflows.SendEmailWorkflow)
 }
 
 func (m *WorkerConfig) registerActivities(w worker.Worker, acts ...interface{}) {
@@ -84,7 +220,7 @@ func (m *WorkerConfig) registerActivities(w worker.Worker, acts ...interface{})
 func (m *WorkerConfig) run(w worker.Worker) {
 	m.Logger.Infow("Temporal Worker started",
 		"queue", m.Config.Temporal.TaskQueue,
-		"modules", []string{"Projects", "Workloads", "RBAC"},
+		"modules", []string{"Projects", "Workloads", "Add-ons", "RBAC"},
 	)
 
 	if err := w.Run(worker.InterruptCh()); err != nil {
@@ -6,12 +6,13 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 
 	"github.com/thekrauss/kubemanager/internal/core/configs"
 )
 
 type JWTManager interface {
-	GenerateAccessToken(userID string, globalRole string, duration time.Duration) (string, error)
+	GenerateAccessToken(userID string, globalRole string, sessionID string, duration time.Duration) (string, error)
 	VerifyAccessToken(tokenString string) (*UserClaims, error)
 }
 
@@ -25,6 +26,7 @@ type jwtManager struct {
 type UserClaims struct {
 	UserID     string `json:"user_id"`
 	GlobalRole string `json:"global_role"`
+	SessionID  string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -35,11 +37,13 @@ func NewJWTManager(cfg *configs.JWTConfig, serviceName string) JWTManager {
 	}
 }
 
-func (m *jwtManager) GenerateAccessToken(userID string, globalRole string, duration time.Duration) (string, error) {
+func (m *jwtManager) GenerateAccessToken(userID string, globalRole string, sessionID string, duration time.Duration) (string, error) {
 	claims := UserClaims{
 		UserID:     userID,
 		GlobalRole: globalRole,
+		SessionID:  sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    m.issuer,
@@ -0,0 +1,56 @@
+package security
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// RFC 6238 annexe B, clé SHA1 "12345678901234567890", codes tronqués à 6 chiffres
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		at   int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		step, ok := ValidateTOTP(secret, tc.code, time.Unix(tc.at, 0))
+		if !ok || step != tc.at/30 {
+			t.Errorf("ValidateTOTP(%s at %d) = %d, %v", tc.code, tc.at, step, ok)
+		}
+	}
+
+	// un pas de dérive est toléré, pas deux
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+30, 0)); !ok {
+		t.Error("code of the previous step refused")
+	}
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+60, 0)); ok {
+		t.Error("code two steps old accepted")
+	}
+}
+
+func TestRecoveryCodesNormalize(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not formatted xxxxx-xxxxx", code)
+		}
+		normalized := NormalizeRecoveryCode(code)
+		if seen[normalized] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[normalized] = true
+		if NormalizeRecoveryCode(" "+code[:5]+" "+code[6:]+" ") != normalized {
+			t.Errorf("%q does not normalize like the typed variants", code)
+		}
+	}
+}
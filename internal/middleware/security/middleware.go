@@ -4,16 +4,20 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/thekrauss/kubemanager/internal/core/cache"
 	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/core/events"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	authRepos "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
 )
@@ -21,14 +25,24 @@ import (
 const (
 	UserSessionKey = "user_session"
 	UserIDKey      = "user_id"
+	SessionIDKey   = "session_id"
+
+	ProjectAccessKey = "project_access"
 )
 
+// intervalle minimal entre deux mises à jour de last_seen d'une session
+const lastSeenInterval = time.Minute
+
+// durée de vie des permissions d'un rôle en cache
+const rolePermissionsTTL = 10 * time.Minute
+
 type MiddlewareManager struct {
 	Config    *configs.GlobalConfig
 	JwtMgr    JWTManager
 	CacheRepo cache.CacheRedis
 	Logger    *zap.SugaredLogger
 	AuthRepo  authRepos.AuthRepository
+	Usage     *UsageRecorder
 }
 
 func NewMiddlewareManager(cfg *configs.GlobalConfig, jwtMgr JWTManager, cacheRepo cache.CacheRedis, logger *zap.SugaredLogger, authRepo authRepos.AuthRepository) *MiddlewareManager {
@@ -38,6 +52,7 @@ func NewMiddlewareManager(cfg *configs.GlobalConfig, jwtMgr JWTManager, cacheRep
 		CacheRepo: cacheRepo,
 		Logger:    logger,
 		AuthRepo:  authRepo,
+		Usage:     NewUsageRecorder(authRepo, logger, cfg.APIKeys.UsageFlushInterval),
 	}
 }
 
@@ -50,6 +65,10 @@ func (m *MiddlewareManager) AuthMiddleware() gin.HandlerFunc {
 			"/swagger/swagger-ui-standalone-preset.js", "/swagger/favicon",
 			"/docs", "/docs/", "/openapi.json", "/healthz", "/metrics",
 			"/api/v1/health", "/kmanager/v1/auth/login", "/api/v1/auth/refresh", "/kmanager/v1/auth/register",
+			"/kmanager/v1/git-sync/webhooks/", "/kmanager/v1/registry/token",
+			"/kmanager/v1/auth/verify-email", "/kmanager/v1/auth/resend-verification",
+			"/kmanager/v1/auth/mfa/verify",
+			"/kmanager/v1/auth/oidc/",
 		}
 
 		path := c.Request.URL.Path
@@ -78,7 +97,10 @@ func (m *MiddlewareManager) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-func (m *MiddlewareManager) RequireProjectPermission(perm domain.PermissionType) gin.HandlerFunc {
+// RequireProjectPermission checks that the caller's role in the project targeted by the request
+// grants perm. The role is read from the membership table and not from the session, which
+// only holds the memberships known at login.
+func (m *MiddlewareManager) RequireProjectPermission(perm domain.PermissionType, resolve ProjectResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionVal, exists := c.Get(UserSessionKey)
 		if !exists {
@@ -87,39 +109,165 @@ func (m *MiddlewareManager) RequireProjectPermission(perm domain.PermissionType)
 		}
 		session := sessionVal.(*cache.SessionData)
 
-		if session.GlobalRole == m.Config.Roles.PlatformAdmin {
+		// une clé API reste limitée à ses scopes et projets, même pour un admin
+		isAdmin := session.GlobalRole == m.Config.Roles.PlatformAdmin
+		if isAdmin && session.APIKey == nil {
+			c.Set(ProjectAccessKey, &ProjectAccess{Admin: true})
+			c.Next()
+			return
+		}
+
+		projectID, err := resolve(c)
+		if err != nil {
+			if errors.Is(err, ErrResourceNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if session.APIKey != nil && !session.APIKey.Allows(projectID.String(), perm.String()) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Forbidden: API key is not allowed %s on this project", perm.String()),
+			})
+			return
+		}
+
+		if isAdmin {
+			c.Set(ProjectAccessKey, &ProjectAccess{ProjectID: projectID, Admin: true, APIKey: session.APIKey})
 			c.Next()
 			return
 		}
 
-		projectID := c.Param("project_id")
-		if projectID == "" {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Project ID missing in URL"})
+		userID, err := uuid.Parse(session.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
 
-		roleName, hasRole := session.ProjectRoles[projectID]
-		if !hasRole {
+		roleName, err := m.AuthRepo.GetProjectRoleName(c.Request.Context(), projectID, userID)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: No project access"})
 			return
 		}
 
-		roleType, err := domain.RoleTypes.NewFromString(c.Request.Context(), roleName)
+		perms, err := m.rolePermissions(c.Request.Context(), roleName)
 		if err != nil {
+			m.Logger.Errorw("failed to load role permissions", "role", roleName, "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Invalid role configuration"})
 			return
 		}
 
-		if !domain.RoleHasPermission(roleType, perm) {
+		if !domain.HasPermission(perms, perm) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 				"error": fmt.Sprintf("Forbidden: Role %s lacks permission %s", roleName, perm.String()),
 			})
 			return
 		}
 
+		if !session.MFA && domain.RoleRequiresMFA(roleName, perms) && m.projectRequiresMFA(c.Request.Context(), projectID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Forbidden: This project requires multi-factor authentication for your role",
+			})
+			return
+		}
+
+		c.Set(ProjectAccessKey, &ProjectAccess{ProjectID: projectID, Role: roleName, Permissions: perms, APIKey: session.APIKey})
+		c.Next()
+	}
+}
+
+// ProjectAccess is what RequireProjectPermission learnt about the caller on the project, for the
+// handlers whose payload needs more permissions than the route itself.
+type ProjectAccess struct {
+	ProjectID   uuid.UUID
+	Admin       bool
+	Role        string // rôle du membre, vide pour un admin
+	Permissions []string
+	APIKey      *cache.APIKeyScope
+}
+
+// Allows tells whether the caller holds perm on the project, within the scopes of its API key.
+func (a *ProjectAccess) Allows(perm domain.PermissionType) bool {
+	if a.APIKey != nil && !a.APIKey.Allows(a.ProjectID.String(), perm.String()) {
+		return false
+	}
+	return a.Admin || domain.HasPermission(a.Permissions, perm)
+}
+
+// ProjectAccessFrom returns the access checked for the request, nil on a route without AddRight.
+func ProjectAccessFrom(c *gin.Context) *ProjectAccess {
+	val, ok := c.Get(ProjectAccessKey)
+	if !ok {
+		return nil
+	}
+	access, _ := val.(*ProjectAccess)
+	return access
+}
+
+// rolePermissions returns the permission slugs of a role from Redis, or from the database on a
+// miss. Editing a role drops its entry, the TTL only bounds what a missed invalidation costs.
+func (m *MiddlewareManager) rolePermissions(ctx context.Context, roleName string) ([]string, error) {
+	if perms, err := m.CacheRepo.GetRolePermissions(ctx, roleName); err == nil {
+		return perms, nil
+	}
+
+	role, err := m.AuthRepo.GetRoleByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	perms := make([]string, len(role.Permissions))
+	for i, p := range role.Permissions {
+		perms[i] = p.Slug
+	}
+
+	if err := m.CacheRepo.SetRolePermissions(ctx, roleName, perms, rolePermissionsTTL); err != nil {
+		m.Logger.Warnw("failed to cache role permissions", "role", roleName, "error", err)
+	}
+	return perms, nil
+}
+
+// projectRequiresMFA reads the project policy, an unknown project is treated as not requiring
+// MFA since the handler will answer 404 anyway.
+func (m *MiddlewareManager) projectRequiresMFA(ctx context.Context, projectID uuid.UUID) bool {
+	required, err := m.AuthRepo.GetProjectMFAPolicy(ctx, projectID)
+	if err != nil {
+		return false
+	}
+	return required
+}
+
+// RequireVerifiedEmail blocks unverified accounts when verification is enforced. The user is
+// read from the database, a session opened before verification would be stale.
+func (m *MiddlewareManager) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.Config.Verify.Required {
+			c.Next()
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString(UserIDKey))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		user, err := m.AuthRepo.GetUserByID(c.Request.Context(), userID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		if !user.IsVerified {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: Email address not verified"})
+			return
+		}
+
 		c.Next()
 	}
 }
+
 func (m *MiddlewareManager) GetSessionFromCtx(c context.Context) (*cache.SessionData, error) {
 	if ginCtx, ok := c.(*gin.Context); ok {
 		val, exists := ginCtx.Get(UserSessionKey)
@@ -139,8 +287,13 @@ func (m *MiddlewareManager) handleJWTAuth(c *gin.Context, tokenString string) {
 		return
 	}
 
-	session, err := m.CacheRepo.GetSession(c.Request.Context(), claims.UserID)
-	if err != nil {
+	if claims.SessionID == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Invalid token"})
+		return
+	}
+
+	session, err := m.CacheRepo.GetSession(c.Request.Context(), claims.SessionID)
+	if err != nil || session.UserID != claims.UserID {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Session expired"})
 		return
 	}
@@ -150,34 +303,45 @@ func (m *MiddlewareManager) handleJWTAuth(c *gin.Context, tokenString string) {
 		return
 	}
 
+	if time.Since(session.LastSeenAt) > lastSeenInterval {
+		m.touchSession(c.Request.Context(), *session)
+	}
+
 	c.Set(UserSessionKey, session)
 	c.Set(UserIDKey, claims.UserID)
+	c.Set(SessionIDKey, claims.SessionID)
+	c.Request = c.Request.WithContext(events.WithActor(c.Request.Context(), events.Actor{
+		Type:  events.ActorUser,
+		ID:    session.UserID,
+		Email: session.Email,
+	}))
 	c.Next()
 }
 
-func (m *MiddlewareManager) handleAPIKeyAuth(c *gin.Context, rawKey string) {
-	if len(rawKey) < 10 || !strings.Contains(rawKey, "_") {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key format"})
+// touchSession records the last activity of a session, keeping its remaining TTL.
+func (m *MiddlewareManager) touchSession(ctx context.Context, session cache.SessionData) {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
 		return
 	}
-
-	prefixLen := 7
-	if len(rawKey) < prefixLen {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key"})
-		return
+	session.LastSeenAt = time.Now()
+	if err := m.CacheRepo.StoreSession(ctx, session.SessionID, session, ttl); err != nil {
+		m.Logger.Warnw("failed to update session last seen", "session_id", session.SessionID, "error", err)
 	}
-	prefix := rawKey[:prefixLen]
+}
 
-	apiKey, err := m.AuthRepo.GetAPIKeyByPrefix(c.Request.Context(), prefix)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key"})
+func (m *MiddlewareManager) handleAPIKeyAuth(c *gin.Context, rawKey string) {
+	if len(rawKey) < 10 || !strings.Contains(rawKey, "_") {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key format"})
 		return
 	}
 
 	hash := sha256.Sum256([]byte(rawKey))
 	hashedIncoming := hex.EncodeToString(hash[:])
 
-	if apiKey.KeyHash != hashedIncoming {
+	// recherche par empreinte : le préfixe affiché est trop court pour être unique
+	apiKey, err := m.AuthRepo.GetAPIKeyByHash(c.Request.Context(), hashedIncoming)
+	if err != nil || !apiKey.Matches(hashedIncoming, time.Now()) {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key"})
 		return
 	}
@@ -187,9 +351,15 @@ func (m *MiddlewareManager) handleAPIKeyAuth(c *gin.Context, rawKey string) {
 		return
 	}
 
-	go func() {
-		_ = m.AuthRepo.UpdateAPIKeyUsage(context.Background(), apiKey.ID)
-	}()
+	if !ipAllowed(c.ClientIP(), apiKey.AllowedCIDRs) {
+		m.Logger.Warnw("API key used from a disallowed address", "key_id", apiKey.ID, "client_ip", c.ClientIP())
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: API Key not allowed from this address"})
+		return
+	}
+
+	if m.Usage != nil {
+		m.Usage.Record(apiKey.ID, c.ClientIP())
+	}
 
 	user, err := m.AuthRepo.GetUserByID(c.Request.Context(), apiKey.UserID)
 	if err != nil {
@@ -203,15 +373,50 @@ func (m *MiddlewareManager) handleAPIKeyAuth(c *gin.Context, rawKey string) {
 		projectRoles[mem.ProjectID.String()] = mem.Role.Name
 	}
 
+	keyScope := &cache.APIKeyScope{KeyID: apiKey.ID.String()}
+	for _, scope := range apiKey.Scopes {
+		keyScope.Scopes = append(keyScope.Scopes, scope.String())
+	}
+	for _, id := range apiKey.ProjectIDs {
+		keyScope.ProjectIDs = append(keyScope.ProjectIDs, id.String())
+	}
+
 	virtualSession := &cache.SessionData{
 		UserID:       user.ID.String(),
 		Email:        user.Email,
 		GlobalRole:   user.Role,
 		ProjectRoles: projectRoles,
+		APIKey:       keyScope,
 	}
 
 	c.Set(UserSessionKey, virtualSession)
 	c.Set(UserIDKey, user.ID.String())
 
+	// les événements d'une CI désignent le compte de service et non un humain
+	actor := events.Actor{Type: events.ActorUser, ID: user.ID.String(), Email: user.Email, APIKeyID: apiKey.ID.String()}
+	if user.IsServiceAccount() {
+		actor.Type = events.ActorServiceAccount
+	}
+	c.Request = c.Request.WithContext(events.WithActor(c.Request.Context(), actor))
+
 	c.Next()
 }
+
+// ipAllowed checks the client address against the key allow-list, an empty list allows any
+// address.
+func ipAllowed(clientIP string, cidrs []string) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
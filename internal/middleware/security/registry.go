@@ -0,0 +1,222 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+)
+
+// RegistryTokenIssuer is the issuer of the registry tokens, auth.token.issuer of the registry.
+const RegistryTokenIssuer = "kubemanager"
+
+// Logins handed to the registry: "project/<namespace>" pulls the images of a project,
+// "build/<id>" pushes the image of a running build.
+const (
+	RegistryAccountProject = "project"
+	RegistryAccountBuild   = "build"
+)
+
+// RegistryCredentials derives the registry passwords from the platform key, nothing is stored:
+// the token endpoint recomputes them.
+type RegistryCredentials struct {
+	key []byte
+}
+
+// RegistryCredentialsFromConfig uses the key of the secrets stored by the platform, jwt.secret when unset.
+func RegistryCredentialsFromConfig(cfg *configs.GlobalConfig) *RegistryCredentials {
+	key := cfg.Secrets.EncryptionKey
+	if key == "" {
+		key = cfg.JWT.Secret
+	}
+	return &RegistryCredentials{key: []byte(key)}
+}
+
+func (r *RegistryCredentials) Project(namespace string) (string, string) {
+	return r.login(RegistryAccountProject + "/" + namespace)
+}
+
+func (r *RegistryCredentials) Build(buildID string) (string, string) {
+	return r.login(RegistryAccountBuild + "/" + buildID)
+}
+
+// Check returns the kind and the subject (namespace or build id) of a login.
+func (r *RegistryCredentials) Check(username, password string) (string, string, bool) {
+	kind, subject, found := strings.Cut(username, "/")
+	if !found || subject == "" || (kind != RegistryAccountProject && kind != RegistryAccountBuild) {
+		return "", "", false
+	}
+	_, expected := r.login(username)
+	if !hmac.Equal([]byte(password), []byte(expected)) {
+		return "", "", false
+	}
+	return kind, subject, true
+}
+
+func (r *RegistryCredentials) login(username string) (string, string) {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte("registry-login:" + username))
+	return username, base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RegistryAccess is an entry of the "access" claim, e.g. repository kubemanager/km-shop/api pull,push.
+type RegistryAccess struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+}
+
+// RegistryTokenSigner signs the bearer tokens of the registry token authentication. The registry
+// trusts the certificate of the key (auth.token.rootcertbundle), sent in the x5c header.
+type RegistryTokenSigner struct {
+	key     crypto.Signer
+	method  jwt.SigningMethod
+	keyID   string
+	chain   []string
+	service string
+}
+
+// NewRegistryTokenSigner loads the key and certificate of the build configuration, nil when
+// builds are disabled.
+func NewRegistryTokenSigner(cfg configs.BuildConfig) (*RegistryTokenSigner, error) {
+	if cfg.Registry == "" {
+		return nil, nil
+	}
+	if cfg.TokenKeyFile == "" || cfg.TokenCertFile == "" {
+		return nil, errors.New("build.token_key_file and build.token_cert_file are required with build.registry")
+	}
+
+	key, err := readSigningKey(cfg.TokenKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := readCertificateChain(cfg.TokenCertFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RegistryTokenSigner{key: key, chain: chain, service: cfg.TokenService}
+	if s.service == "" {
+		s.service, _, _ = strings.Cut(cfg.Registry, "/")
+	}
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		s.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("registry token key: only P-256 ECDSA keys are supported")
+		}
+		s.method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("registry token key: unsupported key type %T", pub)
+	}
+	if s.keyID, err = libtrustKeyID(key.Public()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Sign issues a token for the access granted to the login.
+func (s *RegistryTokenSigner) Sign(subject string, access []RegistryAccess, ttl time.Duration) (string, error) {
+	if access == nil {
+		access = []RegistryAccess{}
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(s.method, jwt.MapClaims{
+		"iss":    RegistryTokenIssuer,
+		"sub":    subject,
+		"aud":    s.service,
+		"iat":    now.Unix(),
+		"nbf":    now.Add(-time.Minute).Unix(),
+		"exp":    now.Add(ttl).Unix(),
+		"jti":    hex.EncodeToString(jti),
+		"access": access,
+	})
+	token.Header["kid"] = s.keyID
+	token.Header["x5c"] = s.chain
+	return token.SignedString(s.key)
+}
+
+func readSigningKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("registry token key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("registry token key: no PEM block")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("registry token key: unsupported private key format")
+}
+
+func readCertificateChain(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("registry token certificate: %w", err)
+	}
+	var chain []string
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("registry token certificate: %w", err)
+		}
+		chain = append(chain, base64.StdEncoding.EncodeToString(block.Bytes))
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("registry token certificate: no certificate found")
+	}
+	return chain, nil
+}
+
+// libtrustKeyID is the "kid" the registry computes for the keys of its rootcertbundle:
+// the SHA-256 of the public key, truncated to 240 bits, in base32 groups of four.
+func libtrustKeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	encoded := base32.StdEncoding.EncodeToString(sum[:30])
+
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, ":"), nil
+}
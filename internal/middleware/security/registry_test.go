@@ -0,0 +1,119 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+)
+
+func TestRegistryCredentials(t *testing.T) {
+	creds := RegistryCredentialsFromConfig(&configs.GlobalConfig{Secrets: configs.SecretsConfig{EncryptionKey: "k1"}})
+
+	user, pass := creds.Project("km-shop")
+	if kind, subject, ok := creds.Check(user, pass); !ok || kind != RegistryAccountProject || subject != "km-shop" {
+		t.Fatalf("Check(project) = %s, %s, %v", kind, subject, ok)
+	}
+	buildUser, buildPass := creds.Build("6f1c")
+	if kind, subject, ok := creds.Check(buildUser, buildPass); !ok || kind != RegistryAccountBuild || subject != "6f1c" {
+		t.Fatalf("Check(build) = %s, %s, %v", kind, subject, ok)
+	}
+
+	other := RegistryCredentialsFromConfig(&configs.GlobalConfig{Secrets: configs.SecretsConfig{EncryptionKey: "k2"}})
+	_, otherPass := other.Project("km-shop")
+	for name, login := range map[string][2]string{
+		"password of another namespace": {"project/km-blog", pass},
+		"password of another key":       {user, otherPass},
+		"unknown account kind":          {"admin/km-shop", pass},
+		"no subject":                    {"project/", pass},
+		"empty password":                {user, ""},
+	} {
+		if _, _, ok := creds.Check(login[0], login[1]); ok {
+			t.Errorf("%s: login accepted", name)
+		}
+	}
+}
+
+func TestRegistryTokenSigner(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubemanager registry token"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "token.key"), "EC PRIVATE KEY", keyDER)
+	writePEM(t, filepath.Join(dir, "token.crt"), "CERTIFICATE", certDER)
+
+	if _, err := NewRegistryTokenSigner(configs.BuildConfig{Registry: "registry.example.com/km"}); err == nil {
+		t.Fatal("a registry without token key was accepted")
+	}
+	if s, err := NewRegistryTokenSigner(configs.BuildConfig{}); s != nil || err != nil {
+		t.Fatalf("builds disabled: %v, %v", s, err)
+	}
+
+	signer, err := NewRegistryTokenSigner(configs.BuildConfig{
+		Registry:      "registry.example.com:5000/km",
+		TokenKeyFile:  filepath.Join(dir, "token.key"),
+		TokenCertFile: filepath.Join(dir, "token.crt"),
+	})
+	if err != nil {
+		t.Fatalf("NewRegistryTokenSigner: %v", err)
+	}
+
+	raw, err := signer.Sign("project/km-shop", []RegistryAccess{{Type: "repository", Name: "km/km-shop/api", Actions: []string{"pull"}}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil },
+		jwt.WithValidMethods([]string{"ES256"}), jwt.WithIssuer(RegistryTokenIssuer), jwt.WithAudience("registry.example.com:5000"))
+	if err != nil {
+		t.Fatalf("token rejected: %v", err)
+	}
+	if aud, _ := claims["aud"].(string); aud != "registry.example.com:5000" {
+		t.Errorf("aud = %v, the registry expects a string", claims["aud"])
+	}
+	access, _ := claims["access"].([]interface{})
+	if len(access) != 1 || access[0].(map[string]interface{})["name"] != "km/km-shop/api" {
+		t.Errorf("access = %v", claims["access"])
+	}
+
+	chain, _ := token.Header["x5c"].([]interface{})
+	if len(chain) != 1 || chain[0] != base64.StdEncoding.EncodeToString(certDER) {
+		t.Errorf("x5c = %v, want the certificate", token.Header["x5c"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	if groups := strings.Split(kid, ":"); len(groups) != 12 || len(groups[0]) != 4 {
+		t.Errorf("kid = %q, want 12 groups of 4 characters", kid)
+	}
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ErrResourceNotFound is returned by a resolver when the targeted resource does not exist.
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ProjectResolver finds the project a request acts on, so that the caller's role in that
+// project can be checked before the handler runs.
+type ProjectResolver func(c *gin.Context) (uuid.UUID, error)
+
+// ProjectFromParam reads the project ID from a path parameter, e.g. /projects/:id.
+func ProjectFromParam(name string) ProjectResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		id, err := uuid.Parse(c.Param(name))
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid project ID in path")
+		}
+		return id, nil
+	}
+}
+
+// ProjectFromBody reads the project ID from a field of the JSON body. The body is put back
+// for the handler.
+func ProjectFromBody(field string) ProjectResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("unreadable request body")
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			return uuid.Nil, fmt.Errorf("invalid JSON body")
+		}
+		value, _ := body[field].(string)
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid %s in body", field)
+		}
+		return id, nil
+	}
+}
+
+// ProjectFromResource maps a resource ID taken from a path parameter to the project owning
+// it, lookup returns ErrResourceNotFound for an unknown ID.
+func ProjectFromResource(param string, lookup func(c *gin.Context, id uuid.UUID) (uuid.UUID, error)) ProjectResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		id, err := uuid.Parse(c.Param(param))
+		if err != nil {
+			return uuid.Nil, ErrResourceNotFound
+		}
+		return lookup(c, id)
+	}
+}
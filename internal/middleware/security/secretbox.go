@@ -0,0 +1,86 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+)
+
+// SecretBox encrypts small secrets at rest with AES-256-GCM.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox derives the AES key from a passphrase of any length.
+func NewSecretBox(passphrase string) (*SecretBox, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty encryption key")
+	}
+	key := sha256.Sum256([]byte(passphrase))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// SecretBoxFromConfig uses the key configured for the secrets stored by the platform (git
+// tokens of builds and sources), jwt.secret when unset.
+func SecretBoxFromConfig(cfg *configs.GlobalConfig) (*SecretBox, error) {
+	key := cfg.Secrets.EncryptionKey
+	if key == "" {
+		key = cfg.JWT.Secret
+	}
+	return NewSecretBox(key)
+}
+
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *SecretBox) Open(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	size := b.aead.NonceSize()
+	if len(data) < size {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := b.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// SealOptional seals a secret that may be left empty, an empty value stays empty.
+func (b *SecretBox) SealOptional(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	return b.Seal(plaintext)
+}
+
+// OpenOptional opens a secret sealed by SealOptional, an empty value stays empty.
+func (b *SecretBox) OpenOptional(ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	return b.Open(ciphertext)
+}
@@ -0,0 +1,110 @@
+package security
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	authRepos "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+)
+
+const defaultUsageFlushInterval = 10 * time.Second
+
+// UsageRecorder counts the requests made with each API key in memory and writes them in batches,
+// a busy CI key costs one update per flush instead of one per request. The counts of the
+// last interval are lost if the process is killed without a shutdown.
+type UsageRecorder struct {
+	Repo     authRepos.AuthRepository
+	Logger   *zap.SugaredLogger
+	Interval time.Duration
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]*domain.APIKeyUsage
+}
+
+func NewUsageRecorder(repo authRepos.AuthRepository, logger *zap.SugaredLogger, interval time.Duration) *UsageRecorder {
+	if interval <= 0 {
+		interval = defaultUsageFlushInterval
+	}
+	return &UsageRecorder{
+		Repo:     repo,
+		Logger:   logger.With("component", "APIKeyUsageRecorder"),
+		Interval: interval,
+		pending:  make(map[uuid.UUID]*domain.APIKeyUsage),
+	}
+}
+
+// Record notes one request of the key, it never blocks on the database.
+func (r *UsageRecorder) Record(keyID uuid.UUID, clientIP string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.pending[keyID]
+	if !ok {
+		u = &domain.APIKeyUsage{KeyID: keyID}
+		r.pending[keyID] = u
+	}
+	u.Requests++
+	u.LastUsedAt = time.Now()
+	u.LastUsedIP = clientIP
+}
+
+// Run flushes the counts every interval until ctx is canceled, then writes what is left.
+func (r *UsageRecorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx est annulé, le dernier lot est écrit avec un délai propre
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.Flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			r.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes the pending counts. On failure they are merged back to be retried with the
+// next batch.
+func (r *UsageRecorder) Flush(ctx context.Context) {
+	r.mu.Lock()
+	if len(r.pending) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	batch := make([]domain.APIKeyUsage, 0, len(r.pending))
+	for _, u := range r.pending {
+		batch = append(batch, *u)
+	}
+	r.pending = make(map[uuid.UUID]*domain.APIKeyUsage, len(batch))
+	r.mu.Unlock()
+
+	if err := r.Repo.RecordAPIKeyUsage(ctx, batch); err != nil {
+		r.Logger.Warnw("failed to record api key usage", "keys", len(batch), "error", err)
+		r.merge(batch)
+	}
+}
+
+func (r *UsageRecorder) merge(batch []domain.APIKeyUsage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, old := range batch {
+		u, ok := r.pending[old.KeyID]
+		if !ok {
+			o := old
+			r.pending[old.KeyID] = &o
+			continue
+		}
+		// les requêtes plus récentes gardent leur date et leur adresse
+		u.Requests += old.Requests
+	}
+}
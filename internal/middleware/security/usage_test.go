@@ -0,0 +1,64 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	authRepos "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+)
+
+// usageRepo fails the writes while down is set and keeps the batches it accepted.
+type usageRepo struct {
+	authRepos.AuthRepository
+	down    bool
+	batches [][]domain.APIKeyUsage
+}
+
+func (r *usageRepo) RecordAPIKeyUsage(_ context.Context, usage []domain.APIKeyUsage) error {
+	if r.down {
+		return errors.New("database unavailable")
+	}
+	r.batches = append(r.batches, usage)
+	return nil
+}
+
+func TestUsageRecorderMergesAFailedFlush(t *testing.T) {
+	repo := &usageRepo{down: true}
+	r := NewUsageRecorder(repo, zap.NewNop().Sugar(), 0)
+	ctx := context.Background()
+	ci, deploy := uuid.New(), uuid.New()
+
+	r.Record(ci, "10.0.0.1")
+	r.Record(ci, "10.0.0.1")
+	r.Record(deploy, "10.0.0.5")
+	r.Flush(ctx)
+
+	// les requêtes arrivées pendant la panne gardent leur adresse
+	r.Record(ci, "10.0.0.2")
+	repo.down = false
+	r.Flush(ctx)
+
+	if len(repo.batches) != 1 {
+		t.Fatalf("%d batches written, want 1", len(repo.batches))
+	}
+	got := map[uuid.UUID]domain.APIKeyUsage{}
+	for _, u := range repo.batches[0] {
+		got[u.KeyID] = u
+	}
+	if got[ci].Requests != 3 || got[ci].LastUsedIP != "10.0.0.2" {
+		t.Errorf("ci usage = %+v, want 3 requests from 10.0.0.2", got[ci])
+	}
+	if got[deploy].Requests != 1 || got[deploy].LastUsedIP != "10.0.0.5" {
+		t.Errorf("deploy usage = %+v, want 1 request from 10.0.0.5", got[deploy])
+	}
+
+	r.Flush(ctx)
+	if len(repo.batches) != 1 {
+		t.Error("a flush without new requests wrote an empty batch")
+	}
+}
@@ -0,0 +1,100 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadActivities "github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+)
+
+// SyncWorkloadBindings rewrites the envFrom of a running workload from the secret references and
+// bindings stored in DB, so concurrent bind/unbind calls always converge to the last state. Helm renders the same list
+// (values "envSecrets") on the next deploy. A Job is immutable: its bindings apply to the next run.
+func (a *AddonActivities) SyncWorkloadBindings(ctx context.Context, workloadID string) error {
+	wID, err := uuid.Parse(workloadID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid workload id", "InvalidInput", err)
+	}
+
+	workload, err := a.WorkloadRepo.GetByID(ctx, wID)
+	if err != nil {
+		return fmt.Errorf("workload not found: %w", err)
+	}
+
+	bindings, err := a.Repo.ListBindingsByWorkload(ctx, wID)
+	if err != nil {
+		return err
+	}
+
+	refs := make([]workloadActivities.EnvSecretRef, 0, len(workload.SecretRefs)+len(bindings))
+	for _, name := range workload.SecretRefs {
+		refs = append(refs, workloadActivities.EnvSecretRef{Name: name})
+	}
+	for _, b := range bindings {
+		refs = append(refs, workloadActivities.EnvSecretRef{Name: b.Addon.SecretName, Prefix: b.EnvPrefix})
+	}
+	envFrom := buildEnvFrom(workload.Name, refs)
+
+	ns := workload.Namespace
+	switch workload.Kind {
+	case utils.WorkloadKindJob:
+		return nil
+
+	case utils.WorkloadKindCronJob:
+		cronJob, err := a.K8sClient.BatchV1().CronJobs(ns).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return ignoreNotDeployed(err)
+		}
+		if len(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers) == 0 {
+			return nil
+		}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].EnvFrom = envFrom
+		_, err = a.K8sClient.BatchV1().CronJobs(ns).Update(ctx, cronJob, metav1.UpdateOptions{})
+		return err
+
+	default:
+		deployment, err := a.K8sClient.AppsV1().Deployments(ns).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return ignoreNotDeployed(err)
+		}
+		if len(deployment.Spec.Template.Spec.Containers) == 0 {
+			return nil
+		}
+		deployment.Spec.Template.Spec.Containers[0].EnvFrom = envFrom
+		_, err = a.K8sClient.AppsV1().Deployments(ns).Update(ctx, deployment, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+// buildEnvFrom mirrors the envFrom rendered by the standard-app chart.
+func buildEnvFrom(releaseName string, refs []workloadActivities.EnvSecretRef) []corev1.EnvFromSource {
+	optional := true
+	envFrom := []corev1.EnvFromSource{{
+		SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: releaseName + "-env"}},
+	}}
+	for _, ref := range refs {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			Prefix: ref.Prefix,
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+				Optional:             &optional,
+			},
+		})
+	}
+	return envFrom
+}
+
+// le workload n'est pas encore installé : le chart prendra les bindings au déploiement
+func ignoreNotDeployed(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package activities
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+)
+
+type AddonDBActivities struct {
+	Repo   repository.AddonRepository
+	Logger *zap.SugaredLogger
+}
+
+func (a *AddonDBActivities) UpdateAddonStatus(ctx context.Context, addonID string, status string, phase string) error {
+	a.Logger.Infow("Updating add-on status in DB", "id", addonID, "status", status, "phase", phase)
+
+	uID, _ := uuid.Parse(addonID)
+	return a.Repo.UpdateStatus(ctx, uID, status, phase)
+}
+
+func (a *AddonDBActivities) SetAddonFinalSnapshot(ctx context.Context, addonID string, snapshot string) error {
+	uID, _ := uuid.Parse(addonID)
+	return a.Repo.SetFinalSnapshot(ctx, uID, snapshot)
+}
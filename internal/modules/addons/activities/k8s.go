@@ -0,0 +1,190 @@
+package activities
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/addons/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+	workloadRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const chartsDir = "/app/internal/infrastructure/helm/charts"
+
+// heartbeat interval of the helm install, must stay below the workflow HeartbeatTimeout
+const helmHeartbeatInterval = 10 * time.Second
+
+type AddonActivities struct {
+	K8sClient    *kubernetes.Clientset
+	Repo         repository.AddonRepository
+	WorkloadRepo workloadRepo.WorkloadRepository
+}
+
+type AddonSecretInput struct {
+	Namespace  string
+	SecretName string
+	Type       string
+	Host       string
+	Port       int
+}
+
+type InstallAddonInput struct {
+	ReleaseName  string
+	Namespace    string
+	Type         string
+	Version      string
+	SecretName   string
+	CPULimit     string
+	MemoryLimit  string
+	StorageSize  string
+	StorageClass string
+}
+
+// EnsureAddonSecret generates the add-on credentials once, an existing Secret is kept
+// so a retried provisioning never rotates the password under a running database.
+func (a *AddonActivities) EnsureAddonSecret(ctx context.Context, input AddonSecretInput) error {
+	_, err := a.K8sClient.CoreV1().Secrets(input.Namespace).Get(ctx, input.SecretName, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	password, err := randomPassword()
+	if err != nil {
+		return err
+	}
+
+	var data map[string]string
+	switch input.Type {
+	case domain.AddonTypePostgres:
+		data = map[string]string{
+			"PGHOST":       input.Host,
+			"PGPORT":       fmt.Sprint(input.Port),
+			"PGUSER":       "app",
+			"PGPASSWORD":   password,
+			"PGDATABASE":   "app",
+			"DATABASE_URL": fmt.Sprintf("postgres://app:%s@%s:%d/app?sslmode=disable", password, input.Host, input.Port),
+		}
+	case domain.AddonTypeRedis:
+		data = map[string]string{
+			"REDIS_HOST":     input.Host,
+			"REDIS_PORT":     fmt.Sprint(input.Port),
+			"REDIS_PASSWORD": password,
+			"REDIS_URL":      fmt.Sprintf("redis://:%s@%s:%d/0", password, input.Host, input.Port),
+		}
+	default:
+		return temporal.NewNonRetryableApplicationError("unknown add-on type: "+input.Type, "InvalidInput", nil)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      input.SecretName,
+			Namespace: input.Namespace,
+			Labels:    map[string]string{"kubemanager.io/managed": "true"},
+		},
+		StringData: data,
+	}
+
+	_, err = a.K8sClient.CoreV1().Secrets(input.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func (a *AddonActivities) DeleteAddonSecret(ctx context.Context, namespace, secretName string) error {
+	err := a.K8sClient.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (a *AddonActivities) InstallAddonChart(ctx context.Context, input InstallAddonInput) error {
+	def, ok := domain.Catalog[input.Type]
+	if !ok {
+		return temporal.NewNonRetryableApplicationError("unknown add-on type: "+input.Type, "InvalidInput", nil)
+	}
+
+	settings := cli.New()
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(settings.RESTClientGetter(), input.Namespace, "secret", func(format string, v ...interface{}) {
+		fmt.Printf(format, v...)
+	}); err != nil {
+		return err
+	}
+
+	client := action.NewUpgrade(actionConfig)
+	client.Install = true
+	client.Namespace = input.Namespace
+	client.Wait = true
+	client.Timeout = 5 * time.Minute
+
+	chart, err := loader.Load(filepath.Join(chartsDir, def.Chart))
+	if err != nil {
+		return fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	vals := map[string]interface{}{
+		"version":    input.Version,
+		"secretName": input.SecretName,
+		"resources": map[string]interface{}{
+			"limits": map[string]interface{}{
+				"cpu":    input.CPULimit,
+				"memory": input.MemoryLimit,
+			},
+		},
+		"storage": map[string]interface{}{
+			"size":         input.StorageSize,
+			"storageClass": input.StorageClass,
+		},
+	}
+
+	// helm bloque pendant le Wait : on heartbeat pour que Temporal puisse nous livrer une annulation
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.RunWithContext(ctx, input.ReleaseName, chart, vals)
+		done <- err
+	}()
+
+	ticker := time.NewTicker(helmHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("helm release failed: %w", err)
+			}
+			return nil
+		case <-ticker.C:
+			activity.RecordHeartbeat(ctx, input.ReleaseName)
+		case <-ctx.Done():
+			<-done
+			return ctx.Err()
+		}
+	}
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
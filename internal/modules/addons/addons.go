@@ -0,0 +1,54 @@
+package addons
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/thekrauss/kubemanager/internal/modules/addons/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/service"
+)
+
+type IAddonController interface {
+	CreateAddon(c *gin.Context, in *domain.CreateAddonRequest) (*domain.AddonResponse, error)
+	ListAddons(c *gin.Context, in *domain.ListAddonsRequest) ([]domain.AddonResponse, error)
+	GetAddon(c *gin.Context, in *domain.AddonPathRequest) (*domain.AddonResponse, error)
+	DeleteAddon(c *gin.Context, in *domain.DeleteAddonRequest) (*domain.AddonResponse, error)
+	BindAddon(c *gin.Context, in *domain.BindAddonRequest) (*domain.AddonBindingResponse, error)
+	UnbindAddon(c *gin.Context, in *domain.UnbindAddonRequest) (*domain.AddonBindingResponse, error)
+	GetCatalog(c *gin.Context) ([]domain.AddonCatalogEntry, error)
+}
+
+type AddonHandler struct {
+	AddonService service.IAddonService
+}
+
+func NewAddonHandler(svc service.IAddonService) *AddonHandler {
+	return &AddonHandler{AddonService: svc}
+}
+
+func (h *AddonHandler) CreateAddon(c *gin.Context, in *domain.CreateAddonRequest) (*domain.AddonResponse, error) {
+	return h.AddonService.CreateAddon(c.Request.Context(), *in)
+}
+
+func (h *AddonHandler) ListAddons(c *gin.Context, in *domain.ListAddonsRequest) ([]domain.AddonResponse, error) {
+	return h.AddonService.ListAddons(c.Request.Context(), in.ProjectID)
+}
+
+func (h *AddonHandler) GetAddon(c *gin.Context, in *domain.AddonPathRequest) (*domain.AddonResponse, error) {
+	return h.AddonService.GetAddon(c.Request.Context(), in.ProjectID, in.AddonID)
+}
+
+func (h *AddonHandler) DeleteAddon(c *gin.Context, in *domain.DeleteAddonRequest) (*domain.AddonResponse, error) {
+	return h.AddonService.DeleteAddon(c.Request.Context(), in.ProjectID, in.AddonID, in.FinalSnapshot)
+}
+
+func (h *AddonHandler) BindAddon(c *gin.Context, in *domain.BindAddonRequest) (*domain.AddonBindingResponse, error) {
+	return h.AddonService.BindAddon(c.Request.Context(), *in)
+}
+
+func (h *AddonHandler) UnbindAddon(c *gin.Context, in *domain.UnbindAddonRequest) (*domain.AddonBindingResponse, error) {
+	return h.AddonService.UnbindAddon(c.Request.Context(), in.ProjectID, in.AddonID, in.WorkloadID)
+}
+
+func (h *AddonHandler) GetCatalog(c *gin.Context) ([]domain.AddonCatalogEntry, error) {
+	return h.AddonService.Catalog(), nil
+}
@@ -0,0 +1,50 @@
+package domain
+
+const (
+	AddonTypePostgres = "postgres"
+	AddonTypeRedis    = "redis"
+)
+
+type AddonDefinition struct {
+	Chart          string // dossier du chart sous helm/charts
+	Port           int
+	Versions       []string
+	DefaultVersion string
+}
+
+type AddonSize struct {
+	CPU     string `json:"cpu"`
+	Memory  string `json:"memory"`
+	Storage string `json:"storage"`
+}
+
+// Catalog lists the add-ons that can be provisioned into a project.
+var Catalog = map[string]AddonDefinition{
+	AddonTypePostgres: {
+		Chart:          "postgres",
+		Port:           5432,
+		Versions:       []string{"14", "15", "16", "17"},
+		DefaultVersion: "16",
+	},
+	AddonTypeRedis: {
+		Chart:          "redis",
+		Port:           6379,
+		Versions:       []string{"6.2", "7.2", "7.4"},
+		DefaultVersion: "7.4",
+	},
+}
+
+var Sizes = map[string]AddonSize{
+	"small":  {CPU: "250m", Memory: "256Mi", Storage: "1Gi"},
+	"medium": {CPU: "500m", Memory: "512Mi", Storage: "5Gi"},
+	"large":  {CPU: "1", Memory: "1Gi", Storage: "20Gi"},
+}
+
+func (d AddonDefinition) SupportsVersion(version string) bool {
+	for _, v := range d.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
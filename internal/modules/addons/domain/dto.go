@@ -0,0 +1,75 @@
+package domain
+
+import "time"
+
+type CreateAddonRequest struct {
+	ProjectID    string `path:"id" desc:"ID du projet"`
+	Name         string `json:"name" binding:"required,min=3,max=30" desc:"Nom de l'add-on (release et hôte)"`
+	Type         string `json:"type" binding:"required,oneof=postgres redis" desc:"postgres ou redis"`
+	Version      string `json:"version" desc:"Version majeure, vide = version par défaut du catalogue"`
+	Size         string `json:"size" default:"small" binding:"omitempty,oneof=small medium large" desc:"small, medium ou large"`
+	StorageClass string `json:"storage_class" default:"local-path"`
+}
+
+type AddonPathRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	AddonID   string `path:"addonID" desc:"ID de l'add-on"`
+}
+
+type ListAddonsRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+}
+
+type DeleteAddonRequest struct {
+	ProjectID     string `path:"id" desc:"ID du projet"`
+	AddonID       string `path:"addonID" desc:"ID de l'add-on"`
+	FinalSnapshot bool   `query:"final_snapshot" desc:"Prendre un snapshot du volume avant la suppression"`
+}
+
+type BindAddonRequest struct {
+	ProjectID  string `path:"id" desc:"ID du projet"`
+	AddonID    string `path:"addonID" desc:"ID de l'add-on"`
+	WorkloadID string `json:"workload_id" binding:"required" desc:"Workload recevant les variables de connexion"`
+	EnvPrefix  string `json:"env_prefix" binding:"omitempty,max=20" desc:"Préfixe des variables (ex: ORDERS_), pour lier plusieurs add-ons"`
+}
+
+type UnbindAddonRequest struct {
+	ProjectID  string `path:"id" desc:"ID du projet"`
+	AddonID    string `path:"addonID" desc:"ID de l'add-on"`
+	WorkloadID string `path:"workloadID" desc:"ID du workload"`
+}
+
+type AddonBindingResponse struct {
+	WorkloadID string    `json:"workload_id"`
+	EnvPrefix  string    `json:"env_prefix,omitempty"`
+	WorkflowID string    `json:"workflow_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AddonResponse struct {
+	ID            string                 `json:"id"`
+	ProjectID     string                 `json:"project_id"`
+	Name          string                 `json:"name"`
+	Type          string                 `json:"type"`
+	Version       string                 `json:"version"`
+	Size          string                 `json:"size"`
+	Status        string                 `json:"status"`
+	Phase         string                 `json:"phase"`
+	Host          string                 `json:"host"`
+	Port          int                    `json:"port"`
+	SecretName    string                 `json:"secret_name"`
+	CPULimit      string                 `json:"cpu_limit"`
+	MemoryLimit   string                 `json:"memory_limit"`
+	StorageSize   string                 `json:"storage_size"`
+	FinalSnapshot string                 `json:"final_snapshot,omitempty"`
+	WorkflowID    string                 `json:"workflow_id,omitempty"`
+	Bindings      []AddonBindingResponse `json:"bindings,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+type AddonCatalogEntry struct {
+	Type           string               `json:"type"`
+	Versions       []string             `json:"versions"`
+	DefaultVersion string               `json:"default_version"`
+	Sizes          map[string]AddonSize `json:"sizes"`
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Addon struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	// Helm Identity
+	Name      string `gorm:"type:varchar(30);not null"` // release et Service (ex: "orders-db")
+	Namespace string `gorm:"not null"`
+	Type      string `gorm:"type:varchar(20);not null"` // postgres | redis
+	Version   string `gorm:"type:varchar(20);not null"`
+	Size      string `gorm:"type:varchar(20);not null;default:'small'"`
+
+	// Ressources comptées dans le quota du projet
+	CPULimit     string `gorm:"type:varchar(20)"`
+	MemoryLimit  string `gorm:"type:varchar(20)"`
+	StorageSize  string `gorm:"type:varchar(20)"`
+	StorageClass string `gorm:"type:varchar(50);default:'local-path'"`
+
+	// Connexion : le mot de passe ne vit que dans le Secret K8s
+	SecretName string `gorm:"type:varchar(100);not null"`
+	Host       string `gorm:"type:varchar(255)"`
+	Port       int
+
+	// Engine State
+	Status         string `gorm:"not null;default:'PROVISIONING'"`
+	CurrentPhase   string `gorm:"not null;default:'ADDON_SECRET_CREATING'"`
+	LastWorkflowID string `gorm:"type:varchar(100)"`
+
+	// snapshot pris à la suppression, conservé après la désinstallation
+	FinalSnapshot string `gorm:"type:varchar(100)"`
+
+	Bindings []AddonBinding `gorm:"foreignKey:AddonID"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AddonBinding injects the add-on connection Secret into a workload (envFrom).
+type AddonBinding struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	AddonID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_addon_binding;not null"`
+	WorkloadID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_addon_binding;index;not null"`
+	EnvPrefix  string    `gorm:"type:varchar(20)"`
+
+	Addon *Addon `gorm:"foreignKey:AddonID"`
+
+	CreatedAt time.Time
+}
+
+// PVCName is the claim created by the StatefulSet volumeClaimTemplate "data".
+func (a *Addon) PVCName() string {
+	return "data-" + a.Name + "-0"
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type AddonRepository interface {
+	Create(ctx context.Context, addon *domain.Addon) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Addon, error)
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Addon, error)
+	ExistsByName(ctx context.Context, projectID uuid.UUID, name string) (bool, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string, phase string) error
+	SetLastWorkflowID(ctx context.Context, id uuid.UUID, workflowID string) error
+	SetFinalSnapshot(ctx context.Context, id uuid.UUID, snapshot string) error
+
+	CreateBinding(ctx context.Context, binding *domain.AddonBinding) error
+	DeleteBinding(ctx context.Context, addonID, workloadID uuid.UUID) error
+	ListBindingsByWorkload(ctx context.Context, workloadID uuid.UUID) ([]domain.AddonBinding, error)
+	DeleteBindingsByWorkload(ctx context.Context, workloadID uuid.UUID) error
+
+	GetTotalUsageByProject(ctx context.Context, projectID uuid.UUID) (totalCPU int64, totalMem int64, totalStorage int64, err error)
+}
+
+type addonRepository struct {
+	db *gorm.DB
+}
+
+func NewAddonRepository(db *gorm.DB) AddonRepository {
+	return &addonRepository{db: db}
+}
+
+func (r *addonRepository) Create(ctx context.Context, addon *domain.Addon) error {
+	return r.db.WithContext(ctx).Create(addon).Error
+}
+
+func (r *addonRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Addon, error) {
+	var addon domain.Addon
+	err := r.db.WithContext(ctx).Preload("Bindings").First(&addon, "id = ?", id).Error
+	return &addon, err
+}
+
+func (r *addonRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Addon, error) {
+	var addons []domain.Addon
+	err := r.db.WithContext(ctx).
+		Preload("Bindings").
+		Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Find(&addons).Error
+	return addons, err
+}
+
+// ExistsByName ignores deleted add-ons, their name can be reused
+func (r *addonRepository) ExistsByName(ctx context.Context, projectID uuid.UUID, name string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Addon{}).
+		Where("project_id = ? AND name = ? AND status <> ?", projectID, name, utils.AddonDeleted).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *addonRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string, phase string) error {
+	return r.db.WithContext(ctx).Model(&domain.Addon{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"current_phase": phase,
+		}).Error
+}
+
+func (r *addonRepository) SetLastWorkflowID(ctx context.Context, id uuid.UUID, workflowID string) error {
+	return r.db.WithContext(ctx).Model(&domain.Addon{}).
+		Where("id = ?", id).
+		Update("last_workflow_id", workflowID).Error
+}
+
+func (r *addonRepository) SetFinalSnapshot(ctx context.Context, id uuid.UUID, snapshot string) error {
+	return r.db.WithContext(ctx).Model(&domain.Addon{}).
+		Where("id = ?", id).
+		Update("final_snapshot", snapshot).Error
+}
+
+func (r *addonRepository) CreateBinding(ctx context.Context, binding *domain.AddonBinding) error {
+	return r.db.WithContext(ctx).Create(binding).Error
+}
+
+func (r *addonRepository) DeleteBinding(ctx context.Context, addonID, workloadID uuid.UUID) error {
+	res := r.db.WithContext(ctx).Delete(&domain.AddonBinding{}, "addon_id = ? AND workload_id = ?", addonID, workloadID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *addonRepository) ListBindingsByWorkload(ctx context.Context, workloadID uuid.UUID) ([]domain.AddonBinding, error) {
+	var bindings []domain.AddonBinding
+	err := r.db.WithContext(ctx).
+		Preload("Addon").
+		Where("workload_id = ?", workloadID).
+		Order("created_at ASC").
+		Find(&bindings).Error
+	return bindings, err
+}
+
+func (r *addonRepository) DeleteBindingsByWorkload(ctx context.Context, workloadID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.AddonBinding{}, "workload_id = ?", workloadID).Error
+}
+
+// GetTotalUsageByProject returns the add-ons consumption, in the same units as the workloads one (milli-cpu, Mi, Mi).
+func (r *addonRepository) GetTotalUsageByProject(ctx context.Context, projectID uuid.UUID) (totalCPU int64, totalMem int64, totalStorage int64, err error) {
+	var addons []domain.Addon
+
+	err = r.db.WithContext(ctx).
+		Where("project_id = ? AND status NOT IN (?, ?)", projectID, utils.AddonFailed, utils.AddonDeleted).
+		Find(&addons).Error
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, a := range addons {
+		totalCPU += parseQuantity(a.CPULimit).MilliValue()
+		totalMem += parseQuantity(a.MemoryLimit).Value() / (1024 * 1024)
+		totalStorage += parseQuantity(a.StorageSize).Value() / (1024 * 1024)
+	}
+
+	return totalCPU, totalMem, totalStorage, nil
+}
+
+func parseQuantity(s string) *resource.Quantity {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return resource.NewQuantity(0, resource.DecimalSI)
+	}
+	return &q
+}
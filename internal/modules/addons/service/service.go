@@ -0,0 +1,455 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/addons/workflows"
+	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+)
+
+type IAddonService interface {
+	CreateAddon(ctx context.Context, req domain.CreateAddonRequest) (*domain.AddonResponse, error)
+	ListAddons(ctx context.Context, projectID string) ([]domain.AddonResponse, error)
+	GetAddon(ctx context.Context, projectID, addonID string) (*domain.AddonResponse, error)
+	DeleteAddon(ctx context.Context, projectID, addonID string, finalSnapshot bool) (*domain.AddonResponse, error)
+	BindAddon(ctx context.Context, req domain.BindAddonRequest) (*domain.AddonBindingResponse, error)
+	UnbindAddon(ctx context.Context, projectID, addonID, workloadID string) (*domain.AddonBindingResponse, error)
+	Catalog() []domain.AddonCatalogEntry
+}
+
+var _ IAddonService = (*AddonService)(nil)
+
+var envPrefixRegex = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+type AddonService struct {
+	TemporalClient client.Client
+	Config         *configs.GlobalConfig
+	Logger         *zap.SugaredLogger
+	Repo           repository.AddonRepository
+	ProjectRepo    projectRepo.ProjectRepository
+	WorkloadRepo   workloadRepo.WorkloadRepository
+}
+
+func NewAddonService(
+	tc client.Client,
+	cfg *configs.GlobalConfig,
+	log *zap.SugaredLogger,
+	repo repository.AddonRepository,
+	pRepo projectRepo.ProjectRepository,
+	wRepo workloadRepo.WorkloadRepository,
+) IAddonService {
+	return &AddonService{
+		TemporalClient: tc,
+		Config:         cfg,
+		Logger:         log.With("service", "AddonService"),
+		Repo:           repo,
+		ProjectRepo:    pRepo,
+		WorkloadRepo:   wRepo,
+	}
+}
+
+func (s *AddonService) CreateAddon(ctx context.Context, req domain.CreateAddonRequest) (*domain.AddonResponse, error) {
+	pID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+
+	project, err := s.ProjectRepo.GetProjectByID(ctx, pID.String())
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+
+	def, ok := domain.Catalog[req.Type]
+	if !ok {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "unknown add-on type: "+req.Type)
+	}
+	version := req.Version
+	if version == "" {
+		version = def.DefaultVersion
+	}
+	if !def.SupportsVersion(version) {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("unsupported %s version: %s", req.Type, version))
+	}
+
+	sizeName := req.Size
+	if sizeName == "" {
+		sizeName = "small"
+	}
+	size, ok := domain.Sizes[sizeName]
+	if !ok {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "unknown add-on size: "+sizeName)
+	}
+
+	if err := s.checkNameAvailable(ctx, pID, req.Name); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, pID, project.CpuLimit, project.MemoryLimit, project.StorageLimit, size); err != nil {
+		return nil, err
+	}
+
+	addonID := uuid.New()
+	namespace := fmt.Sprintf("km-%s", project.Name)
+	workflowID := "addon-provision-" + addonID.String()
+
+	addon := &domain.Addon{
+		ID:             addonID,
+		ProjectID:      pID,
+		Name:           req.Name,
+		Namespace:      namespace,
+		Type:           req.Type,
+		Version:        version,
+		Size:           sizeName,
+		CPULimit:       size.CPU,
+		MemoryLimit:    size.Memory,
+		StorageSize:    size.Storage,
+		StorageClass:   req.StorageClass,
+		SecretName:     req.Name + "-credentials",
+		Host:           fmt.Sprintf("%s.%s.svc.cluster.local", req.Name, namespace),
+		Port:           def.Port,
+		Status:         utils.AddonProvisioning,
+		CurrentPhase:   utils.PhaseAddonSecretCreating,
+		LastWorkflowID: workflowID,
+	}
+
+	if err := s.Repo.Create(ctx, addon); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save add-on")
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: "kubemanager-tasks",
+	}
+
+	_, err = s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.ProvisionAddonWorkflow, workflows.ProvisionAddonInput{
+		AddonID: addonID.String(),
+		Secret: activities.AddonSecretInput{
+			Namespace:  namespace,
+			SecretName: addon.SecretName,
+			Type:       addon.Type,
+			Host:       addon.Host,
+			Port:       addon.Port,
+		},
+		Install: activities.InstallAddonInput{
+			ReleaseName:  addon.Name,
+			Namespace:    namespace,
+			Type:         addon.Type,
+			Version:      addon.Version,
+			SecretName:   addon.SecretName,
+			CPULimit:     addon.CPULimit,
+			MemoryLimit:  addon.MemoryLimit,
+			StorageSize:  addon.StorageSize,
+			StorageClass: addon.StorageClass,
+		},
+	})
+	if err != nil {
+		_ = s.Repo.UpdateStatus(ctx, addonID, utils.AddonFailed, utils.PhaseAddonFailed)
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start add-on provisioning")
+	}
+
+	resp := toAddonResponse(addon)
+	resp.WorkflowID = workflowID
+	return resp, nil
+}
+
+func (s *AddonService) ListAddons(ctx context.Context, projectID string) ([]domain.AddonResponse, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+
+	addons, err := s.Repo.ListByProject(ctx, pID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list add-ons")
+	}
+
+	resp := make([]domain.AddonResponse, 0, len(addons))
+	for i := range addons {
+		resp = append(resp, *toAddonResponse(&addons[i]))
+	}
+	return resp, nil
+}
+
+func (s *AddonService) GetAddon(ctx context.Context, projectID, addonID string) (*domain.AddonResponse, error) {
+	addon, err := s.getProjectAddon(ctx, projectID, addonID)
+	if err != nil {
+		return nil, err
+	}
+	return toAddonResponse(addon), nil
+}
+
+func (s *AddonService) DeleteAddon(ctx context.Context, projectID, addonID string, finalSnapshot bool) (*domain.AddonResponse, error) {
+	addon, err := s.getProjectAddon(ctx, projectID, addonID)
+	if err != nil {
+		return nil, err
+	}
+
+	if addon.Status == utils.AddonDeleted {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "add-on already deleted")
+	}
+	if len(addon.Bindings) > 0 {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "add-on is still bound to workloads, unbind them first")
+	}
+
+	input := workflows.DeleteAddonInput{
+		AddonID:     addon.ID.String(),
+		Namespace:   addon.Namespace,
+		ReleaseName: addon.Name,
+		SecretName:  addon.SecretName,
+		PVCName:     addon.PVCName(),
+	}
+	if finalSnapshot {
+		input.FinalSnapshot = fmt.Sprintf("%s-final-%s", addon.Name, uuid.New().String()[:8])
+		input.SnapshotClass = s.Config.Kubernetes.SnapshotClass
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       "addon-delete-" + addon.ID.String(),
+		TaskQueue:                "kubemanager-tasks",
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.DeleteAddonWorkflow, input)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "add-on deletion already in progress")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start add-on deletion")
+	}
+
+	if err := s.Repo.SetLastWorkflowID(ctx, addon.ID, we.GetID()); err != nil {
+		return nil, err
+	}
+
+	addon.Status = utils.AddonDeleting
+	resp := toAddonResponse(addon)
+	resp.WorkflowID = we.GetID()
+	return resp, nil
+}
+
+func (s *AddonService) BindAddon(ctx context.Context, req domain.BindAddonRequest) (*domain.AddonBindingResponse, error) {
+	addon, err := s.getProjectAddon(ctx, req.ProjectID, req.AddonID)
+	if err != nil {
+		return nil, err
+	}
+	if addon.Status == utils.AddonDeleting || addon.Status == utils.AddonDeleted {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "add-on is being deleted")
+	}
+
+	if req.EnvPrefix != "" && !envPrefixRegex.MatchString(req.EnvPrefix) {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "env prefix must be upper case letters, digits and underscores")
+	}
+
+	wID, err := uuid.Parse(req.WorkloadID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+	workload, err := s.WorkloadRepo.GetByID(ctx, wID)
+	if err != nil || workload.ProjectID != addon.ProjectID {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "workload not found in this project")
+	}
+
+	for _, b := range addon.Bindings {
+		if b.WorkloadID == wID {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "add-on already bound to this workload")
+		}
+	}
+
+	binding := &domain.AddonBinding{
+		ID:         uuid.New(),
+		AddonID:    addon.ID,
+		WorkloadID: wID,
+		EnvPrefix:  req.EnvPrefix,
+	}
+	if err := s.Repo.CreateBinding(ctx, binding); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save binding")
+	}
+
+	workflowID, err := s.syncBindings(ctx, wID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AddonBindingResponse{
+		WorkloadID: wID.String(),
+		EnvPrefix:  binding.EnvPrefix,
+		WorkflowID: workflowID,
+		CreatedAt:  binding.CreatedAt,
+	}, nil
+}
+
+func (s *AddonService) UnbindAddon(ctx context.Context, projectID, addonID, workloadID string) (*domain.AddonBindingResponse, error) {
+	addon, err := s.getProjectAddon(ctx, projectID, addonID)
+	if err != nil {
+		return nil, err
+	}
+
+	wID, err := uuid.Parse(workloadID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	if err := s.Repo.DeleteBinding(ctx, addon.ID, wID); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "binding not found")
+	}
+
+	workflowID, err := s.syncBindings(ctx, wID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AddonBindingResponse{
+		WorkloadID: wID.String(),
+		WorkflowID: workflowID,
+	}, nil
+}
+
+func (s *AddonService) Catalog() []domain.AddonCatalogEntry {
+	entries := make([]domain.AddonCatalogEntry, 0, len(domain.Catalog))
+	for addonType, def := range domain.Catalog {
+		entries = append(entries, domain.AddonCatalogEntry{
+			Type:           addonType,
+			Versions:       def.Versions,
+			DefaultVersion: def.DefaultVersion,
+			Sizes:          domain.Sizes,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Type < entries[j].Type })
+	return entries
+}
+
+// syncBindings pushes the bindings stored in DB to the running workload.
+func (s *AddonService) syncBindings(ctx context.Context, workloadID uuid.UUID) (string, error) {
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("workload-bindings-%s-%s", workloadID, uuid.New().String()[:8]),
+		TaskQueue: "kubemanager-tasks",
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.SyncBindingsWorkflow, workloadID.String())
+	if err != nil {
+		return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to apply bindings to the workload")
+	}
+	return we.GetID(), nil
+}
+
+func (s *AddonService) checkNameAvailable(ctx context.Context, projectID uuid.UUID, name string) error {
+	taken, err := s.Repo.ExistsByName(ctx, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	// même namespace : un workload du même nom partagerait la release Helm et le Service
+	workloads, err := s.WorkloadRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		if w.Name == name {
+			taken = true
+		}
+	}
+
+	if taken {
+		return betoerrors.New(betoerrors.CodeConflict, "name already used in this project")
+	}
+	return nil
+}
+
+func (s *AddonService) checkQuota(ctx context.Context, projectID uuid.UUID, cpuLimit, memLimit, storageLimit string, size domain.AddonSize) error {
+	cpu, mem, storage, err := s.WorkloadRepo.GetTotalUsageByProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	addonCPU, addonMem, addonStorage, err := s.Repo.GetTotalUsageByProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	if cpu+addonCPU+milliCPU(size.CPU) > milliCPU(cpuLimit) {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "quota exceeded: CPU limit reached")
+	}
+	if mem+addonMem+mebibytes(size.Memory) > mebibytes(memLimit) {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "quota exceeded: Memory limit reached")
+	}
+	if storage+addonStorage+mebibytes(size.Storage) > mebibytes(storageLimit) {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "quota exceeded: Storage limit reached")
+	}
+	return nil
+}
+
+func (s *AddonService) getProjectAddon(ctx context.Context, projectID, addonID string) (*domain.Addon, error) {
+	aID, err := uuid.Parse(addonID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid add-on id")
+	}
+
+	addon, err := s.Repo.GetByID(ctx, aID)
+	if err != nil || addon.ProjectID.String() != projectID {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "add-on not found")
+	}
+	return addon, nil
+}
+
+func toAddonResponse(a *domain.Addon) *domain.AddonResponse {
+	resp := &domain.AddonResponse{
+		ID:            a.ID.String(),
+		ProjectID:     a.ProjectID.String(),
+		Name:          a.Name,
+		Type:          a.Type,
+		Version:       a.Version,
+		Size:          a.Size,
+		Status:        a.Status,
+		Phase:         a.CurrentPhase,
+		Host:          a.Host,
+		Port:          a.Port,
+		SecretName:    a.SecretName,
+		CPULimit:      a.CPULimit,
+		MemoryLimit:   a.MemoryLimit,
+		StorageSize:   a.StorageSize,
+		FinalSnapshot: a.FinalSnapshot,
+		CreatedAt:     a.CreatedAt,
+	}
+	for _, b := range a.Bindings {
+		resp.Bindings = append(resp.Bindings, domain.AddonBindingResponse{
+			WorkloadID: b.WorkloadID.String(),
+			EnvPrefix:  b.EnvPrefix,
+			CreatedAt:  b.CreatedAt,
+		})
+	}
+	return resp
+}
+
+func milliCPU(cpu string) int64 {
+	q, err := resource.ParseQuantity(cpu)
+	if err != nil {
+		return 0
+	}
+	return q.MilliValue()
+}
+
+func mebibytes(size string) int64 {
+	q, err := resource.ParseQuantity(size)
+	if err != nil {
+		return 0
+	}
+	return q.Value() / (1024 * 1024)
+}
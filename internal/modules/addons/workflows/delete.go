@@ -0,0 +1,92 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/addons/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadActivities "github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type DeleteAddonInput struct {
+	AddonID     string
+	Namespace   string
+	ReleaseName string
+	SecretName  string
+	PVCName     string
+
+	// vide = pas de snapshot final
+	FinalSnapshot string
+	SnapshotClass string
+}
+
+func DeleteAddonWorkflow(ctx workflow.Context, input DeleteAddonInput) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	// polling : snapshot et suppression de PVC peuvent prendre plusieurs minutes
+	pollCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    1 * time.Minute,
+			MaximumAttempts:    40,
+		},
+	})
+
+	var dbActs *activities.AddonDBActivities
+	var addonActs *activities.AddonActivities
+	var k8sActs *workloadActivities.WorkloadActivities
+
+	phase := utils.PhaseAddonUninstalling
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
+	if input.FinalSnapshot != "" {
+		phase = utils.PhaseAddonSnapshotting
+		workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonDeleting, phase).Get(ctx, nil)
+
+		err := workflow.ExecuteActivity(ctx, k8sActs.CreateVolumeSnapshot, input.Namespace, input.FinalSnapshot, input.PVCName, input.SnapshotClass).Get(ctx, nil)
+		if err == nil {
+			err = workflow.ExecuteActivity(pollCtx, k8sActs.WaitForSnapshotReady, input.Namespace, input.FinalSnapshot).Get(ctx, nil)
+		}
+		if err != nil {
+			// sans snapshot on ne détruit pas les données : l'add-on reste en service
+			phase = utils.PhaseAddonSnapshotFailed
+			workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonReady, phase).Get(ctx, nil)
+			return err
+		}
+		workflow.ExecuteActivity(ctx, dbActs.SetAddonFinalSnapshot, input.AddonID, input.FinalSnapshot).Get(ctx, nil)
+	}
+
+	phase = utils.PhaseAddonUninstalling
+	workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonDeleting, phase).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, k8sActs.UninstallRelease, input.Namespace, input.ReleaseName).Get(ctx, nil); err != nil {
+		return err
+	}
+
+	// les PVC d'un volumeClaimTemplate ne sont pas supprimées avec le StatefulSet
+	if err := workflow.ExecuteActivity(pollCtx, k8sActs.DeletePVC, input.Namespace, input.PVCName).Get(ctx, nil); err != nil {
+		return err
+	}
+
+	if err := workflow.ExecuteActivity(ctx, addonActs.DeleteAddonSecret, input.Namespace, input.SecretName).Get(ctx, nil); err != nil {
+		return err
+	}
+
+	phase = utils.PhaseAddonDeleted
+	workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonDeleted, phase).Get(ctx, nil)
+	return nil
+}
@@ -0,0 +1,82 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/addons/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type ProvisionAddonInput struct {
+	AddonID string
+	Secret  activities.AddonSecretInput
+	Install activities.InstallAddonInput
+}
+
+func ProvisionAddonWorkflow(ctx workflow.Context, input ProvisionAddonInput) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	var dbActs *activities.AddonDBActivities
+	var k8sActs *activities.AddonActivities
+
+	phase := utils.PhaseAddonSecretCreating
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
+	fail := func(err error) error {
+		phase = utils.PhaseAddonFailed
+		workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonFailed, phase).Get(ctx, nil)
+		return err
+	}
+
+	workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonProvisioning, phase).Get(ctx, nil)
+
+	// les identifiants sont générés dans l'activité : ils ne passent jamais par l'historique Temporal
+	if err := workflow.ExecuteActivity(ctx, k8sActs.EnsureAddonSecret, input.Secret).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	phase = utils.PhaseAddonInstalling
+	workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonProvisioning, phase).Get(ctx, nil)
+
+	installCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		HeartbeatTimeout:    30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+	if err := workflow.ExecuteActivity(installCtx, k8sActs.InstallAddonChart, input.Install).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	phase = utils.PhaseAddonReady
+	workflow.ExecuteActivity(ctx, dbActs.UpdateAddonStatus, input.AddonID, utils.AddonReady, phase).Get(ctx, nil)
+	return nil
+}
+
+// SyncBindingsWorkflow applies the add-on bindings of a workload to its running pods.
+func SyncBindingsWorkflow(ctx workflow.Context, workloadID string) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 5,
+		},
+	})
+
+	var k8sActs *activities.AddonActivities
+	return workflow.ExecuteActivity(ctx, k8sActs.SyncWorkloadBindings, workloadID).Get(ctx, nil)
+}
@@ -28,24 +28,55 @@ type MessageResponse struct {
 type LoginRequest struct {
 	Identifier string `json:"identifier" binding:"required"`
 	Password   string `json:"password" binding:"required"`
+	UserAgent  string `json:"-"`
+	ClientIP   string `json:"-"`
 }
 
 type LogoutRequest struct {
 	UserID       string `json:"-"`
+	SessionID    string `json:"-"`
 	RefreshToken string `json:"refresh_token" binding:"required"`
 	AccessToken  string `json:"-"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
+	UserAgent    string `json:"-"`
+	ClientIP     string `json:"-"`
 }
 
+// LoginResponse carries the tokens, or only MFAToken when a second factor is still expected.
 type LoginResponse struct {
-	Token        string `json:"token"`
-	RefreshToken string `json:"refresh_token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	UserID       string `json:"user_id"`
-	ExpiresAt    string `json:"expires_at"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
 	Message      string `json:"message"`
+	MFARequired  bool   `json:"mfa_required"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+// MFAVerifyRequest completes a login with either a TOTP code or a recovery code.
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	UserAgent    string `json:"-"`
+	ClientIP     string `json:"-"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Message       string   `json:"message"`
 }
 
 type RefreshTokenResponse struct {
@@ -54,6 +85,16 @@ type RefreshTokenResponse struct {
 	AccessExpiresAt string `json:"access_expires_at"`
 }
 
+// SessionDTO describes one login of the user, ID is the session ID carried by its tokens.
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
 type TokenRequest struct {
 	Token string `json:"token" binding:"required"`
 }
@@ -65,9 +106,23 @@ type TokenResponse struct {
 type RoleDTO struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	IsSystem    bool     `json:"is_system"`
 	Permissions []string `json:"permissions"`
 }
 
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
+type UpdateRoleRequest struct {
+	ID          string   `path:"id" validate:"required,uuid"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
 type ProjectMemberDTO struct {
 	UserID    string `json:"user_id"`
 	Email     string `json:"email"`
@@ -77,6 +132,60 @@ type ProjectMemberDTO struct {
 	JoinedAt  string `json:"joined_at"`
 }
 
+type CreateInvitationRequest struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	Email     string `json:"email" validate:"required,email"`
+	Role      string `json:"role" validate:"required"`
+}
+
+type InvitationDTO struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Email     string    `json:"email"`
+	RoleName  string    `json:"role_name"`
+	Status    string    `json:"status"`
+	InvitedBy string    `json:"invited_by"`
+	Expired   bool      `json:"expired"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateInvitationResponse struct {
+	Invitation *InvitationDTO `json:"invitation,omitempty"`
+	// l'adresse est déjà membre du projet : son rôle est modifié, aucune invitation n'est envoyée
+	MemberUpdated bool   `json:"member_updated"`
+	RoleName      string `json:"role_name"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type InvitationAcceptedResponse struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	RoleName    string `json:"role_name"`
+}
+
+type ServiceAccountDTO struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	RoleName  string    `json:"role_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateServiceAccountRequest struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	Name      string `json:"name" validate:"required"` // "github-actions"
+	Role      string `json:"role" validate:"required"`
+}
+
+type UpdateServiceAccountRequest struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	AccountID string `path:"accountID" validate:"required,uuid"`
+	Role      string `json:"role" validate:"required"`
+}
+
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
@@ -19,6 +19,11 @@ type Project struct {
 	Status       string          `gorm:"default:'PENDING'"`
 	CurrentPhase string          `gorm:"default:'DB_INITIALIZING'"`
 
+	LastWorkflowID string `gorm:"type:varchar(100)"`
+
+	// impose le MFA aux membres propriétaires ou autorisés au shell
+	RequireMFA bool `gorm:"default:false"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -36,6 +41,13 @@ type User struct {
 	IsActive   bool `gorm:"default:true"`
 	IsVerified bool `gorm:"default:false"`
 
+	// secret TOTP chiffré, renseigné dès l'enrôlement et actif une fois MFAEnabled confirmé
+	MFASecret  string `gorm:"type:text" json:"-"`
+	MFAEnabled bool   `gorm:"default:false"`
+
+	// projet propriétaire d'un compte de service : pas de connexion par mot de passe, uniquement des clés API
+	ServiceAccountProjectID *uuid.UUID `gorm:"type:uuid;index"`
+
 	Sessions    []UserSession   `gorm:"foreignKey:UserID"`
 	APIKeys     []APIKey        `gorm:"foreignKey:UserID"`
 	Memberships []ProjectMember `gorm:"foreignKey:UserID"`
@@ -44,20 +56,43 @@ type User struct {
 	UpdatedAt time.Time
 }
 
+// IsServiceAccount tells whether the user is a project service account rather than a person.
+func (u *User) IsServiceAccount() bool {
+	return u.ServiceAccountProjectID != nil
+}
+
+// UserSession is one refresh token. Each refresh rotates it into a new row of the same
+// family, the rotated row is kept so that presenting it again can be detected.
 type UserSession struct {
 	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	UserID           uuid.UUID `gorm:"not null"`
+	UserID           uuid.UUID `gorm:"not null;index"`
+	FamilyID         uuid.UUID `gorm:"type:uuid;index"` // identifiant de la connexion d'origine, exposé comme ID de session
+	StartedAt        time.Time // date de la connexion, recopiée à chaque rotation
 	RefreshTokenHash string    `gorm:"not null"`
 	UserAgent        string
 	ClientIP         string
-	IsBlocked        bool      `gorm:"default:false"`
-	ExpiresAt        time.Time `gorm:"not null"`
+	IsBlocked        bool       `gorm:"default:false"`
+	MFAVerified      bool       `gorm:"default:false"` // connexion validée par un second facteur
+	RotatedAt        *time.Time // non nul une fois le jeton remplacé
+	ExpiresAt        time.Time  `gorm:"not null"`
 	CreatedAt        time.Time
 }
 
+// MFARecoveryCode is a single-use fallback for a lost authenticator, only its hash is kept.
+type MFARecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	CodeHash  string    `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
 type Role struct {
-	ID          uuid.UUID    `gorm:"type:uuid;primary_key"`
-	Name        string       `gorm:"unique"`
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	Name        string    `gorm:"unique"`
+	Description string
+	// rôle intégré (OWNER, DEVELOPER, VIEWER), réécrit au démarrage et non modifiable
+	IsSystem    bool         `gorm:"default:false"`
 	Permissions []Permission `gorm:"many2many:role_permissions;"`
 }
 
@@ -79,18 +114,81 @@ type ProjectMember struct {
 	JoinedAt time.Time
 }
 
+// ProjectInvitation lets a member invite someone by email. The invitee becomes a member only
+// once they accept with the token sent to that address; only the hash of the token is kept.
+// UserIdentity links an account to the subject of an identity provider. Later logins go
+// through this link rather than the email, which the IdP may let its users change.
+type UserIdentity struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	User     User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	Provider string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_identity_subject"`
+	Subject  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_subject"`
+	Email    string    `gorm:"type:varchar(255)"` // adresse au moment de la liaison
+
+	CreatedAt time.Time
+}
+
+type ProjectInvitation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Project   Project   `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE;"`
+	Email     string    `gorm:"type:varchar(255);not null;index"`
+
+	RoleID uuid.UUID `gorm:"type:uuid;not null"`
+	Role   Role      `gorm:"foreignKey:RoleID"`
+
+	InvitedBy uuid.UUID `gorm:"type:uuid"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Status    string    `gorm:"type:varchar(20);default:'PENDING';index"`
+
+	ExpiresAt   time.Time `gorm:"not null"`
+	RespondedAt *time.Time
+	CreatedAt   time.Time
+}
+
 type APIKey struct {
 	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	UserID uuid.UUID `gorm:"not null"`
 
 	Name    string `gorm:"type:varchar(100);not null"`
 	Prefix  string `gorm:"type:varchar(10);not null;index"`
-	KeyHash string `gorm:"type:varchar(255);not null"`
+	KeyHash string `gorm:"type:varchar(255);not null;index"`
 
-	Scopes []PermissionType `gorm:"type:jsonb;serializer:json"`
+	// vides pour les anciennes clés : aucune restriction au-delà des rôles de l'utilisateur
+	Scopes       []PermissionType `gorm:"type:jsonb;serializer:json"`
+	ProjectIDs   []uuid.UUID      `gorm:"type:jsonb;serializer:json"`
+	AllowedCIDRs []string         `gorm:"type:jsonb;serializer:json"`
 
-	LastUsedAt *time.Time
-	ExpiresAt  *time.Time
+	// ancien secret, encore accepté jusqu'à PreviousExpiresAt après une rotation
+	PreviousKeyHash   string `gorm:"type:varchar(255);index"`
+	PreviousExpiresAt *time.Time
+	RotatedAt         *time.Time
+
+	LastUsedAt   *time.Time
+	LastUsedIP   string `gorm:"type:varchar(45)"`
+	RequestCount int64  `gorm:"default:0"`
+
+	ExpiresAt        *time.Time
+	ExpiryNotifiedAt *time.Time // préavis d'expiration envoyé
 
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
+
+// Matches tells whether hash is the current secret of the key, or the previous one during the
+// grace period of a rotation.
+func (k *APIKey) Matches(hash string, now time.Time) bool {
+	if k.KeyHash == hash {
+		return true
+	}
+	return k.PreviousKeyHash != "" && k.PreviousKeyHash == hash &&
+		k.PreviousExpiresAt != nil && now.Before(*k.PreviousExpiresAt)
+}
+
+// APIKeyUsage is the usage of a key accumulated between two writes of the usage recorder.
+type APIKeyUsage struct {
+	KeyID      uuid.UUID
+	Requests   int64
+	LastUsedAt time.Time
+	LastUsedIP string
+}
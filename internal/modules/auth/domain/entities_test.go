@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyMatchesThePreviousSecretDuringTheGrace(t *testing.T) {
+	rotated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	graceUntil := rotated.Add(time.Hour)
+	key := &APIKey{KeyHash: "new", PreviousKeyHash: "old", PreviousExpiresAt: &graceUntil}
+
+	for name, tc := range map[string]struct {
+		hash string
+		now  time.Time
+		want bool
+	}{
+		"current secret after the grace":  {"new", graceUntil.Add(time.Hour), true},
+		"previous secret during":          {"old", rotated, true},
+		"previous secret just before end": {"old", graceUntil.Add(-time.Nanosecond), true},
+		"previous secret at the end":      {"old", graceUntil, false},
+		"previous secret after":           {"old", graceUntil.Add(time.Second), false},
+		"unknown secret":                  {"other", rotated, false},
+	} {
+		if got := key.Matches(tc.hash, tc.now); got != tc.want {
+			t.Errorf("%s: Matches = %v, want %v", name, got, tc.want)
+		}
+	}
+
+	// une clé jamais tournée n'accepte pas un hash vide comme ancien secret
+	fresh := &APIKey{KeyHash: "new"}
+	if fresh.Matches("", rotated) {
+		t.Error("an empty hash matched a key without previous secret")
+	}
+}
@@ -1,39 +1,35 @@
 package domain
 
-func RoleHasPermission(role RoleType, perm PermissionType) bool {
-
-	switch role {
-	case RoleTypes.Owner:
-		return true
-
-	case RoleTypes.Developer:
-		allowed := []PermissionType{
+// DefaultRolePermissions is the permission set of the built-in roles, written to the database
+// at startup. Owner always gets every permission of the enum, new ones included.
+func DefaultRolePermissions() map[RoleType][]PermissionType {
+	return map[RoleType][]PermissionType{
+		RoleTypes.Viewer: {
 			PermissionTypes.ProjectView,
-			PermissionTypes.ProjectEdit,
-			PermissionTypes.WorkloadCreate,
 			PermissionTypes.LogsView,
-		}
-		return containsPermission(allowed, perm)
-
-	case RoleTypes.Viewer:
-		allowed := []PermissionType{
+		},
+		RoleTypes.Developer: {
 			PermissionTypes.ProjectView,
+			PermissionTypes.ProjectEdit,
+			PermissionTypes.WorkloadCreate,
 			PermissionTypes.LogsView,
-		}
-		return containsPermission(allowed, perm)
-
-	case RoleTypes.User:
-		return false
+		},
+		RoleTypes.Owner: PermissionTypes.All(),
 	}
-
-	return false
 }
 
-func containsPermission(slice []PermissionType, item PermissionType) bool {
-	for _, p := range slice {
-		if p == item {
+// HasPermission tells whether perm is part of the permission slugs loaded for a role.
+func HasPermission(perms []string, perm PermissionType) bool {
+	for _, p := range perms {
+		if p == perm.String() {
 			return true
 		}
 	}
 	return false
 }
+
+// RoleRequiresMFA tells whether a project policy requiring MFA applies to the role: owners
+// and any role allowed to open a shell in the workloads.
+func RoleRequiresMFA(roleName string, perms []string) bool {
+	return roleName == RoleTypes.Owner.String() || HasPermission(perms, PermissionTypes.ShellExec)
+}
@@ -92,6 +92,7 @@ var PermissionTypes = permissionTypes{
 	WorkloadDelete: PermissionType{value: "k8s:workload:delete"},
 	ProjectDelete:  PermissionType{value: "project:delete"},
 	ProjectEdit:    PermissionType{value: "project:edit"},
+	MembersManage:  PermissionType{value: "project:members:manage"},
 	ProjectView:    PermissionType{value: "project:view"},
 }
 
@@ -103,6 +104,7 @@ type permissionTypes struct {
 	WorkloadDelete PermissionType
 	ProjectDelete  PermissionType
 	ProjectEdit    PermissionType
+	MembersManage  PermissionType
 	ProjectView    PermissionType
 }
 
@@ -121,6 +123,8 @@ func (f permissionTypes) NewFromString(ctx context.Context, str string) (Permiss
 		return f.ProjectDelete, nil
 	case "project:edit":
 		return f.ProjectEdit, nil
+	case "project:members:manage":
+		return f.MembersManage, nil
 	case "project:view":
 		return f.ProjectView, nil
 	default:
@@ -136,6 +140,7 @@ func (f permissionTypes) All() []PermissionType {
 		f.WorkloadDelete,
 		f.ProjectDelete,
 		f.ProjectEdit,
+		f.MembersManage,
 		f.ProjectView,
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
 
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
 )
 
@@ -11,6 +12,8 @@ type IAPIKeyController interface {
 	ListKeys(c *gin.Context) ([]service.APIKeyDTO, error)
 	CreateKey(c *gin.Context, input *service.CreateAPIKeyInput) (*service.APIKeyCreatedResponse, error)
 	RevokeKey(c *gin.Context, in *RevokeKeyInput) error
+	RotateKey(c *gin.Context, in *RotateKeyInput) (*service.APIKeyCreatedResponse, error)
+	GetKeyUsage(c *gin.Context, in *RevokeKeyInput) (*service.APIKeyUsageDTO, error)
 }
 
 type APIKeyController struct {
@@ -22,7 +25,7 @@ func NewAPIKeyController(s *service.APIKeyService) *APIKeyController {
 }
 
 func (ctrl *APIKeyController) ListKeys(c *gin.Context) ([]service.APIKeyDTO, error) {
-	userID := c.GetString("userID")
+	userID := c.GetString(security.UserIDKey)
 	if userID == "" {
 		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
 	}
@@ -30,10 +33,13 @@ func (ctrl *APIKeyController) ListKeys(c *gin.Context) ([]service.APIKeyDTO, err
 }
 
 func (ctrl *APIKeyController) CreateKey(c *gin.Context, input *service.CreateAPIKeyInput) (*service.APIKeyCreatedResponse, error) {
-	userID := c.GetString("userID")
+	userID := c.GetString(security.UserIDKey)
 	if userID == "" {
 		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
 	}
+	if err := rejectAPIKeySession(c); err != nil {
+		return nil, err
+	}
 	input.UserID = userID
 
 	return ctrl.Service.CreateAPIKey(c.Request.Context(), *input)
@@ -44,6 +50,24 @@ type RevokeKeyInput struct {
 }
 
 func (ctrl *APIKeyController) RevokeKey(c *gin.Context, in *RevokeKeyInput) error {
-	userID := c.GetString("userID")
+	userID := c.GetString(security.UserIDKey)
 	return ctrl.Service.RevokeKey(c.Request.Context(), userID, in.KeyID)
 }
+
+type RotateKeyInput struct {
+	KeyID string `path:"id"`
+	service.RotateAPIKeyInput
+}
+
+func (ctrl *APIKeyController) RotateKey(c *gin.Context, in *RotateKeyInput) (*service.APIKeyCreatedResponse, error) {
+	if err := rejectAPIKeySession(c); err != nil {
+		return nil, err
+	}
+	userID := c.GetString(security.UserIDKey)
+	return ctrl.Service.RotateKey(c.Request.Context(), userID, in.KeyID, in.RotateAPIKeyInput)
+}
+
+func (ctrl *APIKeyController) GetKeyUsage(c *gin.Context, in *RevokeKeyInput) (*service.APIKeyUsageDTO, error) {
+	userID := c.GetString(security.UserIDKey)
+	return ctrl.Service.GetKeyUsage(c.Request.Context(), userID, in.KeyID)
+}
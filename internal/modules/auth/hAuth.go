@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/thekrauss/beto-shared/pkg/errors"
 
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
 )
@@ -20,6 +21,8 @@ type IAuthController interface {
 	ResetPassword(c *gin.Context, in *service.ResetPasswordInput) (*MessageResponse, error)
 	ChangePassword(c *gin.Context, in *service.ChangePasswordInput) (*MessageResponse, error)
 	UpdateProfile(c *gin.Context, in *service.UpdateUserInput) (*domain.User, error)
+	VerifyEmail(c *gin.Context, in *VerifyEmailRequest) (*MessageResponse, error)
+	ResendVerification(c *gin.Context, in *ResendVerificationRequest) (*MessageResponse, error)
 }
 type AuthController struct {
 	AuthService *service.AuthService
@@ -34,6 +37,8 @@ func NewAuthController(authSvc *service.AuthService, rbacSvc *service.RBACServic
 }
 
 func (ctrl *AuthController) Login(c *gin.Context, in *domain.LoginRequest) (*domain.LoginResponse, error) {
+	in.UserAgent = c.Request.UserAgent()
+	in.ClientIP = c.ClientIP()
 	return ctrl.AuthService.Login(c.Request.Context(), in)
 }
 
@@ -42,6 +47,8 @@ func (ctrl *AuthController) ValidateToken(c *gin.Context, in *domain.TokenReques
 }
 
 func (ctrl *AuthController) RefreshToken(c *gin.Context, in *domain.RefreshTokenRequest) (*domain.RefreshTokenResponse, error) {
+	in.UserAgent = c.Request.UserAgent()
+	in.ClientIP = c.ClientIP()
 	return ctrl.AuthService.RefreshToken(c.Request.Context(), in)
 }
 
@@ -64,7 +71,7 @@ func (ctrl *AuthController) Register(c *gin.Context, in *domain.RegisterRequest)
 }
 
 func (ctrl *AuthController) Logout(c *gin.Context, in *domain.LogoutRequest) error {
-	userID := c.GetString("userID")
+	userID := c.GetString(security.UserIDKey)
 	if userID == "" {
 		return errors.New(errors.CodeUnauthorized, "User ID not found in context")
 	}
@@ -76,14 +83,14 @@ func (ctrl *AuthController) Logout(c *gin.Context, in *domain.LogoutRequest) err
 	}
 
 	in.UserID = userID
+	in.SessionID = c.GetString(security.SessionIDKey)
 	in.AccessToken = accessToken
 
 	return ctrl.AuthService.Logout(c.Request.Context(), in)
 }
 
 type MessageResponse struct {
-	Message    string `json:"message"`
-	DebugToken string `json:"debug_token,omitempty"`
+	Message string `json:"message"`
 }
 
 type ForgotPasswordEmailRequest struct {
@@ -91,11 +98,10 @@ type ForgotPasswordEmailRequest struct {
 }
 
 func (ctrl *AuthController) ForgotPassword(c *gin.Context, in *ForgotPasswordEmailRequest) (*MessageResponse, error) {
-	token, err := ctrl.AuthService.ForgotPassword(c.Request.Context(), in.Email)
-	if err != nil {
+	if err := ctrl.AuthService.ForgotPassword(c.Request.Context(), in.Email); err != nil {
 		return nil, err
 	}
-	return &MessageResponse{Message: "Si cet email existe, un lien a été envoyé.", DebugToken: token}, nil
+	return &MessageResponse{Message: "Si cet email existe, un lien a été envoyé."}, nil
 }
 
 func (ctrl *AuthController) ResetPassword(c *gin.Context, in *service.ResetPasswordInput) (*MessageResponse, error) {
@@ -105,8 +111,30 @@ func (ctrl *AuthController) ResetPassword(c *gin.Context, in *service.ResetPassw
 	return &MessageResponse{Message: "Mot de passe réinitialisé avec succès."}, nil
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func (ctrl *AuthController) VerifyEmail(c *gin.Context, in *VerifyEmailRequest) (*MessageResponse, error) {
+	if err := ctrl.AuthService.VerifyEmail(c.Request.Context(), in.Token); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Adresse email vérifiée."}, nil
+}
+
+func (ctrl *AuthController) ResendVerification(c *gin.Context, in *ResendVerificationRequest) (*MessageResponse, error) {
+	if err := ctrl.AuthService.ResendVerification(c.Request.Context(), in.Email); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Si ce compte existe et n'est pas vérifié, un lien a été envoyé."}, nil
+}
+
 func (ctrl *AuthController) ChangePassword(c *gin.Context, in *service.ChangePasswordInput) (*MessageResponse, error) {
-	userID := c.GetString("userID")
+	userID := c.GetString(security.UserIDKey)
 	if userID == "" {
 		return nil, errors.New(errors.CodeUnauthorized, "User ID not found in context")
 	}
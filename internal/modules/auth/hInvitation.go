@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
+)
+
+type IInvitationController interface {
+	Invite(c *gin.Context, in *domain.CreateInvitationRequest) (*domain.CreateInvitationResponse, error)
+	List(c *gin.Context, in *InvitationsInput) ([]domain.InvitationDTO, error)
+	Revoke(c *gin.Context, in *InvitationInput) error
+	Accept(c *gin.Context, in *domain.InvitationTokenRequest) (*domain.InvitationAcceptedResponse, error)
+	Decline(c *gin.Context, in *domain.InvitationTokenRequest) error
+}
+
+type InvitationsInput struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+}
+
+type InvitationInput struct {
+	ProjectID    string `path:"id" validate:"required,uuid"`
+	InvitationID string `path:"invitationID" validate:"required,uuid"`
+}
+
+type InvitationController struct {
+	Service *service.InvitationService
+}
+
+func NewInvitationController(s *service.InvitationService) *InvitationController {
+	return &InvitationController{Service: s}
+}
+
+func (ctrl *InvitationController) Invite(c *gin.Context, in *domain.CreateInvitationRequest) (*domain.CreateInvitationResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.Service.Invite(c.Request.Context(), security.ProjectAccessFrom(c), userID, in)
+}
+
+func (ctrl *InvitationController) List(c *gin.Context, in *InvitationsInput) ([]domain.InvitationDTO, error) {
+	return ctrl.Service.ListPending(c.Request.Context(), in.ProjectID)
+}
+
+func (ctrl *InvitationController) Revoke(c *gin.Context, in *InvitationInput) error {
+	return ctrl.Service.Revoke(c.Request.Context(), in.ProjectID, in.InvitationID)
+}
+
+func (ctrl *InvitationController) Accept(c *gin.Context, in *domain.InvitationTokenRequest) (*domain.InvitationAcceptedResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.Service.Accept(c.Request.Context(), userID, in.Token)
+}
+
+func (ctrl *InvitationController) Decline(c *gin.Context, in *domain.InvitationTokenRequest) error {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.Service.Decline(c.Request.Context(), userID, in.Token)
+}
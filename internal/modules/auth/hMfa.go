@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
+)
+
+type IMFAController interface {
+	Verify(c *gin.Context, in *domain.MFAVerifyRequest) (*domain.LoginResponse, error)
+	Enroll(c *gin.Context) (*domain.MFAEnrollResponse, error)
+	Confirm(c *gin.Context, in *domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error)
+	Disable(c *gin.Context, in *domain.MFACodeRequest) (*MessageResponse, error)
+	RegenerateRecoveryCodes(c *gin.Context, in *domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error)
+}
+
+type MFAController struct {
+	AuthService *service.AuthService
+}
+
+func NewMFAController(authSvc *service.AuthService) *MFAController {
+	return &MFAController{AuthService: authSvc}
+}
+
+func (ctrl *MFAController) Verify(c *gin.Context, in *domain.MFAVerifyRequest) (*domain.LoginResponse, error) {
+	in.UserAgent = c.Request.UserAgent()
+	in.ClientIP = c.ClientIP()
+	return ctrl.AuthService.VerifyMFA(c.Request.Context(), in)
+}
+
+func (ctrl *MFAController) Enroll(c *gin.Context) (*domain.MFAEnrollResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.AuthService.EnrollMFA(c.Request.Context(), userID)
+}
+
+func (ctrl *MFAController) Confirm(c *gin.Context, in *domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.AuthService.ConfirmMFA(c.Request.Context(), userID, in.Code)
+}
+
+func (ctrl *MFAController) Disable(c *gin.Context, in *domain.MFACodeRequest) (*MessageResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	if err := ctrl.AuthService.DisableMFA(c.Request.Context(), userID, in.Code); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Authentification à deux facteurs désactivée."}, nil
+}
+
+func (ctrl *MFAController) RegenerateRecoveryCodes(c *gin.Context, in *domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.AuthService.RegenerateRecoveryCodes(c.Request.Context(), userID, in.Code)
+}
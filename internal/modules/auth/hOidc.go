@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/loopfz/gadgeto/tonic"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
+)
+
+type IOIDCController interface {
+	Login(c *gin.Context)
+	Callback(c *gin.Context, in *OIDCCallbackRequest) (*domain.LoginResponse, error)
+}
+
+type OIDCCallbackRequest struct {
+	Provider         string `path:"provider" binding:"required"`
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
+
+type OIDCController struct {
+	OIDCService *service.OIDCService
+}
+
+func NewOIDCController(oidcSvc *service.OIDCService) *OIDCController {
+	return &OIDCController{OIDCService: oidcSvc}
+}
+
+// Login is a plain gin handler since the browser must be redirected to the identity provider.
+func (ctrl *OIDCController) Login(c *gin.Context) {
+	url, err := ctrl.OIDCService.StartLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		c.JSON(tonic.GetErrorHook()(c, err))
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+func (ctrl *OIDCController) Callback(c *gin.Context, in *OIDCCallbackRequest) (*domain.LoginResponse, error) {
+	return ctrl.OIDCService.Callback(c.Request.Context(), service.OIDCCallbackInput{
+		Provider:         in.Provider,
+		Code:             in.Code,
+		State:            in.State,
+		Error:            in.Error,
+		ErrorDescription: in.ErrorDescription,
+		UserAgent:        c.Request.UserAgent(),
+		ClientIP:         c.ClientIP(),
+	})
+}
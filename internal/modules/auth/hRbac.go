@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/thekrauss/kubemanager/internal/core/configs"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
 )
@@ -12,14 +13,20 @@ type IRBACController interface {
 	AssignRole(c *gin.Context, in *domain.AssignRoleRequest) error
 	ListProjectMembers(c *gin.Context, in *ListMembersInput) ([]domain.ProjectMemberDTO, error)
 	RevokeProjectAccess(c *gin.Context, in *RevokeAccessInput) error
+
+	ListPermissions(c *gin.Context) ([]string, error)
+	CreateRole(c *gin.Context, in *domain.CreateRoleRequest) (*domain.RoleDTO, error)
+	UpdateRole(c *gin.Context, in *domain.UpdateRoleRequest) (*domain.RoleDTO, error)
+	DeleteRole(c *gin.Context, in *RolePathInput) (*MessageResponse, error)
 }
 
 type RBACController struct {
 	RBACService *service.RBACService
+	Config      *configs.GlobalConfig
 }
 
-func NewRBACController(s *service.RBACService) *RBACController {
-	return &RBACController{RBACService: s}
+func NewRBACController(s *service.RBACService, cfg *configs.GlobalConfig) *RBACController {
+	return &RBACController{RBACService: s, Config: cfg}
 }
 
 type ListMembersInput struct {
@@ -31,6 +38,10 @@ type RevokeAccessInput struct {
 	UserID    string `path:"userID" validate:"required,uuid"`
 }
 
+type RolePathInput struct {
+	ID string `path:"id" validate:"required,uuid"`
+}
+
 func (ctrl *RBACController) ListAllRoles(c *gin.Context) ([]domain.RoleDTO, error) {
 	return ctrl.RBACService.ListAllRoles(c.Request.Context())
 }
@@ -46,3 +57,35 @@ func (ctrl *RBACController) ListProjectMembers(c *gin.Context, in *ListMembersIn
 func (ctrl *RBACController) RevokeProjectAccess(c *gin.Context, in *RevokeAccessInput) error {
 	return ctrl.RBACService.RevokeProjectAccess(c.Request.Context(), in.ProjectID, in.UserID)
 }
+
+func (ctrl *RBACController) ListPermissions(c *gin.Context) ([]string, error) {
+	return ctrl.RBACService.ListPermissions(), nil
+}
+
+func (ctrl *RBACController) CreateRole(c *gin.Context, in *domain.CreateRoleRequest) (*domain.RoleDTO, error) {
+	if err := ctrl.requirePlatformAdmin(c); err != nil {
+		return nil, err
+	}
+	return ctrl.RBACService.CreateRole(c.Request.Context(), in)
+}
+
+func (ctrl *RBACController) UpdateRole(c *gin.Context, in *domain.UpdateRoleRequest) (*domain.RoleDTO, error) {
+	if err := ctrl.requirePlatformAdmin(c); err != nil {
+		return nil, err
+	}
+	return ctrl.RBACService.UpdateRole(c.Request.Context(), in)
+}
+
+func (ctrl *RBACController) DeleteRole(c *gin.Context, in *RolePathInput) (*MessageResponse, error) {
+	if err := ctrl.requirePlatformAdmin(c); err != nil {
+		return nil, err
+	}
+	if err := ctrl.RBACService.DeleteRole(c.Request.Context(), in.ID); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Rôle supprimé."}, nil
+}
+
+func (ctrl *RBACController) requirePlatformAdmin(c *gin.Context) error {
+	return requirePlatformAdmin(c, ctrl.Config.Roles.PlatformAdmin, "only platform admins can manage roles")
+}
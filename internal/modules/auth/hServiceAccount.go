@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
+)
+
+type IServiceAccountController interface {
+	List(c *gin.Context, in *ServiceAccountsInput) ([]domain.ServiceAccountDTO, error)
+	Create(c *gin.Context, in *domain.CreateServiceAccountRequest) (*domain.ServiceAccountDTO, error)
+	Update(c *gin.Context, in *domain.UpdateServiceAccountRequest) (*domain.ServiceAccountDTO, error)
+	Delete(c *gin.Context, in *ServiceAccountInput) error
+	ListKeys(c *gin.Context, in *ServiceAccountInput) ([]service.APIKeyDTO, error)
+	CreateKey(c *gin.Context, in *CreateServiceAccountKeyInput) (*service.APIKeyCreatedResponse, error)
+	RevokeKey(c *gin.Context, in *ServiceAccountKeyInput) error
+	RotateKey(c *gin.Context, in *RotateServiceAccountKeyInput) (*service.APIKeyCreatedResponse, error)
+}
+
+type ServiceAccountsInput struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+}
+
+type ServiceAccountInput struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	AccountID string `path:"accountID" validate:"required,uuid"`
+}
+
+type ServiceAccountKeyInput struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	AccountID string `path:"accountID" validate:"required,uuid"`
+	KeyID     string `path:"keyID" validate:"required,uuid"`
+}
+
+type CreateServiceAccountKeyInput struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	AccountID string `path:"accountID" validate:"required,uuid"`
+	service.CreateAPIKeyInput
+}
+
+type RotateServiceAccountKeyInput struct {
+	ProjectID string `path:"id" validate:"required,uuid"`
+	AccountID string `path:"accountID" validate:"required,uuid"`
+	KeyID     string `path:"keyID" validate:"required,uuid"`
+	service.RotateAPIKeyInput
+}
+
+type ServiceAccountController struct {
+	Service *service.ServiceAccountService
+}
+
+func NewServiceAccountController(s *service.ServiceAccountService) *ServiceAccountController {
+	return &ServiceAccountController{Service: s}
+}
+
+func (ctrl *ServiceAccountController) List(c *gin.Context, in *ServiceAccountsInput) ([]domain.ServiceAccountDTO, error) {
+	return ctrl.Service.List(c.Request.Context(), in.ProjectID)
+}
+
+func (ctrl *ServiceAccountController) Create(c *gin.Context, in *domain.CreateServiceAccountRequest) (*domain.ServiceAccountDTO, error) {
+	return ctrl.Service.Create(c.Request.Context(), security.ProjectAccessFrom(c), in)
+}
+
+func (ctrl *ServiceAccountController) Update(c *gin.Context, in *domain.UpdateServiceAccountRequest) (*domain.ServiceAccountDTO, error) {
+	return ctrl.Service.UpdateRole(c.Request.Context(), security.ProjectAccessFrom(c), in)
+}
+
+func (ctrl *ServiceAccountController) Delete(c *gin.Context, in *ServiceAccountInput) error {
+	return ctrl.Service.Delete(c.Request.Context(), in.ProjectID, in.AccountID)
+}
+
+func (ctrl *ServiceAccountController) ListKeys(c *gin.Context, in *ServiceAccountInput) ([]service.APIKeyDTO, error) {
+	return ctrl.Service.ListKeys(c.Request.Context(), in.ProjectID, in.AccountID)
+}
+
+func (ctrl *ServiceAccountController) CreateKey(c *gin.Context, in *CreateServiceAccountKeyInput) (*service.APIKeyCreatedResponse, error) {
+	if err := rejectAPIKeySession(c); err != nil {
+		return nil, err
+	}
+	return ctrl.Service.IssueKey(c.Request.Context(), in.ProjectID, in.AccountID, in.CreateAPIKeyInput)
+}
+
+func (ctrl *ServiceAccountController) RevokeKey(c *gin.Context, in *ServiceAccountKeyInput) error {
+	return ctrl.Service.RevokeKey(c.Request.Context(), in.ProjectID, in.AccountID, in.KeyID)
+}
+
+func (ctrl *ServiceAccountController) RotateKey(c *gin.Context, in *RotateServiceAccountKeyInput) (*service.APIKeyCreatedResponse, error) {
+	if err := rejectAPIKeySession(c); err != nil {
+		return nil, err
+	}
+	return ctrl.Service.RotateKey(c.Request.Context(), in.ProjectID, in.AccountID, in.KeyID, in.RotateAPIKeyInput)
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/service"
+)
+
+type ISessionController interface {
+	ListMySessions(c *gin.Context) ([]domain.SessionDTO, error)
+	RevokeMySession(c *gin.Context, in *SessionPathInput) (*MessageResponse, error)
+	RevokeMyOtherSessions(c *gin.Context) (*MessageResponse, error)
+
+	ListUserSessions(c *gin.Context, in *UserPathInput) ([]domain.SessionDTO, error)
+	RevokeUserSession(c *gin.Context, in *UserSessionPathInput) (*MessageResponse, error)
+	RevokeUserSessions(c *gin.Context, in *UserPathInput) (*MessageResponse, error)
+}
+
+type SessionController struct {
+	AuthService *service.AuthService
+}
+
+func NewSessionController(authSvc *service.AuthService) *SessionController {
+	return &SessionController{AuthService: authSvc}
+}
+
+type SessionPathInput struct {
+	SessionID string `path:"id" validate:"required,uuid"`
+}
+
+type UserPathInput struct {
+	UserID string `path:"id" validate:"required,uuid"`
+}
+
+type UserSessionPathInput struct {
+	UserID    string `path:"id" validate:"required,uuid"`
+	SessionID string `path:"sessionID" validate:"required,uuid"`
+}
+
+func (ctrl *SessionController) ListMySessions(c *gin.Context) ([]domain.SessionDTO, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	return ctrl.AuthService.ListSessions(c.Request.Context(), userID, c.GetString(security.SessionIDKey))
+}
+
+func (ctrl *SessionController) RevokeMySession(c *gin.Context, in *SessionPathInput) (*MessageResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	if userID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	if err := ctrl.AuthService.RevokeSession(c.Request.Context(), userID, in.SessionID); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Session révoquée."}, nil
+}
+
+// RevokeMyOtherSessions is "log out everywhere else": the session of the request is kept.
+func (ctrl *SessionController) RevokeMyOtherSessions(c *gin.Context) (*MessageResponse, error) {
+	userID := c.GetString(security.UserIDKey)
+	sessionID := c.GetString(security.SessionIDKey)
+	if userID == "" || sessionID == "" {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	if err := ctrl.AuthService.RevokeOtherSessions(c.Request.Context(), userID, sessionID); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Toutes les autres sessions ont été déconnectées."}, nil
+}
+
+func (ctrl *SessionController) ListUserSessions(c *gin.Context, in *UserPathInput) ([]domain.SessionDTO, error) {
+	if err := ctrl.requirePlatformAdmin(c); err != nil {
+		return nil, err
+	}
+	return ctrl.AuthService.ListSessions(c.Request.Context(), in.UserID, c.GetString(security.SessionIDKey))
+}
+
+func (ctrl *SessionController) RevokeUserSession(c *gin.Context, in *UserSessionPathInput) (*MessageResponse, error) {
+	if err := ctrl.requirePlatformAdmin(c); err != nil {
+		return nil, err
+	}
+	if err := ctrl.AuthService.RevokeSession(c.Request.Context(), in.UserID, in.SessionID); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Session révoquée."}, nil
+}
+
+func (ctrl *SessionController) RevokeUserSessions(c *gin.Context, in *UserPathInput) (*MessageResponse, error) {
+	if err := ctrl.requirePlatformAdmin(c); err != nil {
+		return nil, err
+	}
+	if err := ctrl.AuthService.RevokeOtherSessions(c.Request.Context(), in.UserID, ""); err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: "Toutes les sessions de l'utilisateur ont été déconnectées."}, nil
+}
+
+func (ctrl *SessionController) requirePlatformAdmin(c *gin.Context) error {
+	return requirePlatformAdmin(c, ctrl.AuthService.Config.Roles.PlatformAdmin, "only platform admins can manage other users' sessions")
+}
+
+// requirePlatformAdmin checks the global role of the caller's session, message is returned
+// to anyone else. An API key never acts as a platform admin, whatever its owner's role.
+func requirePlatformAdmin(c *gin.Context, adminRole, message string) error {
+	val, ok := c.Get(security.UserSessionKey)
+	if !ok {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	session, ok := val.(*cache.SessionData)
+	if !ok || session.GlobalRole != adminRole || session.APIKey != nil {
+		return betoerrors.New(betoerrors.CodeForbidden, message)
+	}
+	return nil
+}
+
+// rejectAPIKeySession keeps API keys from issuing keys: a new key would not inherit the scopes,
+// projects and addresses the calling key is limited to.
+func rejectAPIKeySession(c *gin.Context) error {
+	val, ok := c.Get(security.UserSessionKey)
+	if !ok {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	if session, ok := val.(*cache.SessionData); !ok || session.APIKey != nil {
+		return betoerrors.New(betoerrors.CodeForbidden, "api keys cannot be managed with an api key, sign in instead")
+	}
+	return nil
+}
@@ -8,8 +8,11 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/thekrauss/kubemanager/internal/core/events"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
 )
 
 type pgAuthRepo struct {
@@ -41,6 +44,37 @@ func (r *pgAuthRepo) GetUserByEmail(ctx context.Context, email string) (*domain.
 	return &user, nil
 }
 
+// GetUserByIdentity returns the account linked to the IdP subject, nil when none is.
+func (r *pgAuthRepo) GetUserByIdentity(ctx context.Context, provider, subject string) (*domain.User, error) {
+	var identity domain.UserIdentity
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity.User, nil
+}
+
+func (r *pgAuthRepo) LinkIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// CreateUserWithIdentity provisions an SSO account and its link in one transaction.
+func (r *pgAuthRepo) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
 func (r *pgAuthRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	var user domain.User
 	result := r.db.WithContext(ctx).First(&user, "id = ?", id)
@@ -80,6 +114,60 @@ func (r *pgAuthRepo) RevokeAllUserSessions(ctx context.Context, userID uuid.UUID
 		Update("is_blocked", true).Error
 }
 
+// RotateSession marks the current refresh token as rotated and stores its successor. It
+// returns false when the token was already rotated or revoked, e.g. by a concurrent refresh.
+func (r *pgAuthRepo) RotateSession(ctx context.Context, currentID uuid.UUID, next *domain.UserSession) (bool, error) {
+	rotated := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.UserSession{}).
+			Where("id = ? AND rotated_at IS NULL AND is_blocked = false", currentID).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		rotated = true
+		return tx.Create(next).Error
+	})
+	return rotated, err
+}
+
+// ListActiveSessions returns the current refresh token of every live session of the user.
+func (r *pgAuthRepo) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]domain.UserSession, error) {
+	var sessions []domain.UserSession
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND rotated_at IS NULL AND is_blocked = false AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *pgAuthRepo) RevokeSessionFamily(ctx context.Context, userID, familyID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.UserSession{}).
+		Where("user_id = ? AND family_id = ? AND is_blocked = false", userID, familyID).
+		Update("is_blocked", true)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *pgAuthRepo) RevokeOtherSessions(ctx context.Context, userID, keepFamilyID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.UserSession{}).
+		Where("user_id = ? AND family_id <> ?", userID, keepFamilyID).
+		Update("is_blocked", true).Error
+}
+
 func (r *pgAuthRepo) CheckProjectPermission(ctx context.Context, userID uuid.UUID, projectID uuid.UUID, permSlug string) (bool, error) {
 	var count int64
 
@@ -101,13 +189,37 @@ func (r *pgAuthRepo) CheckProjectPermission(ctx context.Context, userID uuid.UUI
 }
 
 func (r *pgAuthRepo) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
-	return r.db.WithContext(ctx).Create(key).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventAPIKeyCreated, "", map[string]interface{}{
+			"key_id":  key.ID.String(),
+			"user_id": key.UserID.String(),
+			"name":    key.Name,
+			"prefix":  key.Prefix,
+			"scopes":  key.Scopes,
+		}))
+	})
+}
+
+// GetAPIKeyByHash finds the key of a secret, the previous secret of a rotated key is matched
+// too and checked against its grace period by the caller.
+func (r *pgAuthRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).
+		Where("key_hash = ? OR previous_key_hash = ?", hash, hash).
+		First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
 }
 
-func (r *pgAuthRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+func (r *pgAuthRepo) GetUserAPIKey(ctx context.Context, keyID uuid.UUID, userID uuid.UUID) (*domain.APIKey, error) {
 	var key domain.APIKey
 	err := r.db.WithContext(ctx).
-		Where("prefix = ?", prefix).
+		Where("id = ? AND user_id = ?", keyID, userID).
 		First(&key).Error
 	if err != nil {
 		return nil, err
@@ -124,55 +236,117 @@ func (r *pgAuthRepo) ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]d
 }
 
 func (r *pgAuthRepo) RevokeAPIKey(ctx context.Context, keyID uuid.UUID, userID uuid.UUID) error {
-	return r.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", keyID, userID).
-		Delete(&domain.APIKey{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND user_id = ?", keyID, userID).Delete(&domain.APIKey{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return events.Record(tx, events.New(utils.EventAPIKeyRevoked, "", map[string]interface{}{
+			"key_id":  keyID.String(),
+			"user_id": userID.String(),
+		}))
+	})
 }
 
-func (r *pgAuthRepo) UpdateAPIKeyUsage(ctx context.Context, keyID uuid.UUID) error {
-	now := time.Now()
-	return r.db.WithContext(ctx).
-		Model(&domain.APIKey{}).
-		Where("id = ?", keyID).
-		Update("last_used_at", &now).Error
+// RotateAPIKey stores the new secret of the key, the previous one stays valid until
+// PreviousExpiresAt.
+func (r *pgAuthRepo) RotateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&domain.APIKey{}).
+			Where("id = ? AND user_id = ?", key.ID, key.UserID).
+			Updates(map[string]interface{}{
+				"prefix":              key.Prefix,
+				"key_hash":            key.KeyHash,
+				"previous_key_hash":   key.PreviousKeyHash,
+				"previous_expires_at": key.PreviousExpiresAt,
+				"rotated_at":          key.RotatedAt,
+				"expires_at":          key.ExpiresAt,
+				"expiry_notified_at":  key.ExpiryNotifiedAt,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return events.Record(tx, events.New(utils.EventAPIKeyRotated, "", map[string]interface{}{
+			"key_id":               key.ID.String(),
+			"user_id":              key.UserID.String(),
+			"prefix":               key.Prefix,
+			"previous_valid_until": key.PreviousExpiresAt,
+		}))
+	})
 }
 
-func (r *pgAuthRepo) SeedDefaultRoles(ctx context.Context) error {
-	roleSpecs := map[domain.RoleType][]domain.PermissionType{
-		domain.RoleTypes.Viewer: {
-			domain.PermissionTypes.ProjectView,
-			domain.PermissionTypes.LogsView,
-		},
-		domain.RoleTypes.Developer: {
-			domain.PermissionTypes.ProjectView,
-			domain.PermissionTypes.ProjectEdit,
-			domain.PermissionTypes.WorkloadCreate,
-			domain.PermissionTypes.LogsView,
-		},
-		domain.RoleTypes.Owner: {
-			domain.PermissionTypes.ProjectView,
-			domain.PermissionTypes.ProjectEdit,
-			domain.PermissionTypes.ProjectDelete,
-			domain.PermissionTypes.WorkloadCreate,
-			domain.PermissionTypes.WorkloadDelete,
-			domain.PermissionTypes.LogsView,
-			domain.PermissionTypes.ShellExec,
-		},
+// RecordAPIKeyUsage adds the counts gathered by the usage recorder, in one transaction per batch.
+func (r *pgAuthRepo) RecordAPIKeyUsage(ctx context.Context, usage []domain.APIKeyUsage) error {
+	if len(usage) == 0 {
+		return nil
 	}
-
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		for roleEnum, permEnums := range roleSpecs {
-			var permissions []domain.Permission
+		for _, u := range usage {
+			err := tx.Model(&domain.APIKey{}).
+				Where("id = ?", u.KeyID).
+				Updates(map[string]interface{}{
+					"request_count": gorm.Expr("request_count + ?", u.Requests),
+					"last_used_at":  u.LastUsedAt,
+					"last_used_ip":  u.LastUsedIP,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimExpiringAPIKeys marks as notified the keys expiring before the given date and returns
+// them. The rows are locked with SKIP LOCKED so that each key is notified by one instance only.
+func (r *pgAuthRepo) ClaimExpiringAPIKeys(ctx context.Context, before time.Time, limit int) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("expires_at > ? AND expires_at <= ? AND expiry_notified_at IS NULL", now, before).
+			Order("expires_at").
+			Limit(limit).
+			Find(&keys).Error
+		if err != nil || len(keys) == 0 {
+			return err
+		}
 
-			for _, permEnum := range permEnums {
-				var perm domain.Permission
+		ids := make([]uuid.UUID, len(keys))
+		for i, k := range keys {
+			ids[i] = k.ID
+		}
+		if err := tx.Model(&domain.APIKey{}).Where("id IN ?", ids).Update("expiry_notified_at", now).Error; err != nil {
+			return err
+		}
 
-				if err := tx.Where(domain.Permission{Slug: permEnum.String()}).
-					Attrs(domain.Permission{ID: uuid.New()}).
-					FirstOrCreate(&perm).Error; err != nil {
-					return err
-				}
-				permissions = append(permissions, perm)
+		batch := make([]events.Event, 0, len(keys))
+		for _, k := range keys {
+			batch = append(batch, events.New(utils.EventAPIKeyExpiring, "", map[string]interface{}{
+				"key_id":     k.ID.String(),
+				"user_id":    k.UserID.String(),
+				"name":       k.Name,
+				"expires_at": k.ExpiresAt,
+			}))
+		}
+		return events.Record(tx, batch...)
+	})
+	return keys, err
+}
+
+func (r *pgAuthRepo) SeedDefaultRoles(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for roleEnum, permEnums := range domain.DefaultRolePermissions() {
+			slugs := make([]string, len(permEnums))
+			for i, p := range permEnums {
+				slugs[i] = p.String()
+			}
+			permissions, err := permissionsBySlug(tx, slugs)
+			if err != nil {
+				return err
 			}
 
 			var role domain.Role
@@ -181,6 +355,11 @@ func (r *pgAuthRepo) SeedDefaultRoles(ctx context.Context) error {
 				FirstOrCreate(&role).Error; err != nil {
 				return err
 			}
+			if !role.IsSystem {
+				if err := tx.Model(&role).Update("is_system", true).Error; err != nil {
+					return err
+				}
+			}
 
 			if err := tx.Model(&role).Association("Permissions").Replace(permissions); err != nil {
 				return err
@@ -192,6 +371,86 @@ func (r *pgAuthRepo) SeedDefaultRoles(ctx context.Context) error {
 	})
 }
 
+// permissionsBySlug returns the permission rows, creating the ones not used by any role yet.
+func permissionsBySlug(tx *gorm.DB, slugs []string) ([]domain.Permission, error) {
+	permissions := make([]domain.Permission, 0, len(slugs))
+	for _, slug := range slugs {
+		var perm domain.Permission
+		if err := tx.Where(domain.Permission{Slug: slug}).
+			Attrs(domain.Permission{ID: uuid.New()}).
+			FirstOrCreate(&perm).Error; err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, perm)
+	}
+	return permissions, nil
+}
+
+func (r *pgAuthRepo) GetRoleByID(ctx context.Context, id uuid.UUID) (*domain.Role, error) {
+	var role domain.Role
+	err := r.db.WithContext(ctx).
+		Preload("Permissions").
+		Where("id = ?", id).
+		First(&role).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *pgAuthRepo) CreateRole(ctx context.Context, role *domain.Role, permissionSlugs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		permissions, err := permissionsBySlug(tx, permissionSlugs)
+		if err != nil {
+			return err
+		}
+		role.Permissions = permissions
+		return tx.Create(role).Error
+	})
+}
+
+func (r *pgAuthRepo) UpdateRole(ctx context.Context, role *domain.Role, permissionSlugs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		permissions, err := permissionsBySlug(tx, permissionSlugs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(role).Update("description", role.Description).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Permissions").Replace(permissions); err != nil {
+			return err
+		}
+		role.Permissions = permissions
+		return nil
+	})
+}
+
+// DeleteRole removes a role no member holds anymore. The check and the delete share the
+// transaction and the role row is locked, an assignment cannot slip in between.
+func (r *pgAuthRepo) DeleteRole(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var role domain.Role
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&role).Error; err != nil {
+			return err
+		}
+
+		var members int64
+		if err := tx.Model(&domain.ProjectMember{}).Where("role_id = ?", id).Count(&members).Error; err != nil {
+			return err
+		}
+		if members > 0 {
+			return ErrRoleInUse
+		}
+
+		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+}
+
 func (r *pgAuthRepo) GetRoleByName(ctx context.Context, name string) (*domain.Role, error) {
 	var role domain.Role
 	err := r.db.WithContext(ctx).
@@ -205,14 +464,49 @@ func (r *pgAuthRepo) GetRoleByName(ctx context.Context, name string) (*domain.Ro
 	return &role, nil
 }
 
+// AddProjectMember adds the member or changes its role, the event tells which one happened.
 func (r *pgAuthRepo) AddProjectMember(ctx context.Context, member *domain.ProjectMember) error {
-	return r.db.WithContext(ctx).Save(member).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := saveProjectMember(tx, member)
+		return err
+	})
+}
+
+// saveProjectMember upserts the membership in tx and records member.added or member.updated,
+// it reports whether the user was not a member yet.
+func saveProjectMember(tx *gorm.DB, member *domain.ProjectMember) (bool, error) {
+	var count int64
+	err := tx.Model(&domain.ProjectMember{}).
+		Where("project_id = ? AND user_id = ?", member.ProjectID, member.UserID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Save(member).Error; err != nil {
+		return false, err
+	}
+
+	eventType := utils.EventMemberAdded
+	if count > 0 {
+		eventType = utils.EventMemberUpdated
+	}
+	err = events.Record(tx, events.New(eventType, member.ProjectID.String(), map[string]interface{}{
+		"user_id": member.UserID.String(),
+		"role_id": member.RoleID.String(),
+	}))
+	return count == 0, err
 }
 
 func (r *pgAuthRepo) RemoveProjectMember(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) error {
-	return r.db.WithContext(ctx).
-		Where("project_id = ? AND user_id = ?", projectID, userID).
-		Delete(&domain.ProjectMember{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&domain.ProjectMember{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return events.Record(tx, events.New(utils.EventMemberRemoved, projectID.String(), map[string]interface{}{
+			"user_id": userID.String(),
+		}))
+	})
 }
 
 func (r *pgAuthRepo) GetProjectMember(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (*domain.ProjectMember, error) {
@@ -230,6 +524,26 @@ func (r *pgAuthRepo) GetProjectMember(ctx context.Context, projectID uuid.UUID,
 	return &member, nil
 }
 
+// GetProjectRoleName returns the name of the member's role, gorm.ErrRecordNotFound when the
+// user is not a member of the project.
+func (r *pgAuthRepo) GetProjectRoleName(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (string, error) {
+	var name string
+	err := r.db.WithContext(ctx).
+		Table("project_members").
+		Select("roles.name").
+		Joins("JOIN roles ON roles.id = project_members.role_id").
+		Where("project_members.project_id = ? AND project_members.user_id = ?", projectID, userID).
+		Limit(1).
+		Scan(&name).Error
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", gorm.ErrRecordNotFound
+	}
+	return name, nil
+}
+
 func (r *pgAuthRepo) GetUserProjectMemberships(ctx context.Context, userID uuid.UUID) ([]domain.ProjectMember, error) {
 	var memberships []domain.ProjectMember
 	err := r.db.WithContext(ctx).
@@ -239,12 +553,196 @@ func (r *pgAuthRepo) GetUserProjectMemberships(ctx context.Context, userID uuid.
 	return memberships, err
 }
 
+// CreateInvitation stores a pending invitation. A pending invitation of the same address to the
+// same project is revoked, so that only the last link sent works.
+func (r *pgAuthRepo) CreateInvitation(ctx context.Context, inv *domain.ProjectInvitation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.ProjectInvitation{}).
+			Where("project_id = ? AND email = ? AND status = ?", inv.ProjectID, inv.Email, utils.InvitationPending).
+			Update("status", utils.InvitationRevoked).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(inv).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventInvitationCreated, inv.ProjectID.String(), map[string]interface{}{
+			"invitation_id": inv.ID.String(),
+			"email":         inv.Email,
+			"role_id":       inv.RoleID.String(),
+			"invited_by":    inv.InvitedBy.String(),
+		}))
+	})
+}
+
+func (r *pgAuthRepo) ListPendingInvitations(ctx context.Context, projectID uuid.UUID) ([]domain.ProjectInvitation, error) {
+	var invitations []domain.ProjectInvitation
+	err := r.db.WithContext(ctx).
+		Preload("Role").
+		Where("project_id = ? AND status = ?", projectID, utils.InvitationPending).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *pgAuthRepo) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*domain.ProjectInvitation, error) {
+	var inv domain.ProjectInvitation
+	err := r.db.WithContext(ctx).
+		Preload("Role").
+		Preload("Project").
+		Where("token_hash = ?", tokenHash).
+		First(&inv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// AcceptInvitation closes the invitation and upserts the membership in one transaction, it
+// reports whether the user was not a member yet. gorm.ErrRecordNotFound when the invitation is
+// no longer pending or has expired.
+func (r *pgAuthRepo) AcceptInvitation(ctx context.Context, inv *domain.ProjectInvitation, userID uuid.UUID) (bool, error) {
+	var isNew bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := respondInvitation(tx, inv.ID, utils.InvitationAccepted); err != nil {
+			return err
+		}
+
+		var err error
+		isNew, err = saveProjectMember(tx, &domain.ProjectMember{
+			ProjectID: inv.ProjectID,
+			UserID:    userID,
+			RoleID:    inv.RoleID,
+			JoinedAt:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventInvitationAccepted, inv.ProjectID.String(), map[string]interface{}{
+			"invitation_id": inv.ID.String(),
+			"user_id":       userID.String(),
+		}))
+	})
+	return isNew, err
+}
+
+func (r *pgAuthRepo) DeclineInvitation(ctx context.Context, inv *domain.ProjectInvitation, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := respondInvitation(tx, inv.ID, utils.InvitationDeclined); err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventInvitationDeclined, inv.ProjectID.String(), map[string]interface{}{
+			"invitation_id": inv.ID.String(),
+			"user_id":       userID.String(),
+		}))
+	})
+}
+
+// RevokeInvitation cancels a pending invitation, gorm.ErrRecordNotFound when the project has no
+// such pending invitation.
+func (r *pgAuthRepo) RevokeInvitation(ctx context.Context, projectID uuid.UUID, invitationID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&domain.ProjectInvitation{}).
+			Where("id = ? AND project_id = ? AND status = ?", invitationID, projectID, utils.InvitationPending).
+			Updates(map[string]interface{}{"status": utils.InvitationRevoked, "responded_at": time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return events.Record(tx, events.New(utils.EventInvitationRevoked, projectID.String(), map[string]interface{}{
+			"invitation_id": invitationID.String(),
+		}))
+	})
+}
+
+// respondInvitation moves a pending, unexpired invitation to status. The condition on the status
+// makes a token usable once even with concurrent requests.
+func respondInvitation(tx *gorm.DB, id uuid.UUID, status string) error {
+	now := time.Now()
+	res := tx.Model(&domain.ProjectInvitation{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, utils.InvitationPending, now).
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateServiceAccount creates the account and its membership in the project that owns it.
+func (r *pgAuthRepo) CreateServiceAccount(ctx context.Context, account *domain.User, roleID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+		member := &domain.ProjectMember{
+			ProjectID: *account.ServiceAccountProjectID,
+			UserID:    account.ID,
+			RoleID:    roleID,
+			JoinedAt:  time.Now(),
+		}
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventServiceAccountCreated, member.ProjectID.String(), map[string]interface{}{
+			"account_id": account.ID.String(),
+			"name":       account.FullName,
+			"role_id":    roleID.String(),
+		}))
+	})
+}
+
+func (r *pgAuthRepo) ListServiceAccounts(ctx context.Context, projectID uuid.UUID) ([]domain.ProjectMember, error) {
+	var members []domain.ProjectMember
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Role").
+		Joins("JOIN users ON users.id = project_members.user_id").
+		Where("project_members.project_id = ? AND users.service_account_project_id = ?", projectID, projectID).
+		Order("users.full_name").
+		Find(&members).Error
+	return members, err
+}
+
+// DeleteServiceAccount removes the account with its API keys and membership, gorm.ErrRecordNotFound
+// when the project owns no such account.
+func (r *pgAuthRepo) DeleteServiceAccount(ctx context.Context, projectID uuid.UUID, accountID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var account domain.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND service_account_project_id = ?", accountID, projectID).
+			First(&account).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", accountID).Delete(&domain.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", accountID).Delete(&domain.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&account).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventServiceAccountDeleted, projectID.String(), map[string]interface{}{
+			"account_id": accountID.String(),
+			"name":       account.FullName,
+		}))
+	})
+}
+
+// FindRefreshTokenByJTI returns the session row whatever its state, rotated or revoked rows
+// are needed by the caller to detect token reuse.
 func (r *pgAuthRepo) FindRefreshTokenByJTI(ctx context.Context, jti uuid.UUID) (*domain.UserSession, error) {
 	var session domain.UserSession
 
 	err := r.db.WithContext(ctx).
-		Where("id = ? AND is_blocked = ?", jti, false).
-		Where("expires_at > ?", time.Now()).
+		Where("id = ?", jti).
 		First(&session).Error
 
 	if err != nil {
@@ -284,6 +782,96 @@ func (r *pgAuthRepo) UpdatePassword(ctx context.Context, userID uuid.UUID, hash
 	return nil
 }
 
+func (r *pgAuthRepo) MarkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"is_verified": true,
+			"updated_at":  time.Now(),
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+func (r *pgAuthRepo) SaveMFASecret(ctx context.Context, userID uuid.UUID, encryptedSecret string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"mfa_secret":  encryptedSecret,
+			"mfa_enabled": false,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+// EnableMFA activates the enrolled secret and stores the first set of recovery codes.
+func (r *pgAuthRepo) EnableMFA(ctx context.Context, userID uuid.UUID, codes []domain.MFARecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{"mfa_enabled": true, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func (r *pgAuthRepo) DisableMFA(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{"mfa_secret": "", "mfa_enabled": false, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error
+	})
+}
+
+func (r *pgAuthRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []domain.MFARecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codes []domain.MFARecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode burns a recovery code, false means unknown or already used.
+func (r *pgAuthRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *pgAuthRepo) GetProjectMFAPolicy(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	var project domain.Project
+	err := r.db.WithContext(ctx).
+		Select("require_mfa").
+		First(&project, "id = ?", projectID).Error
+	return project.RequireMFA, err
+}
+
 func (r *pgAuthRepo) ListRoles(ctx context.Context) ([]domain.Role, error) {
 	var roles []domain.Role
 	err := r.db.WithContext(ctx).Preload("Permissions").Find(&roles).Error
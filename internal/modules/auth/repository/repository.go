@@ -2,18 +2,35 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 )
 
+// ErrRoleInUse is returned when deleting a role still held by project members.
+var ErrRoleInUse = errors.New("role is still assigned to project members")
+
 type AuthRepository interface {
 	CreateUser(ctx context.Context, user *domain.User) error
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) error
 	UpdatePassword(ctx context.Context, userID uuid.UUID, hash string) error
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID) error
+
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*domain.User, error)
+	LinkIdentity(ctx context.Context, identity *domain.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error
+
+	SaveMFASecret(ctx context.Context, userID uuid.UUID, encryptedSecret string) error
+	EnableMFA(ctx context.Context, userID uuid.UUID, codes []domain.MFARecoveryCode) error
+	DisableMFA(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []domain.MFARecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	GetProjectMFAPolicy(ctx context.Context, projectID uuid.UUID) (bool, error)
 
 	SeedDefaultRoles(ctx context.Context) error
 	ListRoles(ctx context.Context) ([]domain.Role, error)
@@ -23,21 +40,44 @@ type AuthRepository interface {
 	GetSessionByID(ctx context.Context, id uuid.UUID) (*domain.UserSession, error)
 	RevokeSession(ctx context.Context, id uuid.UUID) error
 	RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) error
+	RotateSession(ctx context.Context, currentID uuid.UUID, next *domain.UserSession) (bool, error)
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]domain.UserSession, error)
+	RevokeSessionFamily(ctx context.Context, userID, familyID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, keepFamilyID uuid.UUID) error
 
 	CheckProjectPermission(ctx context.Context, userID uuid.UUID, projectID uuid.UUID, permissionSlug string) (bool, error)
 
 	CreateAPIKey(ctx context.Context, key *domain.APIKey) error
-	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	GetUserAPIKey(ctx context.Context, keyID uuid.UUID, userID uuid.UUID) (*domain.APIKey, error)
 	ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error)
 	RevokeAPIKey(ctx context.Context, keyID uuid.UUID, userID uuid.UUID) error
-	UpdateAPIKeyUsage(ctx context.Context, keyID uuid.UUID) error
+	RotateAPIKey(ctx context.Context, key *domain.APIKey) error
+	RecordAPIKeyUsage(ctx context.Context, usage []domain.APIKeyUsage) error
+	ClaimExpiringAPIKeys(ctx context.Context, before time.Time, limit int) ([]domain.APIKey, error)
 
 	GetRoleByName(ctx context.Context, name string) (*domain.Role, error)
+	GetRoleByID(ctx context.Context, id uuid.UUID) (*domain.Role, error)
+	CreateRole(ctx context.Context, role *domain.Role, permissionSlugs []string) error
+	UpdateRole(ctx context.Context, role *domain.Role, permissionSlugs []string) error
+	DeleteRole(ctx context.Context, id uuid.UUID) error
 	AddProjectMember(ctx context.Context, member *domain.ProjectMember) error
 	RemoveProjectMember(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) error
 	GetProjectMember(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (*domain.ProjectMember, error)
+	GetProjectRoleName(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (string, error)
 	GetUserProjectMemberships(ctx context.Context, userID uuid.UUID) ([]domain.ProjectMember, error)
 
+	CreateInvitation(ctx context.Context, inv *domain.ProjectInvitation) error
+	ListPendingInvitations(ctx context.Context, projectID uuid.UUID) ([]domain.ProjectInvitation, error)
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*domain.ProjectInvitation, error)
+	AcceptInvitation(ctx context.Context, inv *domain.ProjectInvitation, userID uuid.UUID) (bool, error)
+	DeclineInvitation(ctx context.Context, inv *domain.ProjectInvitation, userID uuid.UUID) error
+	RevokeInvitation(ctx context.Context, projectID uuid.UUID, invitationID uuid.UUID) error
+
+	CreateServiceAccount(ctx context.Context, account *domain.User, roleID uuid.UUID) error
+	ListServiceAccounts(ctx context.Context, projectID uuid.UUID) ([]domain.ProjectMember, error)
+	DeleteServiceAccount(ctx context.Context, projectID uuid.UUID, accountID uuid.UUID) error
+
 	FindRefreshTokenByJTI(ctx context.Context, jti uuid.UUID) (*domain.UserSession, error)
 	DeleteRefreshTokenByJTI(ctx context.Context, jti uuid.UUID) error
 }
@@ -3,64 +3,122 @@ package service
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
 
+	"github.com/thekrauss/kubemanager/internal/core/configs"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	mailSvc "github.com/thekrauss/kubemanager/internal/modules/mailer/service"
 	"github.com/thekrauss/kubemanager/internal/modules/utils"
 )
 
+const (
+	defaultRotationGrace = 24 * time.Hour
+	// une rotation ne garde jamais l'ancien secret plus d'une semaine
+	maxRotationGrace    = 7 * 24 * time.Hour
+	defaultExpiryNotice = 7 * 24 * time.Hour
+	expiryCheckInterval = time.Hour
+	expiryBatchSize     = 100
+)
+
 type APIKeyService struct {
-	Repo repository.AuthRepository
+	Repo   repository.AuthRepository
+	Config *configs.GlobalConfig
+	Mailer mailSvc.IMailService
+	Logger *zap.SugaredLogger
 }
 
-func NewAPIKeyService(repo repository.AuthRepository) *APIKeyService {
-	return &APIKeyService{Repo: repo}
+func NewAPIKeyService(repo repository.AuthRepository, cfg *configs.GlobalConfig, mailer mailSvc.IMailService, logger *zap.SugaredLogger) *APIKeyService {
+	return &APIKeyService{
+		Repo:   repo,
+		Config: cfg,
+		Mailer: mailer,
+		Logger: logger.With("service", "APIKeyService"),
+	}
 }
 
 type CreateAPIKeyInput struct {
-	UserID string   `json:"-"`
-	Name   string   `json:"name"`   // "MacBook Pro CLI"
-	Scopes []string `json:"scopes"` // ["read", "write"]
+	UserID       string     `json:"-"`
+	Name         string     `json:"name"`                    // "MacBook Pro CLI"
+	Scopes       []string   `json:"scopes" validate:"min=1"` // ["project:view", "k8s:logs:view"]
+	ProjectIDs   []string   `json:"project_ids"`             // vide : tous les projets de l'utilisateur
+	AllowedCIDRs []string   `json:"allowed_cidrs"`           // ["10.0.0.0/8", "203.0.113.7"]
+	ExpiresAt    *time.Time `json:"expires_at"`
 }
 
 type APIKeyCreatedResponse struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	RawAPIKey string    `json:"api_key"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	RawAPIKey string     `json:"api_key"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	// après une rotation, date jusqu'à laquelle l'ancien secret reste accepté
+	PreviousValidUntil *time.Time `json:"previous_valid_until,omitempty"`
 }
 
 func (s *APIKeyService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*APIKeyCreatedResponse, error) {
 	uID, _ := uuid.Parse(input.UserID)
 
+	// les clés d'un compte de service sont gérées par les membres de son projet
+	user, err := s.Repo.GetUserByID(ctx, uID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	if user.IsServiceAccount() {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "service accounts cannot create their own API keys")
+	}
+
+	return s.createKey(ctx, uID, input)
+}
+
+func (s *APIKeyService) createKey(ctx context.Context, uID uuid.UUID, input CreateAPIKeyInput) (*APIKeyCreatedResponse, error) {
 	var validScopes []domain.PermissionType
 
 	for _, scopeStr := range input.Scopes {
 		perm, err := domain.PermissionTypes.NewFromString(ctx, scopeStr)
 		if err != nil {
-			return nil, betoerrors.Wrap(err, betoerrors.CodeDBNotFound, fmt.Sprintf("invalid scope provided: %s", scopeStr))
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("invalid scope provided: %s", scopeStr))
 		}
 		validScopes = append(validScopes, perm)
 	}
+	if len(validScopes) == 0 {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "at least one scope is required")
+	}
+
+	projectIDs, err := s.keyProjects(ctx, uID, input.ProjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	cidrs, err := normalizeCIDRs(input.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "expires_at must be in the future")
+	}
 
 	rawKey, err := utils.GenerateSecureKey("k8m", 32)
 	if err != nil {
 		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate key")
 	}
 
-	keyHash := utils.HashAPIKey(rawKey)
-	dbPrefix := rawKey[:7]
-
 	apiKey := &domain.APIKey{
-		UserID:  uID,
-		Name:    input.Name,
-		Prefix:  dbPrefix,
-		KeyHash: keyHash,
-		Scopes:  validScopes,
+		UserID:       uID,
+		Name:         input.Name,
+		Prefix:       rawKey[:7],
+		KeyHash:      utils.HashAPIKey(rawKey),
+		Scopes:       validScopes,
+		ProjectIDs:   projectIDs,
+		AllowedCIDRs: cidrs,
+		ExpiresAt:    input.ExpiresAt,
 	}
 
 	if err := s.Repo.CreateAPIKey(ctx, apiKey); err != nil {
@@ -71,16 +129,63 @@ func (s *APIKeyService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInpu
 		ID:        apiKey.ID.String(),
 		Name:      apiKey.Name,
 		RawAPIKey: rawKey,
+		ExpiresAt: apiKey.ExpiresAt,
 		CreatedAt: apiKey.CreatedAt,
 	}, nil
 }
 
+// keyProjects checks that the user belongs to every project the key is restricted to.
+func (s *APIKeyService) keyProjects(ctx context.Context, userID uuid.UUID, ids []string) ([]uuid.UUID, error) {
+	projectIDs := make([]uuid.UUID, 0, len(ids))
+	for _, idStr := range ids {
+		pID, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("invalid project id: %s", idStr))
+		}
+		if _, err := s.Repo.GetProjectMember(ctx, pID, userID); err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("you are not a member of project %s", idStr))
+		}
+		projectIDs = append(projectIDs, pID)
+	}
+	return projectIDs, nil
+}
+
+// normalizeCIDRs accepts CIDR blocks and single addresses, stored as /32 or /128 blocks.
+func normalizeCIDRs(values []string) ([]string, error) {
+	cidrs := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if ip := net.ParseIP(v); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip.String(), bits))
+			continue
+		}
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("invalid CIDR: %s", v))
+		}
+		cidrs = append(cidrs, network.String())
+	}
+	return cidrs, nil
+}
+
 type APIKeyDTO struct {
-	ID         string     `json:"id"`
-	Name       string     `json:"name"`
-	Prefix     string     `json:"prefix"`
-	LastUsedAt *time.Time `json:"last_used_at"`
-	CreatedAt  time.Time  `json:"created_at"`
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Prefix             string     `json:"prefix"`
+	Scopes             []string   `json:"scopes"`
+	ProjectIDs         []string   `json:"project_ids,omitempty"`
+	AllowedCIDRs       []string   `json:"allowed_cidrs,omitempty"`
+	LastUsedAt         *time.Time `json:"last_used_at"`
+	LastUsedIP         string     `json:"last_used_ip,omitempty"`
+	RequestCount       int64      `json:"request_count"`
+	RotatedAt          *time.Time `json:"rotated_at,omitempty"`
+	PreviousValidUntil *time.Time `json:"previous_valid_until,omitempty"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
 }
 
 func (s *APIKeyService) ListUserKeys(ctx context.Context, userIDStr string) ([]APIKeyDTO, error) {
@@ -92,17 +197,37 @@ func (s *APIKeyService) ListUserKeys(ctx context.Context, userIDStr string) ([]A
 
 	var result []APIKeyDTO
 	for _, k := range keys {
-		result = append(result, APIKeyDTO{
-			ID:         k.ID.String(),
-			Name:       k.Name,
-			Prefix:     k.Prefix + "...",
-			LastUsedAt: k.LastUsedAt,
-			CreatedAt:  k.CreatedAt,
-		})
+		result = append(result, apiKeyToDTO(&k))
 	}
 	return result, nil
 }
 
+func apiKeyToDTO(k *domain.APIKey) APIKeyDTO {
+	dto := APIKeyDTO{
+		ID:           k.ID.String(),
+		Name:         k.Name,
+		Prefix:       k.Prefix + "...",
+		AllowedCIDRs: k.AllowedCIDRs,
+		LastUsedAt:   k.LastUsedAt,
+		LastUsedIP:   k.LastUsedIP,
+		RequestCount: k.RequestCount,
+		RotatedAt:    k.RotatedAt,
+		ExpiresAt:    k.ExpiresAt,
+		CreatedAt:    k.CreatedAt,
+	}
+	// l'ancien secret n'est plus mentionné une fois sa période de grâce passée
+	if k.PreviousExpiresAt != nil && k.PreviousExpiresAt.After(time.Now()) {
+		dto.PreviousValidUntil = k.PreviousExpiresAt
+	}
+	for _, scope := range k.Scopes {
+		dto.Scopes = append(dto.Scopes, scope.String())
+	}
+	for _, id := range k.ProjectIDs {
+		dto.ProjectIDs = append(dto.ProjectIDs, id.String())
+	}
+	return dto
+}
+
 func (s *APIKeyService) RevokeKey(ctx context.Context, userIDStr, keyIDStr string) error {
 	uID, _ := uuid.Parse(userIDStr)
 	kID, err := uuid.Parse(keyIDStr)
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	maildomain "github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+type RotateAPIKeyInput struct {
+	// durée pendant laquelle l'ancien secret reste accepté, la valeur de configuration à défaut ;
+	// 0 le révoque immédiatement
+	GraceSeconds *int       `json:"grace_seconds" validate:"omitempty,min=0"`
+	ExpiresAt    *time.Time `json:"expires_at"` // nouvelle expiration, inchangée si absente
+}
+
+type APIKeyUsageDTO struct {
+	KeyID        string     `json:"key_id"`
+	RequestCount int64      `json:"request_count"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	LastUsedIP   string     `json:"last_used_ip,omitempty"`
+}
+
+// RotateKey issues a new secret for the key. Scopes, restrictions and ID are kept, so the CI
+// only swaps the secret; the old one keeps working during the grace period. Rotating again
+// within that period drops the oldest secret.
+func (s *APIKeyService) RotateKey(ctx context.Context, userIDStr, keyIDStr string, in RotateAPIKeyInput) (*APIKeyCreatedResponse, error) {
+	key, err := s.userKey(ctx, userIDStr, keyIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) && in.ExpiresAt == nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "key is expired, give a new expires_at to rotate it")
+	}
+	if in.ExpiresAt != nil {
+		if !in.ExpiresAt.After(now) {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "expires_at must be in the future")
+		}
+		key.ExpiresAt = in.ExpiresAt
+		key.ExpiryNotifiedAt = nil
+	}
+
+	grace := s.rotationGrace()
+	if in.GraceSeconds != nil {
+		grace = time.Duration(*in.GraceSeconds) * time.Second
+	}
+	if grace > maxRotationGrace {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "grace period cannot exceed 7 days")
+	}
+
+	rawKey, err := utils.GenerateSecureKey("k8m", 32)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate key")
+	}
+
+	graceUntil := now.Add(grace)
+	key.PreviousKeyHash = key.KeyHash
+	key.PreviousExpiresAt = &graceUntil
+	key.RotatedAt = &now
+	key.Prefix = rawKey[:7]
+	key.KeyHash = utils.HashAPIKey(rawKey)
+
+	if err := s.Repo.RotateAPIKey(ctx, key); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, betoerrors.New(betoerrors.CodeNotFound, "api key not found")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to rotate api key")
+	}
+
+	s.Logger.Infow("api key rotated", "key_id", key.ID, "user_id", key.UserID, "previous_valid_until", graceUntil)
+
+	resp := &APIKeyCreatedResponse{
+		ID:        key.ID.String(),
+		Name:      key.Name,
+		RawAPIKey: rawKey,
+		ExpiresAt: key.ExpiresAt,
+		CreatedAt: key.CreatedAt,
+	}
+	if grace > 0 {
+		resp.PreviousValidUntil = &graceUntil
+	}
+	return resp, nil
+}
+
+// GetKeyUsage returns the usage summary of a key. The counters are written in batches by the
+// usage recorder and lag behind by its flush interval.
+func (s *APIKeyService) GetKeyUsage(ctx context.Context, userIDStr, keyIDStr string) (*APIKeyUsageDTO, error) {
+	key, err := s.userKey(ctx, userIDStr, keyIDStr)
+	if err != nil {
+		return nil, err
+	}
+	return &APIKeyUsageDTO{
+		KeyID:        key.ID.String(),
+		RequestCount: key.RequestCount,
+		LastUsedAt:   key.LastUsedAt,
+		LastUsedIP:   key.LastUsedIP,
+	}, nil
+}
+
+// RunExpiryNotifications emails the owners of the keys about to expire, every hour until ctx
+// is canceled. Each key is notified once, by one instance.
+func (s *APIKeyService) RunExpiryNotifications(ctx context.Context) {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.NotifyExpiringKeys(ctx); err != nil {
+			s.Logger.Warnw("api key expiry notifications failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NotifyExpiringKeys sends the notices due now and returns how many keys were notified.
+func (s *APIKeyService) NotifyExpiringKeys(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		keys, err := s.Repo.ClaimExpiringAPIKeys(ctx, time.Now().Add(s.expiryNotice()), expiryBatchSize)
+		if err != nil {
+			return total, err
+		}
+		for i := range keys {
+			s.notifyExpiry(ctx, &keys[i])
+		}
+		total += len(keys)
+		if len(keys) < expiryBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// notifyExpiry emails the owner of the key, or the owners of the project for a service
+// account. The key is already marked as notified, a failed send is only logged.
+func (s *APIKeyService) notifyExpiry(ctx context.Context, key *domain.APIKey) {
+	user, err := s.Repo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		s.Logger.Warnw("api key owner not found", "key_id", key.ID, "user_id", key.UserID, "error", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Name":      displayName(user),
+		"KeyName":   key.Name,
+		"Prefix":    key.Prefix,
+		"ExpiresAt": key.ExpiresAt.UTC().Format("02/01/2006 15:04 MST"),
+		"Link":      s.Mailer.Link("/settings/api-keys"),
+	}
+	recipients := []string{user.Email}
+	if user.IsServiceAccount() {
+		recipients, err = s.projectOwnerEmails(ctx, *user.ServiceAccountProjectID)
+		if err != nil || len(recipients) == 0 {
+			s.Logger.Warnw("no recipient for service account key expiry", "key_id", key.ID, "project", user.ServiceAccountProjectID, "error", err)
+			return
+		}
+		// envoyé aux propriétaires du projet, pas au compte lui-même
+		data["Name"] = ""
+		data["Account"] = user.FullName
+		data["Link"] = s.Mailer.Link("/projects/" + user.ServiceAccountProjectID.String() + "/service-accounts")
+	}
+
+	err = s.Mailer.Send(ctx, maildomain.EmailRequest{
+		To:       recipients,
+		Template: maildomain.TemplateAPIKeyExpiring,
+		Data:     data,
+	})
+	if err != nil {
+		s.Logger.Warnw("failed to send api key expiry notice", "key_id", key.ID, "error", err)
+	}
+}
+
+func (s *APIKeyService) projectOwnerEmails(ctx context.Context, projectID uuid.UUID) ([]string, error) {
+	members, err := s.Repo.ListProjectMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	var emails []string
+	for _, m := range members {
+		if m.Role.Name == domain.RoleTypes.Owner.String() && !m.User.IsServiceAccount() {
+			emails = append(emails, m.User.Email)
+		}
+	}
+	return emails, nil
+}
+
+func (s *APIKeyService) userKey(ctx context.Context, userIDStr, keyIDStr string) (*domain.APIKey, error) {
+	uID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	kID, err := uuid.Parse(keyIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid key id")
+	}
+
+	key, err := s.Repo.GetUserAPIKey(ctx, kID, uID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, betoerrors.New(betoerrors.CodeNotFound, "api key not found")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to load api key")
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) rotationGrace() time.Duration {
+	if s.Config.APIKeys.RotationGrace > 0 {
+		return s.Config.APIKeys.RotationGrace
+	}
+	return defaultRotationGrace
+}
+
+func (s *APIKeyService) expiryNotice() time.Duration {
+	if s.Config.APIKeys.ExpiryNotice > 0 {
+		return s.Config.APIKeys.ExpiryNotice
+	}
+	return defaultExpiryNotice
+}
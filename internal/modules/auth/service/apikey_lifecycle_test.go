@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+func newRotationTestService(t *testing.T) (*APIKeyService, *memRepo, *domain.APIKey, string) {
+	t.Helper()
+	repo := newMemRepo()
+	raw := "k8m_first_secret"
+	key := &domain.APIKey{ID: uuid.New(), UserID: uuid.New(), Name: "ci", Prefix: raw[:7], KeyHash: utils.HashAPIKey(raw)}
+	repo.apiKeys[key.ID] = key
+	return &APIKeyService{Repo: repo, Config: &configs.GlobalConfig{}, Logger: zap.NewNop().Sugar()}, repo, key, raw
+}
+
+// accepts tells whether the stored key still takes the raw secret.
+func accepts(repo *memRepo, keyID uuid.UUID, raw string) bool {
+	return repo.apiKeys[keyID].Matches(utils.HashAPIKey(raw), time.Now())
+}
+
+func TestRotateKeyWithoutGraceRevokesTheOldSecret(t *testing.T) {
+	s, repo, key, first := newRotationTestService(t)
+	zero := 0
+
+	resp, err := s.RotateKey(context.Background(), key.UserID.String(), key.ID.String(), RotateAPIKeyInput{GraceSeconds: &zero})
+	if err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	if resp.PreviousValidUntil != nil {
+		t.Errorf("PreviousValidUntil = %v, want none without grace", resp.PreviousValidUntil)
+	}
+	if accepts(repo, key.ID, first) {
+		t.Error("grace_seconds=0 kept the old secret")
+	}
+	if !accepts(repo, key.ID, resp.RawAPIKey) {
+		t.Error("the new secret is refused")
+	}
+}
+
+func TestRotateKeyTwiceDropsTheOldestSecret(t *testing.T) {
+	s, repo, key, first := newRotationTestService(t)
+	ctx := context.Background()
+
+	second, err := s.RotateKey(ctx, key.UserID.String(), key.ID.String(), RotateAPIKeyInput{})
+	if err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	if second.PreviousValidUntil == nil || !accepts(repo, key.ID, first) {
+		t.Fatal("the first secret is refused during the grace")
+	}
+
+	// deuxième rotation dans la fenêtre : seul le secret précédent reste en grâce
+	third, err := s.RotateKey(ctx, key.UserID.String(), key.ID.String(), RotateAPIKeyInput{})
+	if err != nil {
+		t.Fatalf("second RotateKey: %v", err)
+	}
+	if accepts(repo, key.ID, first) {
+		t.Error("the first secret survived a second rotation")
+	}
+	if !accepts(repo, key.ID, second.RawAPIKey) || !accepts(repo, key.ID, third.RawAPIKey) {
+		t.Error("the last two secrets must both be accepted")
+	}
+}
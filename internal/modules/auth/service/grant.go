@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+)
+
+// checkGrantable refuses to hand out more than the caller holds on the project: every permission
+// of the role must be allowed to the caller, API key scopes included, and only an OWNER makes
+// someone OWNER.
+func checkGrantable(access *security.ProjectAccess, role *domain.Role) error {
+	if access == nil {
+		return betoerrors.New(betoerrors.CodeForbidden, "forbidden")
+	}
+	if role.Name == domain.RoleTypes.Owner.String() && !access.Admin && access.Role != role.Name {
+		return betoerrors.New(betoerrors.CodeForbidden, "only an OWNER can grant the OWNER role")
+	}
+	for _, p := range role.Permissions {
+		perm, err := domain.PermissionTypes.NewFromString(context.Background(), p.Slug)
+		if err != nil || !access.Allows(perm) {
+			return betoerrors.New(betoerrors.CodeForbidden, "role "+role.Name+" grants "+p.Slug+", which you do not hold on this project")
+		}
+	}
+	return nil
+}
@@ -20,10 +20,17 @@ type IRBACService interface {
 	AssignProjectRole(ctx context.Context, req *domain.AssignRoleRequest) error
 	CheckPermission(ctx context.Context, req *domain.CheckPermissionRequest) (bool, error)
 	GetUserProjectPermissions(ctx context.Context, projectIDStr, userIDStr string) (*domain.PermissionsResponse, error)
+	ListPermissions() []string
+	CreateRole(ctx context.Context, req *domain.CreateRoleRequest) (*domain.RoleDTO, error)
+	UpdateRole(ctx context.Context, req *domain.UpdateRoleRequest) (*domain.RoleDTO, error)
+	DeleteRole(ctx context.Context, roleIDStr string) error
 }
 
 type IAPIKeyService interface {
 	CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*APIKeyCreatedResponse, error)
 	ListUserKeys(ctx context.Context, userIDStr string) ([]APIKeyDTO, error)
 	RevokeKey(ctx context.Context, userIDStr, keyIDStr string) error
+	RotateKey(ctx context.Context, userIDStr, keyIDStr string, in RotateAPIKeyInput) (*APIKeyCreatedResponse, error)
+	GetKeyUsage(ctx context.Context, userIDStr, keyIDStr string) (*APIKeyUsageDTO, error)
+	RunExpiryNotifications(ctx context.Context)
 }
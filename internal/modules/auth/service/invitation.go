@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	maildomain "github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+	mailSvc "github.com/thekrauss/kubemanager/internal/modules/mailer/service"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	webhookDomain "github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+)
+
+// durée de validité du lien d'invitation
+const invitationTTL = 7 * 24 * time.Hour
+
+// InvitationService invites people to a project by email. Unlike AssignProjectRole the invitee
+// needs no account yet and joins only by accepting.
+type InvitationService struct {
+	Repo    repository.AuthRepository
+	Config  *configs.GlobalConfig
+	Mailer  mailSvc.IMailService
+	Emitter webhookDomain.EventEmitter
+	Logger  *zap.SugaredLogger
+}
+
+func NewInvitationService(repo repository.AuthRepository, cfg *configs.GlobalConfig, mailer mailSvc.IMailService, emitter webhookDomain.EventEmitter, logger *zap.SugaredLogger) *InvitationService {
+	return &InvitationService{
+		Repo:    repo,
+		Config:  cfg,
+		Mailer:  mailer,
+		Emitter: emitter,
+		Logger:  logger.With("service", "InvitationService"),
+	}
+}
+
+// Invite emails an invitation link. An address that is already a member gets the new role
+// right away instead, the membership key (project, user) is never inserted twice. The role
+// cannot exceed what the caller holds on the project.
+func (s *InvitationService) Invite(ctx context.Context, access *security.ProjectAccess, inviterIDStr string, req *domain.CreateInvitationRequest) (*domain.CreateInvitationResponse, error) {
+	pID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	inviterID, err := uuid.Parse(inviterIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	role, err := s.Repo.GetRoleByName(ctx, strings.ToUpper(strings.TrimSpace(req.Role)))
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "role not found")
+	}
+	if err := checkGrantable(access, role); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to look up user")
+	}
+	if existing != nil {
+		if existing.IsServiceAccount() {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "service accounts are managed under /projects/:id/service-accounts")
+		}
+		if member, err := s.Repo.GetProjectMember(ctx, pID, existing.ID); err == nil {
+			return s.updateMemberRole(ctx, access, member, role)
+		}
+	}
+
+	nonce, token, err := s.newToken()
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate invitation token")
+	}
+
+	inv := &domain.ProjectInvitation{
+		ProjectID: pID,
+		Email:     email,
+		RoleID:    role.ID,
+		InvitedBy: inviterID,
+		TokenHash: hashToken(nonce),
+		Status:    utils.InvitationPending,
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	if err := s.Repo.CreateInvitation(ctx, inv); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to create invitation")
+	}
+
+	if err := s.sendInvitation(ctx, inv, inviterID, token); err != nil {
+		// sans email le lien est inutilisable, l'invitation est retirée
+		_ = s.Repo.RevokeInvitation(ctx, pID, inv.ID)
+		s.Logger.Errorw("failed to send invitation", "project", pID, "email", email, "error", err)
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to send invitation email")
+	}
+
+	s.Logger.Infow("project invitation sent", "project", pID, "email", email, "role", role.Name)
+	inv.Role = *role
+	dto := invitationToDTO(inv)
+	return &domain.CreateInvitationResponse{Invitation: &dto, RoleName: role.Name}, nil
+}
+
+func (s *InvitationService) ListPending(ctx context.Context, projectIDStr string) ([]domain.InvitationDTO, error) {
+	pID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+
+	invitations, err := s.Repo.ListPendingInvitations(ctx, pID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list invitations")
+	}
+
+	result := make([]domain.InvitationDTO, 0, len(invitations))
+	for i := range invitations {
+		result = append(result, invitationToDTO(&invitations[i]))
+	}
+	return result, nil
+}
+
+func (s *InvitationService) Revoke(ctx context.Context, projectIDStr, invitationIDStr string) error {
+	pID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	invID, err := uuid.Parse(invitationIDStr)
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid invitation id")
+	}
+
+	if err := s.Repo.RevokeInvitation(ctx, pID, invID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return betoerrors.New(betoerrors.CodeNotFound, "pending invitation not found")
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to revoke invitation")
+	}
+
+	s.Logger.Infow("project invitation revoked", "project", pID, "invitation_id", invID)
+	return nil
+}
+
+// Accept makes the caller a member of the project with the invited role. The caller must be
+// signed in with the invited address.
+func (s *InvitationService) Accept(ctx context.Context, userIDStr, token string) (*domain.InvitationAcceptedResponse, error) {
+	inv, user, err := s.open(ctx, userIDStr, token)
+	if err != nil {
+		return nil, err
+	}
+
+	isNew, err := s.Repo.AcceptInvitation(ctx, inv, user.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invitation is invalid or expired")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to accept invitation")
+	}
+
+	s.Logger.Infow("project invitation accepted", "project", inv.ProjectID, "user_id", user.ID, "role", inv.Role.Name)
+
+	if isNew && s.Emitter != nil {
+		event := webhookDomain.NewEvent(utils.EventMemberAdded, inv.ProjectID.String(), map[string]interface{}{
+			"user_id": user.ID.String(),
+			"role":    inv.Role.Name,
+		})
+		// le membre est ajouté, un webhook en échec ne doit pas annuler l'opération
+		if err := s.Emitter.Emit(ctx, event); err != nil {
+			s.Logger.Warnw("failed to emit member.added", "project", inv.ProjectID, "user", user.ID, "error", err)
+		}
+	}
+
+	return &domain.InvitationAcceptedResponse{
+		ProjectID:   inv.ProjectID.String(),
+		ProjectName: inv.Project.Name,
+		RoleName:    inv.Role.Name,
+	}, nil
+}
+
+func (s *InvitationService) Decline(ctx context.Context, userIDStr, token string) error {
+	inv, user, err := s.open(ctx, userIDStr, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.DeclineInvitation(ctx, inv, user.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "invitation is invalid or expired")
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to decline invitation")
+	}
+
+	s.Logger.Infow("project invitation declined", "project", inv.ProjectID, "user_id", user.ID)
+	return nil
+}
+
+// open checks the token and that the invitation, still pending, was sent to the caller.
+func (s *InvitationService) open(ctx context.Context, userIDStr, token string) (*domain.ProjectInvitation, *domain.User, error) {
+	nonce, ok := s.openToken(token)
+	if !ok {
+		return nil, nil, betoerrors.New(betoerrors.CodeInvalidInput, "invitation is invalid or expired")
+	}
+
+	inv, err := s.Repo.GetInvitationByTokenHash(ctx, hashToken(nonce))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, betoerrors.New(betoerrors.CodeInvalidInput, "invitation is invalid or expired")
+		}
+		return nil, nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to load invitation")
+	}
+	if inv.Status != utils.InvitationPending || !inv.ExpiresAt.After(time.Now()) {
+		return nil, nil, betoerrors.New(betoerrors.CodeInvalidInput, "invitation is invalid or expired")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	user, err := s.Repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		return nil, nil, betoerrors.New(betoerrors.CodeForbidden, "this invitation was sent to another email address")
+	}
+	return inv, user, nil
+}
+
+// updateMemberRole only changes a role the caller could have granted, and keeps an OWNER on the
+// project like the manifest members section does.
+func (s *InvitationService) updateMemberRole(ctx context.Context, access *security.ProjectAccess, member *domain.ProjectMember, role *domain.Role) (*domain.CreateInvitationResponse, error) {
+	if err := checkGrantable(access, &member.Role); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "this member holds the role "+member.Role.Name+", which you cannot change")
+	}
+
+	owner := domain.RoleTypes.Owner.String()
+	if member.Role.Name == owner && role.Name != owner {
+		members, err := s.Repo.ListProjectMembers(ctx, member.ProjectID)
+		if err != nil {
+			return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list project members")
+		}
+		owners := 0
+		for _, m := range members {
+			if m.Role.Name == owner {
+				owners++
+			}
+		}
+		if owners <= 1 {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "the project must keep at least one OWNER")
+		}
+	}
+
+	err := s.Repo.AddProjectMember(ctx, &domain.ProjectMember{
+		ProjectID: member.ProjectID,
+		UserID:    member.UserID,
+		RoleID:    role.ID,
+		JoinedAt:  member.JoinedAt,
+	})
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to update member role")
+	}
+
+	s.Logger.Infow("invited address is already a member, role updated", "project", member.ProjectID, "user_id", member.UserID, "role", role.Name)
+	return &domain.CreateInvitationResponse{MemberUpdated: true, RoleName: role.Name}, nil
+}
+
+func (s *InvitationService) sendInvitation(ctx context.Context, inv *domain.ProjectInvitation, inviterID uuid.UUID, token string) error {
+	// recharge l'invitation pour le nom du projet et du rôle
+	full, err := s.Repo.GetInvitationByTokenHash(ctx, inv.TokenHash)
+	if err != nil {
+		return err
+	}
+
+	invitedBy := "Un membre"
+	if inviter, err := s.Repo.GetUserByID(ctx, inviterID); err == nil {
+		invitedBy = displayName(inviter)
+	}
+
+	return s.Mailer.Send(ctx, maildomain.EmailRequest{
+		To:       []string{inv.Email},
+		Template: maildomain.TemplateProjectInvitation,
+		Data: map[string]interface{}{
+			"InvitedBy": invitedBy,
+			"Project":   full.Project.Name,
+			"Role":      full.Role.Name,
+			"Link":      s.Mailer.Link("/invitations?token=" + url.QueryEscape(token)),
+			"ExpiresIn": humanDuration(invitationTTL),
+		},
+	})
+}
+
+// le jeton est "<nonce>.<hmac(nonce)>" comme celui de vérification d'email, seule l'empreinte
+// du nonce est stockée avec l'invitation
+func (s *InvitationService) newToken() (string, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+	return nonce, nonce + "." + s.sign(nonce), nil
+}
+
+func (s *InvitationService) openToken(token string) (string, bool) {
+	nonce, sig, found := strings.Cut(token, ".")
+	if !found || nonce == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.sign(nonce))) {
+		return "", false
+	}
+	return nonce, true
+}
+
+func (s *InvitationService) sign(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.JWT.Secret))
+	mac.Write([]byte("project-invitation:" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashToken(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+func invitationToDTO(inv *domain.ProjectInvitation) domain.InvitationDTO {
+	return domain.InvitationDTO{
+		ID:        inv.ID.String(),
+		ProjectID: inv.ProjectID.String(),
+		Email:     inv.Email,
+		RoleName:  inv.Role.Name,
+		Status:    inv.Status,
+		InvitedBy: inv.InvitedBy.String(),
+		Expired:   !inv.ExpiresAt.After(time.Now()),
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	maildomain "github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+)
+
+// memMailer keeps the emails instead of queuing them.
+type memMailer struct {
+	sent []maildomain.EmailRequest
+}
+
+func (m *memMailer) Send(_ context.Context, req maildomain.EmailRequest) error {
+	m.sent = append(m.sent, req)
+	return nil
+}
+
+func (m *memMailer) Link(path string) string {
+	return "https://app.kubemanager.test" + path
+}
+
+// lastToken reads the token of the link in the last invitation email.
+func (m *memMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatal("no invitation email sent")
+	}
+	link, err := url.Parse(m.sent[len(m.sent)-1].Data["Link"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link.Query().Get("token")
+}
+
+// memberFixture is a project with the built-in roles, a LEAD role managing the members without
+// editing the project, and its owner.
+type memberFixture struct {
+	service *InvitationService
+	repo    *memRepo
+	mailer  *memMailer
+	project uuid.UUID
+	owner   *domain.User
+	roles   map[string]*domain.Role
+}
+
+// access is what the permission middleware hands to the handler for a member holding role.
+func (f *memberFixture) access(role string) *security.ProjectAccess {
+	perms := make([]string, 0, len(f.roles[role].Permissions))
+	for _, p := range f.roles[role].Permissions {
+		perms = append(perms, p.Slug)
+	}
+	return &security.ProjectAccess{ProjectID: f.project, Role: role, Permissions: perms}
+}
+
+func slugs(perms []domain.PermissionType) []string {
+	out := make([]string, len(perms))
+	for i, p := range perms {
+		out[i] = p.String()
+	}
+	return out
+}
+
+func newMemberFixture(t *testing.T) *memberFixture {
+	t.Helper()
+	repo, mailer := newMemRepo(), &memMailer{}
+	f := &memberFixture{
+		service: &InvitationService{
+			Repo:   repo,
+			Config: &configs.GlobalConfig{JWT: configs.JWTConfig{Secret: "test-secret"}},
+			Mailer: mailer,
+			Logger: zap.NewNop().Sugar(),
+		},
+		repo:    repo,
+		mailer:  mailer,
+		project: uuid.New(),
+		roles:   map[string]*domain.Role{},
+	}
+	for roleType, perms := range domain.DefaultRolePermissions() {
+		role := &domain.Role{ID: uuid.New(), Name: roleType.String(), IsSystem: true}
+		_ = repo.CreateRole(context.Background(), role, slugs(perms))
+		f.roles[role.Name] = role
+	}
+	// gère les membres sans pouvoir modifier le projet
+	lead := &domain.Role{ID: uuid.New(), Name: "LEAD"}
+	_ = repo.CreateRole(context.Background(), lead, []string{"project:view", "k8s:logs:view", "project:members:manage"})
+	f.roles[lead.Name] = lead
+	f.owner = repo.addUser(&domain.User{Email: "owner@example.com", IsActive: true})
+	repo.setMember(f.project, f.owner.ID, f.roles["OWNER"].ID)
+	return f
+}
+
+func (f *memberFixture) invite(t *testing.T, email, role string) string {
+	t.Helper()
+	resp, err := f.service.Invite(context.Background(), f.access("OWNER"), f.owner.ID.String(), &domain.CreateInvitationRequest{
+		ProjectID: f.project.String(),
+		Email:     email,
+		Role:      role,
+	})
+	if err != nil {
+		t.Fatalf("Invite %s: %v", email, err)
+	}
+	if resp.MemberUpdated {
+		t.Fatalf("Invite %s updated a member instead of sending an invitation", email)
+	}
+	return f.mailer.lastToken(t)
+}
+
+func TestInvitationIsOnlyOpenedByTheInvitedAddress(t *testing.T) {
+	f := newMemberFixture(t)
+	invitee := f.repo.addUser(&domain.User{Email: "Zoe@Example.com", IsActive: true})
+	other := f.repo.addUser(&domain.User{Email: "mallory@example.com", IsActive: true})
+	token := f.invite(t, " zoe@example.com ", "developer")
+
+	// le lien transféré ou intercepté ne sert à personne d'autre, ni pour accepter ni pour refuser
+	_, err := f.service.Accept(context.Background(), other.ID.String(), token)
+	assertCode(t, err, betoerrors.CodeForbidden)
+	assertCode(t, f.service.Decline(context.Background(), other.ID.String(), token), betoerrors.CodeForbidden)
+	if role := f.repo.memberRole(f.project, other.ID); role != "" {
+		t.Fatalf("the other account joined as %s", role)
+	}
+
+	resp, err := f.service.Accept(context.Background(), invitee.ID.String(), token)
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if resp.RoleName != "DEVELOPER" || resp.ProjectID != f.project.String() {
+		t.Errorf("response = %+v", resp)
+	}
+	if role := f.repo.memberRole(f.project, invitee.ID); role != "DEVELOPER" {
+		t.Errorf("member role = %q, want DEVELOPER", role)
+	}
+
+	// une invitation ne sert qu'une fois
+	_, err = f.service.Accept(context.Background(), invitee.ID.String(), token)
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+}
+
+func TestInvitationTokenChecks(t *testing.T) {
+	f := newMemberFixture(t)
+	invitee := f.repo.addUser(&domain.User{Email: "zoe@example.com", IsActive: true})
+	token := f.invite(t, invitee.Email, "VIEWER")
+
+	nonce := token[:len(token)-4]
+	for name, bad := range map[string]string{
+		"signature altered": nonce + "AAAA",
+		"no signature":      nonce,
+		"empty":             "",
+	} {
+		_, err := f.service.Accept(context.Background(), invitee.ID.String(), bad)
+		if !betoerrors.Is(err, betoerrors.CodeInvalidInput) {
+			t.Errorf("%s: err = %v, want an invalid invitation", name, err)
+		}
+	}
+
+	// signé par une autre instance
+	other := &InvitationService{Config: &configs.GlobalConfig{JWT: configs.JWTConfig{Secret: "other-secret"}}}
+	_, forged, _ := other.newToken()
+	_, err := f.service.Accept(context.Background(), invitee.ID.String(), forged)
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+
+	for _, inv := range f.repo.invites {
+		inv.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+	_, err = f.service.Accept(context.Background(), invitee.ID.String(), token)
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+}
+
+func TestRevokedOrDeclinedInvitationCannotBeAccepted(t *testing.T) {
+	f := newMemberFixture(t)
+	invitee := f.repo.addUser(&domain.User{Email: "zoe@example.com", IsActive: true})
+
+	token := f.invite(t, invitee.Email, "VIEWER")
+	if err := f.service.Decline(context.Background(), invitee.ID.String(), token); err != nil {
+		t.Fatalf("Decline: %v", err)
+	}
+	_, err := f.service.Accept(context.Background(), invitee.ID.String(), token)
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+
+	token = f.invite(t, invitee.Email, "VIEWER")
+	pending, err := f.service.ListPending(context.Background(), f.project.String())
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("ListPending = %v, %v", pending, err)
+	}
+	if err := f.service.Revoke(context.Background(), f.project.String(), pending[0].ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	_, err = f.service.Accept(context.Background(), invitee.ID.String(), token)
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+	if role := f.repo.memberRole(f.project, invitee.ID); role != "" {
+		t.Errorf("joined as %s through a declined or revoked invitation", role)
+	}
+}
+
+func TestInvitingAMemberChangesTheirRole(t *testing.T) {
+	f := newMemberFixture(t)
+	member := f.repo.addUser(&domain.User{Email: "zoe@example.com", IsActive: true})
+	f.repo.setMember(f.project, member.ID, f.roles["VIEWER"].ID)
+
+	resp, err := f.service.Invite(context.Background(), f.access("OWNER"), f.owner.ID.String(), &domain.CreateInvitationRequest{
+		ProjectID: f.project.String(),
+		Email:     member.Email,
+		Role:      "DEVELOPER",
+	})
+	if err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+	if !resp.MemberUpdated || len(f.mailer.sent) != 0 {
+		t.Errorf("response = %+v, %d emails; want the role updated without email", resp, len(f.mailer.sent))
+	}
+	if role := f.repo.memberRole(f.project, member.ID); role != "DEVELOPER" {
+		t.Errorf("member role = %q, want DEVELOPER", role)
+	}
+}
+
+func TestInviteCannotGrantMoreThanTheCallerHolds(t *testing.T) {
+	f := newMemberFixture(t)
+	lead := f.repo.addUser(&domain.User{Email: "lead@example.com", IsActive: true})
+	f.repo.setMember(f.project, lead.ID, f.roles["LEAD"].ID)
+	invite := func(access *security.ProjectAccess, email, role string) error {
+		_, err := f.service.Invite(context.Background(), access, lead.ID.String(), &domain.CreateInvitationRequest{
+			ProjectID: f.project.String(), Email: email, Role: role,
+		})
+		return err
+	}
+
+	if err := invite(f.access("LEAD"), "viewer@example.com", "VIEWER"); err != nil {
+		t.Fatalf("a lead invites a viewer: %v", err)
+	}
+	// DEVELOPER donne project:edit et k8s:workload:create, que LEAD n'a pas
+	assertCode(t, invite(f.access("LEAD"), "dev@example.com", "DEVELOPER"), betoerrors.CodeForbidden)
+	assertCode(t, invite(f.access("LEAD"), "boss@example.com", "OWNER"), betoerrors.CodeForbidden)
+
+	// un rôle avec toutes les permissions n'est pas pour autant OWNER
+	allButOwner := &security.ProjectAccess{ProjectID: f.project, Role: "DEVELOPER", Permissions: slugs(domain.PermissionTypes.All())}
+	assertCode(t, invite(allButOwner, "boss@example.com", "OWNER"), betoerrors.CodeForbidden)
+
+	// la clé API d'un OWNER reste limitée à ses scopes
+	scoped := f.access("OWNER")
+	scoped.APIKey = &cache.APIKeyScope{Scopes: []string{"project:view", "k8s:logs:view", "project:members:manage"}}
+	assertCode(t, invite(scoped, "dev@example.com", "DEVELOPER"), betoerrors.CodeForbidden)
+	if err := invite(scoped, "viewer2@example.com", "VIEWER"); err != nil {
+		t.Errorf("a scoped key invites a viewer: %v", err)
+	}
+
+	// un membre au-dessus de l'appelant garde son rôle
+	assertCode(t, invite(f.access("LEAD"), f.owner.Email, "VIEWER"), betoerrors.CodeForbidden)
+	if role := f.repo.memberRole(f.project, f.owner.ID); role != "OWNER" {
+		t.Errorf("owner demoted to %s by a lead", role)
+	}
+}
+
+func TestInviteKeepsAnOwner(t *testing.T) {
+	f := newMemberFixture(t)
+	demote := func(user *domain.User) error {
+		_, err := f.service.Invite(context.Background(), f.access("OWNER"), f.owner.ID.String(), &domain.CreateInvitationRequest{
+			ProjectID: f.project.String(), Email: user.Email, Role: "DEVELOPER",
+		})
+		return err
+	}
+
+	assertCode(t, demote(f.owner), betoerrors.CodeInvalidInput)
+	if role := f.repo.memberRole(f.project, f.owner.ID); role != "OWNER" {
+		t.Fatalf("the last owner became %s", role)
+	}
+
+	second := f.repo.addUser(&domain.User{Email: "second@example.com", IsActive: true})
+	f.repo.setMember(f.project, second.ID, f.roles["OWNER"].ID)
+	if err := demote(f.owner); err != nil {
+		t.Fatalf("demote with another owner left: %v", err)
+	}
+	assertCode(t, demote(second), betoerrors.CodeInvalidInput)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	mailSvc "github.com/thekrauss/kubemanager/internal/modules/mailer/service"
 )
 
 type AuthService struct {
@@ -24,6 +25,7 @@ type AuthService struct {
 	Logger   *zap.SugaredLogger
 	Config   *configs.GlobalConfig
 	Hasher   security.PasswordHasher
+	Mailer   mailSvc.IMailService
 }
 
 func NewAuthService(
@@ -33,6 +35,7 @@ func NewAuthService(
 	cacheRepo cache.CacheRedis,
 	logger *zap.SugaredLogger,
 	hasher security.PasswordHasher,
+	mailer mailSvc.IMailService,
 ) *AuthService {
 	return &AuthService{
 		AuthRepo: repo,
@@ -41,10 +44,14 @@ func NewAuthService(
 		Logger:   logger.With("service", "AuthService"),
 		Config:   cfg,
 		Hasher:   hasher,
+		Mailer:   mailer,
 	}
 }
 
 func (s *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (*domain.LoginResponse, error) {
+	if s.Config.OIDC.DisablePasswordLogin {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "password login is disabled, use single sign-on")
+	}
 
 	blocked, err := s.Cache.IsUserBlocked(ctx, req.Identifier)
 	if err != nil {
@@ -60,7 +67,8 @@ func (s *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (*dom
 		return nil, betoerrors.New(betoerrors.CodeInternal, "authentication failed")
 	}
 
-	if user == nil || !user.IsActive {
+	// un compte de service ne s'authentifie que par clé API
+	if user == nil || !user.IsActive || user.IsServiceAccount() {
 		s.handleFailedLogin(ctx, req.Identifier)
 		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid credentials")
 	}
@@ -70,6 +78,20 @@ func (s *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (*dom
 		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid credentials")
 	}
 
+	if s.Config.Verify.Required && !s.Config.Verify.AllowUnverifiedLogin && !user.IsVerified {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "email address not verified")
+	}
+
+	if user.MFAEnabled {
+		return s.startMFAChallenge(ctx, user, req.UserAgent, req.ClientIP)
+	}
+
+	return s.startSession(ctx, user, req.UserAgent, req.ClientIP, false)
+}
+
+// startSession opens a session for an authenticated user: refresh token family, access token
+// and cached session. mfa records whether a second factor was checked.
+func (s *AuthService) startSession(ctx context.Context, user *domain.User, userAgent, clientIP string, mfa bool) (*domain.LoginResponse, error) {
 	projectRolesMap := make(map[string]string)
 
 	memberships, err := s.AuthRepo.GetUserProjectMemberships(ctx, user.ID)
@@ -81,28 +103,43 @@ func (s *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (*dom
 		projectRolesMap[m.ProjectID.String()] = m.Role.Name
 	}
 
+	// la famille de refresh tokens sert d'identifiant de session (claim sid)
+	familyID := uuid.New()
+
 	accessToken, err := s.JWT.GenerateAccessToken(
 		user.ID.String(),
 		user.Role,
+		familyID.String(),
 		s.Config.JWT.AccessExpiration,
 	)
 	if err != nil {
 		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate access token")
 	}
 
-	refreshToken := uuid.New().String()
+	userSession, refreshToken, err := s.newSession(user.ID, familyID, time.Now(), userAgent, clientIP)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate refresh token")
+	}
+	userSession.MFAVerified = mfa
+
+	if err := s.AuthRepo.CreateSession(ctx, userSession); err != nil {
+		s.Logger.Errorw("failed to persist refresh session", "user_id", user.ID, "error", err)
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to create session")
+	}
 
 	session := cache.SessionData{
+		SessionID:    familyID.String(),
 		UserID:       user.ID.String(),
 		Email:        user.Email,
 		GlobalRole:   user.Role,
 		ProjectRoles: projectRolesMap,
 		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
 		ExpiresAt:    time.Now().Add(s.Config.JWT.AccessExpiration),
+		LastSeenAt:   time.Now(),
+		MFA:          mfa,
 	}
 
-	if err := s.Cache.StoreSession(ctx, user.ID.String(), session, s.Config.JWT.AccessExpiration); err != nil {
+	if err := s.Cache.StoreSession(ctx, session.SessionID, session, s.Config.JWT.AccessExpiration); err != nil {
 		s.Logger.Errorw("failed to store session in redis", "user_id", user.ID, "error", err)
 	}
 
@@ -145,26 +182,45 @@ func (s *AuthService) ValidateToken(ctx context.Context, req *domain.TokenReques
 	return &domain.TokenResponse{Valid: true}, nil
 }
 func (s *AuthService) RefreshToken(ctx context.Context, req *domain.RefreshTokenRequest) (*domain.RefreshTokenResponse, error) {
-	refreshTokenID, err := uuid.Parse(req.RefreshToken)
+	sessionID, secret, err := parseRefreshToken(req.RefreshToken)
 	if err != nil {
-		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid refresh token format")
+		return nil, err
 	}
 
-	refreshToken, err := s.AuthRepo.FindRefreshTokenByJTI(ctx, refreshTokenID)
-	if err != nil || refreshToken == nil {
+	current, err := s.AuthRepo.FindRefreshTokenByJTI(ctx, sessionID)
+	if err != nil || current == nil || !refreshTokenMatches(current, secret) {
 		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid or expired refresh token")
 	}
 
-	if time.Now().After(refreshToken.ExpiresAt) {
-		_ = s.AuthRepo.DeleteRefreshTokenByJTI(ctx, refreshTokenID)
-		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "refresh token expired")
+	if current.RotatedAt != nil {
+		return nil, s.revokeOnReuse(ctx, current)
 	}
 
-	user, err := s.AuthRepo.GetUserByID(ctx, refreshToken.UserID)
+	if current.IsBlocked || time.Now().After(current.ExpiresAt) {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid or expired refresh token")
+	}
+
+	user, err := s.AuthRepo.GetUserByID(ctx, current.UserID)
 	if err != nil || user == nil || !user.IsActive {
 		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "user account is no longer active or not found")
 	}
 
+	next, newRefreshToken, err := s.newSession(user.ID, current.FamilyID, current.StartedAt, req.UserAgent, req.ClientIP)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate refresh token")
+	}
+	next.MFAVerified = current.MFAVerified
+
+	rotated, err := s.AuthRepo.RotateSession(ctx, current.ID, next)
+	if err != nil {
+		s.Logger.Errorw("failed to rotate refresh session", "session_id", current.ID, "error", err)
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to rotate refresh token")
+	}
+	if !rotated {
+		// un autre appel a consommé le jeton entre la lecture et la rotation
+		return nil, s.revokeOnReuse(ctx, current)
+	}
+
 	projectRolesMap := make(map[string]string)
 	memberships, err := s.AuthRepo.GetUserProjectMemberships(ctx, user.ID)
 	if err == nil {
@@ -176,6 +232,7 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *domain.RefreshToken
 	newAccessToken, err := s.JWT.GenerateAccessToken(
 		user.ID.String(),
 		user.Role,
+		current.FamilyID.String(),
 		s.Config.JWT.AccessExpiration,
 	)
 	if err != nil {
@@ -184,36 +241,50 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *domain.RefreshToken
 	}
 
 	session := cache.SessionData{
+		SessionID:    current.FamilyID.String(),
 		UserID:       user.ID.String(),
 		Email:        user.Email,
 		GlobalRole:   user.Role,
 		ProjectRoles: projectRolesMap,
 		AccessToken:  newAccessToken,
-		RefreshToken: req.RefreshToken,
 		ExpiresAt:    time.Now().Add(s.Config.JWT.AccessExpiration),
+		LastSeenAt:   time.Now(),
+		MFA:          current.MFAVerified,
 	}
 
-	if err := s.Cache.StoreSession(ctx, user.ID.String(), session, s.Config.JWT.AccessExpiration); err != nil {
+	if err := s.Cache.StoreSession(ctx, session.SessionID, session, s.Config.JWT.AccessExpiration); err != nil {
 		s.Logger.Errorw("failed to update cache session during refresh", "error", err)
 	}
 
 	return &domain.RefreshTokenResponse{
 		Token:           newAccessToken,
-		RefreshToken:    req.RefreshToken,
+		RefreshToken:    newRefreshToken,
 		AccessExpiresAt: session.ExpiresAt.Format(time.RFC3339),
 	}, nil
 }
 func (s *AuthService) Logout(ctx context.Context, req *domain.LogoutRequest) error {
 	s.Logger.Infow("User logout requested", "user_id", req.UserID)
 
-	if err := s.Cache.DeleteSession(ctx, req.UserID); err != nil {
-		s.Logger.Warnw("failed to delete session", "user_id", req.UserID, "error", err)
+	if req.SessionID != "" {
+		if err := s.Cache.DeleteSession(ctx, req.SessionID); err != nil {
+			s.Logger.Warnw("failed to delete session", "user_id", req.UserID, "error", err)
+		}
+		userID, errUser := uuid.Parse(req.UserID)
+		familyID, errFamily := uuid.Parse(req.SessionID)
+		if errUser == nil && errFamily == nil {
+			_ = s.AuthRepo.RevokeSessionFamily(ctx, userID, familyID)
+		}
 	}
 
 	if req.RefreshToken != "" {
-		tokenUUID, err := uuid.Parse(req.RefreshToken)
-		if err == nil {
-			_ = s.AuthRepo.DeleteRefreshTokenByJTI(ctx, tokenUUID)
+		// seul le propriétaire du jeton, secret compris, peut bloquer la ligne désignée par son ID
+		if sessionID, secret, err := parseRefreshToken(req.RefreshToken); err == nil {
+			current, err := s.AuthRepo.FindRefreshTokenByJTI(ctx, sessionID)
+			if err == nil && current != nil && current.UserID.String() == req.UserID && refreshTokenMatches(current, secret) {
+				_ = s.AuthRepo.DeleteRefreshTokenByJTI(ctx, sessionID)
+			} else {
+				s.Logger.Warnw("logout ignored a refresh token of another session", "user_id", req.UserID, "session_id", sessionID)
+			}
 		}
 	}
 
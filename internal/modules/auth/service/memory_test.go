@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+// memRepo keeps in memory the rows the tested flows touch. A method it does not override
+// panics on the nil embedded interface, so a test reaching further than intended shows up.
+type memRepo struct {
+	repository.AuthRepository
+
+	mu       sync.Mutex
+	users    map[uuid.UUID]*domain.User
+	recovery map[uuid.UUID][]domain.MFARecoveryCode
+	roles    map[uuid.UUID]*domain.Role
+	inUse    map[uuid.UUID]bool                    // rôles encore attribués à un membre
+	members  map[uuid.UUID]map[uuid.UUID]uuid.UUID // projet -> utilisateur -> rôle
+	invites  map[uuid.UUID]*domain.ProjectInvitation
+	sessions map[uuid.UUID]*domain.UserSession
+	apiKeys  map[uuid.UUID]*domain.APIKey
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		users:    map[uuid.UUID]*domain.User{},
+		recovery: map[uuid.UUID][]domain.MFARecoveryCode{},
+		roles:    map[uuid.UUID]*domain.Role{},
+		inUse:    map[uuid.UUID]bool{},
+		members:  map[uuid.UUID]map[uuid.UUID]uuid.UUID{},
+		invites:  map[uuid.UUID]*domain.ProjectInvitation{},
+		sessions: map[uuid.UUID]*domain.UserSession{},
+		apiKeys:  map[uuid.UUID]*domain.APIKey{},
+	}
+}
+
+func (r *memRepo) addUser(u *domain.User) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	r.users[u.ID] = u
+	return u
+}
+
+func (r *memRepo) GetUserByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memRepo) GetUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memRepo) UpdateUser(context.Context, *domain.User) error { return nil }
+
+func (r *memRepo) CreateSession(_ context.Context, session *domain.UserSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *memRepo) FindRefreshTokenByJTI(_ context.Context, jti uuid.UUID) (*domain.UserSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessions[jti], nil
+}
+
+func (r *memRepo) DeleteRefreshTokenByJTI(_ context.Context, jti uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[jti]; ok {
+		session.IsBlocked = true
+	}
+	return nil
+}
+
+func (r *memRepo) GetUserAPIKey(_ context.Context, keyID, userID uuid.UUID) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.apiKeys[keyID]
+	if !ok || key.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	k := *key
+	return &k, nil
+}
+
+func (r *memRepo) RotateAPIKey(_ context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.apiKeys[key.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	k := *key
+	r.apiKeys[key.ID] = &k
+	return nil
+}
+
+func (r *memRepo) GetUserProjectMemberships(context.Context, uuid.UUID) ([]domain.ProjectMember, error) {
+	return nil, nil
+}
+
+func (r *memRepo) SaveMFASecret(_ context.Context, userID uuid.UUID, encryptedSecret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].MFASecret = encryptedSecret
+	return nil
+}
+
+func (r *memRepo) EnableMFA(_ context.Context, userID uuid.UUID, codes []domain.MFARecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].MFAEnabled = true
+	r.recovery[userID] = codes
+	return nil
+}
+
+func (r *memRepo) UseRecoveryCode(_ context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.recovery[userID] {
+		if c.CodeHash == codeHash && c.UsedAt == nil {
+			now := time.Now()
+			r.recovery[userID][i].UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memRepo) GetRoleByName(_ context.Context, name string) (*domain.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memRepo) GetRoleByID(_ context.Context, id uuid.UUID) (*domain.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if role, ok := r.roles[id]; ok {
+		return role, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memRepo) CreateRole(_ context.Context, role *domain.Role, slugs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	role.Permissions = permissionRows(slugs)
+	r.roles[role.ID] = role
+	return nil
+}
+
+func (r *memRepo) UpdateRole(_ context.Context, role *domain.Role, slugs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	role.Permissions = permissionRows(slugs)
+	r.roles[role.ID] = role
+	return nil
+}
+
+func (r *memRepo) DeleteRole(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inUse[id] {
+		return repository.ErrRoleInUse
+	}
+	if _, ok := r.roles[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.roles, id)
+	return nil
+}
+
+func (r *memRepo) GetProjectMember(_ context.Context, projectID, userID uuid.UUID) (*domain.ProjectMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roleID, ok := r.members[projectID][userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	member := &domain.ProjectMember{ProjectID: projectID, UserID: userID, RoleID: roleID, Role: *r.roles[roleID]}
+	if u, ok := r.users[userID]; ok {
+		member.User = *u
+	}
+	return member, nil
+}
+
+func (r *memRepo) CreateServiceAccount(_ context.Context, account *domain.User, roleID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	account.ID = uuid.New()
+	r.users[account.ID] = account
+	r.setMember(*account.ServiceAccountProjectID, account.ID, roleID)
+	return nil
+}
+
+func (r *memRepo) ListProjectMembers(_ context.Context, projectID uuid.UUID) ([]domain.ProjectMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var members []domain.ProjectMember
+	for userID, roleID := range r.members[projectID] {
+		members = append(members, domain.ProjectMember{ProjectID: projectID, UserID: userID, RoleID: roleID, Role: *r.roles[roleID]})
+	}
+	return members, nil
+}
+
+func (r *memRepo) AddProjectMember(_ context.Context, m *domain.ProjectMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setMember(m.ProjectID, m.UserID, m.RoleID)
+	return nil
+}
+
+func (r *memRepo) setMember(projectID, userID, roleID uuid.UUID) {
+	if r.members[projectID] == nil {
+		r.members[projectID] = map[uuid.UUID]uuid.UUID{}
+	}
+	r.members[projectID][userID] = roleID
+}
+
+// memberRole returns the name of the role held on the project, empty for a non-member.
+func (r *memRepo) memberRole(projectID, userID uuid.UUID) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roleID, ok := r.members[projectID][userID]
+	if !ok {
+		return ""
+	}
+	return r.roles[roleID].Name
+}
+
+func (r *memRepo) CreateInvitation(_ context.Context, inv *domain.ProjectInvitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inv.ID = uuid.New()
+	inv.CreatedAt = time.Now()
+	r.invites[inv.ID] = inv
+	return nil
+}
+
+func (r *memRepo) GetInvitationByTokenHash(_ context.Context, tokenHash string) (*domain.ProjectInvitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, inv := range r.invites {
+		if inv.TokenHash == tokenHash {
+			full := *inv
+			full.Role = *r.roles[inv.RoleID]
+			full.Project = domain.Project{ID: inv.ProjectID, Name: "shop"}
+			return &full, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memRepo) ListPendingInvitations(_ context.Context, projectID uuid.UUID) ([]domain.ProjectInvitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []domain.ProjectInvitation
+	for _, inv := range r.invites {
+		if inv.ProjectID == projectID && inv.Status == utils.InvitationPending {
+			full := *inv
+			full.Role = *r.roles[inv.RoleID]
+			pending = append(pending, full)
+		}
+	}
+	return pending, nil
+}
+
+// respond answers a pending invitation once, like the conditional update of the repository.
+func (r *memRepo) respond(id uuid.UUID, status string) error {
+	inv, ok := r.invites[id]
+	if !ok || inv.Status != utils.InvitationPending {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	inv.Status, inv.RespondedAt = status, &now
+	return nil
+}
+
+func (r *memRepo) AcceptInvitation(_ context.Context, inv *domain.ProjectInvitation, userID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.respond(inv.ID, utils.InvitationAccepted); err != nil {
+		return false, err
+	}
+	_, member := r.members[inv.ProjectID][userID]
+	r.setMember(inv.ProjectID, userID, inv.RoleID)
+	return !member, nil
+}
+
+func (r *memRepo) DeclineInvitation(_ context.Context, inv *domain.ProjectInvitation, _ uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.respond(inv.ID, utils.InvitationDeclined)
+}
+
+func (r *memRepo) RevokeInvitation(_ context.Context, projectID, invitationID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inv, ok := r.invites[invitationID]
+	if !ok || inv.ProjectID != projectID {
+		return gorm.ErrRecordNotFound
+	}
+	return r.respond(invitationID, utils.InvitationRevoked)
+}
+
+func permissionRows(slugs []string) []domain.Permission {
+	perms := make([]domain.Permission, len(slugs))
+	for i, slug := range slugs {
+		perms[i] = domain.Permission{Slug: slug}
+	}
+	return perms
+}
+
+// memCache is the Redis cache of the auth flows, in memory and without expiry.
+type memCache struct {
+	cache.CacheRedis
+
+	mu         sync.Mutex
+	attempts   map[string]int64
+	blocked    map[string]bool
+	sessions   map[string]cache.SessionData
+	challenges map[string]cache.MFAChallenge
+	usedSteps  map[string]bool
+	rolePerms  map[string][]string
+}
+
+func newMemCache() *memCache {
+	return &memCache{
+		attempts:   map[string]int64{},
+		blocked:    map[string]bool{},
+		sessions:   map[string]cache.SessionData{},
+		challenges: map[string]cache.MFAChallenge{},
+		usedSteps:  map[string]bool{},
+		rolePerms:  map[string][]string{},
+	}
+}
+
+func (c *memCache) DeleteUser(context.Context, string) error { return nil }
+
+func (c *memCache) IncrementLoginAttempts(_ context.Context, userID string, _ time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts[userID]++
+	return c.attempts[userID], nil
+}
+
+func (c *memCache) BlockUser(_ context.Context, userID string, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blocked[userID] = true
+	delete(c.attempts, userID)
+	return nil
+}
+
+func (c *memCache) IsUserBlocked(_ context.Context, userID string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocked[userID], nil
+}
+
+func (c *memCache) StoreSession(_ context.Context, sessionID string, session cache.SessionData, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions[sessionID] = session
+	return nil
+}
+
+func (c *memCache) StoreMFAChallenge(_ context.Context, token string, challenge cache.MFAChallenge, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.challenges[token] = challenge
+	return nil
+}
+
+func (c *memCache) GetMFAChallenge(_ context.Context, token string) (*cache.MFAChallenge, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	challenge, ok := c.challenges[token]
+	if !ok {
+		return nil, errors.New("cache miss")
+	}
+	return &challenge, nil
+}
+
+func (c *memCache) DeleteMFAChallenge(_ context.Context, token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.challenges, token)
+	return nil
+}
+
+func (c *memCache) MarkTOTPStepUsed(_ context.Context, userID string, step int64, _ time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := fmt.Sprintf("%s:%d", userID, step)
+	if c.usedSteps[key] {
+		return false, nil
+	}
+	c.usedSteps[key] = true
+	return true, nil
+}
+
+func (c *memCache) SetRolePermissions(_ context.Context, roleName string, perms []string, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rolePerms[roleName] = perms
+	return nil
+}
+
+func (c *memCache) GetRolePermissions(_ context.Context, roleName string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	perms, ok := c.rolePerms[roleName]
+	if !ok {
+		return nil, errors.New("cache miss")
+	}
+	return perms, nil
+}
+
+func (c *memCache) DeleteRolePermissions(_ context.Context, roleName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.rolePerms, roleName)
+	return nil
+}
+
+func newTestAuthService(repo *memRepo, c *memCache) *AuthService {
+	cfg := &configs.GlobalConfig{
+		JWT: configs.JWTConfig{Secret: "test-secret", AccessExpiration: time.Minute, RefreshExpiration: time.Hour},
+	}
+	return &AuthService{
+		AuthRepo: repo,
+		Cache:    c,
+		JWT:      security.NewJWTManager(&cfg.JWT, "kubemanager-test"),
+		Logger:   zap.NewNop().Sugar(),
+		Config:   cfg,
+	}
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if !betoerrors.Is(err, code) {
+		t.Fatalf("err = %v, want code %s", err, code)
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+const (
+	defaultMFAIssuer       = "KubeManager"
+	defaultMFAChallengeTTL = 5 * time.Minute
+	recoveryCodeCount      = 10
+	// couvre la fenêtre de tolérance de ValidateTOTP (pas courant ± 1)
+	totpReplayWindow = 2 * time.Minute
+)
+
+// startMFAChallenge parks a password or SSO login until the second factor is given.
+func (s *AuthService) startMFAChallenge(ctx context.Context, user *domain.User, userAgent, clientIP string) (*domain.LoginResponse, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate mfa challenge")
+	}
+	token := hex.EncodeToString(buf)
+
+	challenge := cache.MFAChallenge{
+		UserID:    user.ID.String(),
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	}
+	if err := s.Cache.StoreMFAChallenge(ctx, token, challenge, s.mfaChallengeTTL()); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to store mfa challenge")
+	}
+
+	return &domain.LoginResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		Message:     "second authentication factor required",
+	}, nil
+}
+
+// VerifyMFA completes a login started by Login with a TOTP or a recovery code. Wrong codes
+// count as failed logins and end up blocking the account like wrong passwords.
+func (s *AuthService) VerifyMFA(ctx context.Context, req *domain.MFAVerifyRequest) (*domain.LoginResponse, error) {
+	if req.Code == "" && req.RecoveryCode == "" {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "code or recovery_code is required")
+	}
+
+	challenge, err := s.Cache.GetMFAChallenge(ctx, req.MFAToken)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "mfa challenge is invalid or expired")
+	}
+
+	userID, err := uuid.Parse(challenge.UserID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "invalid user ID in cache")
+	}
+
+	user, err := s.AuthRepo.GetUserByID(ctx, userID)
+	if err != nil || user == nil || !user.IsActive || !user.MFAEnabled {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "mfa challenge is invalid or expired")
+	}
+
+	if blocked, _ := s.Cache.IsUserBlocked(ctx, user.Email); blocked {
+		_ = s.Cache.DeleteMFAChallenge(ctx, req.MFAToken)
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "Account temporarily blocked due to multiple failed attempts")
+	}
+
+	if req.Code != "" {
+		err = s.checkTOTP(ctx, user, req.Code)
+	} else {
+		err = s.useRecoveryCode(ctx, user, req.RecoveryCode)
+	}
+	if err != nil {
+		s.handleFailedLogin(ctx, user.Email)
+		return nil, err
+	}
+
+	_ = s.Cache.DeleteMFAChallenge(ctx, req.MFAToken)
+
+	return s.startSession(ctx, user, challenge.UserAgent, challenge.ClientIP, true)
+}
+
+// EnrollMFA generates a new secret for the user. It only becomes active once confirmed.
+func (s *AuthService) EnrollMFA(ctx context.Context, userIDStr string) (*domain.MFAEnrollResponse, error) {
+	user, err := s.userByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "mfa is already enabled")
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate mfa secret")
+	}
+
+	box, err := s.secretBox()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := box.Seal(secret)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInternal, "failed to encrypt mfa secret")
+	}
+
+	if err := s.AuthRepo.SaveMFASecret(ctx, user.ID, encrypted); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save mfa secret")
+	}
+
+	return &domain.MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(s.mfaIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA activates the enrolled secret with a first valid code and returns the recovery
+// codes, shown only once.
+func (s *AuthService) ConfirmMFA(ctx context.Context, userIDStr, code string) (*domain.MFARecoveryCodesResponse, error) {
+	user, err := s.userByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "mfa is already enabled")
+	}
+	if user.MFASecret == "" {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "mfa enrollment has not been started")
+	}
+
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AuthRepo.EnableMFA(ctx, user.ID, records); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to enable mfa")
+	}
+	_ = s.Cache.DeleteUser(ctx, userIDStr)
+
+	s.Logger.Infow("mfa enabled", "user_id", userIDStr)
+	return &domain.MFARecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Conservez ces codes de secours, ils ne seront plus affichés.",
+	}, nil
+}
+
+func (s *AuthService) DisableMFA(ctx context.Context, userIDStr, code string) error {
+	user, err := s.userByID(ctx, userIDStr)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "mfa is not enabled")
+	}
+
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return err
+	}
+
+	if err := s.AuthRepo.DisableMFA(ctx, user.ID); err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to disable mfa")
+	}
+	_ = s.Cache.DeleteUser(ctx, userIDStr)
+
+	s.Logger.Infow("mfa disabled", "user_id", userIDStr)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userIDStr, code string) (*domain.MFARecoveryCodesResponse, error) {
+	user, err := s.userByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "mfa is not enabled")
+	}
+
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AuthRepo.ReplaceRecoveryCodes(ctx, user.ID, records); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save recovery codes")
+	}
+
+	return &domain.MFARecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Les anciens codes de secours ne sont plus valables.",
+	}, nil
+}
+
+func (s *AuthService) checkTOTP(ctx context.Context, user *domain.User, code string) error {
+	box, err := s.secretBox()
+	if err != nil {
+		return err
+	}
+	secret, err := box.Open(user.MFASecret)
+	if err != nil {
+		s.Logger.Errorw("failed to decrypt mfa secret", "user_id", user.ID, "error", err)
+		return betoerrors.New(betoerrors.CodeInternal, "failed to read mfa secret")
+	}
+
+	step, ok := security.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "invalid mfa code")
+	}
+
+	// un code déjà accepté ne sert qu'une fois
+	fresh, err := s.Cache.MarkTOTPStepUsed(ctx, user.ID.String(), step, totpReplayWindow)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "invalid mfa code")
+	}
+	return nil
+}
+
+func (s *AuthService) useRecoveryCode(ctx context.Context, user *domain.User, code string) error {
+	used, err := s.AuthRepo.UseRecoveryCode(ctx, user.ID, utils.HashAPIKey(security.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to check recovery code")
+	}
+	if !used {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "invalid recovery code")
+	}
+	s.Logger.Infow("mfa recovery code used", "user_id", user.ID)
+	return nil
+}
+
+func newRecoveryCodes(userID uuid.UUID) ([]string, []domain.MFARecoveryCode, error) {
+	codes, err := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, betoerrors.New(betoerrors.CodeInternal, "failed to generate recovery codes")
+	}
+
+	records := make([]domain.MFARecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, domain.MFARecoveryCode{
+			ID:       uuid.New(),
+			UserID:   userID,
+			CodeHash: utils.HashAPIKey(security.NormalizeRecoveryCode(code)),
+		})
+	}
+	return codes, records, nil
+}
+
+func (s *AuthService) userByID(ctx context.Context, userIDStr string) (*domain.User, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid user ID format")
+	}
+	user, err := s.AuthRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "user not found")
+	}
+	return user, nil
+}
+
+func (s *AuthService) secretBox() (*security.SecretBox, error) {
+	key := s.Config.MFA.EncryptionKey
+	if key == "" {
+		key = s.Config.JWT.Secret
+	}
+	box, err := security.NewSecretBox(key)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "mfa encryption is not configured")
+	}
+	return box, nil
+}
+
+func (s *AuthService) mfaIssuer() string {
+	if s.Config.MFA.Issuer != "" {
+		return s.Config.MFA.Issuer
+	}
+	return defaultMFAIssuer
+}
+
+func (s *AuthService) mfaChallengeTTL() time.Duration {
+	if s.Config.MFA.ChallengeTTL > 0 {
+		return s.Config.MFA.ChallengeTTL
+	}
+	return defaultMFAChallengeTTL
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+)
+
+// totpAt computes the code an authenticator app shows at t (RFC 6238, SHA1, 6 digits, 30s).
+func totpAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+type mfaFixture struct {
+	service  *AuthService
+	repo     *memRepo
+	cache    *memCache
+	user     *domain.User
+	secret   string
+	recovery []string
+	// confirmedAt est l'instant du code donné à ConfirmMFA, ce pas est déjà consommé
+	confirmedAt time.Time
+}
+
+// newMFAFixture enrolls a user through EnrollMFA and ConfirmMFA, as the API does.
+func newMFAFixture(t *testing.T) *mfaFixture {
+	t.Helper()
+	repo, c := newMemRepo(), newMemCache()
+	f := &mfaFixture{
+		service: newTestAuthService(repo, c),
+		repo:    repo,
+		cache:   c,
+		user:    repo.addUser(&domain.User{Email: "zoe@example.com", IsActive: true}),
+	}
+
+	enroll, err := f.service.EnrollMFA(context.Background(), f.user.ID.String())
+	if err != nil {
+		t.Fatalf("EnrollMFA: %v", err)
+	}
+	f.secret = enroll.Secret
+	if f.user.MFASecret == "" || f.user.MFASecret == f.secret {
+		t.Fatalf("the secret must be stored encrypted, got %q", f.user.MFASecret)
+	}
+
+	// le code de confirmation est pris au pas précédent pour laisser le pas courant aux tests
+	f.confirmedAt = time.Now().Add(-30 * time.Second)
+	codes, err := f.service.ConfirmMFA(context.Background(), f.user.ID.String(), totpAt(t, f.secret, f.confirmedAt))
+	if err != nil {
+		t.Fatalf("ConfirmMFA: %v", err)
+	}
+	f.recovery = codes.RecoveryCodes
+	return f
+}
+
+func (f *mfaFixture) challenge(t *testing.T) string {
+	t.Helper()
+	resp, err := f.service.startMFAChallenge(context.Background(), f.user, "test-agent", "203.0.113.7")
+	if err != nil || !resp.MFARequired {
+		t.Fatalf("startMFAChallenge = %+v, %v", resp, err)
+	}
+	return resp.MFAToken
+}
+
+func (f *mfaFixture) verify(t *testing.T, req domain.MFAVerifyRequest) (*domain.LoginResponse, error) {
+	t.Helper()
+	req.MFAToken = f.challenge(t)
+	return f.service.VerifyMFA(context.Background(), &req)
+}
+
+func TestVerifyMFARefusesAReplayedCode(t *testing.T) {
+	f := newMFAFixture(t)
+	code := totpAt(t, f.secret, time.Now())
+
+	resp, err := f.verify(t, domain.MFAVerifyRequest{Code: code})
+	if err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatalf("response = %+v, want a session", resp)
+	}
+
+	_, err = f.verify(t, domain.MFAVerifyRequest{Code: code})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+
+	// le code du pas de confirmation a déjà servi lui aussi
+	_, err = f.verify(t, domain.MFAVerifyRequest{Code: totpAt(t, f.secret, f.confirmedAt)})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	f := newMFAFixture(t)
+	if len(f.recovery) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(f.recovery), recoveryCodeCount)
+	}
+
+	// saisi en majuscules et sans tiret, comme le tapent souvent les utilisateurs
+	typed := strings.ToUpper(strings.ReplaceAll(f.recovery[0], "-", ""))
+	if _, err := f.verify(t, domain.MFAVerifyRequest{RecoveryCode: typed}); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+
+	_, err := f.verify(t, domain.MFAVerifyRequest{RecoveryCode: f.recovery[0]})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+
+	if _, err := f.verify(t, domain.MFAVerifyRequest{RecoveryCode: f.recovery[1]}); err != nil {
+		t.Errorf("another code: %v", err)
+	}
+}
+
+func TestFailedMFAAttemptsBlockTheAccount(t *testing.T) {
+	f := newMFAFixture(t)
+	token := f.challenge(t)
+	valid := totpAt(t, f.secret, time.Now())
+
+	wrong := []domain.MFAVerifyRequest{
+		{Code: "000000"}, {Code: "123456"}, {RecoveryCode: "aaaaa-bbbbb"}, {Code: "999999"}, {RecoveryCode: "ccccc-ddddd"},
+	}
+	for i, req := range wrong {
+		if req.Code == valid {
+			req.Code = "111111"
+		}
+		req.MFAToken = token
+		_, err := f.service.VerifyMFA(context.Background(), &req)
+		assertCode(t, err, betoerrors.CodeUnauthorized)
+		if blocked, _ := f.cache.IsUserBlocked(context.Background(), f.user.Email); blocked != (i == len(wrong)-1) {
+			t.Fatalf("after %d failures blocked = %v", i+1, blocked)
+		}
+	}
+
+	// même avec le bon code, le compte reste bloqué et le défi est consommé
+	_, err := f.service.VerifyMFA(context.Background(), &domain.MFAVerifyRequest{MFAToken: token, Code: valid})
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Fatalf("err = %v, want the account blocked", err)
+	}
+	if _, err := f.cache.GetMFAChallenge(context.Background(), token); err == nil {
+		t.Error("the challenge survived the block")
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/core/oidc"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+)
+
+// durée laissée à l'utilisateur pour s'authentifier auprès de l'IdP
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCService runs the authorization-code flow with PKCE and hands over to the regular
+// session flow once the user is known.
+type OIDCService struct {
+	Auth      *AuthService
+	RBAC      *RBACService
+	Providers *oidc.Registry
+	Logger    *zap.SugaredLogger
+}
+
+func NewOIDCService(auth *AuthService, rbac *RBACService, providers *oidc.Registry, logger *zap.SugaredLogger) *OIDCService {
+	return &OIDCService{
+		Auth:      auth,
+		RBAC:      rbac,
+		Providers: providers,
+		Logger:    logger.With("service", "OIDCService"),
+	}
+}
+
+type OIDCCallbackInput struct {
+	Provider         string
+	Code             string
+	State            string
+	Error            string
+	ErrorDescription string
+	UserAgent        string
+	ClientIP         string
+}
+
+// StartLogin returns the IdP authorization URL to redirect the browser to.
+func (s *OIDCService) StartLogin(ctx context.Context, providerName string) (string, error) {
+	provider, err := s.provider(ctx, providerName)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", betoerrors.New(betoerrors.CodeInternal, "failed to generate oidc state")
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", betoerrors.New(betoerrors.CodeInternal, "failed to generate oidc nonce")
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	data := cache.OIDCState{Provider: providerName, Nonce: nonce, Verifier: verifier}
+	if err := s.Auth.Cache.StoreOIDCState(ctx, state, data, oidcStateTTL); err != nil {
+		return "", betoerrors.New(betoerrors.CodeInternal, "failed to store oidc state")
+	}
+
+	return provider.AuthCodeURL(state, nonce, verifier), nil
+}
+
+// Callback finishes the flow: code exchange, ID token checks, just-in-time provisioning and
+// group to role mapping, then the same tokens as a password login.
+func (s *OIDCService) Callback(ctx context.Context, in OIDCCallbackInput) (*domain.LoginResponse, error) {
+	if in.Error != "" {
+		s.Logger.Warnw("identity provider returned an error", "provider", in.Provider, "error", in.Error, "description", in.ErrorDescription)
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "identity provider refused the authentication")
+	}
+	if in.Code == "" || in.State == "" {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "code and state are required")
+	}
+
+	state, err := s.Auth.Cache.TakeOIDCState(ctx, in.State)
+	if err != nil || state.Provider != in.Provider {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "login request is invalid or expired")
+	}
+
+	provider, err := s.provider(ctx, in.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.Exchange(ctx, in.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		s.Logger.Warnw("oidc code exchange failed", "provider", in.Provider, "error", err)
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "single sign-on failed")
+	}
+
+	cfg, _ := s.Providers.Config(in.Provider)
+	if err := checkIdentity(cfg, claims); err != nil {
+		s.Logger.Warnw("oidc identity rejected", "provider", in.Provider, "email", claims.Email, "error", err)
+		return nil, err
+	}
+
+	user, err := s.provisionUser(ctx, in.Provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyRoleRules(ctx, user, cfg.RoleRules, claims.Groups)
+
+	// le second facteur local reste exigé sauf si l'IdP atteste en avoir vérifié un
+	mfa := hasMFA(claims.AMR)
+	if user.MFAEnabled && !mfa {
+		return s.Auth.startMFAChallenge(ctx, user, in.UserAgent, in.ClientIP)
+	}
+	return s.Auth.startSession(ctx, user, in.UserAgent, in.ClientIP, mfa)
+}
+
+// provisionUser finds the account linked to the IdP subject, links an existing account with
+// the same email, or creates one. An existing account is only linked when the IdP states it
+// verified the address, otherwise anyone able to pick that address at the IdP would take it over.
+func (s *OIDCService) provisionUser(ctx context.Context, providerName string, claims *oidc.Claims) (*domain.User, error) {
+	user, err := s.Auth.AuthRepo.GetUserByIdentity(ctx, providerName, claims.Subject)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to look up user")
+	}
+	if user != nil {
+		if !user.IsActive || user.IsServiceAccount() {
+			return nil, betoerrors.New(betoerrors.CodeForbidden, "account is disabled")
+		}
+		return user, nil
+	}
+
+	identity := &domain.UserIdentity{Provider: providerName, Subject: claims.Subject, Email: claims.Email}
+	verified := claims.EmailVerified != nil && *claims.EmailVerified
+
+	user, err = s.Auth.AuthRepo.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to look up user")
+	}
+
+	if user == nil {
+		// les comptes SSO n'ont pas de mot de passe local utilisable
+		user = &domain.User{
+			Email:        claims.Email,
+			FullName:     claims.Name,
+			PasswordHash: "oidc:" + providerName, // pas un hash bcrypt, aucun mot de passe ne correspond
+			IsActive:     true,
+			IsVerified:   verified,
+		}
+		if err := s.Auth.AuthRepo.CreateUserWithIdentity(ctx, user, identity); err != nil {
+			return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to provision user")
+		}
+		s.Logger.Infow("user provisioned from identity provider", "provider", providerName, "user_id", user.ID, "email", user.Email)
+		return user, nil
+	}
+
+	if !user.IsActive || user.IsServiceAccount() {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "account is disabled")
+	}
+	// un compte provisionné par ce même IdP avant la liaison par sujet est repris tel quel
+	if !verified && user.PasswordHash != "oidc:"+providerName {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "an account already uses this email: the identity provider must verify the address before it can be linked")
+	}
+
+	identity.UserID = user.ID
+	if err := s.Auth.AuthRepo.LinkIdentity(ctx, identity); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to link identity")
+	}
+	s.Logger.Infow("identity linked to existing account", "provider", providerName, "user_id", user.ID, "email", user.Email)
+
+	if !user.IsVerified {
+		if err := s.Auth.AuthRepo.MarkEmailVerified(ctx, user.ID); err == nil {
+			user.IsVerified = true
+		}
+	}
+	return user, nil
+}
+
+// applyRoleRules grants the roles of the matching rules. Memberships are only added or
+// raised, a role given by hand is never lowered by a login and a custom role is never replaced.
+func (s *OIDCService) applyRoleRules(ctx context.Context, user *domain.User, rules []configs.OIDCRoleRule, groups []string) {
+	if len(rules) == 0 || len(groups) == 0 {
+		return
+	}
+
+	inGroup := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		inGroup[g] = true
+	}
+
+	// plusieurs règles peuvent viser le même projet, la plus forte l'emporte
+	wanted := make(map[string]string)
+	for _, rule := range rules {
+		if !inGroup[rule.Group] {
+			continue
+		}
+		if current, ok := wanted[rule.ProjectID]; !ok || roleRank(rule.Role) > roleRank(current) {
+			wanted[rule.ProjectID] = rule.Role
+		}
+	}
+
+	for projectID, roleName := range wanted {
+		pID, err := uuid.Parse(projectID)
+		if err != nil {
+			s.Logger.Warnw("invalid project_id in oidc role rule", "project_id", projectID)
+			continue
+		}
+		if member, err := s.Auth.AuthRepo.GetProjectMember(ctx, pID, user.ID); err == nil &&
+			(!member.Role.IsSystem || roleRank(member.Role.Name) >= roleRank(roleName)) {
+			continue
+		}
+
+		err = s.RBAC.AssignProjectRole(ctx, &domain.AssignRoleRequest{
+			UserID:    user.ID.String(),
+			ProjectID: projectID,
+			RoleName:  roleName,
+		})
+		if err != nil {
+			s.Logger.Warnw("failed to apply oidc role rule", "user_id", user.ID, "project_id", projectID, "role", roleName, "error", err)
+		}
+	}
+}
+
+func (s *OIDCService) provider(ctx context.Context, name string) (*oidc.Provider, error) {
+	provider, err := s.Providers.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			return nil, betoerrors.New(betoerrors.CodeNotFound, "unknown identity provider")
+		}
+		s.Logger.Errorw("identity provider unavailable", "provider", name, "error", err)
+		return nil, betoerrors.New(betoerrors.CodeInternal, "identity provider unavailable")
+	}
+	return provider, nil
+}
+
+func checkIdentity(cfg configs.OIDCProviderConfig, claims *oidc.Claims) error {
+	if claims.Subject == "" || claims.Email == "" {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "identity provider did not return a subject and an email address")
+	}
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return betoerrors.New(betoerrors.CodeUnauthorized, "email address is not verified by the identity provider")
+	}
+
+	if len(cfg.AllowedDomains) > 0 {
+		domainPart := claims.Email[strings.LastIndex(claims.Email, "@")+1:]
+		for _, allowed := range cfg.AllowedDomains {
+			if strings.EqualFold(domainPart, allowed) {
+				return nil
+			}
+		}
+		return betoerrors.New(betoerrors.CodeForbidden, "email domain is not allowed")
+	}
+	return nil
+}
+
+// hasMFA reads the amr claim (RFC 8176): the IdP already checked a second factor.
+func hasMFA(amr []string) bool {
+	for _, method := range amr {
+		switch method {
+		case "mfa", "otp", "hwk", "swk":
+			return true
+		}
+	}
+	return false
+}
+
+func roleRank(roleName string) int {
+	switch roleName {
+	case domain.RoleTypes.Owner.String():
+		return 3
+	case domain.RoleTypes.Developer.String():
+		return 2
+	case domain.RoleTypes.Viewer.String():
+		return 1
+	}
+	return 0
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
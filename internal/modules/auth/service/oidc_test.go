@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/core/oidc"
+	"github.com/thekrauss/kubemanager/internal/core/oidc/oidctest"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+)
+
+// oidcRepo keeps users, identities and memberships in memory, only what the SSO flow touches.
+type oidcRepo struct {
+	repository.AuthRepository
+
+	mu         sync.Mutex
+	users      map[uuid.UUID]*domain.User
+	identities map[string]uuid.UUID // provider + "|" + subject
+	members    map[uuid.UUID]map[uuid.UUID]string
+	roles      map[string]*domain.Role
+}
+
+func newOIDCRepo() *oidcRepo {
+	r := &oidcRepo{
+		users:      map[uuid.UUID]*domain.User{},
+		identities: map[string]uuid.UUID{},
+		members:    map[uuid.UUID]map[uuid.UUID]string{},
+		roles:      map[string]*domain.Role{},
+	}
+	for role := range domain.DefaultRolePermissions() {
+		r.roles[role.String()] = &domain.Role{ID: uuid.New(), Name: role.String(), IsSystem: true}
+	}
+	r.roles["AUDITOR"] = &domain.Role{ID: uuid.New(), Name: "AUDITOR"}
+	return r
+}
+
+func (r *oidcRepo) addUser(u *domain.User) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	r.users[u.ID] = u
+	return u
+}
+
+func (r *oidcRepo) user(id uuid.UUID) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[id]
+}
+
+func (r *oidcRepo) role(projectID, userID uuid.UUID) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.members[projectID][userID]
+}
+
+func (r *oidcRepo) identityOwner(provider, subject string) (uuid.UUID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.identities[provider+"|"+subject]
+	return id, ok
+}
+
+func (r *oidcRepo) GetUserByIdentity(_ context.Context, provider, subject string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.identities[provider+"|"+subject]; ok {
+		return r.users[id], nil
+	}
+	return nil, nil
+}
+
+func (r *oidcRepo) GetUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *oidcRepo) GetUserByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	if u := r.user(id); u != nil {
+		return u, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *oidcRepo) CreateUserWithIdentity(_ context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	user.ID = uuid.New()
+	identity.UserID = user.ID
+	r.addUser(user)
+	return r.LinkIdentity(context.Background(), identity)
+}
+
+func (r *oidcRepo) LinkIdentity(_ context.Context, identity *domain.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identities[identity.Provider+"|"+identity.Subject] = identity.UserID
+	return nil
+}
+
+func (r *oidcRepo) MarkEmailVerified(_ context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].IsVerified = true
+	return nil
+}
+
+func (r *oidcRepo) UpdateUser(context.Context, *domain.User) error           { return nil }
+func (r *oidcRepo) CreateSession(context.Context, *domain.UserSession) error { return nil }
+func (r *oidcRepo) GetRoleByName(_ context.Context, name string) (*domain.Role, error) {
+	if role, ok := r.roles[name]; ok {
+		return role, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *oidcRepo) GetUserProjectMemberships(context.Context, uuid.UUID) ([]domain.ProjectMember, error) {
+	return nil, nil
+}
+
+func (r *oidcRepo) setMember(projectID, userID uuid.UUID, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.members[projectID] == nil {
+		r.members[projectID] = map[uuid.UUID]string{}
+	}
+	r.members[projectID][userID] = role
+}
+
+func (r *oidcRepo) GetProjectMember(_ context.Context, projectID, userID uuid.UUID) (*domain.ProjectMember, error) {
+	name := r.role(projectID, userID)
+	if name == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &domain.ProjectMember{ProjectID: projectID, UserID: userID, Role: *r.roles[name]}, nil
+}
+
+func (r *oidcRepo) AddProjectMember(_ context.Context, m *domain.ProjectMember) error {
+	for name, role := range r.roles {
+		if role.ID == m.RoleID {
+			r.setMember(m.ProjectID, m.UserID, name)
+			return nil
+		}
+	}
+	return errors.New("unknown role")
+}
+
+// oidcCache holds the login states, sessions and MFA challenges in memory.
+type oidcCache struct {
+	cache.CacheRedis
+
+	mu         sync.Mutex
+	states     map[string]cache.OIDCState
+	sessions   map[string]cache.SessionData
+	challenges map[string]cache.MFAChallenge
+}
+
+func newOIDCCache() *oidcCache {
+	return &oidcCache{
+		states:     map[string]cache.OIDCState{},
+		sessions:   map[string]cache.SessionData{},
+		challenges: map[string]cache.MFAChallenge{},
+	}
+}
+
+func (c *oidcCache) StoreOIDCState(_ context.Context, state string, data cache.OIDCState, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states[state] = data
+	return nil
+}
+
+func (c *oidcCache) TakeOIDCState(_ context.Context, state string) (*cache.OIDCState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.states[state]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	delete(c.states, state)
+	return &data, nil
+}
+
+func (c *oidcCache) StoreSession(_ context.Context, id string, session cache.SessionData, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions[id] = session
+	return nil
+}
+
+func (c *oidcCache) StoreMFAChallenge(_ context.Context, token string, challenge cache.MFAChallenge, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.challenges[token] = challenge
+	return nil
+}
+
+func (c *oidcCache) session(id string) (cache.SessionData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.sessions[id]
+	return s, ok
+}
+
+type oidcFixture struct {
+	issuer  *oidctest.Issuer
+	repo    *oidcRepo
+	cache   *oidcCache
+	jwt     security.JWTManager
+	service *OIDCService
+	project uuid.UUID
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	issuer := oidctest.New(t, "kubemanager", "secret")
+	project := uuid.New()
+
+	cfg := &configs.GlobalConfig{
+		JWT: configs.JWTConfig{Secret: "test-secret", AccessExpiration: time.Minute, RefreshExpiration: time.Hour},
+		OIDC: configs.OIDCConfig{Providers: map[string]configs.OIDCProviderConfig{
+			"corp": {
+				Issuer:         issuer.URL(),
+				ClientID:       issuer.ClientID,
+				ClientSecret:   issuer.ClientSecret,
+				RedirectURL:    "https://kubemanager.test/kmanager/v1/auth/oidc/corp/callback",
+				AllowedDomains: []string{"example.com"},
+				RoleRules: []configs.OIDCRoleRule{
+					{Group: "devs", ProjectID: project.String(), Role: domain.RoleTypes.Developer.String()},
+					{Group: "leads", ProjectID: project.String(), Role: domain.RoleTypes.Owner.String()},
+				},
+			},
+		}},
+	}
+
+	logger := zap.NewNop().Sugar()
+	repo := newOIDCRepo()
+	c := newOIDCCache()
+	jwtMgr := security.NewJWTManager(&cfg.JWT, "kubemanager-test")
+	auth := &AuthService{AuthRepo: repo, Cache: c, JWT: jwtMgr, Logger: logger, Config: cfg}
+	rbac := &RBACService{Repo: repo, Cache: c, Logger: logger}
+	registry := oidc.NewRegistry(cfg.OIDC, issuer.Server.Client())
+
+	return &oidcFixture{
+		issuer:  issuer,
+		repo:    repo,
+		cache:   c,
+		jwt:     jwtMgr,
+		service: NewOIDCService(auth, rbac, registry, logger),
+		project: project,
+	}
+}
+
+// login goes through StartLogin, the IdP and Callback as the browser would.
+func (f *oidcFixture) login(t *testing.T, claims map[string]interface{}) (*domain.LoginResponse, error) {
+	t.Helper()
+	authURL, err := f.service.StartLogin(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	if u, _ := url.Parse(authURL); u.Query().Get("redirect_uri") == "" {
+		t.Fatalf("authorization URL %s has no redirect_uri", authURL)
+	}
+	code, state, err := f.issuer.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return f.service.Callback(context.Background(), OIDCCallbackInput{Provider: "corp", Code: code, State: state})
+}
+
+// sessionUser checks the access token and its session, and returns the logged in user.
+func (f *oidcFixture) sessionUser(t *testing.T, resp *domain.LoginResponse) uuid.UUID {
+	t.Helper()
+	if resp.MFARequired || resp.Token == "" {
+		t.Fatalf("response = %+v, want a session", resp)
+	}
+	claims, err := f.jwt.VerifyAccessToken(resp.Token)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if _, ok := f.cache.session(claims.SessionID); !ok {
+		t.Fatalf("session %s not stored", claims.SessionID)
+	}
+	return uuid.MustParse(claims.UserID)
+}
+
+func TestOIDCProvisionsAndLinksBySubject(t *testing.T) {
+	f := newOIDCFixture(t)
+	claims := map[string]interface{}{"sub": "idp-1", "email": "zoe@example.com", "email_verified": true, "name": "Zoé"}
+
+	resp, err := f.login(t, claims)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	userID := f.sessionUser(t, resp)
+	if owner, ok := f.repo.identityOwner("corp", "idp-1"); !ok || owner != userID {
+		t.Fatalf("identity linked to %v, want %v", owner, userID)
+	}
+	if u := f.repo.user(userID); u.FullName != "Zoé" || !u.IsVerified {
+		t.Errorf("provisioned user = %+v", u)
+	}
+
+	// l'email change côté IdP : le compte reste celui du sujet
+	claims["email"] = "zoe.martin@example.com"
+	resp, err = f.login(t, claims)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if got := f.sessionUser(t, resp); got != userID {
+		t.Errorf("second login opened a session for %v, want %v", got, userID)
+	}
+}
+
+func TestOIDCLinkingAnExistingAccountNeedsAVerifiedEmail(t *testing.T) {
+	f := newOIDCFixture(t)
+	existing := f.repo.addUser(&domain.User{Email: "admin@example.com", PasswordHash: "bcrypt", IsActive: true, IsVerified: true})
+
+	// claim absent : l'IdP ne garantit pas la possession de l'adresse
+	_, err := f.login(t, map[string]interface{}{"sub": "attacker", "email": "admin@example.com"})
+	assertCode(t, err, betoerrors.CodeForbidden)
+	if _, ok := f.repo.identityOwner("corp", "attacker"); ok {
+		t.Fatal("an unverified identity was linked to an existing account")
+	}
+
+	_, err = f.login(t, map[string]interface{}{"sub": "attacker", "email": "admin@example.com", "email_verified": false})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+
+	resp, err := f.login(t, map[string]interface{}{"sub": "owner", "email": "admin@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("verified login: %v", err)
+	}
+	if got := f.sessionUser(t, resp); got != existing.ID {
+		t.Errorf("session for %v, want the existing account %v", got, existing.ID)
+	}
+	if owner, _ := f.repo.identityOwner("corp", "owner"); owner != existing.ID {
+		t.Errorf("identity linked to %v, want %v", owner, existing.ID)
+	}
+}
+
+func TestOIDCKeepsTheLocalSecondFactor(t *testing.T) {
+	f := newOIDCFixture(t)
+	user := f.repo.addUser(&domain.User{Email: "zoe@example.com", PasswordHash: "bcrypt", IsActive: true, IsVerified: true, MFAEnabled: true})
+	claims := map[string]interface{}{"sub": "idp-1", "email": "zoe@example.com", "email_verified": true, "amr": []string{"pwd"}}
+
+	resp, err := f.login(t, claims)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !resp.MFARequired || resp.MFAToken == "" || resp.Token != "" {
+		t.Fatalf("response = %+v, want an MFA challenge", resp)
+	}
+
+	// l'IdP atteste un second facteur : la session est ouverte et marquée MFA
+	claims["amr"] = []string{"pwd", "otp"}
+	resp, err = f.login(t, claims)
+	if err != nil {
+		t.Fatalf("login with amr: %v", err)
+	}
+	if got := f.sessionUser(t, resp); got != user.ID {
+		t.Fatalf("session for %v, want %v", got, user.ID)
+	}
+	tok, _ := f.jwt.VerifyAccessToken(resp.Token)
+	if s, _ := f.cache.session(tok.SessionID); !s.MFA {
+		t.Error("session opened with an IdP second factor is not marked MFA")
+	}
+}
+
+func TestOIDCRoleRulesOnlyRaiseSystemRoles(t *testing.T) {
+	f := newOIDCFixture(t)
+	viewer := f.repo.addUser(&domain.User{Email: "viewer@example.com", PasswordHash: "oidc:corp", IsActive: true})
+	owner := f.repo.addUser(&domain.User{Email: "owner@example.com", PasswordHash: "oidc:corp", IsActive: true})
+	auditor := f.repo.addUser(&domain.User{Email: "auditor@example.com", PasswordHash: "oidc:corp", IsActive: true})
+	f.repo.setMember(f.project, viewer.ID, domain.RoleTypes.Viewer.String())
+	f.repo.setMember(f.project, owner.ID, domain.RoleTypes.Owner.String())
+	f.repo.setMember(f.project, auditor.ID, "AUDITOR")
+
+	cases := []struct {
+		user   *domain.User
+		groups []string
+		want   string
+	}{
+		{viewer, []string{"devs"}, domain.RoleTypes.Developer.String()},
+		{owner, []string{"devs"}, domain.RoleTypes.Owner.String()},
+		{auditor, []string{"devs", "leads"}, "AUDITOR"},
+	}
+	for _, tc := range cases {
+		_, err := f.login(t, map[string]interface{}{"sub": tc.user.Email, "email": tc.user.Email, "groups": tc.groups})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.user.Email, err)
+		}
+		if got := f.repo.role(f.project, tc.user.ID); got != tc.want {
+			t.Errorf("%s: role = %s, want %s", tc.user.Email, got, tc.want)
+		}
+	}
+
+	// nouvel utilisateur dans deux groupes : la règle la plus forte l'emporte
+	resp, err := f.login(t, map[string]interface{}{"sub": "lead", "email": "lead@example.com", "email_verified": true, "groups": []string{"devs", "leads"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.repo.role(f.project, f.sessionUser(t, resp)); got != domain.RoleTypes.Owner.String() {
+		t.Errorf("new lead role = %s, want OWNER", got)
+	}
+}
+
+func TestOIDCCallbackRejections(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	_, err := f.login(t, map[string]interface{}{"sub": "x", "email": "x@other.org", "email_verified": true})
+	assertCode(t, err, betoerrors.CodeForbidden)
+
+	_, err = f.login(t, map[string]interface{}{"email": "nosub@example.com", "email_verified": true})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+
+	// un état inconnu ou déjà consommé
+	_, err = f.service.Callback(context.Background(), OIDCCallbackInput{Provider: "corp", Code: "c", State: "forged"})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+
+	// un état émis pour un autre fournisseur
+	authURL, _ := f.service.StartLogin(context.Background(), "corp")
+	code, state, _ := f.issuer.Authorize(authURL, map[string]interface{}{"sub": "y", "email": "y@example.com"})
+	_, err = f.service.Callback(context.Background(), OIDCCallbackInput{Provider: "other", Code: code, State: state})
+	assertCode(t, err, betoerrors.CodeUnauthorized)
+
+	_, err = f.service.StartLogin(context.Background(), "other")
+	assertCode(t, err, betoerrors.CodeNotFound)
+
+	disabled := f.repo.addUser(&domain.User{Email: "gone@example.com", PasswordHash: "oidc:corp", IsActive: false})
+	_, err = f.login(t, map[string]interface{}{"sub": "gone", "email": disabled.Email, "email_verified": true})
+	assertCode(t, err, betoerrors.CodeForbidden)
+}
@@ -2,12 +2,15 @@ package service
 
 import (
 	"context"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/thekrauss/beto-shared/pkg/errors"
 
 	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	maildomain "github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
 )
 
 type ChangePasswordInput struct {
@@ -22,21 +25,46 @@ type ResetPasswordInput struct {
 	NewPassword string
 }
 
-func (s *AuthService) ForgotPassword(ctx context.Context, email string) (string, error) {
+// durée de validité du lien de réinitialisation
+const resetTokenTTL = 15 * time.Minute
+
+// ForgotPassword emails a reset link. The answer is the same whether the account exists or
+// not, the token only travels by email.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
 	user, err := s.AuthRepo.GetUserByEmail(ctx, email)
 
-	if err != nil || user == nil {
-		return "", nil
+	if err != nil || user == nil || !user.IsActive || user.IsServiceAccount() {
+		return nil
 	}
 
 	resetToken := uuid.New().String()
 
-	err = s.Cache.StoreResetToken(ctx, resetToken, user.ID.String(), 15*time.Minute)
+	err = s.Cache.StoreResetToken(ctx, resetToken, user.ID.String(), resetTokenTTL)
+	if err != nil {
+		return errors.New(errors.CodeInternal, "failed to generate reset token")
+	}
+
+	err = s.Mailer.Send(ctx, maildomain.EmailRequest{
+		To:       []string{user.Email},
+		Template: maildomain.TemplatePasswordReset,
+		Data: map[string]interface{}{
+			"Name":      displayName(user),
+			"Link":      s.Mailer.Link("/reset-password?token=" + url.QueryEscape(resetToken)),
+			"ExpiresIn": "15 minutes",
+		},
+	})
 	if err != nil {
-		return "", errors.New(errors.CodeInternal, "failed to generate reset token")
+		_ = s.Cache.DeleteResetToken(ctx, resetToken)
+		return err
 	}
+	return nil
+}
 
-	return resetToken, nil
+func displayName(user *domain.User) string {
+	if user.FullName != "" {
+		return user.FullName
+	}
+	return user.Email
 }
 
 func (s *AuthService) ResetPassword(ctx context.Context, input ResetPasswordInput) error {
@@ -65,7 +93,7 @@ func (s *AuthService) ResetPassword(ctx context.Context, input ResetPasswordInpu
 
 	_ = s.Cache.DeleteResetToken(ctx, input.Token)
 
-	_ = s.Cache.DeleteSession(ctx, userIDStr)
+	_ = s.revokeSessions(ctx, userUUID, uuid.Nil)
 
 	_ = s.Cache.DeleteUser(ctx, userIDStr)
 
@@ -7,19 +7,26 @@ import (
 	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/thekrauss/kubemanager/internal/core/cache"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	webhookDomain "github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
 )
 
 type RBACService struct {
-	Repo   repository.AuthRepository
-	Logger *zap.SugaredLogger
+	Repo    repository.AuthRepository
+	Cache   cache.CacheRedis
+	Logger  *zap.SugaredLogger
+	Emitter webhookDomain.EventEmitter
 }
 
-func NewRBACService(repo repository.AuthRepository, logger *zap.SugaredLogger) *RBACService {
+func NewRBACService(repo repository.AuthRepository, cacheRepo cache.CacheRedis, logger *zap.SugaredLogger, emitter webhookDomain.EventEmitter) *RBACService {
 	return &RBACService{
-		Repo:   repo,
-		Logger: logger,
+		Repo:    repo,
+		Cache:   cacheRepo,
+		Logger:  logger,
+		Emitter: emitter,
 	}
 }
 
@@ -31,15 +38,7 @@ func (s *RBACService) ListAllRoles(ctx context.Context) ([]domain.RoleDTO, error
 
 	var result []domain.RoleDTO
 	for _, r := range roles {
-		perms := make([]string, len(r.Permissions))
-		for i, p := range r.Permissions {
-			perms[i] = p.Slug
-		}
-		result = append(result, domain.RoleDTO{
-			ID:          r.ID.String(),
-			Name:        r.Name,
-			Permissions: perms,
-		})
+		result = append(result, roleToDTO(&r))
 	}
 	return result, nil
 }
@@ -84,6 +83,9 @@ func (s *RBACService) RevokeProjectAccess(ctx context.Context, projectIDStr, use
 	if err != nil {
 		return betoerrors.New(betoerrors.CodeNotFound, "member not found in this project")
 	}
+	if err := s.rejectServiceAccount(ctx, uID); err != nil {
+		return err
+	}
 
 	if err := s.Repo.RemoveProjectMember(ctx, pID, uID); err != nil {
 		s.Logger.Errorw("failed to revoke access", "project", pID, "user", uID, "error", err)
@@ -104,11 +106,19 @@ func (s *RBACService) AssignProjectRole(ctx context.Context, req *domain.AssignR
 		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
 	}
 
+	if err := s.rejectServiceAccount(ctx, uID); err != nil {
+		return err
+	}
+
 	role, err := s.Repo.GetRoleByName(ctx, req.RoleName)
 	if err != nil {
 		return betoerrors.New(betoerrors.CodeNotFound, "role not found")
 	}
 
+	// un changement de rôle d'un membre existant n'est pas un nouvel arrivant
+	_, err = s.Repo.GetProjectMember(ctx, pID, uID)
+	isNew := err != nil
+
 	member := &domain.ProjectMember{
 		ProjectID: pID,
 		UserID:    uID,
@@ -121,6 +131,27 @@ func (s *RBACService) AssignProjectRole(ctx context.Context, req *domain.AssignR
 	}
 
 	s.Logger.Infow("Role assigned successfully", "user", req.UserID, "project", req.ProjectID, "role", req.RoleName)
+
+	if isNew && s.Emitter != nil {
+		event := webhookDomain.NewEvent(utils.EventMemberAdded, req.ProjectID, map[string]interface{}{
+			"user_id": req.UserID,
+			"role":    req.RoleName,
+		})
+		// le membre est ajouté, un webhook en échec ne doit pas annuler l'opération
+		if err := s.Emitter.Emit(ctx, event); err != nil {
+			s.Logger.Warnw("failed to emit member.added", "project", req.ProjectID, "user", req.UserID, "error", err)
+		}
+	}
+	return nil
+}
+
+// rejectServiceAccount keeps service accounts out of the member routes: they only belong to the
+// project owning them and are managed with it.
+func (s *RBACService) rejectServiceAccount(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.Repo.GetUserByID(ctx, userID)
+	if err == nil && user.IsServiceAccount() {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "service accounts are managed under /projects/:id/service-accounts")
+	}
 	return nil
 }
 
@@ -14,6 +14,9 @@ import (
 )
 
 func (s *AuthService) CreateUser(ctx context.Context, req *domain.RegisterRequest) (*domain.User, error) {
+	if s.Config.OIDC.DisablePasswordLogin {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "registration is disabled, use single sign-on")
+	}
 
 	existing, _ := s.AuthRepo.GetUserByEmail(ctx, req.Email)
 	if existing != nil {
@@ -38,6 +41,11 @@ func (s *AuthService) CreateUser(ctx context.Context, req *domain.RegisterReques
 	cacheKey := fmt.Sprintf("%s%s%s", cache.ServiceKeyPrefix, cache.KeyUser, newUser.ID)
 	_ = s.Cache.SetUser(ctx, cacheKey, newUser, time.Hour)
 
+	// l'inscription reste valide si l'envoi échoue, le lien peut être redemandé
+	if err := s.SendVerificationEmail(ctx, newUser); err != nil {
+		s.Logger.Warnw("failed to send verification email", "user_id", newUser.ID, "error", err)
+	}
+
 	return newUser, nil
 }
 
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+)
+
+// les noms de rôles sont stockés en majuscules comme les rôles intégrés
+var roleNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_-]{1,49}$`)
+
+// ListPermissions returns the permissions a custom role can be built from.
+func (s *RBACService) ListPermissions() []string {
+	all := domain.PermissionTypes.All()
+	perms := make([]string, len(all))
+	for i, p := range all {
+		perms[i] = p.String()
+	}
+	return perms
+}
+
+// CreateRole adds a custom role, e.g. a DEPLOYER allowed to create workloads but not to
+// delete them. It can then be assigned like the built-in roles.
+func (s *RBACService) CreateRole(ctx context.Context, req *domain.CreateRoleRequest) (*domain.RoleDTO, error) {
+	name := strings.ToUpper(strings.TrimSpace(req.Name))
+	if !roleNamePattern.MatchString(name) {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "role name must be 2 to 50 letters, digits, '_' or '-'")
+	}
+	if _, err := domain.RoleTypes.NewFromString(ctx, name); err == nil {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "role name is reserved")
+	}
+	if existing, err := s.Repo.GetRoleByName(ctx, name); err == nil && existing != nil {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "a role with this name already exists")
+	}
+
+	perms, err := normalizePermissions(ctx, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &domain.Role{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: req.Description,
+	}
+	if err := s.Repo.CreateRole(ctx, role, perms); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to create role")
+	}
+
+	s.Logger.Infow("custom role created", "role", name, "permissions", perms)
+	dto := roleToDTO(role)
+	return &dto, nil
+}
+
+// UpdateRole replaces the permissions of a custom role. Members holding it get the new set on
+// their next request, the cached permissions are dropped. The name cannot change since
+// sessions reference roles by name.
+func (s *RBACService) UpdateRole(ctx context.Context, req *domain.UpdateRoleRequest) (*domain.RoleDTO, error) {
+	role, err := s.customRole(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	perms, err := normalizePermissions(ctx, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+
+	if err := s.Repo.UpdateRole(ctx, role, perms); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to update role")
+	}
+	s.invalidateRole(ctx, role.Name)
+
+	s.Logger.Infow("custom role updated", "role", role.Name, "permissions", perms)
+	dto := roleToDTO(role)
+	return &dto, nil
+}
+
+// DeleteRole removes a custom role once no member holds it anymore.
+func (s *RBACService) DeleteRole(ctx context.Context, roleIDStr string) error {
+	role, err := s.customRole(ctx, roleIDStr)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.DeleteRole(ctx, role.ID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRoleInUse):
+			return betoerrors.New(betoerrors.CodeConflict, "role is still assigned to project members, reassign them first")
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return betoerrors.New(betoerrors.CodeNotFound, "role not found")
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to delete role")
+	}
+	s.invalidateRole(ctx, role.Name)
+
+	s.Logger.Infow("custom role deleted", "role", role.Name)
+	return nil
+}
+
+// customRole loads a role that may be changed, the built-in ones are rewritten at startup.
+func (s *RBACService) customRole(ctx context.Context, roleIDStr string) (*domain.Role, error) {
+	roleID, err := uuid.Parse(roleIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid role id")
+	}
+	role, err := s.Repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "role not found")
+	}
+	if role.IsSystem {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "built-in roles cannot be modified")
+	}
+	return role, nil
+}
+
+func (s *RBACService) invalidateRole(ctx context.Context, roleName string) {
+	if err := s.Cache.DeleteRolePermissions(ctx, roleName); err != nil {
+		s.Logger.Warnw("failed to invalidate cached role permissions", "role", roleName, "error", err)
+	}
+}
+
+// normalizePermissions checks every slug against the permission enum and drops duplicates.
+func normalizePermissions(ctx context.Context, slugs []string) ([]string, error) {
+	seen := make(map[string]bool, len(slugs))
+	perms := make([]string, 0, len(slugs))
+	for _, slug := range slugs {
+		perm, err := domain.PermissionTypes.NewFromString(ctx, strings.TrimSpace(slug))
+		if err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("unknown permission %q", slug))
+		}
+		if seen[perm.String()] {
+			continue
+		}
+		seen[perm.String()] = true
+		perms = append(perms, perm.String())
+	}
+	if len(perms) == 0 {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "a role needs at least one permission")
+	}
+	return perms, nil
+}
+
+func roleToDTO(r *domain.Role) domain.RoleDTO {
+	perms := make([]string, len(r.Permissions))
+	for i, p := range r.Permissions {
+		perms[i] = p.Slug
+	}
+	return domain.RoleDTO{
+		ID:          r.ID.String(),
+		Name:        r.Name,
+		Description: r.Description,
+		IsSystem:    r.IsSystem,
+		Permissions: perms,
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+)
+
+func newTestRBACService(repo *memRepo, c *memCache) *RBACService {
+	return &RBACService{Repo: repo, Cache: c, Logger: zap.NewNop().Sugar()}
+}
+
+// cachedPermissions reads a role like the middleware does: Redis first, the role tables on a
+// miss, and the answer is cached again.
+func cachedPermissions(t *testing.T, repo *memRepo, c *memCache, name string) string {
+	t.Helper()
+	ctx := context.Background()
+	if perms, err := c.GetRolePermissions(ctx, name); err == nil {
+		return strings.Join(perms, ",")
+	}
+	role, err := repo.GetRoleByName(ctx, name)
+	if err != nil {
+		t.Fatalf("role %s: %v", name, err)
+	}
+	perms := make([]string, len(role.Permissions))
+	for i, p := range role.Permissions {
+		perms[i] = p.Slug
+	}
+	_ = c.SetRolePermissions(ctx, name, perms, 0)
+	return strings.Join(perms, ",")
+}
+
+func TestUpdateRoleDropsCachedPermissions(t *testing.T) {
+	repo, c := newMemRepo(), newMemCache()
+	s := newTestRBACService(repo, c)
+	ctx := context.Background()
+
+	deployer, err := s.CreateRole(ctx, &domain.CreateRoleRequest{Name: "deployer", Permissions: []string{"project:view", "k8s:workload:create"}})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	auditor, err := s.CreateRole(ctx, &domain.CreateRoleRequest{Name: "AUDITOR", Permissions: []string{"project:view", "k8s:logs:view"}})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if got := cachedPermissions(t, repo, c, "DEPLOYER"); got != "project:view,k8s:workload:create" {
+		t.Fatalf("DEPLOYER = %s", got)
+	}
+	cachedPermissions(t, repo, c, auditor.Name)
+
+	if _, err := s.UpdateRole(ctx, &domain.UpdateRoleRequest{ID: deployer.ID, Permissions: []string{"project:view", "k8s:workload:create", "k8s:workload:delete", "k8s:workload:delete"}}); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	if _, cached := c.rolePerms["DEPLOYER"]; cached {
+		t.Fatal("the updated role is still cached")
+	}
+	if got := cachedPermissions(t, repo, c, "DEPLOYER"); got != "project:view,k8s:workload:create,k8s:workload:delete" {
+		t.Errorf("DEPLOYER after update = %s", got)
+	}
+	if _, cached := c.rolePerms[auditor.Name]; !cached {
+		t.Error("updating DEPLOYER dropped the cache of another role")
+	}
+}
+
+func TestDeleteRoleDropsCachedPermissions(t *testing.T) {
+	repo, c := newMemRepo(), newMemCache()
+	s := newTestRBACService(repo, c)
+	ctx := context.Background()
+
+	role, err := s.CreateRole(ctx, &domain.CreateRoleRequest{Name: "DEPLOYER", Permissions: []string{"k8s:workload:create"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachedPermissions(t, repo, c, role.Name)
+
+	// encore attribué : refusé, le cache reste valable
+	repo.inUse[uuid.MustParse(role.ID)] = true
+	assertCode(t, s.DeleteRole(ctx, role.ID), betoerrors.CodeConflict)
+	if _, cached := c.rolePerms[role.Name]; !cached {
+		t.Error("a refused deletion dropped the cache")
+	}
+
+	repo.inUse[uuid.MustParse(role.ID)] = false
+	if err := s.DeleteRole(ctx, role.ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if _, cached := c.rolePerms[role.Name]; cached {
+		t.Error("the deleted role is still cached, members would keep its permissions until the TTL")
+	}
+}
+
+func TestBuiltInRolesCannotBeChanged(t *testing.T) {
+	repo, c := newMemRepo(), newMemCache()
+	s := newTestRBACService(repo, c)
+	ctx := context.Background()
+
+	owner := &domain.Role{ID: uuid.New(), Name: "OWNER", IsSystem: true}
+	_ = repo.CreateRole(ctx, owner, []string{"project:view", "project:delete"})
+	cachedPermissions(t, repo, c, "OWNER")
+
+	_, err := s.UpdateRole(ctx, &domain.UpdateRoleRequest{ID: owner.ID.String(), Permissions: []string{"project:view"}})
+	assertCode(t, err, betoerrors.CodeForbidden)
+	assertCode(t, s.DeleteRole(ctx, owner.ID.String()), betoerrors.CodeForbidden)
+	if got := cachedPermissions(t, repo, c, "OWNER"); got != "project:view,project:delete" {
+		t.Errorf("OWNER = %s", got)
+	}
+
+	_, err = s.CreateRole(ctx, &domain.CreateRoleRequest{Name: "viewer", Permissions: []string{"project:view"}})
+	assertCode(t, err, betoerrors.CodeConflict)
+	_, err = s.CreateRole(ctx, &domain.CreateRoleRequest{Name: "OPS", Permissions: []string{"k8s:root"}})
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+)
+
+// les noms servent aussi à construire l'adresse technique du compte
+var serviceAccountNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,48}[a-z0-9]$`)
+
+// domaine des adresses des comptes de service, jamais livrable
+const serviceAccountEmailDomain = "sa.kubemanager.internal"
+
+// ServiceAccountService manages the non-human members of a project, used by CI pipelines. Their
+// API keys outlive the people who created them.
+type ServiceAccountService struct {
+	Repo   repository.AuthRepository
+	Keys   *APIKeyService
+	Logger *zap.SugaredLogger
+}
+
+func NewServiceAccountService(repo repository.AuthRepository, keys *APIKeyService, logger *zap.SugaredLogger) *ServiceAccountService {
+	return &ServiceAccountService{
+		Repo:   repo,
+		Keys:   keys,
+		Logger: logger.With("service", "ServiceAccountService"),
+	}
+}
+
+func (s *ServiceAccountService) List(ctx context.Context, projectIDStr string) ([]domain.ServiceAccountDTO, error) {
+	pID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+
+	members, err := s.Repo.ListServiceAccounts(ctx, pID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list service accounts")
+	}
+
+	result := make([]domain.ServiceAccountDTO, 0, len(members))
+	for _, m := range members {
+		result = append(result, serviceAccountToDTO(&m))
+	}
+	return result, nil
+}
+
+// Create adds the account as a member of the project with the given role. It has no password,
+// only API keys issued through IssueKey.
+func (s *ServiceAccountService) Create(ctx context.Context, access *security.ProjectAccess, req *domain.CreateServiceAccountRequest) (*domain.ServiceAccountDTO, error) {
+	pID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !serviceAccountNamePattern.MatchString(name) {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "name must be 3 to 50 lowercase letters, digits or '-'")
+	}
+
+	role, err := s.assignableRole(ctx, access, req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	email := fmt.Sprintf("%s@%s.%s", name, pID, serviceAccountEmailDomain)
+	if existing, err := s.Repo.GetUserByEmail(ctx, email); err == nil && existing != nil {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "a service account with this name already exists in the project")
+	}
+
+	account := &domain.User{
+		Email:                   email,
+		FullName:                name,
+		PasswordHash:            "service-account", // pas un hash bcrypt, aucun mot de passe ne correspond
+		IsActive:                true,
+		IsVerified:              true,
+		ServiceAccountProjectID: &pID,
+	}
+	if err := s.Repo.CreateServiceAccount(ctx, account, role.ID); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to create service account")
+	}
+
+	s.Logger.Infow("service account created", "project", pID, "account_id", account.ID, "name", name, "role", role.Name)
+	return &domain.ServiceAccountDTO{
+		ID:        account.ID.String(),
+		Name:      account.FullName,
+		RoleName:  role.Name,
+		CreatedAt: account.CreatedAt,
+	}, nil
+}
+
+// UpdateRole changes the role of the account in its project.
+func (s *ServiceAccountService) UpdateRole(ctx context.Context, access *security.ProjectAccess, req *domain.UpdateServiceAccountRequest) (*domain.ServiceAccountDTO, error) {
+	member, err := s.get(ctx, req.ProjectID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.assignableRole(ctx, access, req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	member.RoleID = role.ID
+	member.Role = *role
+	if err := s.Repo.AddProjectMember(ctx, &domain.ProjectMember{
+		ProjectID: member.ProjectID,
+		UserID:    member.UserID,
+		RoleID:    role.ID,
+		JoinedAt:  member.JoinedAt,
+	}); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to update service account role")
+	}
+
+	s.Logger.Infow("service account role updated", "project", member.ProjectID, "account_id", member.UserID, "role", role.Name)
+	dto := serviceAccountToDTO(member)
+	return &dto, nil
+}
+
+// Delete removes the account and revokes all its API keys at once.
+func (s *ServiceAccountService) Delete(ctx context.Context, projectIDStr, accountIDStr string) error {
+	member, err := s.get(ctx, projectIDStr, accountIDStr)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.DeleteServiceAccount(ctx, member.ProjectID, member.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return betoerrors.New(betoerrors.CodeNotFound, "service account not found")
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to delete service account")
+	}
+
+	s.Logger.Infow("service account deleted", "project", member.ProjectID, "account_id", member.UserID)
+	return nil
+}
+
+// IssueKey creates an API key for the account. Without project_ids the key is bound to the
+// project owning the account.
+func (s *ServiceAccountService) IssueKey(ctx context.Context, projectIDStr, accountIDStr string, input CreateAPIKeyInput) (*APIKeyCreatedResponse, error) {
+	member, err := s.get(ctx, projectIDStr, accountIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.ProjectIDs) == 0 {
+		input.ProjectIDs = []string{member.ProjectID.String()}
+	}
+	return s.Keys.createKey(ctx, member.UserID, input)
+}
+
+func (s *ServiceAccountService) RotateKey(ctx context.Context, projectIDStr, accountIDStr, keyIDStr string, in RotateAPIKeyInput) (*APIKeyCreatedResponse, error) {
+	member, err := s.get(ctx, projectIDStr, accountIDStr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Keys.RotateKey(ctx, member.UserID.String(), keyIDStr, in)
+}
+
+func (s *ServiceAccountService) ListKeys(ctx context.Context, projectIDStr, accountIDStr string) ([]APIKeyDTO, error) {
+	member, err := s.get(ctx, projectIDStr, accountIDStr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Keys.ListUserKeys(ctx, member.UserID.String())
+}
+
+func (s *ServiceAccountService) RevokeKey(ctx context.Context, projectIDStr, accountIDStr, keyIDStr string) error {
+	member, err := s.get(ctx, projectIDStr, accountIDStr)
+	if err != nil {
+		return err
+	}
+	return s.Keys.RevokeKey(ctx, member.UserID.String(), keyIDStr)
+}
+
+// get returns the membership of a service account owned by the project, a person or an account
+// of another project is reported as not found.
+func (s *ServiceAccountService) get(ctx context.Context, projectIDStr, accountIDStr string) (*domain.ProjectMember, error) {
+	pID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	aID, err := uuid.Parse(accountIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid service account id")
+	}
+
+	member, err := s.Repo.GetProjectMember(ctx, pID, aID)
+	if err != nil || member.User.ServiceAccountProjectID == nil || *member.User.ServiceAccountProjectID != pID {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "service account not found")
+	}
+	return member, nil
+}
+
+// assignableRole resolves the role, a service account never owns the project nor gets more
+// than the caller holds: its keys would act beyond the rights of whoever created them.
+func (s *ServiceAccountService) assignableRole(ctx context.Context, access *security.ProjectAccess, roleName string) (*domain.Role, error) {
+	name := strings.ToUpper(strings.TrimSpace(roleName))
+	if name == domain.RoleTypes.Owner.String() {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "a service account cannot be an OWNER")
+	}
+	role, err := s.Repo.GetRoleByName(ctx, name)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "role not found")
+	}
+	if err := checkGrantable(access, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func serviceAccountToDTO(m *domain.ProjectMember) domain.ServiceAccountDTO {
+	return domain.ServiceAccountDTO{
+		ID:        m.UserID.String(),
+		Name:      m.User.FullName,
+		RoleName:  m.Role.Name,
+		CreatedAt: m.User.CreatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+)
+
+func TestServiceAccountRoleWithinTheCallerPermissions(t *testing.T) {
+	f := newMemberFixture(t)
+	s := &ServiceAccountService{Repo: f.repo, Logger: zap.NewNop().Sugar()}
+	ctx := context.Background()
+
+	// LEAD gère les membres mais ne déploie pas : ses comptes de service non plus
+	_, err := s.Create(ctx, f.access("LEAD"), &domain.CreateServiceAccountRequest{ProjectID: f.project.String(), Name: "ci-deploy", Role: "DEVELOPER"})
+	assertCode(t, err, betoerrors.CodeForbidden)
+
+	account, err := s.Create(ctx, f.access("LEAD"), &domain.CreateServiceAccountRequest{ProjectID: f.project.String(), Name: "ci-read", Role: "VIEWER"})
+	if err != nil {
+		t.Fatalf("Create VIEWER: %v", err)
+	}
+
+	update := &domain.UpdateServiceAccountRequest{ProjectID: f.project.String(), AccountID: account.ID, Role: "DEVELOPER"}
+	_, err = s.UpdateRole(ctx, f.access("LEAD"), update)
+	assertCode(t, err, betoerrors.CodeForbidden)
+
+	if _, err := s.UpdateRole(ctx, f.access("OWNER"), update); err != nil {
+		t.Fatalf("an owner promotes the account: %v", err)
+	}
+	update.Role = "OWNER"
+	_, err = s.UpdateRole(ctx, f.access("OWNER"), update)
+	assertCode(t, err, betoerrors.CodeInvalidInput)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+// durée de vie d'un refresh token quand jwt.refresh_expiration n'est pas renseigné
+const defaultRefreshExpiration = 7 * 24 * time.Hour
+
+// newSession builds a refresh token row and the raw token handed to the client. The token is
+// "<session id>.<secret>", only the hash of the secret is stored.
+func (s *AuthService) newSession(userID, familyID uuid.UUID, startedAt time.Time, userAgent, clientIP string) (*domain.UserSession, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	secret := hex.EncodeToString(buf)
+
+	session := &domain.UserSession{
+		ID:               uuid.New(),
+		UserID:           userID,
+		FamilyID:         familyID,
+		StartedAt:        startedAt,
+		RefreshTokenHash: utils.HashAPIKey(secret),
+		UserAgent:        userAgent,
+		ClientIP:         clientIP,
+		ExpiresAt:        time.Now().Add(s.refreshExpiration()),
+	}
+	return session, session.ID.String() + "." + secret, nil
+}
+
+func parseRefreshToken(raw string) (uuid.UUID, string, error) {
+	idPart, secret, found := strings.Cut(raw, ".")
+	if !found || secret == "" {
+		return uuid.Nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid refresh token format")
+	}
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return uuid.Nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid refresh token format")
+	}
+	return id, secret, nil
+}
+
+func refreshTokenMatches(session *domain.UserSession, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(utils.HashAPIKey(secret))) == 1
+}
+
+// revokeOnReuse reacts to a rotated refresh token being presented again: the token has
+// leaked, every session of the user is revoked.
+func (s *AuthService) revokeOnReuse(ctx context.Context, session *domain.UserSession) error {
+	s.Logger.Warnw("refresh token reuse detected, revoking sessions",
+		"user_id", session.UserID, "family_id", session.FamilyID, "session_id", session.ID)
+
+	if err := s.revokeSessions(ctx, session.UserID, uuid.Nil); err != nil {
+		s.Logger.Errorw("failed to revoke sessions after reuse", "user_id", session.UserID, "error", err)
+	}
+
+	return betoerrors.New(betoerrors.CodeUnauthorized, "refresh token reuse detected, please log in again")
+}
+
+// ListSessions returns the live sessions of a user, currentSessionID flags the caller's own.
+func (s *AuthService) ListSessions(ctx context.Context, userIDStr, currentSessionID string) ([]domain.SessionDTO, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid user ID format")
+	}
+
+	sessions, err := s.AuthRepo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list sessions")
+	}
+
+	dtos := make([]domain.SessionDTO, 0, len(sessions))
+	for _, us := range sessions {
+		sessionID := us.FamilyID.String()
+
+		// dernière activité : le dernier refresh, ou la dernière requête vue par le middleware
+		lastSeen := us.CreatedAt
+		if cached, err := s.Cache.GetSession(ctx, sessionID); err == nil && cached.LastSeenAt.After(lastSeen) {
+			lastSeen = cached.LastSeenAt
+		}
+
+		createdAt := us.StartedAt
+		if createdAt.IsZero() {
+			createdAt = us.CreatedAt
+		}
+
+		dtos = append(dtos, domain.SessionDTO{
+			ID:         sessionID,
+			Device:     us.UserAgent,
+			IPAddress:  us.ClientIP,
+			CreatedAt:  createdAt,
+			LastSeenAt: lastSeen,
+			Current:    sessionID == currentSessionID,
+		})
+	}
+	return dtos, nil
+}
+
+// RevokeSession ends one session of the user: its refresh tokens and its cached access state.
+func (s *AuthService) RevokeSession(ctx context.Context, userIDStr, sessionIDStr string) error {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid user ID format")
+	}
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid session ID format")
+	}
+
+	if err := s.AuthRepo.RevokeSessionFamily(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return betoerrors.New(betoerrors.CodeNotFound, "session not found")
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to revoke session")
+	}
+	_ = s.Cache.DeleteSession(ctx, sessionIDStr)
+
+	s.Logger.Infow("session revoked", "user_id", userIDStr, "session_id", sessionIDStr)
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except keepSessionID. An empty
+// keepSessionID ends them all.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userIDStr, keepSessionID string) error {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid user ID format")
+	}
+
+	keep := uuid.Nil
+	if keepSessionID != "" {
+		if keep, err = uuid.Parse(keepSessionID); err != nil {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "invalid session ID format")
+		}
+	}
+
+	if err := s.revokeSessions(ctx, userID, keep); err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to revoke sessions")
+	}
+	return nil
+}
+
+// revokeSessions blocks the refresh tokens and drops the cached sessions of the user, keep
+// (uuid.Nil for none) is left untouched.
+func (s *AuthService) revokeSessions(ctx context.Context, userID, keep uuid.UUID) error {
+	active, err := s.AuthRepo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if keep == uuid.Nil {
+		err = s.AuthRepo.RevokeAllUserSessions(ctx, userID)
+	} else {
+		err = s.AuthRepo.RevokeOtherSessions(ctx, userID, keep)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, us := range active {
+		if us.FamilyID != keep {
+			_ = s.Cache.DeleteSession(ctx, us.FamilyID.String())
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) refreshExpiration() time.Duration {
+	if s.Config.JWT.RefreshExpiration > 0 {
+		return s.Config.JWT.RefreshExpiration
+	}
+	return defaultRefreshExpiration
+}
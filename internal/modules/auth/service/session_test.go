@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+)
+
+func TestLogoutOnlyBlocksTheOwnRefreshToken(t *testing.T) {
+	repo := newMemRepo()
+	s := newTestAuthService(repo, newMemCache())
+	ctx := context.Background()
+
+	owner, other := uuid.New(), uuid.New()
+	session, token, err := s.newSession(owner, uuid.New(), time.Now(), "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = repo.CreateSession(ctx, session)
+	forged := session.ID.String() + ".not-the-secret"
+
+	for name, req := range map[string]*domain.LogoutRequest{
+		"another user":    {UserID: other.String(), RefreshToken: token},
+		"a wrong secret":  {UserID: owner.String(), RefreshToken: forged},
+		"an unknown user": {RefreshToken: token},
+	} {
+		if err := s.Logout(ctx, req); err != nil {
+			t.Fatalf("%s: Logout: %v", name, err)
+		}
+		if session.IsBlocked {
+			t.Fatalf("logout of %s blocked the session", name)
+		}
+	}
+
+	if err := s.Logout(ctx, &domain.LogoutRequest{UserID: owner.String(), RefreshToken: token}); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if !session.IsBlocked {
+		t.Error("the owner's logout left the refresh token usable")
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	maildomain "github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+)
+
+// valeurs par défaut quand la section verification n'est pas renseignée
+const (
+	defaultVerifyTTL    = 24 * time.Hour
+	defaultResendLimit  = 3
+	defaultResendWindow = time.Hour
+)
+
+// VerifyEmail consumes a verification token and marks the account as verified.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	nonce, ok := s.openVerificationToken(token)
+	if !ok {
+		return errors.New(errors.CodeInvalidInput, "verification link is invalid or expired")
+	}
+
+	userIDStr, err := s.Cache.GetVerificationToken(ctx, nonce)
+	if err != nil {
+		if err == cache.ErrCacheMiss {
+			return errors.New(errors.CodeInvalidInput, "verification link is invalid or expired")
+		}
+		return errors.Wrap(err, errors.CodeInternal, "failed to validate token")
+	}
+
+	userUUID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return errors.New(errors.CodeInternal, "invalid user ID in cache")
+	}
+
+	if err := s.AuthRepo.MarkEmailVerified(ctx, userUUID); err != nil {
+		return errors.Wrap(err, errors.CodeInternal, "failed to verify email")
+	}
+
+	_ = s.Cache.DeleteVerificationToken(ctx, nonce)
+
+	_ = s.Cache.DeleteUser(ctx, userIDStr)
+
+	s.Logger.Infow("email verified", "user_id", userIDStr)
+	return nil
+}
+
+// ResendVerification emails a new verification link. Like ForgotPassword the answer does not
+// depend on the account, sends over the per-user limit are dropped silently.
+func (s *AuthService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.AuthRepo.GetUserByEmail(ctx, email)
+
+	if err != nil || user == nil || !user.IsActive || user.IsVerified || user.IsServiceAccount() {
+		return nil
+	}
+
+	limit, window := s.resendLimit()
+	count, err := s.Cache.IncrementVerificationResend(ctx, user.ID.String(), window)
+	if err != nil {
+		return err
+	}
+	if count > int64(limit) {
+		s.Logger.Warnw("verification resend limit reached", "user_id", user.ID, "count", count)
+		return nil
+	}
+
+	return s.SendVerificationEmail(ctx, user)
+}
+
+// SendVerificationEmail issues a signed single-use token and emails the verification link.
+func (s *AuthService) SendVerificationEmail(ctx context.Context, user *domain.User) error {
+	nonce, token, err := s.newVerificationToken()
+	if err != nil {
+		return errors.New(errors.CodeInternal, "failed to generate verification token")
+	}
+
+	ttl := s.verifyTTL()
+	if err := s.Cache.StoreVerificationToken(ctx, nonce, user.ID.String(), ttl); err != nil {
+		return errors.New(errors.CodeInternal, "failed to store verification token")
+	}
+
+	err = s.Mailer.Send(ctx, maildomain.EmailRequest{
+		To:       []string{user.Email},
+		Template: maildomain.TemplateEmailVerification,
+		Data: map[string]interface{}{
+			"Name":      displayName(user),
+			"Link":      s.Mailer.Link("/verify-email?token=" + url.QueryEscape(token)),
+			"ExpiresIn": humanDuration(ttl),
+		},
+	})
+	if err != nil {
+		_ = s.Cache.DeleteVerificationToken(ctx, nonce)
+		return err
+	}
+	return nil
+}
+
+// le jeton est "<nonce>.<hmac(nonce)>" : la signature écarte les jetons forgés sans aller
+// jusqu'à Redis, la clé du nonce porte l'expiration et le rend à usage unique
+func (s *AuthService) newVerificationToken() (string, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+	return nonce, nonce + "." + s.signVerification(nonce), nil
+}
+
+func (s *AuthService) openVerificationToken(token string) (string, bool) {
+	nonce, sig, found := strings.Cut(token, ".")
+	if !found || nonce == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signVerification(nonce))) {
+		return "", false
+	}
+	return nonce, true
+}
+
+func (s *AuthService) signVerification(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.JWT.Secret))
+	mac.Write([]byte("verify-email:" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *AuthService) verifyTTL() time.Duration {
+	if s.Config.Verify.TokenTTL > 0 {
+		return s.Config.Verify.TokenTTL
+	}
+	return defaultVerifyTTL
+}
+
+func (s *AuthService) resendLimit() (int, time.Duration) {
+	limit, window := s.Config.Verify.ResendLimit, s.Config.Verify.ResendWindow
+	if limit <= 0 {
+		limit = defaultResendLimit
+	}
+	if window <= 0 {
+		window = defaultResendWindow
+	}
+	return limit, window
+}
+
+func humanDuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d == day {
+		return "1 jour"
+	}
+	if d > day && d%day == 0 {
+		return fmt.Sprintf("%d jours", int(d/day))
+	}
+	if d == time.Hour {
+		return "1 heure"
+	}
+	if d > time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d heures", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d minutes", int(d.Minutes()))
+}
@@ -0,0 +1,89 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.temporal.io/sdk/temporal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	workloadSvc "github.com/thekrauss/kubemanager/internal/modules/workloads/service"
+)
+
+type BuildActivities struct {
+	K8sClient *kubernetes.Clientset
+	Config    *configs.GlobalConfig
+	Repo      repository.BuildRepository
+	Workloads *workloadSvc.WorkloadService
+	Secrets   *security.SecretBox // ouvre le token git chiffré en base
+	Registry  *security.RegistryCredentials
+	Logger    *zap.SugaredLogger
+}
+
+func (a *BuildActivities) UpdateBuildStatus(ctx context.Context, buildID string, result repository.BuildResult) error {
+	id, err := uuid.Parse(buildID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid build id", "InvalidBuild", err)
+	}
+	return a.Repo.UpdateResult(ctx, id, result)
+}
+
+// DeployBuild rolls the pushed image out by digest, a tag can be overwritten in the registry
+// while a digest always designates the image that was built.
+func (a *BuildActivities) DeployBuild(ctx context.Context, buildID, digest string) (string, error) {
+	build, err := a.getBuild(ctx, buildID)
+	if err != nil {
+		return "", err
+	}
+
+	image := ImageRepository(build.Image) + "@" + digest
+	_, workflowID, err := a.Workloads.DeployImage(ctx, build.WorkloadID.String(), image, "workload-build-deploy-"+buildID)
+	if err != nil {
+		// workload supprimé ou sans déploiement initial : réessayer n'y changera rien
+		if betoerrors.Is(err, betoerrors.CodeConflict) || betoerrors.Is(err, betoerrors.CodeNotFound) {
+			return "", temporal.NewNonRetryableApplicationError(err.Error(), "DeployRejected", err)
+		}
+		return "", err
+	}
+
+	a.Logger.Infow("built image deployed", "build_id", buildID, "workload_id", build.WorkloadID, "image", image)
+	return workflowID, nil
+}
+
+// ImageRepository strips the tag of an image reference, the registry port is kept.
+func ImageRepository(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// getBuild does not retry on a missing build, it was created before the workflow started.
+func (a *BuildActivities) getBuild(ctx context.Context, buildID string) (*domain.Build, error) {
+	id, err := uuid.Parse(buildID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid build id", "InvalidBuild", err)
+	}
+	build, err := a.Repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, temporal.NewNonRetryableApplicationError(fmt.Sprintf("build %s not found", buildID), "InvalidBuild", err)
+		}
+		return nil, err
+	}
+	// une clé de chiffrement changée ne se corrige pas en réessayant
+	if build.GitToken, err = a.Secrets.OpenOptional(build.GitToken); err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("failed to decrypt the git token, check secrets.encryption_key", "InvalidBuild", err)
+	}
+	return build, nil
+}
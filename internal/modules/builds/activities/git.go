@@ -0,0 +1,71 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"go.temporal.io/sdk/temporal"
+)
+
+// FullRef turns a branch name into a reference name, "refs/..." is kept as is.
+func FullRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+	return "refs/heads/" + ref
+}
+
+// ResolveBuildRef pins the build to the commit the ref points to right now, like `git ls-remote`.
+// Later pushes on the branch do not change what this build produces.
+func (a *BuildActivities) ResolveBuildRef(ctx context.Context, buildID string) (string, error) {
+	build, err := a.getBuild(ctx, buildID)
+	if err != nil {
+		return "", err
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{build.RepoURL},
+	})
+
+	listOpts := &git.ListOptions{PeelingOption: git.AppendPeeled}
+	if build.GitToken != "" {
+		username := build.GitUsername
+		if username == "" {
+			username = "git" // ignoré par la plupart des forges pour un token
+		}
+		listOpts.Auth = &githttp.BasicAuth{Username: username, Password: build.GitToken}
+	}
+
+	refs, err := remote.ListContext(ctx, listOpts)
+	if err != nil {
+		if errors.Is(err, transport.ErrRepositoryNotFound) || errors.Is(err, transport.ErrAuthenticationRequired) ||
+			errors.Is(err, transport.ErrAuthorizationFailed) {
+			return "", temporal.NewNonRetryableApplicationError(err.Error(), "SourceUnavailable", err)
+		}
+		return "", fmt.Errorf("failed to list remote refs: %w", err)
+	}
+
+	// un tag annoté pointe sur l'objet tag, sa version "^{}" pointe sur le commit
+	name := FullRef(build.Ref)
+	var commit string
+	for _, ref := range refs {
+		switch ref.Name().String() {
+		case name + "^{}":
+			return ref.Hash().String(), nil
+		case name:
+			commit = ref.Hash().String()
+		}
+	}
+	if commit == "" {
+		return "", temporal.NewNonRetryableApplicationError(fmt.Sprintf("ref %s not found in %s", build.Ref, build.RepoURL), "RefNotFound", nil)
+	}
+	return commit, nil
+}
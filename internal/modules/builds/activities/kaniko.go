@@ -0,0 +1,349 @@
+package activities
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadActivities "github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+)
+
+// ErrBuildRunning is returned while the Kaniko Job has neither completed nor failed, the workflow retry policy polls on it.
+const ErrBuildRunning = "BuildRunning"
+
+// BuildContainer is the name of the Kaniko container, its logs are the build logs.
+const BuildContainer = "kaniko"
+
+const (
+	defaultKanikoImage  = "gcr.io/kaniko-project/executor:v1.23.2"
+	defaultBuildCPU     = "1"
+	defaultBuildMemory  = "2Gi"
+	DefaultBuildTimeout = 30 * time.Minute
+
+	// le Job et ses logs restent consultables une heure, la fin des logs est gardée en base
+	buildJobTTL  = 3600
+	savedLogTail = 1000
+)
+
+type BuildJobResult struct {
+	Phase  string // utils.PhaseJobSucceeded | utils.PhaseJobFailed
+	Digest string
+	Reason string
+}
+
+// StartBuildJob writes the pull login of the project in the namespace and starts the Kaniko Job,
+// the source is the commit resolved by ResolveBuildRef. The Dockerfile runs in the Kaniko
+// container: it only gets the push login of this build, useless once the build is over.
+func (a *BuildActivities) StartBuildJob(ctx context.Context, buildID, commit string) error {
+	build, err := a.getBuild(ctx, buildID)
+	if err != nil {
+		return err
+	}
+	cfg := a.Config.Build
+
+	if err := a.ensureRegistrySecret(ctx, build.Namespace); err != nil {
+		return fmt.Errorf("failed to write registry secret: %w", err)
+	}
+
+	cpu, err := resource.ParseQuantity(withDefault(cfg.CPU, defaultBuildCPU))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid build cpu", "InvalidConfig", err)
+	}
+	memory, err := resource.ParseQuantity(withDefault(cfg.Memory, defaultBuildMemory))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid build memory", "InvalidConfig", err)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultBuildTimeout
+	}
+
+	// kaniko clone le dépôt lui-même : git://hôte/dépôt#référence#commit
+	args := []string{
+		"--context=git://" + strings.TrimPrefix(build.RepoURL, "https://") + "#" + FullRef(build.Ref) + "#" + commit,
+		"--dockerfile=" + build.Dockerfile,
+		"--destination=" + build.Image,
+		"--digest-file=/dev/termination-log",
+	}
+	if build.ContextDir != "." {
+		args = append(args, "--context-sub-path="+build.ContextDir)
+	}
+	if cfg.Insecure {
+		args = append(args, "--insecure")
+	}
+
+	labels := map[string]string{
+		"kubemanager.io/managed": "true",
+		"kubemanager.io/build":   buildID,
+	}
+
+	container := corev1.Container{
+		Name:  BuildContainer,
+		Image: withDefault(cfg.KanikoImage, defaultKanikoImage),
+		Args:  args,
+		// en cas d'échec, la fin des logs remplace le digest dans le message de terminaison
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "docker-config", MountPath: "/kaniko/.docker"}},
+	}
+	if build.GitToken != "" {
+		container.Env = []corev1.EnvVar{
+			secretEnv("GIT_USERNAME", utils.BuildGitSecretName(build.JobName), "username"),
+			secretEnv("GIT_PASSWORD", utils.BuildGitSecretName(build.JobName), "token"),
+		}
+	}
+
+	backoffLimit, ttl := int32(0), int32(buildJobTTL)
+	deadline := int64(timeout.Seconds())
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      build.JobName,
+			Namespace: build.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{container},
+					Volumes: []corev1.Volume{{
+						Name: "docker-config",
+						VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+							SecretName: utils.BuildRegistrySecretName(build.JobName),
+							Items:      []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}},
+						}},
+					}},
+				},
+			},
+		},
+	}
+
+	created, err := a.K8sClient.BatchV1().Jobs(build.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		if created, err = a.K8sClient.BatchV1().Jobs(build.Namespace).Get(ctx, build.JobName, metav1.GetOptions{}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// les Secrets appartiennent au Job : ils disparaissent avec lui, le pod attend leur création
+	owner := *metav1.NewControllerRef(created, batchv1.SchemeGroupVersion.WithKind("Job"))
+	dockerConfig, err := a.dockerConfig(a.Registry.Build(buildID))
+	if err != nil {
+		return err
+	}
+	err = a.createJobSecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.BuildRegistrySecretName(build.JobName)},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+	}, build.Namespace, labels, owner)
+	if err != nil || build.GitToken == "" {
+		return err
+	}
+	return a.createJobSecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.BuildGitSecretName(build.JobName)},
+		StringData: map[string]string{
+			"username": withDefault(build.GitUsername, "git"),
+			"token":    build.GitToken,
+		},
+	}, build.Namespace, labels, owner)
+}
+
+func (a *BuildActivities) createJobSecret(ctx context.Context, secret *corev1.Secret, namespace string, labels map[string]string, owner metav1.OwnerReference) error {
+	secret.Namespace = namespace
+	secret.Labels = labels
+	secret.OwnerReferences = []metav1.OwnerReference{owner}
+	_, err := a.K8sClient.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// WaitForBuildJob polls the Kaniko Job, on success the digest written by Kaniko
+// is read back from the termination message of its container.
+func (a *BuildActivities) WaitForBuildJob(ctx context.Context, buildID string) (BuildJobResult, error) {
+	build, err := a.getBuild(ctx, buildID)
+	if err != nil {
+		return BuildJobResult{}, err
+	}
+
+	job, err := a.K8sClient.BatchV1().Jobs(build.Namespace).Get(ctx, build.JobName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return BuildJobResult{}, temporal.NewNonRetryableApplicationError("build job not found", "NotFound", err)
+		}
+		return BuildJobResult{}, err
+	}
+
+	phase, reason := workloadActivities.JobPhase(job)
+	if phase == utils.PhaseJobRunning {
+		return BuildJobResult{}, temporal.NewApplicationError(fmt.Sprintf("build job %s still running", job.Name), ErrBuildRunning)
+	}
+
+	result := BuildJobResult{Phase: phase, Reason: reason}
+	message := terminationMessage(ctx, a.K8sClient, build)
+
+	if phase == utils.PhaseJobFailed {
+		if message != "" {
+			result.Reason = message
+		}
+		return result, nil
+	}
+
+	if !strings.HasPrefix(message, "sha256:") {
+		return result, temporal.NewNonRetryableApplicationError("build succeeded but no image digest was reported", "DigestMissing", nil)
+	}
+	result.Digest = message
+	return result, nil
+}
+
+// SaveBuildLogs keeps the end of the build logs in the database, the Job is garbage collected after an hour.
+func (a *BuildActivities) SaveBuildLogs(ctx context.Context, buildID string) error {
+	build, err := a.getBuild(ctx, buildID)
+	if err != nil {
+		return err
+	}
+
+	pod, err := BuildPod(ctx, a.K8sClient, build)
+	if err != nil {
+		return err
+	}
+
+	tail := int64(savedLogTail)
+	stream, err := a.K8sClient.CoreV1().Pods(build.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: BuildContainer,
+		TailLines: &tail,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	return a.Repo.SaveLogs(ctx, build.ID, string(logs))
+}
+
+// BuildPod returns the pod of the build Job, the most recent one if the Job was recreated.
+func BuildPod(ctx context.Context, client kubernetes.Interface, build *domain.Build) (*corev1.Pod, error) {
+	pods, err := client.CoreV1().Pods(build.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: batchv1.JobNameLabel + "=" + build.JobName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if latest == nil || pod.CreationTimestamp.After(latest.CreationTimestamp.Time) {
+			latest = pod
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no pod found for build job %s", build.JobName)
+	}
+	return latest, nil
+}
+
+func terminationMessage(ctx context.Context, client kubernetes.Interface, build *domain.Build) string {
+	pod, err := BuildPod(ctx, client, build)
+	if err != nil {
+		return ""
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == BuildContainer && status.State.Terminated != nil {
+			return strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+	return ""
+}
+
+// ensureRegistrySecret writes the pull login of the project in its namespace, the imagePullSecret
+// of the workloads running a built image.
+func (a *BuildActivities) ensureRegistrySecret(ctx context.Context, namespace string) error {
+	dockerConfig, err := a.dockerConfig(a.Registry.Project(namespace))
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.RegistrySecretName,
+			Namespace: namespace,
+			Labels:    map[string]string{"kubemanager.io/managed": "true"},
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+	}
+
+	secrets := a.K8sClient.CoreV1().Secrets(namespace)
+	current, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	current.Data = secret.Data
+	_, err = secrets.Update(ctx, current, metav1.UpdateOptions{})
+	return err
+}
+
+// dockerConfig is the config.json of a registry login, the registry exchanges it for a token
+// scoped by kubemanager.
+func (a *BuildActivities) dockerConfig(username, password string) ([]byte, error) {
+	host, _, _ := strings.Cut(a.Config.Build.Registry, "/")
+	return json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			host: map[string]string{
+				"username": username,
+				"password": password,
+				"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	})
+}
+
+func secretEnv(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			Key:                  key,
+		}},
+	}
+}
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
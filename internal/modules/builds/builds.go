@@ -0,0 +1,87 @@
+package builds
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/loopfz/gadgeto/tonic"
+
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/service"
+)
+
+type IBuildController interface {
+	StartBuild(c *gin.Context, in *domain.CreateBuildRequest) (*domain.BuildResponse, error)
+	ListBuilds(c *gin.Context, in *domain.WorkloadBuildsRequest) ([]domain.BuildResponse, error)
+	GetBuild(c *gin.Context, in *domain.BuildPathRequest) (*domain.BuildResponse, error)
+	StreamBuildLogs(c *gin.Context)
+	RegistryToken(c *gin.Context)
+}
+
+type BuildHandler struct {
+	BuildService service.IBuildService
+}
+
+func NewBuildHandler(svc service.IBuildService) *BuildHandler {
+	return &BuildHandler{BuildService: svc}
+}
+
+func (h *BuildHandler) StartBuild(c *gin.Context, in *domain.CreateBuildRequest) (*domain.BuildResponse, error) {
+	return h.BuildService.StartBuild(c.Request.Context(), *in)
+}
+
+func (h *BuildHandler) ListBuilds(c *gin.Context, in *domain.WorkloadBuildsRequest) ([]domain.BuildResponse, error) {
+	return h.BuildService.ListBuilds(c.Request.Context(), in.WorkloadID)
+}
+
+func (h *BuildHandler) GetBuild(c *gin.Context, in *domain.BuildPathRequest) (*domain.BuildResponse, error) {
+	return h.BuildService.GetBuild(c.Request.Context(), in.WorkloadID, in.BuildID)
+}
+
+// StreamBuildLogs is a plain gin handler, tonic would buffer the body: the logs are written
+// as text while Kaniko produces them when ?follow=true.
+func (h *BuildHandler) StreamBuildLogs(c *gin.Context) {
+	follow, _ := strconv.ParseBool(c.Query("follow"))
+
+	logs, err := h.BuildService.BuildLogs(c.Request.Context(), c.Param("id"), c.Param("buildID"), follow)
+	if err != nil {
+		c.JSON(tonic.GetErrorHook()(c, err))
+		return
+	}
+	defer logs.Close()
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	buf := make([]byte, 4096)
+	c.Stream(func(w io.Writer) bool {
+		n, err := logs.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return false
+			}
+		}
+		return err == nil
+	})
+}
+
+// RegistryToken answers the registry token authentication (GET ?service=&scope=) for the logins
+// written by kubemanager, sent in basic auth by the kubelet and Kaniko.
+func (h *BuildHandler) RegistryToken(c *gin.Context) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		c.Header("WWW-Authenticate", `Basic realm="kubemanager-registry"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.BuildService.RegistryToken(c.Request.Context(), username, password, c.QueryArray("scope"))
+	if err != nil {
+		c.JSON(tonic.GetErrorHook()(c, err))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
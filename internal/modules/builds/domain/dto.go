@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+type CreateBuildRequest struct {
+	WorkloadID  string `path:"id" desc:"ID du workload"`
+	RepoURL     string `json:"repo_url" binding:"required,url" desc:"URL HTTPS du dépôt"`
+	Ref         string `json:"ref" default:"main" desc:"Branche, ou référence complète (refs/tags/v1.2.0)"`
+	ContextDir  string `json:"context_dir" default:"." desc:"Dossier du contexte de build dans le dépôt"`
+	Dockerfile  string `json:"dockerfile" default:"Dockerfile" desc:"Chemin du Dockerfile, relatif au contexte"`
+	GitUsername string `json:"git_username" desc:"Utilisateur HTTPS (dépôt privé)"`
+	GitToken    string `json:"git_token" desc:"Token d'accès (dépôt privé)"`
+}
+
+type WorkloadBuildsRequest struct {
+	WorkloadID string `path:"id" desc:"ID du workload"`
+}
+
+type BuildPathRequest struct {
+	WorkloadID string `path:"id" desc:"ID du workload"`
+	BuildID    string `path:"buildID" desc:"ID du build"`
+}
+
+// RegistryTokenResponse is the body the registry token authentication expects.
+type RegistryTokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	IssuedAt    string `json:"issued_at"`
+}
+
+type BuildResponse struct {
+	ID         string `json:"id"`
+	WorkloadID string `json:"workload_id"`
+	RepoURL    string `json:"repo_url"`
+	Ref        string `json:"ref"`
+	Commit     string `json:"commit,omitempty"`
+	ContextDir string `json:"context_dir"`
+	Dockerfile string `json:"dockerfile"`
+
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+
+	Status           string     `json:"status"`
+	Phase            string     `json:"phase,omitempty"`
+	Error            string     `json:"error,omitempty"`
+	WorkflowID       string     `json:"workflow_id,omitempty"`
+	DeployWorkflowID string     `json:"deploy_workflow_id,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Build is one image build of a workload: a Kaniko Job clones the repository at Ref,
+// builds the Dockerfile and pushes the image, which is then rolled out by digest.
+type Build struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	WorkloadID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ProjectID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	Namespace  string    `gorm:"not null"`
+
+	// Source
+	RepoURL    string `gorm:"type:text;not null"`
+	Ref        string `gorm:"type:varchar(255);not null"`
+	Commit     string `gorm:"type:varchar(40)"` // commit résolu au démarrage, le build est reproductible
+	ContextDir string `gorm:"type:varchar(255);not null;default:'.'"`
+	Dockerfile string `gorm:"type:varchar(255);not null;default:'Dockerfile'"`
+
+	// identifiants HTTPS d'un dépôt privé, lus par l'activité et jamais renvoyés
+	GitUsername string `gorm:"type:varchar(255)"`
+	GitToken    string `gorm:"type:text"`
+
+	// Résultat
+	Image   string `gorm:"type:text;not null"` // tag poussé (registry/namespace/workload:build)
+	Digest  string `gorm:"type:varchar(100)"`
+	JobName string `gorm:"type:varchar(63);not null"`
+
+	Status       string `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	CurrentPhase string `gorm:"type:varchar(50)"`
+	Error        string `gorm:"type:text"`
+	Logs         string `gorm:"type:text"` // fin des logs, conservée après la suppression du Job
+
+	WorkflowID       string `gorm:"type:varchar(100)"`
+	DeployWorkflowID string `gorm:"type:varchar(100)"`
+
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
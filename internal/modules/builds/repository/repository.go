@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+// BuildResult is a status change of a build, empty fields keep their previous value.
+type BuildResult struct {
+	Status           string
+	Phase            string
+	Commit           string
+	Digest           string
+	Error            string
+	DeployWorkflowID string
+}
+
+type BuildRepository interface {
+	Create(ctx context.Context, build *domain.Build) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Build, error)
+	ListByWorkload(ctx context.Context, workloadID uuid.UUID) ([]domain.Build, error)
+	GetActiveByWorkload(ctx context.Context, workloadID uuid.UUID) (*domain.Build, error)
+	UpdateResult(ctx context.Context, id uuid.UUID, result BuildResult) error
+	SaveLogs(ctx context.Context, id uuid.UUID, logs string) error
+}
+
+type buildRepository struct {
+	db *gorm.DB
+}
+
+func NewBuildRepository(db *gorm.DB) BuildRepository {
+	return &buildRepository{db: db}
+}
+
+func (r *buildRepository) Create(ctx context.Context, build *domain.Build) error {
+	return r.db.WithContext(ctx).Create(build).Error
+}
+
+func (r *buildRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Build, error) {
+	var build domain.Build
+	err := r.db.WithContext(ctx).First(&build, "id = ?", id).Error
+	return &build, err
+}
+
+// newest first
+func (r *buildRepository) ListByWorkload(ctx context.Context, workloadID uuid.UUID) ([]domain.Build, error) {
+	var builds []domain.Build
+	err := r.db.WithContext(ctx).
+		Omit("logs").
+		Where("workload_id = ?", workloadID).
+		Order("created_at DESC").
+		Find(&builds).Error
+	return builds, err
+}
+
+func (r *buildRepository) GetActiveByWorkload(ctx context.Context, workloadID uuid.UUID) (*domain.Build, error) {
+	var build domain.Build
+	err := r.db.WithContext(ctx).
+		Where("workload_id = ? AND status IN ?", workloadID, []string{utils.BuildPending, utils.BuildRunning}).
+		First(&build).Error
+	return &build, err
+}
+
+// UpdateResult also drops the git token once the build is over, the Job no longer needs it.
+func (r *buildRepository) UpdateResult(ctx context.Context, id uuid.UUID, result BuildResult) error {
+	updates := map[string]interface{}{
+		"status":        result.Status,
+		"current_phase": result.Phase,
+	}
+	if result.Commit != "" {
+		updates["commit"] = result.Commit
+	}
+	if result.Digest != "" {
+		updates["digest"] = result.Digest
+	}
+	if result.Error != "" {
+		updates["error"] = result.Error
+	}
+	if result.DeployWorkflowID != "" {
+		updates["deploy_workflow_id"] = result.DeployWorkflowID
+	}
+
+	switch result.Status {
+	case utils.BuildRunning:
+		updates["started_at"] = gorm.Expr("COALESCE(started_at, ?)", time.Now())
+	case utils.BuildSucceeded, utils.BuildFailed:
+		updates["finished_at"] = time.Now()
+		updates["git_token"] = ""
+	}
+
+	return r.db.WithContext(ctx).Model(&domain.Build{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func (r *buildRepository) SaveLogs(ctx context.Context, id uuid.UUID, logs string) error {
+	return r.db.WithContext(ctx).Model(&domain.Build{}).
+		Where("id = ?", id).
+		Update("logs", logs).Error
+}
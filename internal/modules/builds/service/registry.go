@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+// registryTokenTTL is short, the kubelet and Kaniko ask for a new token with the same login.
+const registryTokenTTL = 5 * time.Minute
+
+// RegistryToken grants the requested scopes the login is entitled to, the others are dropped as
+// the token authentication expects. A project login pulls the repositories of its namespace;
+// a build login also pushes the repository of its image, until the build ends.
+func (s *BuildService) RegistryToken(ctx context.Context, username, password string, scopes []string) (*domain.RegistryTokenResponse, error) {
+	if s.Tokens == nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "builds are disabled, no registry is configured")
+	}
+
+	kind, subject, ok := s.Registry.Check(username, password)
+	if !ok {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid registry credentials")
+	}
+
+	namespace, pushRepo := subject, ""
+	if kind == security.RegistryAccountBuild {
+		build, err := s.runningBuild(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+		namespace = build.Namespace
+		pushRepo = s.repositoryName(activities.ImageRepository(build.Image))
+	}
+	prefix := s.repositoryName(strings.TrimSuffix(s.Config.Build.Registry, "/")+"/"+namespace) + "/"
+
+	var access []security.RegistryAccess
+	for _, scope := range scopes {
+		// repository:<nom>:<actions>, le nom peut contenir un port de registre
+		first, last := strings.Index(scope, ":"), strings.LastIndex(scope, ":")
+		if first < 0 || first == last || scope[:first] != "repository" {
+			continue
+		}
+		name := scope[first+1 : last]
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		var granted []string
+		for _, action := range strings.Split(scope[last+1:], ",") {
+			if action == "pull" || (action == "push" && name == pushRepo) {
+				granted = append(granted, action)
+			}
+		}
+		if len(granted) > 0 {
+			access = append(access, security.RegistryAccess{Type: "repository", Name: name, Actions: granted})
+		}
+	}
+
+	token, err := s.Tokens.Sign(username, access, registryTokenTTL)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to sign registry token")
+	}
+	return &domain.RegistryTokenResponse{
+		Token:       token,
+		AccessToken: token,
+		ExpiresIn:   int(registryTokenTTL.Seconds()),
+		IssuedAt:    time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// runningBuild refuses the login of a finished build: its token may have been printed by the
+// Dockerfile in the build logs.
+func (s *BuildService) runningBuild(ctx context.Context, buildID string) (*domain.Build, error) {
+	id, err := uuid.Parse(buildID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid registry credentials")
+	}
+	build, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid registry credentials")
+	}
+	if build.Status != utils.BuildPending && build.Status != utils.BuildRunning {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "build is finished, its registry login expired")
+	}
+	return build, nil
+}
+
+// repositoryName strips the registry host, the token scopes name repositories without it.
+func (s *BuildService) repositoryName(image string) string {
+	host, _, _ := strings.Cut(s.Config.Build.Registry, "/")
+	return strings.TrimPrefix(image, host+"/")
+}
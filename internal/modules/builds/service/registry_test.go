@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+type memBuildRepo struct {
+	repository.BuildRepository
+	builds map[uuid.UUID]*domain.Build
+}
+
+func (r *memBuildRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Build, error) {
+	if b, ok := r.builds[id]; ok {
+		return b, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func newRegistryTestService(t *testing.T, registry string) (*BuildService, *memBuildRepo) {
+	t.Helper()
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test"}, NotAfter: time.Now().Add(time.Hour)}
+	certDER, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	for name, block := range map[string]*pem.Block{
+		"token.key": {Type: "EC PRIVATE KEY", Bytes: keyDER},
+		"token.crt": {Type: "CERTIFICATE", Bytes: certDER},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &configs.GlobalConfig{Build: configs.BuildConfig{
+		Registry:      registry,
+		TokenKeyFile:  filepath.Join(dir, "token.key"),
+		TokenCertFile: filepath.Join(dir, "token.crt"),
+	}}
+	cfg.JWT.Secret = "test-secret"
+	signer, err := security.NewRegistryTokenSigner(cfg.Build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &memBuildRepo{builds: map[uuid.UUID]*domain.Build{}}
+	return &BuildService{
+		Config:   cfg,
+		Repo:     repo,
+		Registry: security.RegistryCredentialsFromConfig(cfg),
+		Tokens:   signer,
+	}, repo
+}
+
+// granted reads the access claim of the token as "name:actions" entries.
+func granted(t *testing.T, token string) []string {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, entry := range claims["access"].([]interface{}) {
+		access := entry.(map[string]interface{})
+		var actions []string
+		for _, a := range access["actions"].([]interface{}) {
+			actions = append(actions, a.(string))
+		}
+		out = append(out, access["name"].(string)+":"+strings.Join(actions, ","))
+	}
+	return out
+}
+
+func TestProjectLoginOnlyPullsItsNamespace(t *testing.T) {
+	s, _ := newRegistryTestService(t, "registry.example.com/km")
+	user, pass := s.Registry.Project("km-shop")
+
+	resp, err := s.RegistryToken(context.Background(), user, pass, []string{
+		"repository:km/km-shop/api:pull,push",
+		"repository:km/km-shop-evil/api:pull",
+		"repository:km/km-blog/api:pull",
+		"registry:catalog:*",
+	})
+	if err != nil {
+		t.Fatalf("RegistryToken: %v", err)
+	}
+	if got := strings.Join(granted(t, resp.Token), " "); got != "km/km-shop/api:pull" {
+		t.Errorf("granted %q, want only the pull of its namespace", got)
+	}
+
+	_, err = s.RegistryToken(context.Background(), "project/km-blog", pass, []string{"repository:km/km-blog/api:pull"})
+	if !betoerrors.Is(err, betoerrors.CodeUnauthorized) {
+		t.Errorf("the password of km-shop opened km-blog: %v", err)
+	}
+}
+
+func TestBuildLoginPushesItsImageWhileRunning(t *testing.T) {
+	s, repo := newRegistryTestService(t, "registry.example.com:5000")
+	build := &domain.Build{ID: uuid.New(), Namespace: "km-shop", Image: "registry.example.com:5000/km-shop/api:build-1a2b", Status: utils.BuildRunning}
+	repo.builds[build.ID] = build
+	user, pass := s.Registry.Build(build.ID.String())
+
+	scopes := []string{"repository:km-shop/api:push,pull", "repository:km-shop/worker:push,pull", "repository:km-shop/base:pull"}
+	resp, err := s.RegistryToken(context.Background(), user, pass, scopes)
+	if err != nil {
+		t.Fatalf("RegistryToken: %v", err)
+	}
+	if got := strings.Join(granted(t, resp.Token), " "); got != "km-shop/api:push,pull km-shop/worker:pull km-shop/base:pull" {
+		t.Errorf("granted %q", got)
+	}
+
+	// la fin du build rend inutile un login affiché dans ses logs
+	for _, status := range []string{utils.BuildSucceeded, utils.BuildFailed} {
+		build.Status = status
+		_, err = s.RegistryToken(context.Background(), user, pass, scopes)
+		if !betoerrors.Is(err, betoerrors.CodeUnauthorized) {
+			t.Errorf("%s build: err = %v, want unauthorized", status, err)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/workflows"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+)
+
+type IBuildService interface {
+	StartBuild(ctx context.Context, req domain.CreateBuildRequest) (*domain.BuildResponse, error)
+	ListBuilds(ctx context.Context, workloadID string) ([]domain.BuildResponse, error)
+	GetBuild(ctx context.Context, workloadID, buildID string) (*domain.BuildResponse, error)
+	BuildLogs(ctx context.Context, workloadID, buildID string, follow bool) (io.ReadCloser, error)
+	RegistryToken(ctx context.Context, username, password string, scopes []string) (*domain.RegistryTokenResponse, error)
+}
+
+var _ IBuildService = (*BuildService)(nil)
+
+type BuildService struct {
+	TemporalClient client.Client
+	Config         *configs.GlobalConfig
+	Logger         *zap.SugaredLogger
+	Repo           repository.BuildRepository
+	WorkloadRepo   workloadRepo.WorkloadRepository
+	K8sClient      *kubernetes.Clientset
+	Registry       *security.RegistryCredentials
+	Tokens         *security.RegistryTokenSigner // nil sans registre configuré
+}
+
+func NewBuildService(
+	tc client.Client,
+	cfg *configs.GlobalConfig,
+	log *zap.SugaredLogger,
+	repo repository.BuildRepository,
+	wRepo workloadRepo.WorkloadRepository,
+	k8s *kubernetes.Clientset,
+	tokens *security.RegistryTokenSigner,
+) IBuildService {
+	return &BuildService{
+		TemporalClient: tc,
+		Config:         cfg,
+		Logger:         log.With("service", "BuildService"),
+		Repo:           repo,
+		WorkloadRepo:   wRepo,
+		K8sClient:      k8s,
+		Registry:       security.RegistryCredentialsFromConfig(cfg),
+		Tokens:         tokens,
+	}
+}
+
+// StartBuild records the build and starts its workflow, one build at a time per workload.
+// The workload must have been deployed once: the built image replaces the image of its last deploy.
+func (s *BuildService) StartBuild(ctx context.Context, req domain.CreateBuildRequest) (*domain.BuildResponse, error) {
+	registry := strings.TrimSuffix(s.Config.Build.Registry, "/")
+	if registry == "" {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "builds are disabled, no registry is configured")
+	}
+
+	wID, err := uuid.Parse(req.WorkloadID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+	workload, err := s.WorkloadRepo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+	if workload.Status == utils.WorkloadDeleting {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "workload is being deleted")
+	}
+	if workload.LastWorkflowID == "" {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "workload has never been deployed")
+	}
+
+	// kaniko ne clone qu'en HTTPS (contexte git://)
+	if u, err := url.Parse(req.RepoURL); err != nil || u.Scheme != "https" || u.Host == "" || u.Fragment != "" {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "repo_url must be an https git repository")
+	}
+	if req.Ref == "" {
+		req.Ref = "main"
+	}
+	if strings.ContainsAny(req.Ref, "# \t") || strings.Contains(req.Ref, "..") {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid ref")
+	}
+	contextDir, err := cleanRepoPath(req.ContextDir, ".")
+	if err != nil {
+		return nil, err
+	}
+	dockerfile, err := cleanRepoPath(req.Dockerfile, "Dockerfile")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repo.GetActiveByWorkload(ctx, wID); err == nil {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "a build is already running for this workload")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to check running builds")
+	}
+
+	secrets, err := security.SecretBoxFromConfig(s.Config)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "secret encryption is not configured")
+	}
+	gitToken, err := secrets.SealOptional(req.GitToken)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to encrypt git token")
+	}
+
+	buildID := uuid.New()
+	shortID := buildID.String()[:8]
+	build := &domain.Build{
+		ID:          buildID,
+		WorkloadID:  workload.ID,
+		ProjectID:   workload.ProjectID,
+		Namespace:   workload.Namespace,
+		RepoURL:     req.RepoURL,
+		Ref:         req.Ref,
+		ContextDir:  contextDir,
+		Dockerfile:  dockerfile,
+		GitUsername: req.GitUsername,
+		GitToken:    gitToken,
+		Image:       fmt.Sprintf("%s/%s/%s:build-%s", registry, workload.Namespace, workload.Name, shortID),
+		JobName:     jobName(workload.Name, shortID),
+		Status:      utils.BuildPending,
+		WorkflowID:  "workload-build-" + buildID.String(),
+	}
+
+	if err := s.Repo.Create(ctx, build); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save build")
+	}
+
+	timeout := s.Config.Build.Timeout
+	if timeout <= 0 {
+		timeout = activities.DefaultBuildTimeout
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       build.WorkflowID,
+		TaskQueue:                "kubemanager-tasks",
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	_, err = s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.BuildWorkloadWorkflow, workflows.BuildWorkloadInput{
+		BuildID: buildID.String(),
+		Timeout: timeout,
+	})
+	if err != nil {
+		_ = s.Repo.UpdateResult(ctx, buildID, repository.BuildResult{
+			Status: utils.BuildFailed,
+			Phase:  utils.PhaseBuildFailed,
+			Error:  "failed to start build workflow",
+		})
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start build workflow")
+	}
+
+	s.Logger.Infow("build started", "build_id", buildID, "workload_id", workload.ID, "repo", build.RepoURL, "ref", build.Ref)
+	return toBuildResponse(build), nil
+}
+
+func (s *BuildService) ListBuilds(ctx context.Context, workloadID string) ([]domain.BuildResponse, error) {
+	wID, err := uuid.Parse(workloadID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	builds, err := s.Repo.ListByWorkload(ctx, wID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list builds")
+	}
+
+	resp := make([]domain.BuildResponse, 0, len(builds))
+	for i := range builds {
+		resp = append(resp, *toBuildResponse(&builds[i]))
+	}
+	return resp, nil
+}
+
+func (s *BuildService) GetBuild(ctx context.Context, workloadID, buildID string) (*domain.BuildResponse, error) {
+	build, err := s.getWorkloadBuild(ctx, workloadID, buildID)
+	if err != nil {
+		return nil, err
+	}
+	return toBuildResponse(build), nil
+}
+
+// BuildLogs reads the logs of the Kaniko pod, with follow the stream ends with the build.
+// Once the Job is garbage collected, the tail saved at the end of the build is returned.
+func (s *BuildService) BuildLogs(ctx context.Context, workloadID, buildID string, follow bool) (io.ReadCloser, error) {
+	build, err := s.getWorkloadBuild(ctx, workloadID, buildID)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := activities.BuildPod(ctx, s.K8sClient, build)
+	if err != nil {
+		if build.Logs != "" {
+			return io.NopCloser(strings.NewReader(build.Logs)), nil
+		}
+		if build.Status == utils.BuildPending || build.Status == utils.BuildRunning {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "build has not started yet")
+		}
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "build logs are no longer available")
+	}
+	if pod.Status.Phase == corev1.PodPending {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "build pod is starting, retry in a few seconds")
+	}
+
+	stream, err := s.K8sClient.CoreV1().Pods(build.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: activities.BuildContainer,
+		Follow:    follow,
+	}).Stream(ctx)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to read build logs")
+	}
+	return stream, nil
+}
+
+func (s *BuildService) getWorkloadBuild(ctx context.Context, workloadID, buildID string) (*domain.Build, error) {
+	bID, err := uuid.Parse(buildID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid build id")
+	}
+	build, err := s.Repo.GetByID(ctx, bID)
+	if err != nil || build.WorkloadID.String() != workloadID {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "build not found")
+	}
+	return build, nil
+}
+
+// jobName keeps the Job name a valid DNS label (63 characters).
+func jobName(workloadName, shortID string) string {
+	if len(workloadName) > 40 {
+		workloadName = strings.TrimSuffix(workloadName[:40], "-")
+	}
+	return fmt.Sprintf("build-%s-%s", workloadName, shortID)
+}
+
+// cleanRepoPath keeps a path inside the repository.
+func cleanRepoPath(p, fallback string) (string, error) {
+	if strings.Contains(p, "..") {
+		return "", betoerrors.New(betoerrors.CodeInvalidInput, "paths must stay inside the repository")
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if cleaned == "" {
+		return fallback, nil
+	}
+	return cleaned, nil
+}
+
+func toBuildResponse(b *domain.Build) *domain.BuildResponse {
+	return &domain.BuildResponse{
+		ID:               b.ID.String(),
+		WorkloadID:       b.WorkloadID.String(),
+		RepoURL:          b.RepoURL,
+		Ref:              b.Ref,
+		Commit:           b.Commit,
+		ContextDir:       b.ContextDir,
+		Dockerfile:       b.Dockerfile,
+		Image:            b.Image,
+		Digest:           b.Digest,
+		Status:           b.Status,
+		Phase:            b.CurrentPhase,
+		Error:            b.Error,
+		WorkflowID:       b.WorkflowID,
+		DeployWorkflowID: b.DeployWorkflowID,
+		StartedAt:        b.StartedAt,
+		FinishedAt:       b.FinishedAt,
+		CreatedAt:        b.CreatedAt,
+	}
+}
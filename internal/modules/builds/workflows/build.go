@@ -0,0 +1,114 @@
+package workflows
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/builds/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/builds/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+type BuildWorkloadInput struct {
+	BuildID string        `json:"build_id"`
+	Timeout time.Duration `json:"timeout"` // durée max du Job Kaniko
+}
+
+type BuildWorkloadResult struct {
+	Commit           string `json:"commit"`
+	Digest           string `json:"digest"`
+	DeployWorkflowID string `json:"deploy_workflow_id"`
+}
+
+// BuildWorkloadWorkflow resolves the ref, runs the Kaniko Job and hands the pushed digest to
+// DeployWorkloadWorkflow. The build succeeds once the deploy is started, its outcome is the workload status.
+func BuildWorkloadWorkflow(ctx workflow.Context, input BuildWorkloadInput) (BuildWorkloadResult, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: 5 * time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	// polling : un build peut durer jusqu'à l'ActiveDeadlineSeconds du Job
+	pollCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout:    30 * time.Second,
+		ScheduleToCloseTimeout: input.Timeout + 5*time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    30 * time.Second,
+		},
+	})
+
+	var a *activities.BuildActivities
+	var result BuildWorkloadResult
+
+	phase := utils.PhaseBuildResolving
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return result, err
+	}
+
+	fail := func(err error) (BuildWorkloadResult, error) {
+		phase = utils.PhaseBuildFailed
+		_ = workflow.ExecuteActivity(ctx, a.UpdateBuildStatus, input.BuildID, repository.BuildResult{
+			Status: utils.BuildFailed,
+			Phase:  phase,
+			Error:  err.Error(),
+		}).Get(ctx, nil)
+		return result, err
+	}
+
+	if err := workflow.ExecuteActivity(ctx, a.ResolveBuildRef, input.BuildID).Get(ctx, &result.Commit); err != nil {
+		return fail(err)
+	}
+
+	phase = utils.PhaseBuildRunning
+	workflow.ExecuteActivity(ctx, a.UpdateBuildStatus, input.BuildID, repository.BuildResult{
+		Status: utils.BuildRunning,
+		Phase:  phase,
+		Commit: result.Commit,
+	}).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, a.StartBuildJob, input.BuildID, result.Commit).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	var job activities.BuildJobResult
+	err := workflow.ExecuteActivity(pollCtx, a.WaitForBuildJob, input.BuildID).Get(ctx, &job)
+
+	// les logs sont conservés quel que soit le résultat
+	_ = workflow.ExecuteActivity(ctx, a.SaveBuildLogs, input.BuildID).Get(ctx, nil)
+
+	if err != nil {
+		return fail(err)
+	}
+	if job.Phase == utils.PhaseJobFailed {
+		return fail(temporal.NewApplicationError("build failed: "+job.Reason, "BuildFailed"))
+	}
+	result.Digest = job.Digest
+
+	phase = utils.PhaseBuildDeploying
+	workflow.ExecuteActivity(ctx, a.UpdateBuildStatus, input.BuildID, repository.BuildResult{
+		Status: utils.BuildRunning,
+		Phase:  phase,
+		Digest: result.Digest,
+	}).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, a.DeployBuild, input.BuildID, result.Digest).Get(ctx, &result.DeployWorkflowID); err != nil {
+		return fail(err)
+	}
+
+	phase = utils.PhaseBuildSucceeded
+	err = workflow.ExecuteActivity(ctx, a.UpdateBuildStatus, input.BuildID, repository.BuildResult{
+		Status:           utils.BuildSucceeded,
+		Phase:            phase,
+		DeployWorkflowID: result.DeployWorkflowID,
+	}).Get(ctx, nil)
+	return result, err
+}
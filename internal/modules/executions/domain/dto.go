@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+type WorkflowExecutionResponse struct {
+	WorkflowID      string                `json:"workflow_id"`
+	RunID           string                `json:"run_id"`
+	WorkflowType    string                `json:"workflow_type"`
+	Status          string                `json:"status"`
+	CurrentPhase    string                `json:"current_phase,omitempty"` // via query handler (workflow en cours)
+	CurrentActivity string                `json:"current_activity,omitempty"`
+	Pending         []PendingActivityDTO  `json:"pending_activities"`
+	Failures        []string              `json:"failures"`
+	Timeline        []WorkflowTimelineDTO `json:"timeline"`
+	StartTime       *time.Time            `json:"start_time,omitempty"`
+	CloseTime       *time.Time            `json:"close_time,omitempty"`
+}
+
+type PendingActivityDTO struct {
+	ActivityID  string     `json:"activity_id"`
+	Name        string     `json:"name"`
+	State       string     `json:"state"`
+	Attempt     int32      `json:"attempt"`
+	MaxAttempts int32      `json:"max_attempts"`
+	LastFailure string     `json:"last_failure,omitempty"`
+	LastStarted *time.Time `json:"last_started_at,omitempty"`
+}
+
+type WorkflowTimelineDTO struct {
+	EventID   int64     `json:"event_id"`
+	EventType string    `json:"event_type"`
+	Activity  string    `json:"activity,omitempty"`
+	Attempt   int32     `json:"attempt,omitempty"`
+	Failure   string    `json:"failure,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
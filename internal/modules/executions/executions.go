@@ -0,0 +1,28 @@
+package executions
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/thekrauss/kubemanager/internal/modules/executions/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/executions/service"
+)
+
+type IExecutionController interface {
+	GetWorkflow(c *gin.Context, in *GetWorkflowRequest) (*domain.WorkflowExecutionResponse, error)
+}
+
+type ExecutionHandler struct {
+	ExecutionService service.IExecutionService
+}
+
+func NewExecutionHandler(svc service.IExecutionService) *ExecutionHandler {
+	return &ExecutionHandler{ExecutionService: svc}
+}
+
+type GetWorkflowRequest struct {
+	WorkflowID string `path:"id" desc:"ID du workflow Temporal"`
+}
+
+func (h *ExecutionHandler) GetWorkflow(c *gin.Context, in *GetWorkflowRequest) (*domain.WorkflowExecutionResponse, error) {
+	return h.ExecutionService.GetWorkflowExecution(c.Request.Context(), in.WorkflowID)
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/thekrauss/kubemanager/internal/modules/executions/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+type IExecutionService interface {
+	GetWorkflowExecution(ctx context.Context, workflowID string) (*domain.WorkflowExecutionResponse, error)
+}
+
+var _ IExecutionService = (*ExecutionService)(nil)
+
+type ExecutionService struct {
+	TemporalClient client.Client
+	Logger         *zap.SugaredLogger
+}
+
+func NewExecutionService(tc client.Client, log *zap.SugaredLogger) *ExecutionService {
+	return &ExecutionService{
+		TemporalClient: tc,
+		Logger:         log.With("service", "ExecutionService"),
+	}
+}
+
+func (s *ExecutionService) GetWorkflowExecution(ctx context.Context, workflowID string) (*domain.WorkflowExecutionResponse, error) {
+	desc, err := s.TemporalClient.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		s.Logger.Warnw("workflow not found", "workflowID", workflowID, "error", err)
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "workflow execution not found")
+	}
+
+	info := desc.GetWorkflowExecutionInfo()
+	res := &domain.WorkflowExecutionResponse{
+		WorkflowID:   info.GetExecution().GetWorkflowId(),
+		RunID:        info.GetExecution().GetRunId(),
+		WorkflowType: info.GetType().GetName(),
+		Status:       info.GetStatus().String(),
+		StartTime:    toTime(info.GetStartTime()),
+		CloseTime:    toTime(info.GetCloseTime()),
+		Pending:      []domain.PendingActivityDTO{},
+		Failures:     []string{},
+	}
+
+	for _, pa := range desc.GetPendingActivities() {
+		dto := domain.PendingActivityDTO{
+			ActivityID:  pa.GetActivityId(),
+			Name:        pa.GetActivityType().GetName(),
+			State:       pa.GetState().String(),
+			Attempt:     pa.GetAttempt(),
+			MaxAttempts: pa.GetMaximumAttempts(),
+			LastStarted: toTime(pa.GetLastStartedTime()),
+		}
+		if f := pa.GetLastFailure(); f != nil {
+			dto.LastFailure = f.GetMessage()
+		}
+		res.Pending = append(res.Pending, dto)
+	}
+	if len(res.Pending) > 0 {
+		res.CurrentActivity = res.Pending[0].Name
+	}
+
+	// phase exposée par le query handler, uniquement tant que le workflow tourne
+	if info.GetStatus() == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		if val, err := s.TemporalClient.QueryWorkflow(ctx, res.WorkflowID, res.RunID, utils.QueryCurrentPhase); err == nil {
+			_ = val.Get(&res.CurrentPhase)
+		} else {
+			s.Logger.Debugw("phase query failed", "workflowID", workflowID, "error", err)
+		}
+	}
+
+	timeline, failures, err := s.buildTimeline(ctx, res.WorkflowID, res.RunID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to read workflow history")
+	}
+	res.Timeline = timeline
+	res.Failures = append(res.Failures, failures...)
+
+	return res, nil
+}
+
+func (s *ExecutionService) buildTimeline(ctx context.Context, workflowID, runID string) ([]domain.WorkflowTimelineDTO, []string, error) {
+	iter := s.TemporalClient.GetWorkflowHistory(ctx, workflowID, runID, false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+
+	activityNames := make(map[int64]string)
+	timeline := []domain.WorkflowTimelineDTO{}
+	failures := []string{}
+
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entry := domain.WorkflowTimelineDTO{
+			EventID:   event.GetEventId(),
+			EventType: event.GetEventType().String(),
+			Timestamp: event.GetEventTime().AsTime(),
+		}
+
+		switch event.GetEventType() {
+		case enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+			name := event.GetActivityTaskScheduledEventAttributes().GetActivityType().GetName()
+			activityNames[event.GetEventId()] = name
+			entry.Activity = name
+
+		case enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED:
+			attrs := event.GetActivityTaskStartedEventAttributes()
+			entry.Activity = activityNames[attrs.GetScheduledEventId()]
+			entry.Attempt = attrs.GetAttempt()
+
+		case enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+			entry.Activity = activityNames[event.GetActivityTaskCompletedEventAttributes().GetScheduledEventId()]
+
+		case enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED:
+			attrs := event.GetActivityTaskFailedEventAttributes()
+			entry.Activity = activityNames[attrs.GetScheduledEventId()]
+			entry.Failure = attrs.GetFailure().GetMessage()
+
+		case enumspb.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT:
+			attrs := event.GetActivityTaskTimedOutEventAttributes()
+			entry.Activity = activityNames[attrs.GetScheduledEventId()]
+			entry.Failure = attrs.GetFailure().GetMessage()
+
+		case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED:
+			entry.Failure = event.GetWorkflowExecutionFailedEventAttributes().GetFailure().GetMessage()
+
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED,
+			enumspb.EVENT_TYPE_WORKFLOW_TASK_STARTED,
+			enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED:
+			// bruit interne de Temporal, inutile pour l'UI
+			continue
+		}
+
+		if entry.Failure != "" {
+			failures = append(failures, describeFailure(entry))
+		}
+		timeline = append(timeline, entry)
+	}
+
+	return timeline, failures, nil
+}
+
+func describeFailure(e domain.WorkflowTimelineDTO) string {
+	if e.Activity == "" {
+		return e.Failure
+	}
+	return e.Activity + ": " + e.Failure
+}
+
+func toTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,178 @@
+package activities
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	projectDomain "github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	projectSvc "github.com/thekrauss/kubemanager/internal/modules/projects/service"
+)
+
+type FetchOptions struct {
+	URL      string
+	Branch   string
+	Path     string
+	Username string
+	Token    string
+}
+
+type FetchResult struct {
+	Commit   string `json:"commit"`
+	Hash     string `json:"hash"`     // sha256 des fichiers de manifeste, stable d'un commit à l'autre
+	Manifest []byte `json:"manifest"` // manifestes fusionnés, en JSON
+	Files    int    `json:"files"`
+}
+
+// GuardGitTransport sends the http(s) remotes of go-git through c, whose dialer refuses internal
+// addresses. go-git only has process-wide transports, the refs of the builds are listed through it too.
+func GuardGitTransport(c *http.Client) {
+	transport := githttp.NewClient(c)
+	gitclient.InstallProtocol("https", transport)
+	gitclient.InstallProtocol("http", transport)
+}
+
+// FetchManifests shallow-clones the branch in memory and merges the manifests found
+// under the path. The URL can be any go-git endpoint, a local bare repository included.
+func FetchManifests(ctx context.Context, opts FetchOptions) (*FetchResult, error) {
+	cloneOpts := &git.CloneOptions{
+		URL:           opts.URL,
+		ReferenceName: plumbing.NewBranchReferenceName(opts.Branch),
+		SingleBranch:  true,
+		Depth:         1,
+		Tags:          git.NoTags,
+	}
+	if opts.Token != "" {
+		username := opts.Username
+		if username == "" {
+			username = "git" // ignoré par la plupart des forges pour un token
+		}
+		cloneOpts.Auth = &githttp.BasicAuth{Username: username, Password: opts.Token}
+	}
+
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), cloneOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s@%s: %w", opts.URL, opts.Branch, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", head.Hash(), err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree: %w", err)
+	}
+	if dir := strings.Trim(path.Clean(opts.Path), "/"); dir != "." && dir != "" {
+		if tree, err = tree.Tree(dir); err != nil {
+			return nil, fmt.Errorf("path %q not found in %s", opts.Path, head.Hash())
+		}
+	}
+
+	files := make(map[string]string)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		switch strings.ToLower(path.Ext(f.Name)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		files[f.Name] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no manifest (.yaml, .yml, .json) found under %q", opts.Path)
+	}
+
+	manifest, hash, err := mergeManifests(files)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FetchResult{
+		Commit:   head.Hash().String(),
+		Hash:     hash,
+		Manifest: raw,
+		Files:    len(files),
+	}, nil
+}
+
+// mergeManifests concatenates the sections of the files in name order. A section absent
+// from every file stays nil, so it remains unmanaged. Only one file may set the quotas.
+func mergeManifests(files map[string]string) (*projectDomain.ProjectManifest, string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	merged := &projectDomain.ProjectManifest{}
+	hash := sha256.New()
+	quotasFile := ""
+
+	for _, name := range names {
+		hash.Write([]byte(name + "\x00" + files[name] + "\x00"))
+
+		m, err := projectSvc.DecodeManifest([]byte(files[name]))
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", name, err)
+		}
+
+		if m.Quotas != nil {
+			if quotasFile != "" {
+				return nil, "", fmt.Errorf("quotas are defined in both %s and %s", quotasFile, name)
+			}
+			quotasFile = name
+			merged.Quotas = m.Quotas
+		}
+		if m.Members != nil {
+			merged.Members = append(nonNil(merged.Members), m.Members...)
+		}
+		if m.Workloads != nil {
+			merged.Workloads = append(nonNil(merged.Workloads), m.Workloads...)
+		}
+		if m.NetworkPolicies != nil {
+			merged.NetworkPolicies = append(nonNil(merged.NetworkPolicies), m.NetworkPolicies...)
+		}
+	}
+
+	return merged, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// nonNil keeps an empty section managed ("workloads: []" prunes everything) once merged.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,245 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	projectDomain "github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	webhookActivities "github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+)
+
+// testRemote is a working copy pushing to a local bare repository, the bare one plays the forge.
+type testRemote struct {
+	t    *testing.T
+	bare string
+	work string
+	repo *git.Repository
+}
+
+func newTestRemote(t *testing.T) *testRemote {
+	t.Helper()
+	root := t.TempDir()
+	bare := filepath.Join(root, "remote.git")
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatalf("init bare: %v", err)
+	}
+	work := filepath.Join(root, "work")
+	repo, err := git.PlainInit(work, false)
+	if err != nil {
+		t.Fatalf("init work: %v", err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}}); err != nil {
+		t.Fatalf("add remote: %v", err)
+	}
+	return &testRemote{t: t, bare: bare, work: work, repo: repo}
+}
+
+// commit writes the files on the branch and pushes it.
+func (r *testRemote) commit(branch string, files map[string]string) string {
+	r.t.Helper()
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	if head, err := r.repo.Head(); err == nil && head.Name() != ref {
+		_, missing := r.repo.Reference(ref, false)
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: ref, Create: missing != nil, Keep: true}); err != nil {
+			r.t.Fatalf("checkout %s: %v", branch, err)
+		}
+	}
+
+	for name, content := range files {
+		full := filepath.Join(r.work, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			r.t.Fatalf("add %s: %v", name, err)
+		}
+	}
+
+	hash, err := wt.Commit("update manifests", &git.CommitOptions{
+		Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		r.t.Fatalf("commit: %v", err)
+	}
+
+	head, err := r.repo.Head()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if head.Name() != ref {
+		// premier commit : la branche par défaut du dépôt est renommée en branch
+		if err := r.repo.Storer.SetReference(plumbing.NewHashReference(ref, hash)); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	spec := config.RefSpec(ref.String() + ":" + ref.String())
+	if err := r.repo.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{spec}, Force: true}); err != nil {
+		r.t.Fatalf("push %s: %v", branch, err)
+	}
+	return hash.String()
+}
+
+func decodeResult(t *testing.T, res *FetchResult) *projectDomain.ProjectManifest {
+	t.Helper()
+	var m projectDomain.ProjectManifest
+	if err := json.Unmarshal(res.Manifest, &m); err != nil {
+		t.Fatalf("decode merged manifest: %v", err)
+	}
+	return &m
+}
+
+func TestFetchManifestsFromBareRepository(t *testing.T) {
+	remote := newTestRemote(t)
+	commit := remote.commit("main", map[string]string{
+		"deploy/quotas.yaml":  "quotas:\n  cpu: 2000m\n  memory: 4Gi\n  storage: 10Gi\n",
+		"deploy/members.yml":  "members:\n  - email: dev@example.com\n    role: DEVELOPER\n",
+		"deploy/network.json": `{"network_policies":[{"name":"web","ports":[8080]}]}`,
+		"deploy/README.md":    "ignored, not a manifest",
+		"other/members.yaml":  "members:\n  - email: outside@example.com\n    role: OWNER\n",
+	})
+
+	res, err := FetchManifests(context.Background(), FetchOptions{URL: remote.bare, Branch: "main", Path: "/deploy/"})
+	if err != nil {
+		t.Fatalf("FetchManifests: %v", err)
+	}
+	if res.Commit != commit {
+		t.Errorf("commit = %s, want %s", res.Commit, commit)
+	}
+	if res.Files != 3 {
+		t.Errorf("files = %d, want 3", res.Files)
+	}
+
+	m := decodeResult(t, res)
+	if m.Quotas == nil || m.Quotas.CPU != "2000m" {
+		t.Errorf("quotas = %+v, want cpu 2000m", m.Quotas)
+	}
+	if len(m.Members) != 1 || m.Members[0].Email != "dev@example.com" {
+		t.Errorf("members = %+v, want only the member under deploy/", m.Members)
+	}
+	if len(m.NetworkPolicies) != 1 || m.NetworkPolicies[0].Name != "web" {
+		t.Errorf("network policies = %+v", m.NetworkPolicies)
+	}
+	if m.Workloads != nil {
+		t.Errorf("workloads = %+v, want an unmanaged section", m.Workloads)
+	}
+}
+
+func TestFetchManifestsHashFollowsManifests(t *testing.T) {
+	remote := newTestRemote(t)
+	remote.commit("main", map[string]string{"members.yaml": "members: []\n"})
+	first, err := FetchManifests(context.Background(), FetchOptions{URL: remote.bare, Branch: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// un commit qui ne touche pas aux manifestes ne doit pas relancer une application
+	remote.commit("main", map[string]string{"notes.txt": "hello"})
+	second, err := FetchManifests(context.Background(), FetchOptions{URL: remote.bare, Branch: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Commit == first.Commit {
+		t.Fatal("expected a new commit")
+	}
+	if second.Hash != first.Hash {
+		t.Errorf("hash changed without a manifest change")
+	}
+
+	remote.commit("main", map[string]string{"members.yaml": "members:\n  - email: a@example.com\n    role: VIEWER\n"})
+	third, err := FetchManifests(context.Background(), FetchOptions{URL: remote.bare, Branch: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Hash == second.Hash {
+		t.Errorf("hash unchanged after a manifest change")
+	}
+}
+
+func TestFetchManifestsReadsTheRequestedBranch(t *testing.T) {
+	remote := newTestRemote(t)
+	remote.commit("main", map[string]string{"members.yaml": "members:\n  - email: main@example.com\n    role: VIEWER\n"})
+	staging := remote.commit("staging", map[string]string{"members.yaml": "members:\n  - email: staging@example.com\n    role: VIEWER\n"})
+
+	res, err := FetchManifests(context.Background(), FetchOptions{URL: remote.bare, Branch: "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Commit != staging {
+		t.Errorf("commit = %s, want the staging head %s", res.Commit, staging)
+	}
+	if m := decodeResult(t, res); len(m.Members) != 1 || m.Members[0].Email != "staging@example.com" {
+		t.Errorf("members = %+v, want the staging member", m.Members)
+	}
+}
+
+func TestFetchManifestsErrors(t *testing.T) {
+	remote := newTestRemote(t)
+	remote.commit("main", map[string]string{
+		"a.yaml":        "quotas:\n  cpu: 1000m\n",
+		"b.yaml":        "quotas:\n  cpu: 2000m\n",
+		"bad/bad.yaml":  "unknown_section: true\n",
+		"docs/read.txt": "no manifest here",
+	})
+
+	cases := []struct {
+		name string
+		opts FetchOptions
+		want string
+	}{
+		{"unknown branch", FetchOptions{Branch: "nope"}, "failed to clone"},
+		{"unknown path", FetchOptions{Branch: "main", Path: "missing"}, "not found"},
+		{"no manifest", FetchOptions{Branch: "main", Path: "docs"}, "no manifest"},
+		{"duplicate quotas", FetchOptions{Branch: "main"}, "quotas are defined in both"},
+		{"invalid manifest", FetchOptions{Branch: "main", Path: "bad"}, "bad.yaml"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.URL = remote.bare
+			_, err := FetchManifests(context.Background(), tc.opts)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestGuardedTransportRefusesInternalRemotes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the clone must not reach a loopback remote")
+	}))
+	defer srv.Close()
+
+	GuardGitTransport(webhookActivities.NewDeliveryClient(5 * time.Second))
+	t.Cleanup(func() {
+		gitclient.InstallProtocol("https", githttp.DefaultClient)
+		gitclient.InstallProtocol("http", githttp.DefaultClient)
+	})
+
+	_, err := FetchManifests(context.Background(), FetchOptions{URL: srv.URL + "/team/infra.git", Branch: "main"})
+	if !errors.Is(err, webhookActivities.ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
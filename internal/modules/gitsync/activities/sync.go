@@ -0,0 +1,121 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.temporal.io/sdk/temporal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/repository"
+	projectSvc "github.com/thekrauss/kubemanager/internal/modules/projects/service"
+)
+
+type GitSyncActivities struct {
+	Repo      repository.GitSourceRepository
+	Manifests projectSvc.IManifestService
+	Secrets   *security.SecretBox // ouvre le token du dépôt chiffré en base
+	Logger    *zap.SugaredLogger
+}
+
+// SourceState is what the workflow needs to know about the source, the credentials
+// are read by FetchSource itself and never go through the workflow history.
+type SourceState struct {
+	ProjectID    string `json:"project_id"`
+	ManifestHash string `json:"manifest_hash"`
+	Prune        bool   `json:"prune"`
+}
+
+func (a *GitSyncActivities) GetSourceState(ctx context.Context, sourceID string) (*SourceState, error) {
+	source, err := a.getSource(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	return &SourceState{
+		ProjectID:    source.ProjectID.String(),
+		ManifestHash: source.ManifestHash,
+		Prune:        source.Prune,
+	}, nil
+}
+
+func (a *GitSyncActivities) FetchSource(ctx context.Context, sourceID string) (*FetchResult, error) {
+	source, err := a.getSource(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// une clé de chiffrement changée ne se corrige pas en réessayant
+	token, err := a.Secrets.OpenOptional(source.Token)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("failed to decrypt the repository token, check secrets.encryption_key", "InvalidSource", err)
+	}
+
+	return FetchManifests(ctx, FetchOptions{
+		URL:      source.URL,
+		Branch:   source.Branch,
+		Path:     source.Path,
+		Username: source.Username,
+		Token:    token,
+	})
+}
+
+// ApplySourceManifest goes through the same plan/apply path as POST /projects/:id/apply,
+// it returns the ID of the ApplyManifestWorkflow, empty when the project already matches.
+// The manifest is checked against the current permissions of whoever configured the source.
+func (a *GitSyncActivities) ApplySourceManifest(ctx context.Context, projectID string, manifest []byte, prune bool) (string, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return "", temporal.NewNonRetryableApplicationError("invalid project id", "ManifestRejected", err)
+	}
+	source, err := a.Repo.GetByProject(ctx, pID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load git source: %w", err)
+	}
+	can, err := a.Manifests.MemberPermissions(ctx, pID, source.ConfiguredBy)
+	if err != nil {
+		if betoerrors.Is(err, betoerrors.CodeNotFound) {
+			return "", temporal.NewNonRetryableApplicationError("the user who configured the git source no longer exists, configure it again", "ManifestRejected", err)
+		}
+		return "", err
+	}
+
+	plan, err := a.Manifests.ApplyManifest(ctx, projectID, manifest, true, prune, can)
+	if err != nil {
+		// un manifeste invalide ou hors des droits ne se corrige qu'avec un nouveau commit
+		if betoerrors.Is(err, betoerrors.CodeInvalidInput) || betoerrors.Is(err, betoerrors.CodeNotFound) || betoerrors.Is(err, betoerrors.CodeForbidden) {
+			return "", temporal.NewNonRetryableApplicationError(err.Error(), "ManifestRejected", err)
+		}
+		return "", err
+	}
+	return plan.WorkflowID, nil
+}
+
+func (a *GitSyncActivities) RecordSyncResult(ctx context.Context, sourceID string, result repository.SyncResult) error {
+	id, err := uuid.Parse(sourceID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid source id", "InvalidSource", err)
+	}
+	return a.Repo.UpdateSyncResult(ctx, id, result)
+}
+
+// getSource does not retry on a deleted source, a run already scheduled then fails fast.
+func (a *GitSyncActivities) getSource(ctx context.Context, sourceID string) (*domain.GitSource, error) {
+	id, err := uuid.Parse(sourceID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid source id", "InvalidSource", err)
+	}
+	source, err := a.Repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, temporal.NewNonRetryableApplicationError(fmt.Sprintf("git source %s not found", sourceID), "InvalidSource", err)
+		}
+		return nil, err
+	}
+	return source, nil
+}
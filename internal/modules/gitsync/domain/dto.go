@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+type ConfigureSourceRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	URL       string `json:"url" binding:"required,url" desc:"URL HTTPS du dépôt"`
+	Branch    string `json:"branch" default:"main"`
+	Path      string `json:"path" default:"." desc:"Dossier des manifestes (.yaml, .yml, .json)"`
+	Schedule  string `json:"schedule" default:"*/5 * * * *" desc:"Cron du polling"`
+	Prune     bool   `json:"prune" desc:"Supprimer les ressources absentes des manifestes"`
+	Username  string `json:"username" desc:"Utilisateur HTTPS (dépôt privé)"`
+	Token     string `json:"token" desc:"Token d'accès (dépôt privé)"`
+
+	ConfiguredBy string `json:"-"`
+}
+
+type SourcePathRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+}
+
+type GitSourceResponse struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	URL       string `json:"url"`
+	Branch    string `json:"branch"`
+	Path      string `json:"path"`
+	Schedule  string `json:"schedule"`
+	Prune     bool   `json:"prune"`
+
+	Status         string     `json:"status"`
+	Phase          string     `json:"phase,omitempty"`
+	LastCommit     string     `json:"last_commit,omitempty"`
+	LastSyncedAt   *time.Time `json:"last_synced_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastWorkflowID string     `json:"last_workflow_id,omitempty"`
+
+	WebhookURL    string `json:"webhook_url"`
+	WebhookSecret string `json:"webhook_secret,omitempty"` // uniquement à la configuration
+}
+
+type SyncResponse struct {
+	SourceID   string `json:"source_id"`
+	WorkflowID string `json:"workflow_id,omitempty"`
+	Message    string `json:"message"`
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GitSource is the git repository a project tracks, the manifests found under Path
+// are applied to the project on each change.
+type GitSource struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"` // une seule source par projet, deux manifestes se contrediraient
+
+	URL    string `gorm:"type:text;not null"`
+	Branch string `gorm:"type:varchar(255);not null;default:'main'"`
+	Path   string `gorm:"type:varchar(255);not null;default:'.'"`
+	Prune  bool   `gorm:"default:false"`
+
+	// les manifestes sont appliqués avec les permissions de la personne qui a configuré la source
+	ConfiguredBy uuid.UUID `gorm:"type:uuid"`
+
+	// Cron Temporal du polling
+	Schedule string `gorm:"type:varchar(100);not null"`
+
+	// Dépôt privé (HTTPS), jamais renvoyé par l'API
+	Username string `gorm:"type:varchar(100)"`
+	Token    string `gorm:"type:text"`
+
+	// Clé HMAC des webhooks de push
+	WebhookSecret string `gorm:"type:varchar(100);not null"`
+
+	// Sync State
+	Status         string `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	CurrentPhase   string `gorm:"type:varchar(50)"`
+	LastCommit     string `gorm:"type:varchar(40)"`
+	ManifestHash   string `gorm:"type:varchar(64)"` // empreinte des manifestes appliqués
+	LastSyncedAt   *time.Time
+	LastError      string `gorm:"type:text"`
+	LastWorkflowID string `gorm:"type:varchar(100)"` // dernier ApplyManifestWorkflow lancé
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
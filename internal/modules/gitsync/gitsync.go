@@ -0,0 +1,58 @@
+package gitsync
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/service"
+)
+
+// limite du corps d'un webhook de push
+const maxWebhookPayload = 5 << 20
+
+type IGitSyncController interface {
+	ConfigureSource(c *gin.Context, in *domain.ConfigureSourceRequest) (*domain.GitSourceResponse, error)
+	GetSource(c *gin.Context, in *domain.SourcePathRequest) (*domain.GitSourceResponse, error)
+	DeleteSource(c *gin.Context, in *domain.SourcePathRequest) error
+	TriggerSync(c *gin.Context, in *domain.SourcePathRequest) (*domain.SyncResponse, error)
+	Webhook(c *gin.Context) (*domain.SyncResponse, error)
+}
+
+type GitSyncHandler struct {
+	GitSyncService service.IGitSyncService
+}
+
+func NewGitSyncHandler(svc service.IGitSyncService) *GitSyncHandler {
+	return &GitSyncHandler{GitSyncService: svc}
+}
+
+func (h *GitSyncHandler) ConfigureSource(c *gin.Context, in *domain.ConfigureSourceRequest) (*domain.GitSourceResponse, error) {
+	in.ConfiguredBy = c.GetString(security.UserIDKey)
+	return h.GitSyncService.ConfigureSource(c.Request.Context(), *in)
+}
+
+func (h *GitSyncHandler) GetSource(c *gin.Context, in *domain.SourcePathRequest) (*domain.GitSourceResponse, error) {
+	return h.GitSyncService.GetSource(c.Request.Context(), in.ProjectID)
+}
+
+func (h *GitSyncHandler) DeleteSource(c *gin.Context, in *domain.SourcePathRequest) error {
+	return h.GitSyncService.DeleteSource(c.Request.Context(), in.ProjectID)
+}
+
+func (h *GitSyncHandler) TriggerSync(c *gin.Context, in *domain.SourcePathRequest) (*domain.SyncResponse, error) {
+	return h.GitSyncService.TriggerSync(c.Request.Context(), in.ProjectID)
+}
+
+// Webhook reads the raw body, the HMAC is computed on the exact bytes sent by the forge.
+func (h *GitSyncHandler) Webhook(c *gin.Context) (*domain.SyncResponse, error) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookPayload))
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "payload is too large or unreadable")
+	}
+	return h.GitSyncService.HandleWebhook(c.Request.Context(), c.Param("sourceID"), c.GetHeader(service.SignatureHeader), payload)
+}
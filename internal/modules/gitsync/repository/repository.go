@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+// SyncResult is the outcome of a sync run. An empty hash keeps the previous one.
+type SyncResult struct {
+	Status     string
+	Phase      string
+	Commit     string
+	Hash       string
+	Error      string
+	WorkflowID string
+}
+
+type GitSourceRepository interface {
+	Save(ctx context.Context, source *domain.GitSource) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.GitSource, error)
+	GetByProject(ctx context.Context, projectID uuid.UUID) (*domain.GitSource, error)
+	UpdateSyncResult(ctx context.Context, id uuid.UUID, result SyncResult) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type gitSourceRepository struct {
+	db *gorm.DB
+}
+
+func NewGitSourceRepository(db *gorm.DB) GitSourceRepository {
+	return &gitSourceRepository{db: db}
+}
+
+func (r *gitSourceRepository) Save(ctx context.Context, source *domain.GitSource) error {
+	return r.db.WithContext(ctx).Save(source).Error
+}
+
+func (r *gitSourceRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.GitSource, error) {
+	var source domain.GitSource
+	err := r.db.WithContext(ctx).First(&source, "id = ?", id).Error
+	return &source, err
+}
+
+func (r *gitSourceRepository) GetByProject(ctx context.Context, projectID uuid.UUID) (*domain.GitSource, error) {
+	var source domain.GitSource
+	err := r.db.WithContext(ctx).First(&source, "project_id = ?", projectID).Error
+	return &source, err
+}
+
+func (r *gitSourceRepository) UpdateSyncResult(ctx context.Context, id uuid.UUID, result SyncResult) error {
+	updates := map[string]interface{}{
+		"status":        result.Status,
+		"current_phase": result.Phase,
+		"last_error":    result.Error,
+	}
+	if result.Commit != "" {
+		updates["last_commit"] = result.Commit
+	}
+	if result.Hash != "" {
+		updates["manifest_hash"] = result.Hash
+	}
+	if result.WorkflowID != "" {
+		updates["last_workflow_id"] = result.WorkflowID
+	}
+	if result.Status == utils.GitSyncSynced {
+		updates["last_synced_at"] = time.Now()
+	}
+
+	return r.db.WithContext(ctx).Model(&domain.GitSource{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func (r *gitSourceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.GitSource{}, "id = ?", id).Error
+}
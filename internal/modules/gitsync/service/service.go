@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/workflows"
+	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	webhookActivities "github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+)
+
+// route publique des webhooks de push, sans authentification (signature HMAC)
+const webhookPath = "/kmanager/v1/git-sync/webhooks/"
+
+type IGitSyncService interface {
+	ConfigureSource(ctx context.Context, req domain.ConfigureSourceRequest) (*domain.GitSourceResponse, error)
+	GetSource(ctx context.Context, projectID string) (*domain.GitSourceResponse, error)
+	DeleteSource(ctx context.Context, projectID string) error
+	TriggerSync(ctx context.Context, projectID string) (*domain.SyncResponse, error)
+	HandleWebhook(ctx context.Context, sourceID, signature string, payload []byte) (*domain.SyncResponse, error)
+}
+
+var _ IGitSyncService = (*GitSyncService)(nil)
+
+type GitSyncService struct {
+	TemporalClient client.Client
+	Config         *configs.GlobalConfig
+	Logger         *zap.SugaredLogger
+	Repo           repository.GitSourceRepository
+	ProjectRepo    projectRepo.ProjectRepository
+}
+
+func NewGitSyncService(
+	tc client.Client,
+	cfg *configs.GlobalConfig,
+	log *zap.SugaredLogger,
+	repo repository.GitSourceRepository,
+	pRepo projectRepo.ProjectRepository,
+) IGitSyncService {
+	return &GitSyncService{
+		TemporalClient: tc,
+		Config:         cfg,
+		Logger:         log.With("service", "GitSyncService"),
+		Repo:           repo,
+		ProjectRepo:    pRepo,
+	}
+}
+
+// ConfigureSource creates or replaces the git source of the project and (re)starts its cron.
+// The webhook secret is kept across updates so that the forge configuration stays valid.
+func (s *GitSyncService) ConfigureSource(ctx context.Context, req domain.ConfigureSourceRequest) (*domain.GitSourceResponse, error) {
+	pID, err := s.getProjectID(ctx, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	configuredBy, err := uuid.Parse(req.ConfiguredBy)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "unauthorized")
+	}
+
+	if err := validateSourceURL(req.URL); err != nil {
+		return nil, err
+	}
+	if req.Branch == "" {
+		req.Branch = "main"
+	}
+	if req.Schedule == "" {
+		req.Schedule = "*/5 * * * *"
+	}
+	if _, err := cron.ParseStandard(req.Schedule); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid cron schedule")
+	}
+	dir, err := cleanSourcePath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.Repo.GetByProject(ctx, pID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to generate webhook secret")
+		}
+		source = &domain.GitSource{
+			ID:            uuid.New(),
+			ProjectID:     pID,
+			WebhookSecret: secret,
+			Status:        utils.GitSyncPending,
+		}
+	case err != nil:
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to get git source")
+	default:
+		if err := s.stopSchedule(ctx, source.ID.String(), "git source updated"); err != nil {
+			return nil, err
+		}
+		// la nouvelle source est réappliquée entièrement au prochain passage
+		source.ManifestHash = ""
+	}
+
+	source.URL = req.URL
+	source.Branch = req.Branch
+	source.Path = dir
+	source.Schedule = req.Schedule
+	source.Prune = req.Prune
+	source.Username = req.Username
+	if source.Token, err = s.sealToken(req.Token); err != nil {
+		return nil, err
+	}
+	source.ConfiguredBy = configuredBy
+
+	if err := s.Repo.Save(ctx, source); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save git source")
+	}
+
+	// un cron Temporal ne se modifie pas : il est redémarré à chaque configuration
+	workflowOptions := client.StartWorkflowOptions{
+		ID:           scheduleWorkflowID(source.ID.String()),
+		TaskQueue:    s.Config.Temporal.TaskQueue,
+		CronSchedule: source.Schedule,
+	}
+	if _, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.SyncGitSourceWorkflow, workflows.SyncGitSourceInput{
+		SourceID: source.ID.String(),
+	}); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start git sync schedule")
+	}
+
+	s.Logger.Infow("git source configured", "project_id", pID, "source_id", source.ID, "url", source.URL, "branch", source.Branch)
+
+	resp := toSourceResponse(source)
+	resp.WebhookSecret = source.WebhookSecret
+	return resp, nil
+}
+
+func (s *GitSyncService) GetSource(ctx context.Context, projectID string) (*domain.GitSourceResponse, error) {
+	source, err := s.getSource(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return toSourceResponse(source), nil
+}
+
+// DeleteSource stops the sync, the resources already applied are left in place.
+func (s *GitSyncService) DeleteSource(ctx context.Context, projectID string) error {
+	source, err := s.getSource(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := s.stopSchedule(ctx, source.ID.String(), "git source deleted"); err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, source.ID); err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to delete git source")
+	}
+	return nil
+}
+
+// TriggerSync applies the manifests of the branch even if they did not change,
+// which also brings back resources edited by hand.
+func (s *GitSyncService) TriggerSync(ctx context.Context, projectID string) (*domain.SyncResponse, error) {
+	source, err := s.getSource(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	workflowID, err := s.startSync(ctx, "git-sync-manual-"+source.ID.String(), source.ID.String(), true)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "a sync is already running for this source")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start git sync")
+	}
+
+	return &domain.SyncResponse{
+		SourceID:   source.ID.String(),
+		WorkflowID: workflowID,
+		Message:    "sync started",
+	}, nil
+}
+
+func (s *GitSyncService) startSync(ctx context.Context, workflowID, sourceID string, force bool) (string, error) {
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       workflowID,
+		TaskQueue:                s.Config.Temporal.TaskQueue,
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.SyncGitSourceWorkflow, workflows.SyncGitSourceInput{
+		SourceID: sourceID,
+		Force:    force,
+	})
+	if err != nil {
+		return "", err
+	}
+	return we.GetID(), nil
+}
+
+func (s *GitSyncService) stopSchedule(ctx context.Context, sourceID, reason string) error {
+	if err := s.TemporalClient.TerminateWorkflow(ctx, scheduleWorkflowID(sourceID), "", reason); err != nil {
+		var notFound *serviceerror.NotFound
+		if !errors.As(err, &notFound) {
+			return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to stop git sync schedule")
+		}
+	}
+	return nil
+}
+
+func (s *GitSyncService) getProjectID(ctx context.Context, projectID string) (uuid.UUID, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return uuid.Nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	if _, err := s.ProjectRepo.GetProjectByID(ctx, projectID); err != nil {
+		return uuid.Nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+	return pID, nil
+}
+
+func (s *GitSyncService) getSource(ctx context.Context, projectID string) (*domain.GitSource, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	source, err := s.Repo.GetByProject(ctx, pID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, betoerrors.New(betoerrors.CodeNotFound, "no git source configured for this project")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to get git source")
+	}
+	return source, nil
+}
+
+func scheduleWorkflowID(sourceID string) string {
+	return "git-sync-" + sourceID
+}
+
+// validateSourceURL keeps the worker from cloning internal services, the git transport of the
+// worker checks the resolved address again.
+func validateSourceURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "url must be an http(s) git repository")
+	}
+	if webhookActivities.IsInternalHost(u.Hostname()) {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "url must not target a private or internal host")
+	}
+	return nil
+}
+
+// cleanSourcePath keeps the path inside the repository, "." is the root.
+func cleanSourcePath(p string) (string, error) {
+	if strings.Contains(p, "..") {
+		return "", betoerrors.New(betoerrors.CodeInvalidInput, "path must stay inside the repository")
+	}
+	dir := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if dir == "" {
+		return ".", nil
+	}
+	return dir, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func toSourceResponse(s *domain.GitSource) *domain.GitSourceResponse {
+	return &domain.GitSourceResponse{
+		ID:             s.ID.String(),
+		ProjectID:      s.ProjectID.String(),
+		URL:            s.URL,
+		Branch:         s.Branch,
+		Path:           s.Path,
+		Schedule:       s.Schedule,
+		Prune:          s.Prune,
+		Status:         s.Status,
+		Phase:          s.CurrentPhase,
+		LastCommit:     s.LastCommit,
+		LastSyncedAt:   s.LastSyncedAt,
+		LastError:      s.LastError,
+		LastWorkflowID: s.LastWorkflowID,
+		WebhookURL:     webhookPath + s.ID.String(),
+	}
+}
+
+// sealToken encrypts the repository token before it is stored.
+func (s *GitSyncService) sealToken(token string) (string, error) {
+	secrets, err := security.SecretBoxFromConfig(s.Config)
+	if err != nil {
+		return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "secret encryption is not configured")
+	}
+	sealed, err := secrets.SealOptional(token)
+	if err != nil {
+		return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to encrypt git token")
+	}
+	return sealed, nil
+}
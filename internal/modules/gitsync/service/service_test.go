@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+)
+
+func TestValidateSourceURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://github.com/acme/infra.git":                 true,
+		"http://git.example.com/acme/infra":                 true,
+		"ssh://git@github.com/acme/infra.git":               false,
+		"file:///var/lib/repos/infra.git":                   false,
+		"/var/lib/repos/infra.git":                          false,
+		"http://169.254.169.254/latest/meta-data":           false,
+		"http://127.0.0.1:8080/infra.git":                   false,
+		"https://[::1]/infra.git":                           false,
+		"http://gitea.forge.svc.cluster.local/infra.git":    false,
+		"http://kubemanager-api:8080/kmanager/v1/git-sync/": false,
+	}
+	for raw, valid := range cases {
+		err := validateSourceURL(raw)
+		if valid && err != nil {
+			t.Errorf("%s: %v", raw, err)
+		}
+		if !valid && !betoerrors.Is(err, betoerrors.CodeInvalidInput) {
+			t.Errorf("%s: accepted", raw)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.temporal.io/api/serviceerror"
+
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/domain"
+)
+
+// header envoyé par GitHub, Gitea et Forgejo : "sha256=<hex>"
+const SignatureHeader = "X-Hub-Signature-256"
+
+// pushEvent is the part of the push payload shared by GitHub, GitLab and Gitea.
+type pushEvent struct {
+	Ref string `json:"ref"`
+}
+
+// HandleWebhook checks the HMAC of the payload with the source secret and starts a sync.
+// Pushes to another branch are acknowledged and ignored.
+func (s *GitSyncService) HandleWebhook(ctx context.Context, sourceID, signature string, payload []byte) (*domain.SyncResponse, error) {
+	id, err := uuid.Parse(sourceID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "git source not found")
+	}
+	source, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "git source not found")
+	}
+
+	if !validSignature(source.WebhookSecret, signature, payload) {
+		s.Logger.Warnw("git webhook rejected: invalid signature", "source_id", sourceID)
+		return nil, betoerrors.New(betoerrors.CodeUnauthorized, "invalid webhook signature")
+	}
+
+	resp := &domain.SyncResponse{SourceID: sourceID}
+
+	var event pushEvent
+	if err := json.Unmarshal(payload, &event); err == nil && event.Ref != "" && event.Ref != "refs/heads/"+source.Branch {
+		resp.Message = "push on " + event.Ref + " ignored, the source tracks " + source.Branch
+		return resp, nil
+	}
+
+	workflowID, err := s.startSync(ctx, "git-sync-webhook-"+sourceID, sourceID, false)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			// la forge ne doit pas réessayer, le prochain cron rattrapera le commit
+			resp.Message = "a sync is already running, the change will be picked up by the next run"
+			return resp, nil
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start git sync")
+	}
+
+	resp.WorkflowID = workflowID
+	resp.Message = "sync started"
+	return resp, nil
+}
+
+func validSignature(secret, signature string, payload []byte) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,95 @@
+package workflows
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/gitsync/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+type SyncGitSourceInput struct {
+	SourceID string `json:"source_id"`
+	Force    bool   `json:"force"` // applique même si les manifestes n'ont pas changé (sync manuelle)
+}
+
+type SyncGitSourceResult struct {
+	Commit          string `json:"commit"`
+	Changed         bool   `json:"changed"`
+	ApplyWorkflowID string `json:"apply_workflow_id,omitempty"`
+}
+
+// SyncGitSourceWorkflow runs on the source cron and on webhooks: it fetches the branch,
+// compares the manifests with the last applied ones and hands them to ApplyManifestWorkflow.
+func SyncGitSourceWorkflow(ctx workflow.Context, input SyncGitSourceInput) (SyncGitSourceResult, error) {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: 5 * time.Second,
+			MaximumAttempts: 3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, options)
+
+	var a *activities.GitSyncActivities
+	var result SyncGitSourceResult
+
+	phase := utils.PhaseGitFetching
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return result, err
+	}
+
+	fail := func(commit string, err error) (SyncGitSourceResult, error) {
+		phase = utils.PhaseGitSyncFailed
+		_ = workflow.ExecuteActivity(ctx, a.RecordSyncResult, input.SourceID, repository.SyncResult{
+			Status: utils.GitSyncFailed,
+			Phase:  phase,
+			Commit: commit,
+			Error:  err.Error(),
+		}).Get(ctx, nil)
+		return result, err
+	}
+
+	var state activities.SourceState
+	if err := workflow.ExecuteActivity(ctx, a.GetSourceState, input.SourceID).Get(ctx, &state); err != nil {
+		return result, err
+	}
+
+	var fetched activities.FetchResult
+	if err := workflow.ExecuteActivity(ctx, a.FetchSource, input.SourceID).Get(ctx, &fetched); err != nil {
+		return fail("", err)
+	}
+	result.Commit = fetched.Commit
+
+	if fetched.Hash == state.ManifestHash && !input.Force {
+		phase = utils.PhaseGitUnchanged
+		err := workflow.ExecuteActivity(ctx, a.RecordSyncResult, input.SourceID, repository.SyncResult{
+			Status: utils.GitSyncSynced,
+			Phase:  phase,
+			Commit: fetched.Commit,
+		}).Get(ctx, nil)
+		return result, err
+	}
+	result.Changed = true
+
+	phase = utils.PhaseGitApplying
+	err := workflow.ExecuteActivity(ctx, a.ApplySourceManifest, state.ProjectID, fetched.Manifest, state.Prune).Get(ctx, &result.ApplyWorkflowID)
+	if err != nil {
+		return fail(fetched.Commit, err)
+	}
+
+	phase = utils.PhaseGitSynced
+	err = workflow.ExecuteActivity(ctx, a.RecordSyncResult, input.SourceID, repository.SyncResult{
+		Status:     utils.GitSyncSynced,
+		Phase:      phase,
+		Commit:     fetched.Commit,
+		Hash:       fetched.Hash,
+		WorkflowID: result.ApplyWorkflowID,
+	}).Get(ctx, nil)
+	return result, err
+}
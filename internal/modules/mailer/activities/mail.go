@@ -0,0 +1,86 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+
+	authdomain "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	authRepo "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/templates"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/transport"
+	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+)
+
+type MailActivities struct {
+	Transport   transport.Transport
+	Config      *configs.GlobalConfig
+	AuthRepo    authRepo.AuthRepository
+	ProjectRepo projectRepo.ProjectRepository
+	Logger      *zap.SugaredLogger
+}
+
+// AlertTarget is who receives the alerts of a project and where its page is.
+type AlertTarget struct {
+	Project    string   `json:"project"`
+	ProjectURL string   `json:"project_url"`
+	Recipients []string `json:"recipients"`
+}
+
+// SendEmail renders and sends one email. A template error or a 5xx reply of the server
+// is not retried, a network error or a 4xx reply is.
+func (a *MailActivities) SendEmail(ctx context.Context, req domain.EmailRequest) error {
+	msg, err := templates.Render(req.Template, req.Data)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), "InvalidEmail", err)
+	}
+	msg.To = req.To
+
+	if err := a.Transport.Send(ctx, msg); err != nil {
+		if transport.IsPermanent(err) {
+			return temporal.NewNonRetryableApplicationError(err.Error(), "EmailRejected", err)
+		}
+		return fmt.Errorf("send %s email: %w", req.Template, err)
+	}
+
+	a.Logger.Infow("email sent", "template", req.Template, "recipients", len(req.To))
+	return nil
+}
+
+// ProjectAlertTarget returns the project owners, who receive the deploy alerts.
+func (a *MailActivities) ProjectAlertTarget(ctx context.Context, projectID string) (*AlertTarget, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid project id", "InvalidProject", err)
+	}
+	project, err := a.ProjectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, temporal.NewNonRetryableApplicationError("project not found", "InvalidProject", err)
+		}
+		return nil, err
+	}
+	members, err := a.AuthRepo.ListProjectMembers(ctx, pID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &AlertTarget{
+		Project:    project.Name,
+		ProjectURL: domain.FrontendLink(a.Config.Frontend.BaseURL, "/projects/"+projectID),
+	}
+	for _, m := range members {
+		if m.Role.Name == authdomain.RoleTypes.Owner.String() && m.User.Email != "" && m.User.IsActive {
+			target.Recipients = append(target.Recipients, m.User.Email)
+		}
+	}
+	return target, nil
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "strings"
+
+// modèles disponibles, un fichier .txt et un fichier .html par modèle dans templates/
+const (
+	TemplatePasswordReset     = "password_reset"
+	TemplateEmailVerification = "email_verification"
+	TemplateProjectInvitation = "project_invitation"
+	TemplateDeployFailed      = "deploy_failed"
+	TemplateAPIKeyExpiring    = "api_key_expiring"
+)
+
+// EmailRequest is the input of the send queue, the message is rendered by the worker
+// so the workflow history only holds the template data.
+type EmailRequest struct {
+	To       []string               `json:"to"`
+	Template string                 `json:"template"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// Message is a rendered email, ready for a transport.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// FrontendLink builds the absolute link of a frontend page put in the emails.
+func FrontendLink(baseURL, path string) string {
+	if baseURL == "" {
+		baseURL = "http://localhost:3000"
+	}
+	return strings.TrimRight(baseURL, "/") + path
+}
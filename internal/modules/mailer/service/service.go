@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"net/mail"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/templates"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/workflows"
+)
+
+type IMailService interface {
+	// Send queues the email and returns once the send workflow is started.
+	Send(ctx context.Context, req domain.EmailRequest) error
+	// Link builds an absolute link to a page of the frontend.
+	Link(path string) string
+}
+
+var _ IMailService = (*MailService)(nil)
+
+type MailService struct {
+	TemporalClient client.Client
+	Config         *configs.GlobalConfig
+	Logger         *zap.SugaredLogger
+}
+
+func NewMailService(tc client.Client, cfg *configs.GlobalConfig, log *zap.SugaredLogger) IMailService {
+	return &MailService{
+		TemporalClient: tc,
+		Config:         cfg,
+		Logger:         log.With("service", "MailService"),
+	}
+}
+
+func (s *MailService) Send(ctx context.Context, req domain.EmailRequest) error {
+	if !templates.Exists(req.Template) {
+		return betoerrors.New(betoerrors.CodeInternal, "unknown email template: "+req.Template)
+	}
+	if len(req.To) == 0 {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "email has no recipient")
+	}
+	for _, to := range req.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "invalid email address: "+to)
+		}
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        "email-" + uuid.NewString(),
+		TaskQueue: s.Config.Temporal.TaskQueue,
+	}
+	if _, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.SendEmailWorkflow, req); err != nil {
+		s.Logger.Errorw("failed to queue email", "template", req.Template, "error", err)
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to queue email")
+	}
+	return nil
+}
+
+func (s *MailService) Link(path string) string {
+	return domain.FrontendLink(s.Config.Frontend.BaseURL, path)
+}
@@ -0,0 +1,50 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+)
+
+//go:embed *.txt *.html
+var files embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(files, "*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(files, "*.html"))
+)
+
+// Render builds the message of a template. The subject is the "<name>.subject" block of the
+// text template, the HTML body is wrapped in the shared layout.
+func Render(name string, data map[string]interface{}) (*domain.Message, error) {
+	if textTemplates.Lookup(name+".txt") == nil || htmlTemplates.Lookup(name+".html") == nil {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return nil, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return nil, fmt.Errorf("render %s html: %w", name, err)
+	}
+
+	return &domain.Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// Exists tells whether a template can be rendered, it is checked before queueing an email.
+func Exists(name string) bool {
+	return textTemplates.Lookup(name+".txt") != nil && htmlTemplates.Lookup(name+".html") != nil
+}
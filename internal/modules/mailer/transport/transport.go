@@ -0,0 +1,181 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+)
+
+// Transport delivers a rendered message. The SMTP transport talks to any server, including
+// a local SMTP stub (MailHog, Mailpit) in tests; other implementations can replace it.
+type Transport interface {
+	Send(ctx context.Context, msg *domain.Message) error
+}
+
+// New returns the SMTP transport, or a transport that only logs the messages when mail is disabled.
+func New(cfg configs.MailConfig, logger *zap.SugaredLogger) Transport {
+	if !cfg.Enabled || cfg.SMTPHost == "" {
+		return &logTransport{logger: logger}
+	}
+	return &smtpTransport{cfg: cfg}
+}
+
+// IsPermanent tells whether the server refused the message for good (5xx reply):
+// retrying it would only produce the same answer.
+func IsPermanent(err error) bool {
+	var tpErr *textproto.Error
+	return errors.As(err, &tpErr) && tpErr.Code >= 500
+}
+
+type logTransport struct {
+	logger *zap.SugaredLogger
+}
+
+func (t *logTransport) Send(_ context.Context, msg *domain.Message) error {
+	// le contenu n'est pas journalisé, il peut contenir un lien de connexion
+	t.logger.Infow("mail disabled, email not sent", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
+
+type smtpTransport struct {
+	cfg configs.MailConfig
+}
+
+// Send opens one connection per message: implicit TLS on port 465, STARTTLS when the server
+// offers it otherwise. Authentication is only attempted when a user is configured.
+func (t *smtpTransport) Send(ctx context.Context, msg *domain.Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("email has no recipient")
+	}
+
+	body, err := t.build(msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(t.cfg.SMTPHost, strconv.Itoa(t.cfg.SMTPPort))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	tlsConfig := &tls.Config{ServerName: t.cfg.SMTPHost}
+
+	var conn net.Conn
+	if t.cfg.SMTPPort == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp dial %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, t.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && t.cfg.SMTPPort != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if t.cfg.SMTPUser != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.cfg.SMTPUser, t.cfg.SMTPPassword, t.cfg.SMTPHost)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(t.cfg.FromEmail); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// build writes a multipart/alternative message, the text part first as RFC 2046 expects.
+func (t *smtpTransport) build(msg *domain.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	from := mail.Address{Name: t.cfg.FromName, Address: t.cfg.FromEmail}
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID(t.cfg.FromEmail),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domainPart := "kubemanager"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domainPart = from[i+1:]
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domainPart + ">"
+}
@@ -0,0 +1,344 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/templates"
+)
+
+// received is a message accepted by the stub.
+type received struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// smtpStub is a minimal SMTP server on the loopback, enough for net/smtp: EHLO, AUTH PLAIN,
+// MAIL, RCPT, DATA and QUIT. rcptReplies forces the reply to a recipient.
+type smtpStub struct {
+	listener    net.Listener
+	user, pass  string
+	rcptReplies map[string]string
+
+	mu       sync.Mutex
+	messages []received
+	authed   bool
+}
+
+// newSMTPStub starts the stub, setup adjusts it before the first connection.
+func newSMTPStub(t *testing.T, setup func(s *smtpStub)) *smtpStub {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpStub{listener: l, rcptReplies: map[string]string{}}
+	if setup != nil {
+		setup(s)
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStub) config() configs.MailConfig {
+	port := s.listener.Addr().(*net.TCPAddr).Port
+	return configs.MailConfig{
+		Enabled:      true,
+		SMTPHost:     "127.0.0.1",
+		SMTPPort:     port,
+		SMTPUser:     s.user,
+		SMTPPassword: s.pass,
+		FromEmail:    "noreply@kubemanager.test",
+		FromName:     "KubeManager",
+	}
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := textproto.NewReader(bufio.NewReader(conn))
+	w := bufio.NewWriter(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\r\n", args...)
+		w.Flush()
+	}
+
+	var current received
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.user != "" {
+				reply("250-stub")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 stub")
+			}
+		case "AUTH":
+			_, encoded, _ := strings.Cut(arg, " ")
+			creds, _ := base64.StdEncoding.DecodeString(encoded)
+			if string(creds) != "\x00"+s.user+"\x00"+s.pass {
+				reply("535 authentication failed")
+				continue
+			}
+			s.mu.Lock()
+			s.authed = true
+			s.mu.Unlock()
+			reply("235 authenticated")
+		case "MAIL":
+			current = received{From: addressOf(arg)}
+			reply("250 ok")
+		case "RCPT":
+			to := addressOf(arg)
+			if code, ok := s.rcptReplies[to]; ok {
+				reply("%s", code)
+				continue
+			}
+			current.To = append(current.To, to)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := r.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "RSET", "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func addressOf(arg string) string {
+	start, end := strings.Index(arg, "<"), strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+func (s *smtpStub) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.messages...)
+}
+
+func renderReset(t *testing.T) *domain.Message {
+	t.Helper()
+	msg, err := templates.Render(domain.TemplatePasswordReset, map[string]interface{}{
+		"Name":      "Zoé",
+		"Link":      "https://app.kubemanager.test/reset-password?token=abc",
+		"ExpiresIn": "1 heure",
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return msg
+}
+
+// parts returns the decoded bodies of the multipart message, by content type.
+func parts(t *testing.T, msg *mail.Message) map[string]string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	out := make(map[string]string)
+	var order []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		body, err := io.ReadAll(p) // le quoted-printable est décodé par multipart
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		out[ct] = string(body)
+		order = append(order, ct)
+	}
+	if len(order) != 2 || order[0] != "text/plain" || order[1] != "text/html" {
+		t.Errorf("parts = %v, want text/plain then text/html", order)
+	}
+	return out
+}
+
+func TestSMTPTransportDeliversToTheServer(t *testing.T) {
+	stub := newSMTPStub(t, nil)
+	msg := renderReset(t)
+	msg.To = []string{"zoe@example.com", "ops@example.com"}
+
+	if err := New(stub.config(), zap.NewNop().Sugar()).Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := stub.received()
+	if len(got) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(got))
+	}
+	if got[0].From != "noreply@kubemanager.test" {
+		t.Errorf("MAIL FROM = %q", got[0].From)
+	}
+	if strings.Join(got[0].To, ",") != "zoe@example.com,ops@example.com" {
+		t.Errorf("RCPT TO = %v", got[0].To)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(got[0].Data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Réinitialisation de votre mot de passe" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if from := parsed.Header.Get("From"); !strings.Contains(from, "<noreply@kubemanager.test>") {
+		t.Errorf("From header = %q", from)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@kubemanager.test>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	bodies := parts(t, parsed)
+	for _, ct := range []string{"text/plain", "text/html"} {
+		if !strings.Contains(bodies[ct], "Zoé") || !strings.Contains(bodies[ct], "reset-password?token=abc") {
+			t.Errorf("%s part misses the name or the link:\n%s", ct, bodies[ct])
+		}
+	}
+}
+
+func TestSMTPTransportAuthenticates(t *testing.T) {
+	stub := newSMTPStub(t, func(s *smtpStub) {
+		s.user, s.pass = "mailer", "s3cret"
+	})
+	msg := renderReset(t)
+	msg.To = []string{"zoe@example.com"}
+
+	if err := New(stub.config(), zap.NewNop().Sugar()).Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	stub.mu.Lock()
+	authed := stub.authed
+	stub.mu.Unlock()
+	if !authed || len(stub.received()) != 1 {
+		t.Errorf("authenticated = %v, messages = %d", authed, len(stub.received()))
+	}
+
+	cfg := stub.config()
+	cfg.SMTPPassword = "wrong"
+	err := New(cfg, zap.NewNop().Sugar()).Send(context.Background(), msg)
+	if err == nil || !IsPermanent(err) {
+		t.Errorf("wrong password: err = %v, want a permanent error", err)
+	}
+}
+
+func TestSMTPTransportReplyClasses(t *testing.T) {
+	stub := newSMTPStub(t, func(s *smtpStub) {
+		s.rcptReplies["unknown@example.com"] = "550 no such user"
+		s.rcptReplies["busy@example.com"] = "451 try again later"
+	})
+	transport := New(stub.config(), zap.NewNop().Sugar())
+
+	cases := []struct {
+		to        string
+		permanent bool
+	}{
+		{"unknown@example.com", true},
+		{"busy@example.com", false},
+	}
+	for _, tc := range cases {
+		msg := renderReset(t)
+		msg.To = []string{tc.to}
+		err := transport.Send(context.Background(), msg)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tc.to)
+		}
+		if IsPermanent(err) != tc.permanent {
+			t.Errorf("%s: IsPermanent = %v, want %v (%v)", tc.to, IsPermanent(err), tc.permanent, err)
+		}
+	}
+	if n := len(stub.received()); n != 0 {
+		t.Errorf("server accepted %d messages, want none", n)
+	}
+}
+
+func TestSMTPTransportUnreachableServerIsRetried(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	cfg := configs.MailConfig{Enabled: true, SMTPHost: "127.0.0.1", SMTPPort: port, FromEmail: "noreply@kubemanager.test"}
+	err = New(cfg, zap.NewNop().Sugar()).Send(context.Background(), &domain.Message{To: []string{"zoe@example.com"}})
+	if err == nil || IsPermanent(err) {
+		t.Errorf("err = %v, want a temporary error", err)
+	}
+	if !strings.Contains(err.Error(), "127.0.0.1:"+strconv.Itoa(port)) {
+		t.Errorf("err = %v, want the server address", err)
+	}
+}
+
+func TestNewWithoutServerOnlyLogs(t *testing.T) {
+	for _, cfg := range []configs.MailConfig{
+		{Enabled: false, SMTPHost: "smtp.example.com"},
+		{Enabled: true},
+	} {
+		tr := New(cfg, zap.NewNop().Sugar())
+		if _, ok := tr.(*logTransport); !ok {
+			t.Errorf("New(%+v) = %T, want the log transport", cfg, tr)
+		}
+		if err := tr.Send(context.Background(), &domain.Message{To: []string{"zoe@example.com"}}); err != nil {
+			t.Errorf("log transport: %v", err)
+		}
+	}
+}
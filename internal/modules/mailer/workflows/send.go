@@ -0,0 +1,71 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/mailer/domain"
+)
+
+// SendEmailWorkflow is one entry of the send queue: the email is retried with an exponential
+// backoff for about a day while the SMTP server is unreachable.
+func SendEmailWorkflow(ctx workflow.Context, req domain.EmailRequest) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    30 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    2 * time.Hour,
+			MaximumAttempts:    15,
+		},
+	})
+
+	var a *activities.MailActivities
+	return workflow.ExecuteActivity(ctx, a.SendEmail, req).Get(ctx, nil)
+}
+
+// NotifyDeployFailure queues an alert for the project owners from a workflow. The email is
+// sent by a child workflow abandoned by its parent, a slow SMTP server never delays the caller
+// and a failed lookup of the recipients is only logged.
+func NotifyDeployFailure(ctx workflow.Context, projectID, workloadID string, data map[string]interface{}) {
+	logger := workflow.GetLogger(ctx)
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	var a *activities.MailActivities
+	var target activities.AlertTarget
+	if err := workflow.ExecuteActivity(actCtx, a.ProjectAlertTarget, projectID).Get(ctx, &target); err != nil {
+		logger.Warn("failed to resolve deploy alert recipients", "project_id", projectID, "error", err)
+		return
+	}
+	if len(target.Recipients) == 0 {
+		return
+	}
+	data["Project"] = target.Project
+	data["Link"] = target.ProjectURL + "/workloads/" + workloadID
+
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:        fmt.Sprintf("email-%s-deploy-failed", workflow.GetInfo(ctx).WorkflowExecution.RunID),
+		ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
+	})
+	child := workflow.ExecuteChildWorkflow(childCtx, SendEmailWorkflow, domain.EmailRequest{
+		To:       target.Recipients,
+		Template: domain.TemplateDeployFailed,
+		Data:     data,
+	})
+	// le parent peut se terminer dès que l'enfant a démarré
+	if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+		logger.Warn("failed to queue deploy alert", "project_id", projectID, "error", err)
+	}
+}
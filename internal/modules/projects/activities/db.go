@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"go.temporal.io/sdk/activity"
 	"go.uber.org/zap"
 
 	dauth "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
@@ -16,9 +17,17 @@ type ProjectDBActivities struct {
 }
 
 func (a *ProjectDBActivities) CreateProjectInDB(ctx context.Context, name string, description string, ownerID string) (*dauth.Project, error) {
+	workflowID := activity.GetInfo(ctx).WorkflowExecution.ID
+
+	// a retried run of the same workflow finds the project created by the previous run
+	if existing, err := a.Repo.GetProjectByName(ctx, name); err == nil && existing.LastWorkflowID == workflowID {
+		return existing, nil
+	}
+
 	project := &dauth.Project{
-		Name:        name,
-		Description: description,
+		Name:           name,
+		Description:    description,
+		LastWorkflowID: workflowID,
 	}
 
 	err := a.Repo.CreateProject(ctx, project, ownerID)
@@ -28,6 +37,13 @@ func (a *ProjectDBActivities) CreateProjectInDB(ctx context.Context, name string
 	return project, nil
 }
 
+func (a *ProjectDBActivities) UpdateProjectStatus(ctx context.Context, projectID string, status, phase string) error {
+	if err := a.Repo.UpdateStatus(ctx, projectID, status, phase); err != nil {
+		return fmt.Errorf("failed to update project status: %w", err)
+	}
+	return nil
+}
+
 func (a *ProjectDBActivities) DeleteProjectDBActivity(ctx context.Context, projectID string) error {
 	a.Logger.Warnw("Rolling back project creation in DB", "projectID", projectID)
 	err := a.Repo.DeleteProject(ctx, projectID)
@@ -11,6 +11,7 @@ import (
 	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -42,7 +43,7 @@ func (a *ProjectK8sActivities) CreateNamespace(ctx context.Context, projectID st
 	}
 
 	_, err := a.K8sClient.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
-	if err != nil {
+	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("k8s error: %w", err)
 	}
 	return nil
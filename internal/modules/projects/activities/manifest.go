@@ -0,0 +1,211 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	authdomain "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	authSvc "github.com/thekrauss/kubemanager/internal/modules/auth/service"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	workloadSvc "github.com/thekrauss/kubemanager/internal/modules/workloads/service"
+)
+
+// ManifestActivities converge a project on its manifest. Workloads go through the
+// WorkloadService so that quotas and validation are the same as the REST API.
+type ManifestActivities struct {
+	K8sClient         *kubernetes.Clientset
+	Logger            *zap.SugaredLogger
+	Rbac              authSvc.IRBACService
+	ProjectRepo       repository.ProjectRepository
+	NetworkPolicyRepo repository.NetworkPolicyRepository
+	Workloads         *workloadSvc.WorkloadService
+}
+
+func (a *ManifestActivities) UpdateProjectQuotas(ctx context.Context, projectID string, quotas domain.ManifestQuotas) error {
+	if err := a.ProjectRepo.UpdateQuotas(ctx, projectID, quotas.CPU, quotas.Memory, quotas.Storage); err != nil {
+		return fmt.Errorf("failed to update project quotas: %w", err)
+	}
+	return nil
+}
+
+// CreateManifestWorkload is idempotent on the workload name, a retried activity
+// does not deploy the same workload twice.
+func (a *ManifestActivities) CreateManifestWorkload(ctx context.Context, spec workloadDomain.CreateWorkloadRequest) (string, error) {
+	pID, err := uuid.Parse(spec.ProjectID)
+	if err != nil {
+		return "", temporal.NewNonRetryableApplicationError("invalid project id", "ManifestRejected", err)
+	}
+
+	existing, err := a.Workloads.Repo.ListByProject(ctx, pID)
+	if err != nil {
+		return "", err
+	}
+	for _, w := range existing {
+		if w.Name == spec.Name {
+			return w.ID.String(), nil
+		}
+	}
+
+	workload, err := a.Workloads.DeployNewWorkload(ctx, &workloadSvc.WorkloadServiceRequest{CreateWorkloadRequest: spec})
+	if err != nil {
+		return "", manifestError(err)
+	}
+	return workload.ID.String(), nil
+}
+
+// UpdateManifestWorkload names the deploy after the run of the apply workflow, a retried
+// activity does not redeploy the workload twice.
+func (a *ManifestActivities) UpdateManifestWorkload(ctx context.Context, workloadID string, spec workloadDomain.CreateWorkloadRequest) error {
+	workflowID := "workload-update-" + workloadID + "-" + activity.GetInfo(ctx).WorkflowExecution.RunID
+	if _, _, err := a.Workloads.ApplyWorkloadSpec(ctx, workloadID, &spec, workflowID); err != nil {
+		return manifestError(err)
+	}
+	return nil
+}
+
+func (a *ManifestActivities) DeleteManifestWorkload(ctx context.Context, workloadID string) error {
+	if _, _, err := a.Workloads.DeleteWorkload(ctx, workloadID); err != nil {
+		// déjà supprimé ou en cours de suppression
+		if betoerrors.Is(err, betoerrors.CodeNotFound) || betoerrors.Is(err, betoerrors.CodeConflict) {
+			return nil
+		}
+		return manifestError(err)
+	}
+	return nil
+}
+
+func (a *ManifestActivities) ApplyNetworkPolicy(ctx context.Context, projectID, nsName string, policy domain.ManifestNetworkPolicy) error {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid project id", "ManifestRejected", err)
+	}
+
+	desired := buildNetworkPolicy(nsName, policy)
+	client := a.K8sClient.NetworkingV1().NetworkPolicies(nsName)
+
+	current, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("k8s error: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("k8s error: %w", err)
+	default:
+		current.Labels = desired.Labels
+		current.Spec = desired.Spec
+		if _, err := client.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("k8s error: %w", err)
+		}
+	}
+
+	return a.NetworkPolicyRepo.Upsert(ctx, &domain.NetworkPolicy{
+		ID:        uuid.New(),
+		ProjectID: pID,
+		Name:      policy.Name,
+		Workloads: policy.Workloads,
+		AllowFrom: policy.AllowFrom,
+		Ports:     policy.Ports,
+	})
+}
+
+func (a *ManifestActivities) DeleteNetworkPolicy(ctx context.Context, projectID, nsName, name string) error {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid project id", "ManifestRejected", err)
+	}
+
+	err = a.K8sClient.NetworkingV1().NetworkPolicies(nsName).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("k8s error: %w", err)
+	}
+	return a.NetworkPolicyRepo.Delete(ctx, pID, name)
+}
+
+func (a *ManifestActivities) SetProjectMember(ctx context.Context, projectID, userID, role string) error {
+	err := a.Rbac.AssignProjectRole(ctx, &authdomain.AssignRoleRequest{
+		ProjectID: projectID,
+		UserID:    userID,
+		RoleName:  role,
+	})
+	if err != nil {
+		return manifestError(err)
+	}
+	return nil
+}
+
+func (a *ManifestActivities) RemoveProjectMember(ctx context.Context, projectID, userID string) error {
+	if err := a.Rbac.RevokeProjectAccess(ctx, projectID, userID); err != nil {
+		if betoerrors.Is(err, betoerrors.CodeNotFound) {
+			return nil
+		}
+		return manifestError(err)
+	}
+	return nil
+}
+
+// buildNetworkPolicy selects the pods through the "app: <release>" label set by the charts.
+func buildNetworkPolicy(nsName string, policy domain.ManifestNetworkPolicy) *networkingv1.NetworkPolicy {
+	rule := networkingv1.NetworkPolicyIngressRule{
+		From: []networkingv1.NetworkPolicyPeer{{PodSelector: appSelector(policy.AllowFrom)}},
+	}
+	tcp := corev1.ProtocolTCP
+	for _, port := range policy.Ports {
+		p := intstr.FromInt32(int32(port))
+		rule.Ports = append(rule.Ports, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &p})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policy.Name,
+			Namespace: nsName,
+			Labels: map[string]string{
+				"kubemanager.io/managed":  "true",
+				"kubemanager.io/manifest": "true",
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *appSelector(policy.Workloads),
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{rule},
+		},
+	}
+}
+
+// appSelector matches every pod of the namespace when no workload is given.
+func appSelector(workloads []string) *metav1.LabelSelector {
+	if len(workloads) == 0 {
+		return &metav1.LabelSelector{}
+	}
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      "app",
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   workloads,
+		}},
+	}
+}
+
+// manifestError stops the retries on the errors a new attempt cannot fix.
+func manifestError(err error) error {
+	for _, code := range []string{betoerrors.CodeInvalidInput, betoerrors.CodeConflict, betoerrors.CodeNotFound, betoerrors.CodeForbidden} {
+		if betoerrors.Is(err, code) {
+			return temporal.NewNonRetryableApplicationError(err.Error(), "ManifestRejected", err)
+		}
+	}
+	return err
+}
@@ -17,6 +17,17 @@ type ProjectResponse struct {
 	Message    string `json:"message"`
 }
 
+// MFAPolicyRequest toggles the MFA requirement for owners and shell-enabled members.
+type MFAPolicyRequest struct {
+	ProjectID  string `path:"id" json:"-"`
+	RequireMFA bool   `json:"require_mfa"`
+}
+
+type MFAPolicyResponse struct {
+	ProjectID  string `json:"project_id"`
+	RequireMFA bool   `json:"require_mfa"`
+}
+
 type ProjectStatusResponse struct {
 	ProjectID string           `json:"project_id"`
 	Status    string           `json:"status"`
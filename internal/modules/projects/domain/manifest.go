@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+)
+
+// ProjectManifest is the desired state of a project, sent as YAML or JSON.
+// A section left out of the manifest is not managed: its resources are neither planned nor pruned.
+// No omitempty on the lists, an empty list is a managed section and must survive a round trip.
+type ProjectManifest struct {
+	Quotas          *ManifestQuotas                        `json:"quotas"`
+	Members         []ManifestMember                       `json:"members"`
+	Workloads       []workloadDomain.CreateWorkloadRequest `json:"workloads" desc:"Même format que POST /workloads, sans project_id ni secret_data"`
+	NetworkPolicies []ManifestNetworkPolicy                `json:"network_policies"`
+}
+
+type ManifestQuotas struct {
+	CPU     string `json:"cpu" desc:"ex: 2000m"`
+	Memory  string `json:"memory" desc:"ex: 4Gi"`
+	Storage string `json:"storage" desc:"ex: 10Gi"`
+}
+
+type ManifestMember struct {
+	Email string `json:"email"`
+	Role  string `json:"role" desc:"OWNER, DEVELOPER ou VIEWER"`
+}
+
+// ManifestNetworkPolicy opens ingress traffic to the selected workloads of the project.
+type ManifestNetworkPolicy struct {
+	Name      string   `json:"name"`
+	Workloads []string `json:"workloads" desc:"Workloads ciblés, vide = tous les pods du projet"`
+	AllowFrom []string `json:"allow_from" desc:"Workloads autorisés en entrée, vide = tout le namespace"`
+	Ports     []int    `json:"ports" desc:"Ports TCP ouverts, vide = tous"`
+}
+
+// NetworkPolicy is the DB copy of a network policy applied from a manifest.
+type NetworkPolicy struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_network_policy_name;not null"`
+	Name      string    `gorm:"type:varchar(63);uniqueIndex:idx_network_policy_name;not null"`
+
+	Workloads []string `gorm:"type:jsonb;serializer:json"`
+	AllowFrom []string `gorm:"type:jsonb;serializer:json"`
+	Ports     []int    `gorm:"type:jsonb;serializer:json"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type PlanChange struct {
+	Resource string        `json:"resource"` // quotas | member | workload | network_policy
+	Name     string        `json:"name"`
+	Action   string        `json:"action"` // create | update | delete | orphan
+	Fields   []FieldChange `json:"fields,omitempty"`
+}
+
+type ManifestPlanResponse struct {
+	ProjectID  string       `json:"project_id"`
+	DryRun     bool         `json:"dry_run"`
+	Prune      bool         `json:"prune"`
+	Changes    []PlanChange `json:"changes"`
+	Warnings   []string     `json:"warnings,omitempty"`
+	WorkflowID string       `json:"workflow_id,omitempty"`
+	Message    string       `json:"message"`
+}
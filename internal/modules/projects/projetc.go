@@ -1,8 +1,15 @@
 package projects
 
 import (
+	"io"
+	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
 
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
 	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/projects/service"
 )
@@ -12,17 +19,27 @@ type IProjectController interface {
 	GetProjectStatus(c *gin.Context, in *GetProjectStatusRequest) (*domain.ProjectStatusResponse, error)
 	DeleteProject(c *gin.Context, in *GetProjectStatusRequest) (*domain.ProjectResponse, error)
 	GetProjectMetrics(c *gin.Context, in *GetProjectStatusRequest) (*domain.NamespaceMetrics, error)
+	RetryProject(c *gin.Context, in *GetProjectStatusRequest) (*domain.ProjectResponse, error)
+	ApplyManifest(c *gin.Context) (*domain.ManifestPlanResponse, error)
+	SetMFAPolicy(c *gin.Context, in *domain.MFAPolicyRequest) (*domain.MFAPolicyResponse, error)
 }
 
 type ProjectHandler struct {
-	ProjectService service.IProjectService
+	ProjectService  service.IProjectService
+	ManifestService service.IManifestService
 }
 
-func NewProjectHandlers(ps service.IProjectService) *ProjectHandler {
-	return &ProjectHandler{ProjectService: ps}
+func NewProjectHandlers(ps service.IProjectService, ms service.IManifestService) *ProjectHandler {
+	return &ProjectHandler{ProjectService: ps, ManifestService: ms}
 }
 
+// CreateProject refuses API keys: a key is limited to existing projects, the project it would
+// create would be owned by its user without any of these limits.
 func (h *ProjectHandler) CreateProject(c *gin.Context, in *domain.CreateProjectRequest) (*domain.ProjectResponse, error) {
+	val, _ := c.Get(security.UserSessionKey)
+	if session, ok := val.(*cache.SessionData); !ok || session.APIKey != nil {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "projects cannot be created with an api key, sign in instead")
+	}
 	userID, _ := c.Get("user_id")
 	return h.ProjectService.CreateProject(c.Request.Context(), *in, userID.(string))
 }
@@ -42,3 +59,56 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context, in *GetProjectStatusReque
 func (h *ProjectHandler) GetProjectMetrics(c *gin.Context, in *GetProjectStatusRequest) (*domain.NamespaceMetrics, error) {
 	return h.ProjectService.GetMetrics(c.Request.Context(), in.ProjectID)
 }
+
+func (h *ProjectHandler) RetryProject(c *gin.Context, in *GetProjectStatusRequest) (*domain.ProjectResponse, error) {
+	return h.ProjectService.RetryProject(c.Request.Context(), in.ProjectID)
+}
+
+func (h *ProjectHandler) SetMFAPolicy(c *gin.Context, in *domain.MFAPolicyRequest) (*domain.MFAPolicyResponse, error) {
+	return h.ProjectService.SetMFAPolicy(c.Request.Context(), in.ProjectID, in.RequireMFA)
+}
+
+// limite du corps d'un manifeste
+const maxManifestSize = 1 << 20
+
+// ApplyManifestRequest documents the route, the body is read by hand since it can be YAML.
+type ApplyManifestRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	Apply     bool   `query:"apply" desc:"Appliquer le plan au lieu d'un simple dry-run"`
+	Prune     bool   `query:"prune" desc:"Supprimer les ressources absentes du manifeste"`
+	domain.ProjectManifest
+}
+
+func (h *ProjectHandler) ApplyManifest(c *gin.Context) (*domain.ManifestPlanResponse, error) {
+	apply, err := queryBool(c, "apply")
+	if err != nil {
+		return nil, err
+	}
+	prune, err := queryBool(c, "prune")
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestSize))
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "manifest is too large or unreadable")
+	}
+
+	access := security.ProjectAccessFrom(c)
+	if access == nil {
+		return nil, betoerrors.New(betoerrors.CodeForbidden, "forbidden")
+	}
+	return h.ManifestService.ApplyManifest(c.Request.Context(), c.Param("id"), raw, apply, prune, access.Allows)
+}
+
+func queryBool(c *gin.Context, key string) (bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, betoerrors.New(betoerrors.CodeInvalidInput, "invalid "+key+" query parameter")
+	}
+	return b, nil
+}
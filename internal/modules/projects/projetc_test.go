@@ -0,0 +1,45 @@
+package projects
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/service"
+)
+
+type createOnlyService struct {
+	service.IProjectService
+	created bool
+}
+
+func (s *createOnlyService) CreateProject(_ context.Context, _ domain.CreateProjectRequest, _ string) (*domain.ProjectResponse, error) {
+	s.created = true
+	return &domain.ProjectResponse{}, nil
+}
+
+func TestCreateProjectRefusesAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, session := range map[string]*cache.SessionData{
+		"api key":        {UserID: "u1", APIKey: &cache.APIKeyScope{KeyID: "k1"}},
+		"signed-in user": {UserID: "u1"},
+	} {
+		svc := &createOnlyService{}
+		h := NewProjectHandlers(svc, nil)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/projects", nil)
+		c.Set(security.UserSessionKey, session)
+		c.Set("user_id", session.UserID)
+
+		_, err := h.CreateProject(c, &domain.CreateProjectRequest{Name: "shop"})
+		if wantRefused := session.APIKey != nil; wantRefused != betoerrors.Is(err, betoerrors.CodeForbidden) || wantRefused == svc.created {
+			t.Errorf("%s: err = %v, created = %v", name, err, svc.created)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+)
+
+type NetworkPolicyRepository interface {
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.NetworkPolicy, error)
+	Upsert(ctx context.Context, policy *domain.NetworkPolicy) error
+	Delete(ctx context.Context, projectID uuid.UUID, name string) error
+}
+
+type networkPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewNetworkPolicyRepository(db *gorm.DB) NetworkPolicyRepository {
+	return &networkPolicyRepository{db: db}
+}
+
+func (r *networkPolicyRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.NetworkPolicy, error) {
+	var policies []domain.NetworkPolicy
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("name").Find(&policies).Error
+	return policies, err
+}
+
+func (r *networkPolicyRepository) Upsert(ctx context.Context, policy *domain.NetworkPolicy) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"workloads", "allow_from", "ports", "updated_at"}),
+	}).Create(policy).Error
+}
+
+func (r *networkPolicyRepository) Delete(ctx context.Context, projectID uuid.UUID, name string) error {
+	return r.db.WithContext(ctx).
+		Where("project_id = ? AND name = ?", projectID, name).
+		Delete(&domain.NetworkPolicy{}).Error
+}
@@ -6,14 +6,21 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/thekrauss/kubemanager/internal/core/events"
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	dauth "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
 )
 
 type ProjectRepository interface {
 	CreateProject(ctx context.Context, project *dauth.Project, ownerID string) error
 	DeleteProject(ctx context.Context, projectID string) error
 	GetProjectByID(ctx context.Context, id string) (*dauth.Project, error)
+	GetProjectByName(ctx context.Context, name string) (*dauth.Project, error)
+	GetProjectByWorkflowID(ctx context.Context, workflowID string) (*dauth.Project, error)
+	UpdateQuotas(ctx context.Context, projectID string, cpu, memory, storage string) error
+	UpdateMFAPolicy(ctx context.Context, projectID string, required bool) error
+	UpdateStatus(ctx context.Context, projectID string, status, phase string) error
 }
 
 type pgProjectRepo struct {
@@ -40,12 +47,39 @@ func (r *pgProjectRepo) CreateProject(ctx context.Context, project *dauth.Projec
 			UserID:    uuid.MustParse(ownerID),
 			RoleID:    role.ID,
 		}
-		return tx.Create(member).Error
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+
+		return events.Record(tx, events.New(utils.EventProjectCreated, project.ID.String(), map[string]interface{}{
+			"name":     project.Name,
+			"owner_id": ownerID,
+		}))
 	})
 }
 
+// DeleteProject removes the project with its service accounts and their API keys, the other
+// memberships go with the project through the foreign key cascade.
 func (r *pgProjectRepo) DeleteProject(ctx context.Context, projectID string) error {
-	return r.db.WithContext(ctx).Unscoped().Where("id = ?", projectID).Delete(&domain.Project{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		accounts := tx.Model(&dauth.User{}).Select("id").Where("service_account_project_id = ?", projectID)
+		if err := tx.Where("user_id IN (?)", accounts).Delete(&dauth.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN (?)", accounts).Delete(&dauth.ProjectMember{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Unscoped().Where("id = ?", projectID).Delete(&domain.Project{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		if err := tx.Where("service_account_project_id = ?", projectID).Delete(&dauth.User{}).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, events.New(utils.EventProjectDeleted, projectID, nil))
+	})
 }
 
 func (r *pgProjectRepo) GetProjectByID(ctx context.Context, id string) (*dauth.Project, error) {
@@ -61,3 +95,52 @@ func (r *pgProjectRepo) GetProjectByID(ctx context.Context, id string) (*dauth.P
 	}
 	return &project, nil
 }
+
+func (r *pgProjectRepo) GetProjectByName(ctx context.Context, name string) (*dauth.Project, error) {
+	var project dauth.Project
+
+	err := r.db.WithContext(ctx).
+		Where("name = ?", name).
+		First(&project).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *pgProjectRepo) GetProjectByWorkflowID(ctx context.Context, workflowID string) (*dauth.Project, error) {
+	var project dauth.Project
+	err := r.db.WithContext(ctx).
+		Where("last_workflow_id = ?", workflowID).
+		First(&project).Error
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *pgProjectRepo) UpdateQuotas(ctx context.Context, projectID string, cpu, memory, storage string) error {
+	return r.db.WithContext(ctx).Model(&dauth.Project{}).
+		Where("id = ?", projectID).
+		Updates(map[string]interface{}{
+			"cpu_limit":     cpu,
+			"memory_limit":  memory,
+			"storage_limit": storage,
+		}).Error
+}
+
+func (r *pgProjectRepo) UpdateMFAPolicy(ctx context.Context, projectID string, required bool) error {
+	return r.db.WithContext(ctx).Model(&dauth.Project{}).
+		Where("id = ?", projectID).
+		Update("require_mfa", required).Error
+}
+
+func (r *pgProjectRepo) UpdateStatus(ctx context.Context, projectID string, status, phase string) error {
+	return r.db.WithContext(ctx).Model(&dauth.Project{}).
+		Where("id = ?", projectID).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"current_phase": phase,
+		}).Error
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
 	"go.uber.org/zap"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -23,6 +27,8 @@ type IProjectService interface {
 	DeleteProject(ctx context.Context, projectID string) (*domain.ProjectResponse, error)
 	GetProjectStatus(ctx context.Context, projectID string) (*domain.ProjectStatusResponse, error)
 	GetMetrics(ctx context.Context, projectID string) (*domain.NamespaceMetrics, error)
+	RetryProject(ctx context.Context, projectID string) (*domain.ProjectResponse, error)
+	SetMFAPolicy(ctx context.Context, projectID string, required bool) (*domain.MFAPolicyResponse, error)
 }
 
 var _ IProjectService = (*ProjectService)(nil)
@@ -55,10 +61,7 @@ func NewProjectService(
 func (s *ProjectService) CreateProject(ctx context.Context, req domain.CreateProjectRequest, ownerID string) (*domain.ProjectResponse, error) {
 	workflowID := "project-create-" + uuid.New().String()
 
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        workflowID,
-		TaskQueue: s.Config.Temporal.TaskQueue,
-	}
+	workflowOptions := s.createWorkflowOptions(workflowID)
 
 	input := workflows.CreateProjectInput{
 		Name:        req.Name,
@@ -107,3 +110,60 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID string) (*
 		Message:    "The project deletion and cleanup has been initiated.",
 	}, nil
 }
+
+// createWorkflowOptions guards a project against concurrent provisioning runs: the ID can only
+// be reused once the previous run failed, was canceled or terminated.
+func (s *ProjectService) createWorkflowOptions(workflowID string) client.StartWorkflowOptions {
+	return client.StartWorkflowOptions{
+		ID:                       workflowID,
+		TaskQueue:                s.Config.Temporal.TaskQueue,
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+}
+
+func (s *ProjectService) RetryProject(ctx context.Context, projectID string) (*domain.ProjectResponse, error) {
+	project, err := s.Repos.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+	if project.LastWorkflowID == "" {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "no provisioning workflow recorded for this project")
+	}
+
+	var input workflows.CreateProjectInput
+	if err := utils.LoadWorkflowInput(ctx, s.TemporalClient, project.LastWorkflowID, &input); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeNotFound, "last workflow input not found")
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, s.createWorkflowOptions(project.LastWorkflowID), workflows.CreateProjectWorkflow, input)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "a provisioning workflow is already running or has completed for this project")
+		}
+		s.Logger.Errorw("Failed to restart project workflow", "projectID", projectID, "error", err)
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to restart workflow")
+	}
+
+	return &domain.ProjectResponse{
+		ProjectID:  projectID,
+		WorkflowID: we.GetID(),
+		Status:     utils.ProjectStatusProvisioning,
+		Message:    "the project provisioning has been restarted.",
+	}, nil
+}
+
+// SetMFAPolicy turns on or off the MFA requirement for owners and shell-enabled members.
+func (s *ProjectService) SetMFAPolicy(ctx context.Context, projectID string, required bool) (*domain.MFAPolicyResponse, error) {
+	if _, err := s.Repos.GetProjectByID(ctx, projectID); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+
+	if err := s.Repos.UpdateMFAPolicy(ctx, projectID, required); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to update mfa policy")
+	}
+
+	s.Logger.Infow("Project MFA policy updated", "projectID", projectID, "requireMFA", required)
+	return &domain.MFAPolicyResponse{ProjectID: projectID, RequireMFA: required}, nil
+}
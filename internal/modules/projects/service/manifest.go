@@ -0,0 +1,181 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	addonRepo "github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+	dauth "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	authRepo "github.com/thekrauss/kubemanager/internal/modules/auth/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/workflows"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadRepo "github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+)
+
+type IManifestService interface {
+	ApplyManifest(ctx context.Context, projectID string, raw []byte, apply, prune bool, can PermissionCheck) (*domain.ManifestPlanResponse, error)
+	MemberPermissions(ctx context.Context, projectID, userID uuid.UUID) (PermissionCheck, error)
+}
+
+// PermissionCheck tells whether the caller holds a permission on the project. The route only
+// requires project:edit, the sections of a manifest may need more.
+type PermissionCheck func(perm dauth.PermissionType) bool
+
+var _ IManifestService = (*ManifestService)(nil)
+
+type ManifestService struct {
+	TemporalClient    client.Client
+	Config            *configs.GlobalConfig
+	Logger            *zap.SugaredLogger
+	ProjectRepo       repository.ProjectRepository
+	NetworkPolicyRepo repository.NetworkPolicyRepository
+	AuthRepo          authRepo.AuthRepository
+	WorkloadRepo      workloadRepo.WorkloadRepository
+	AddonRepo         addonRepo.AddonRepository
+}
+
+func NewManifestService(
+	tc client.Client,
+	cfg *configs.GlobalConfig,
+	log *zap.SugaredLogger,
+	pRepo repository.ProjectRepository,
+	npRepo repository.NetworkPolicyRepository,
+	aRepo authRepo.AuthRepository,
+	wRepo workloadRepo.WorkloadRepository,
+	addRepo addonRepo.AddonRepository,
+) IManifestService {
+	return &ManifestService{
+		TemporalClient:    tc,
+		Config:            cfg,
+		Logger:            log.With("service", "ManifestService"),
+		ProjectRepo:       pRepo,
+		NetworkPolicyRepo: npRepo,
+		AuthRepo:          aRepo,
+		WorkloadRepo:      wRepo,
+		AddonRepo:         addRepo,
+	}
+}
+
+// ApplyManifest always computes the plan against the DB. Without apply it is a dry-run,
+// otherwise the plan is handed to ApplyManifestWorkflow. Orphans are only deleted with prune.
+// The whole plan, dry-run included, is rejected if can lacks a permission one of its sections needs.
+func (s *ManifestService) ApplyManifest(ctx context.Context, projectID string, raw []byte, apply, prune bool, can PermissionCheck) (*domain.ManifestPlanResponse, error) {
+	if _, err := uuid.Parse(projectID); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	project, err := s.ProjectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+
+	manifest, err := DecodeManifest(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.buildPlan(ctx, project, manifest, prune)
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.checkPermissions(manifest, can); err != nil {
+		return nil, err
+	}
+	plan.resp.DryRun = !apply
+
+	if !apply {
+		plan.resp.Message = "dry-run, nothing was applied: use ?apply=true to converge the project"
+		return plan.resp, nil
+	}
+	if !plan.hasOperations() {
+		plan.resp.Message = "the project already matches the manifest"
+		return plan.resp, nil
+	}
+	if project.Status != utils.ProjectStatusReady {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "the project must be READY to apply a manifest")
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       "project-apply-" + projectID,
+		TaskQueue:                s.Config.Temporal.TaskQueue,
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.ApplyManifestWorkflow, plan.input)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "a manifest is already being applied to this project")
+		}
+		s.Logger.Errorw("Failed to start apply workflow", "projectID", projectID, "error", err)
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start apply workflow")
+	}
+
+	s.Logger.Infow("manifest apply started", "projectID", projectID, "changes", len(plan.resp.Changes), "prune", prune)
+
+	plan.resp.WorkflowID = we.GetID()
+	plan.resp.Message = "the project is converging on the manifest"
+	return plan.resp, nil
+}
+
+// MemberPermissions checks permissions against the current role of the user in the project, for
+// the applies nobody is behind (git sync). A platform admin holds every permission.
+func (s *ManifestService) MemberPermissions(ctx context.Context, projectID, userID uuid.UUID) (PermissionCheck, error) {
+	user, err := s.AuthRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeNotFound, "user not found")
+	}
+	if user.Role == s.Config.Roles.PlatformAdmin {
+		return func(dauth.PermissionType) bool { return true }, nil
+	}
+
+	// sans rôle dans le projet, aucune permission
+	roleName, err := s.AuthRepo.GetProjectRoleName(ctx, projectID, userID)
+	if err != nil {
+		return func(dauth.PermissionType) bool { return false }, nil
+	}
+	role, err := s.AuthRepo.GetRoleByName(ctx, roleName)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to load role permissions")
+	}
+	perms := make([]string, len(role.Permissions))
+	for i, p := range role.Permissions {
+		perms[i] = p.Slug
+	}
+	return func(perm dauth.PermissionType) bool { return dauth.HasPermission(perms, perm) }, nil
+}
+
+// DecodeManifest accepts YAML or JSON (JSON being valid YAML), unknown fields are rejected
+// so that a typo does not silently leave a setting unmanaged.
+func DecodeManifest(raw []byte) (*domain.ProjectManifest, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "empty manifest")
+	}
+
+	data, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid manifest: "+err.Error())
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var manifest domain.ProjectManifest
+	if err := dec.Decode(&manifest); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid manifest: "+err.Error())
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	dauth "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/workflows"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+)
+
+type manifestPlan struct {
+	resp  *domain.ManifestPlanResponse
+	input workflows.ApplyManifestInput
+}
+
+func (p *manifestPlan) hasOperations() bool {
+	return p.input.Quotas != nil || len(p.input.Workloads) > 0 ||
+		len(p.input.NetworkPolicies) > 0 || len(p.input.Members) > 0
+}
+
+// add records a change in the response. Orphans are only reported, the caller
+// builds the workflow operations for the other actions.
+func (p *manifestPlan) add(resource, name, action string, fields ...domain.FieldChange) {
+	p.resp.Changes = append(p.resp.Changes, domain.PlanChange{
+		Resource: resource,
+		Name:     name,
+		Action:   action,
+		Fields:   fields,
+	})
+}
+
+func (p *manifestPlan) warn(format string, args ...interface{}) {
+	p.resp.Warnings = append(p.resp.Warnings, fmt.Sprintf(format, args...))
+}
+
+// checkPermissions applies the rules of the routes each section stands for: members need
+// members:manage, workloads need workload:create and their deletion workload:delete.
+func (p *manifestPlan) checkPermissions(manifest *domain.ProjectManifest, can PermissionCheck) error {
+	rights := dauth.PermissionTypes
+	if manifest.Members != nil && !can(rights.MembersManage) {
+		return forbiddenSection("members", rights.MembersManage)
+	}
+	for _, op := range p.input.Workloads {
+		switch {
+		case op.Action == utils.ManifestActionDelete && !can(rights.WorkloadDelete):
+			return forbiddenSection("workloads (prune)", rights.WorkloadDelete)
+		case op.Action != utils.ManifestActionDelete && !can(rights.WorkloadCreate):
+			return forbiddenSection("workloads", rights.WorkloadCreate)
+		}
+	}
+	return nil
+}
+
+func forbiddenSection(section string, perm dauth.PermissionType) error {
+	return betoerrors.New(betoerrors.CodeForbidden, fmt.Sprintf("the %s section of the manifest requires the %s permission", section, perm.String()))
+}
+
+func (s *ManifestService) buildPlan(ctx context.Context, project *dauth.Project, manifest *domain.ProjectManifest, prune bool) (*manifestPlan, error) {
+	plan := &manifestPlan{
+		resp: &domain.ManifestPlanResponse{
+			ProjectID: project.ID.String(),
+			Prune:     prune,
+			Changes:   []domain.PlanChange{},
+		},
+		input: workflows.ApplyManifestInput{
+			ProjectID: project.ID.String(),
+			Namespace: "km-" + project.Name,
+		},
+	}
+
+	if err := s.planQuotas(ctx, plan, project, manifest.Quotas); err != nil {
+		return nil, err
+	}
+	if manifest.Members != nil {
+		if err := s.planMembers(ctx, plan, project, manifest.Members, prune); err != nil {
+			return nil, err
+		}
+	}
+
+	workloadNames, err := s.planWorkloads(ctx, plan, project, manifest.Workloads, prune)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.NetworkPolicies != nil {
+		if err := s.planNetworkPolicies(ctx, plan, project, manifest.NetworkPolicies, workloadNames, prune); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// planQuotas keeps the current value of the fields left empty in the manifest.
+func (s *ManifestService) planQuotas(ctx context.Context, plan *manifestPlan, project *dauth.Project, quotas *domain.ManifestQuotas) error {
+	if quotas == nil {
+		return nil
+	}
+
+	desired := domain.ManifestQuotas{
+		CPU:     firstNonEmpty(quotas.CPU, project.CpuLimit),
+		Memory:  firstNonEmpty(quotas.Memory, project.MemoryLimit),
+		Storage: firstNonEmpty(quotas.Storage, project.StorageLimit),
+	}
+
+	var fields []domain.FieldChange
+	for _, q := range []struct{ field, from, to string }{
+		{"cpu", project.CpuLimit, desired.CPU},
+		{"memory", project.MemoryLimit, desired.Memory},
+		{"storage", project.StorageLimit, desired.Storage},
+	} {
+		to, err := resource.ParseQuantity(q.to)
+		if err != nil {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("invalid %s quota: %s", q.field, q.to))
+		}
+		if from, err := resource.ParseQuantity(q.from); err == nil && from.Cmp(to) == 0 {
+			continue
+		}
+		fields = append(fields, domain.FieldChange{Field: q.field, From: q.from, To: q.to})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	usedCPU, usedMem, usedStorage, err := s.WorkloadRepo.GetTotalUsageByProject(ctx, project.ID)
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to compute project usage")
+	}
+	addonCPU, addonMem, addonStorage, _ := s.AddonRepo.GetTotalUsageByProject(ctx, project.ID)
+
+	cpu, mem, storage := resource.MustParse(desired.CPU), resource.MustParse(desired.Memory), resource.MustParse(desired.Storage)
+
+	// les workloads déjà en place ne sont pas arrêtés, seuls les prochains déploiements seront refusés
+	if cpu.MilliValue() < usedCPU+addonCPU {
+		plan.warn("cpu quota %s is below the current reservation (%dm)", desired.CPU, usedCPU+addonCPU)
+	}
+	if mem.Value()/(1024*1024) < usedMem+addonMem {
+		plan.warn("memory quota %s is below the current reservation (%dMi)", desired.Memory, usedMem+addonMem)
+	}
+	if storage.Value()/(1024*1024) < usedStorage+addonStorage {
+		plan.warn("storage quota %s is below the current reservation (%dMi)", desired.Storage, usedStorage+addonStorage)
+	}
+
+	plan.add(utils.ManifestResourceQuotas, project.Name, utils.ManifestActionUpdate, fields...)
+	plan.input.Quotas = &desired
+	return nil
+}
+
+func (s *ManifestService) planMembers(ctx context.Context, plan *manifestPlan, project *dauth.Project, members []domain.ManifestMember, prune bool) error {
+	current, err := s.AuthRepo.ListProjectMembers(ctx, project.ID)
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list project members")
+	}
+	currentByUser := make(map[string]dauth.ProjectMember, len(current))
+	for _, m := range current {
+		currentByUser[m.UserID.String()] = m
+	}
+
+	owners := 0
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		role := strings.ToUpper(m.Role)
+		// les rôles personnalisés sont aussi acceptés
+		if _, err := s.AuthRepo.GetRoleByName(ctx, role); err != nil {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("members[%s]: invalid role %q", m.Email, m.Role))
+		}
+
+		user, err := s.AuthRepo.GetUserByEmail(ctx, m.Email)
+		if err != nil {
+			return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to resolve project members")
+		}
+		if user == nil {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("members[%s]: unknown user", m.Email))
+		}
+		if user.IsServiceAccount() {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("members[%s]: service accounts are managed under /service-accounts", m.Email))
+		}
+
+		userID := user.ID.String()
+		if seen[userID] {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("members[%s]: duplicate member", m.Email))
+		}
+		seen[userID] = true
+		if role == dauth.RoleTypes.Owner.String() {
+			owners++
+		}
+
+		existing, ok := currentByUser[userID]
+		switch {
+		case !ok:
+			plan.add(utils.ManifestResourceMember, m.Email, utils.ManifestActionCreate, domain.FieldChange{Field: "role", To: role})
+			plan.input.Members = append(plan.input.Members, workflows.ManifestMemberOp{
+				Action: utils.ManifestActionCreate, UserID: userID, Email: m.Email, Role: role,
+			})
+		case existing.Role.Name != role:
+			plan.add(utils.ManifestResourceMember, m.Email, utils.ManifestActionUpdate, domain.FieldChange{Field: "role", From: existing.Role.Name, To: role})
+			plan.input.Members = append(plan.input.Members, workflows.ManifestMemberOp{
+				Action: utils.ManifestActionUpdate, UserID: userID, Email: m.Email, Role: role,
+			})
+		}
+	}
+
+	for _, m := range current {
+		// les comptes de service ne figurent pas dans le manifeste, ils ne sont jamais orphelins
+		if seen[m.UserID.String()] || m.User.IsServiceAccount() {
+			continue
+		}
+		if !prune {
+			plan.add(utils.ManifestResourceMember, m.User.Email, utils.ManifestActionOrphan)
+			if m.Role.Name == dauth.RoleTypes.Owner.String() {
+				owners++
+			}
+			continue
+		}
+		plan.add(utils.ManifestResourceMember, m.User.Email, utils.ManifestActionDelete)
+		plan.input.Members = append(plan.input.Members, workflows.ManifestMemberOp{
+			Action: utils.ManifestActionDelete, UserID: m.UserID.String(), Email: m.User.Email,
+		})
+	}
+
+	if owners == 0 {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "the project must keep at least one OWNER")
+	}
+	return nil
+}
+
+// planWorkloads returns the workload names of the project once the manifest is applied.
+// A nil section leaves the workloads unmanaged.
+func (s *ManifestService) planWorkloads(ctx context.Context, plan *manifestPlan, project *dauth.Project, specs []workloadDomain.CreateWorkloadRequest, prune bool) (map[string]bool, error) {
+	workloads, err := s.WorkloadRepo.ListByProject(ctx, project.ID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list workloads")
+	}
+
+	current := make(map[string]workloadDomain.Workload, len(workloads))
+	names := make(map[string]bool, len(workloads))
+	for _, w := range workloads {
+		if w.Status == utils.WorkloadDeleting {
+			continue
+		}
+		current[w.Name] = w
+		names[w.Name] = true
+	}
+	if specs == nil {
+		return names, nil
+	}
+
+	envWarned := false
+	seen := make(map[string]bool, len(specs))
+	for i := range specs {
+		spec := specs[i]
+		if spec.ProjectID != "" && spec.ProjectID != project.ID.String() {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("workloads[%s]: project_id does not match the project", spec.Name))
+		}
+		spec.ProjectID = project.ID.String()
+
+		if len(spec.SecretData) > 0 {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("workloads[%s]: secret_data is not allowed in a manifest, use secret_refs", spec.Name))
+		}
+		applyRequestDefaults(&spec)
+		if err := binding.Validator.ValidateStruct(&spec); err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("workloads[%s]: %s", spec.Name, err.Error()))
+		}
+		if seen[spec.Name] {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("workloads[%s]: duplicate workload", spec.Name))
+		}
+		seen[spec.Name] = true
+
+		if len(spec.EnvVars) > 0 && !envWarned {
+			envWarned = true
+			plan.warn("env_vars are not stored: they are only applied to the workloads created or updated by this plan")
+		}
+
+		existing, ok := current[spec.Name]
+		if !ok {
+			if taken, err := s.AddonRepo.ExistsByName(ctx, project.ID, spec.Name); err != nil {
+				return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to check add-on names")
+			} else if taken {
+				return nil, betoerrors.New(betoerrors.CodeConflict, fmt.Sprintf("workloads[%s]: an add-on already uses this name", spec.Name))
+			}
+
+			plan.add(utils.ManifestResourceWorkload, spec.Name, utils.ManifestActionCreate)
+			plan.input.Workloads = append(plan.input.Workloads, workflows.ManifestWorkloadOp{
+				Action: utils.ManifestActionCreate, Name: spec.Name, Spec: &spec,
+			})
+			continue
+		}
+
+		if spec.Kind != existing.Kind {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("workloads[%s]: kind cannot be changed (%s -> %s)", spec.Name, existing.Kind, spec.Kind))
+		}
+		if spec.PersistenceEnabled != existing.PersistenceEnabled {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("workloads[%s]: persistence cannot be toggled on an existing workload", spec.Name))
+		}
+		if len(spec.Volumes) > 0 {
+			plan.warn("workloads[%s]: volumes are fixed at creation and are ignored on update", spec.Name)
+		}
+
+		fields := diffWorkload(&existing, &spec)
+		if len(fields) == 0 {
+			continue
+		}
+		plan.add(utils.ManifestResourceWorkload, spec.Name, utils.ManifestActionUpdate, fields...)
+		plan.input.Workloads = append(plan.input.Workloads, workflows.ManifestWorkloadOp{
+			Action: utils.ManifestActionUpdate, WorkloadID: existing.ID.String(), Name: spec.Name, Spec: &spec,
+		})
+	}
+
+	orphans := make([]string, 0)
+	for name := range current {
+		if !seen[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+
+	for _, name := range orphans {
+		if !prune {
+			plan.add(utils.ManifestResourceWorkload, name, utils.ManifestActionOrphan)
+			continue
+		}
+		delete(names, name)
+		plan.add(utils.ManifestResourceWorkload, name, utils.ManifestActionDelete)
+		plan.input.Workloads = append(plan.input.Workloads, workflows.ManifestWorkloadOp{
+			Action: utils.ManifestActionDelete, WorkloadID: current[name].ID.String(), Name: name,
+		})
+	}
+
+	for name := range seen {
+		names[name] = true
+	}
+	return names, nil
+}
+
+func (s *ManifestService) planNetworkPolicies(ctx context.Context, plan *manifestPlan, project *dauth.Project, policies []domain.ManifestNetworkPolicy, workloadNames map[string]bool, prune bool) error {
+	existing, err := s.NetworkPolicyRepo.ListByProject(ctx, project.ID)
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list network policies")
+	}
+	current := make(map[string]domain.NetworkPolicy, len(existing))
+	for _, p := range existing {
+		current[p.Name] = p
+	}
+
+	seen := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		if errs := validation.IsDNS1123Label(p.Name); len(errs) > 0 {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("network_policies[%s]: invalid name: %s", p.Name, strings.Join(errs, ", ")))
+		}
+		if seen[p.Name] {
+			return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("network_policies[%s]: duplicate policy", p.Name))
+		}
+		seen[p.Name] = true
+
+		for _, port := range p.Ports {
+			if port < 1 || port > 65535 {
+				return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("network_policies[%s]: invalid port %d", p.Name, port))
+			}
+		}
+		for _, name := range append(slices.Clone(p.Workloads), p.AllowFrom...) {
+			if !workloadNames[name] {
+				plan.warn("network_policies[%s]: workload %q does not exist in the project", p.Name, name)
+			}
+		}
+
+		old, ok := current[p.Name]
+		if !ok {
+			plan.add(utils.ManifestResourceNetworkPolicy, p.Name, utils.ManifestActionCreate)
+			plan.input.NetworkPolicies = append(plan.input.NetworkPolicies, workflows.ManifestPolicyOp{Action: utils.ManifestActionCreate, Policy: p})
+			continue
+		}
+
+		var fields []domain.FieldChange
+		if !slices.Equal(old.Workloads, p.Workloads) {
+			fields = append(fields, domain.FieldChange{Field: "workloads", From: strings.Join(old.Workloads, ","), To: strings.Join(p.Workloads, ",")})
+		}
+		if !slices.Equal(old.AllowFrom, p.AllowFrom) {
+			fields = append(fields, domain.FieldChange{Field: "allow_from", From: strings.Join(old.AllowFrom, ","), To: strings.Join(p.AllowFrom, ",")})
+		}
+		if !slices.Equal(old.Ports, p.Ports) {
+			fields = append(fields, domain.FieldChange{Field: "ports", From: joinInts(old.Ports), To: joinInts(p.Ports)})
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		plan.add(utils.ManifestResourceNetworkPolicy, p.Name, utils.ManifestActionUpdate, fields...)
+		plan.input.NetworkPolicies = append(plan.input.NetworkPolicies, workflows.ManifestPolicyOp{Action: utils.ManifestActionUpdate, Policy: p})
+	}
+
+	for _, p := range existing {
+		if seen[p.Name] {
+			continue
+		}
+		if !prune {
+			plan.add(utils.ManifestResourceNetworkPolicy, p.Name, utils.ManifestActionOrphan)
+			continue
+		}
+		plan.add(utils.ManifestResourceNetworkPolicy, p.Name, utils.ManifestActionDelete)
+		plan.input.NetworkPolicies = append(plan.input.NetworkPolicies, workflows.ManifestPolicyOp{
+			Action: utils.ManifestActionDelete, Policy: domain.ManifestNetworkPolicy{Name: p.Name},
+		})
+	}
+	return nil
+}
+
+// diffWorkload compares the fields ApplyWorkloadSpec is able to change.
+func diffWorkload(current *workloadDomain.Workload, spec *workloadDomain.CreateWorkloadRequest) []domain.FieldChange {
+	var fields []domain.FieldChange
+	compare := func(field, from, to string) {
+		if from != to {
+			fields = append(fields, domain.FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	compare("image", current.Image, spec.Image)
+	compare("replicas", strconv.Itoa(current.Replicas), strconv.Itoa(spec.Replicas))
+	compare("cpu_limit", current.CPULimit, spec.CPULimit)
+	compare("memory_limit", current.MemoryLimit, spec.MemoryLimit)
+	compare("target_port", strconv.Itoa(current.TargetPort), strconv.Itoa(spec.TargetPort))
+	compare("schedule", current.Schedule, spec.Schedule)
+	compare("concurrency_policy", current.ConcurrencyPolicy, spec.ConcurrencyPolicy)
+	compare("backoff_limit", backoffLimit(current.BackoffLimit), backoffLimit(spec.BackoffLimit))
+	compare("ttl_seconds_after_finished", strconv.Itoa(current.TTLSecondsAfterFinished), strconv.Itoa(spec.TTLSecondsAfterFinished))
+	compare("secret_refs", strings.Join(current.SecretRefs, ","), strings.Join(spec.SecretRefs, ","))
+	if current.PersistenceEnabled {
+		compare("storage_size", current.StorageSize, spec.StorageSize)
+	}
+	return fields
+}
+
+// la colonne backoff_limit vaut 6 par défaut, comme le Job K8s
+func backoffLimit(v *int) string {
+	if v == nil {
+		return "6"
+	}
+	return strconv.Itoa(*v)
+}
+
+// applyRequestDefaults fills the empty fields with their `default` tag, the way the DB
+// defaults fill them at creation, so that an omitted field is not planned as a change.
+func applyRequestDefaults(v interface{}) {
+	val := reflect.ValueOf(v).Elem()
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		tag := typ.Field(i).Tag.Get("default")
+
+		switch field.Kind() {
+		case reflect.String:
+			if tag != "" && field.String() == "" {
+				field.SetString(tag)
+			}
+		case reflect.Int:
+			if n, err := strconv.Atoi(tag); err == nil && field.Int() == 0 {
+				field.SetInt(int64(n))
+			}
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.Struct {
+				for j := 0; j < field.Len(); j++ {
+					applyRequestDefaults(field.Index(j).Addr().Interface())
+				}
+			}
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ",")
+}
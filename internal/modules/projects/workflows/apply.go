@@ -0,0 +1,157 @@
+package workflows
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/projects/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/projects/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+)
+
+type ManifestWorkloadOp struct {
+	Action     string                                `json:"action"`
+	WorkloadID string                                `json:"workload_id,omitempty"` // update / delete
+	Name       string                                `json:"name"`
+	Spec       *workloadDomain.CreateWorkloadRequest `json:"spec,omitempty"`
+}
+
+type ManifestMemberOp struct {
+	Action string `json:"action"`
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+}
+
+type ManifestPolicyOp struct {
+	Action string                       `json:"action"`
+	Policy domain.ManifestNetworkPolicy `json:"policy"`
+}
+
+// ApplyManifestInput only carries the changes of the plan, orphans are already
+// turned into deletions when pruning was requested.
+type ApplyManifestInput struct {
+	ProjectID string `json:"project_id"`
+	Namespace string `json:"namespace"`
+
+	Quotas          *domain.ManifestQuotas `json:"quotas,omitempty"`
+	Workloads       []ManifestWorkloadOp   `json:"workloads"`
+	NetworkPolicies []ManifestPolicyOp     `json:"network_policies"`
+	Members         []ManifestMemberOp     `json:"members"`
+}
+
+type ManifestFailure struct {
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	Action   string `json:"action"`
+	Error    string `json:"error"`
+}
+
+type ApplyManifestResult struct {
+	Applied int               `json:"applied"`
+	Failed  []ManifestFailure `json:"failed,omitempty"`
+}
+
+// ApplyManifestWorkflow converges the project: prune first to free the quota, then quotas,
+// workloads, network policies and members. A failed change does not stop the others, the
+// result lists them. Workload changes start their own deploy/delete workflows.
+func ApplyManifestWorkflow(ctx workflow.Context, input ApplyManifestInput) (ApplyManifestResult, error) {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, options)
+
+	var a *activities.ManifestActivities
+	var k8sActs *activities.ProjectK8sActivities
+	var result ApplyManifestResult
+
+	phase := utils.PhaseManifestPruning
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return result, err
+	}
+
+	record := func(resource, name, action string, err error) {
+		if err != nil {
+			workflow.GetLogger(ctx).Error("manifest change failed", "resource", resource, "name", name, "error", err)
+			result.Failed = append(result.Failed, ManifestFailure{Resource: resource, Name: name, Action: action, Error: err.Error()})
+			return
+		}
+		result.Applied++
+	}
+
+	for _, op := range input.Workloads {
+		if op.Action == utils.ManifestActionDelete {
+			err := workflow.ExecuteActivity(ctx, a.DeleteManifestWorkload, op.WorkloadID).Get(ctx, nil)
+			record(utils.ManifestResourceWorkload, op.Name, op.Action, err)
+		}
+	}
+	for _, op := range input.NetworkPolicies {
+		if op.Action == utils.ManifestActionDelete {
+			err := workflow.ExecuteActivity(ctx, a.DeleteNetworkPolicy, input.ProjectID, input.Namespace, op.Policy.Name).Get(ctx, nil)
+			record(utils.ManifestResourceNetworkPolicy, op.Policy.Name, op.Action, err)
+		}
+	}
+
+	if input.Quotas != nil {
+		phase = utils.PhaseManifestQuotas
+		err := workflow.ExecuteActivity(ctx, a.UpdateProjectQuotas, input.ProjectID, *input.Quotas).Get(ctx, nil)
+		if err == nil {
+			err = workflow.ExecuteActivity(ctx, k8sActs.ReconcileProjectResources, input.Namespace, input.Quotas.CPU, input.Quotas.Memory).Get(ctx, nil)
+		}
+		record(utils.ManifestResourceQuotas, input.Namespace, utils.ManifestActionUpdate, err)
+	}
+
+	// les mises à jour passent avant les créations pour libérer le quota réduit
+	phase = utils.PhaseManifestWorkloads
+	for _, op := range input.Workloads {
+		if op.Action == utils.ManifestActionUpdate {
+			err := workflow.ExecuteActivity(ctx, a.UpdateManifestWorkload, op.WorkloadID, *op.Spec).Get(ctx, nil)
+			record(utils.ManifestResourceWorkload, op.Name, op.Action, err)
+		}
+	}
+	for _, op := range input.Workloads {
+		if op.Action == utils.ManifestActionCreate {
+			err := workflow.ExecuteActivity(ctx, a.CreateManifestWorkload, *op.Spec).Get(ctx, nil)
+			record(utils.ManifestResourceWorkload, op.Name, op.Action, err)
+		}
+	}
+
+	phase = utils.PhaseManifestNetworkPolicies
+	for _, op := range input.NetworkPolicies {
+		if op.Action != utils.ManifestActionDelete {
+			err := workflow.ExecuteActivity(ctx, a.ApplyNetworkPolicy, input.ProjectID, input.Namespace, op.Policy).Get(ctx, nil)
+			record(utils.ManifestResourceNetworkPolicy, op.Policy.Name, op.Action, err)
+		}
+	}
+
+	// les retraits en dernier, le projet garde toujours un owner
+	phase = utils.PhaseManifestMembers
+	for _, op := range input.Members {
+		if op.Action != utils.ManifestActionDelete {
+			err := workflow.ExecuteActivity(ctx, a.SetProjectMember, input.ProjectID, op.UserID, op.Role).Get(ctx, nil)
+			record(utils.ManifestResourceMember, op.Email, op.Action, err)
+		}
+	}
+	for _, op := range input.Members {
+		if op.Action == utils.ManifestActionDelete {
+			err := workflow.ExecuteActivity(ctx, a.RemoveProjectMember, input.ProjectID, op.UserID).Get(ctx, nil)
+			record(utils.ManifestResourceMember, op.Email, op.Action, err)
+		}
+	}
+
+	if len(result.Failed) > 0 {
+		phase = utils.PhaseManifestPartial
+		return result, nil
+	}
+	phase = utils.PhaseManifestApplied
+	return result, nil
+}
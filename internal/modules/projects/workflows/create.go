@@ -8,6 +8,7 @@ import (
 	"github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	dauth "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	webhookWorkflows "github.com/thekrauss/kubemanager/internal/modules/webhooks/workflows"
 
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
@@ -22,6 +23,10 @@ func (a *ProjectActivities) CreateNamespace(ctx context.Context, projectID strin
 	return nil
 }
 
+func (a *ProjectActivities) UpdateProjectStatus(ctx context.Context, projectID string, status, phase string) error {
+	return nil
+}
+
 func (a *ProjectActivities) DeleteProjectDBActivity(ctx context.Context, projectID string) error {
 	return nil
 }
@@ -69,39 +74,61 @@ func CreateProjectWorkflow(ctx workflow.Context, input CreateProjectInput) (Proj
 	var dbRes dauth.Project
 	var result ProjectResult
 
+	phase := utils.PhaseDBInitializing
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return result, err
+	}
+
 	err := workflow.ExecuteLocalActivity(ctx, a.CreateProjectInDB, input.Name, input.Description, input.OwnerID).Get(ctx, &dbRes)
 	if err != nil {
 		return result, err
 	}
 	result.ProjectID = dbRes.ID.String()
+	result.Namespace = fmt.Sprintf("km-%s", input.Name)
+
+	// le projet reste en base en cas d'échec : RetryProject relance ce workflow depuis LastWorkflowID
+	fail := func(err error) (ProjectResult, error) {
+		phase = utils.PhaseProvisioningFailed
+		result.Status = utils.ProjectStatusError
+		workflow.ExecuteActivity(ctx, a.UpdateProjectStatus, result.ProjectID, result.Status, phase).Get(ctx, nil)
+		return result, err
+	}
+
+	phase = utils.PhaseK8sNamespaceLinking
+	workflow.ExecuteActivity(ctx, a.UpdateProjectStatus, result.ProjectID, utils.ProjectStatusProvisioning, phase).Get(ctx, nil)
 
 	err = workflow.ExecuteActivity(ctx, a.CreateNamespace, result.ProjectID, input.Name).Get(ctx, nil)
 	if err != nil {
-		compensateOptions := workflow.LocalActivityOptions{StartToCloseTimeout: 1 * time.Minute}
-		ctxComp := workflow.WithLocalActivityOptions(ctx, compensateOptions)
-
-		_ = workflow.ExecuteLocalActivity(ctxComp, a.DeleteProjectDBActivity, result.ProjectID).Get(ctx, nil)
-		return result, fmt.Errorf("failed to provision k8s namespace, rolled back: %w", err)
+		return fail(fmt.Errorf("failed to provision k8s namespace: %w", err))
 	}
+
+	phase = utils.PhaseK8sQuotasApplying
 	err = workflow.ExecuteActivity(ctx, a.ReconcileProjectResources, result.Namespace, input.CpuLimit, input.MemoryLimit).Get(ctx, nil)
 	if err != nil {
-		return result, err
+		return fail(err)
 	}
 
-	result.Status = utils.ProjectStatusReady
-	result.Namespace = fmt.Sprintf("km-%s", input.Name)
-
 	rbacInput := &domain.AssignRoleRequest{
 		UserID:    input.OwnerID,
 		ProjectID: result.ProjectID,
 		RoleName:  domain.RoleTypes.Owner.String(),
 	}
 
+	phase = utils.PhaseRBACSetting
 	err = workflow.ExecuteActivity(ctx, a.AssignProjectRole, rbacInput).Get(ctx, nil)
 	if err != nil {
-		return result, err
+		return fail(err)
 	}
+
+	phase = utils.PhaseProvisioningSuccess
+	result.Status = utils.ProjectStatusReady
+	workflow.ExecuteActivity(ctx, a.UpdateProjectStatus, result.ProjectID, result.Status, phase).Get(ctx, nil)
+	webhookWorkflows.EmitEvent(ctx, utils.EventProjectReady, result.ProjectID, map[string]interface{}{
+		"project_id": result.ProjectID,
+		"name":       input.Name,
+		"namespace":  result.Namespace,
+	})
 	return result, nil
 }
-
-
@@ -0,0 +1,57 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+
+	dauth "github.com/thekrauss/kubemanager/internal/modules/auth/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+)
+
+// TestCreateProjectKeepsTheProjectOnFailure checks that a failed namespace leaves the project
+// in ERROR, with its row, so that RetryProject can run the workflow again.
+func TestCreateProjectKeepsTheProjectOnFailure(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	suite.SetLogger(log.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	env := suite.NewTestWorkflowEnvironment()
+
+	a := &ProjectActivities{}
+	env.RegisterActivity(a)
+
+	projectID := uuid.New()
+	env.OnActivity(a.CreateProjectInDB, mock.Anything, "shop", "", "owner").Return(&dauth.Project{ID: projectID}, nil)
+	env.OnActivity(a.CreateNamespace, mock.Anything, projectID.String(), "shop").Return(errors.New("namespaces is forbidden"))
+
+	var statuses []string
+	env.OnActivity(a.UpdateProjectStatus, mock.Anything, projectID.String(), mock.Anything, mock.Anything).
+		Return(func(_ context.Context, _, status, _ string) error {
+			statuses = append(statuses, status)
+			return nil
+		})
+	deleted := false
+	env.OnActivity(a.DeleteProjectDBActivity, mock.Anything, mock.Anything).
+		Return(func(context.Context, string) error {
+			deleted = true
+			return nil
+		})
+
+	env.ExecuteWorkflow(CreateProjectWorkflow, CreateProjectInput{Name: "shop", OwnerID: "owner"})
+
+	if env.GetWorkflowError() == nil {
+		t.Fatal("the workflow succeeded without a namespace")
+	}
+	if deleted {
+		t.Error("the project row was deleted, RetryProject can no longer find it")
+	}
+	if len(statuses) == 0 || statuses[len(statuses)-1] != utils.ProjectStatusError {
+		t.Errorf("statuses = %v, want the project left in %s", statuses, utils.ProjectStatusError)
+	}
+}
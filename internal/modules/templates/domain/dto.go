@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+type TemplateBody struct {
+	Description string                 `json:"description"`
+	Spec        map[string]interface{} `json:"spec" binding:"required" desc:"Champs partiels de la requête de déploiement (image, cpu_limit, volumes...)"`
+	Parameters  []TemplateParameter    `json:"parameters" binding:"omitempty,dive" desc:"Paramètres substitués dans le spec"`
+}
+
+type CreateTemplateRequest struct {
+	Name string `json:"name" binding:"required,min=3,max=50" desc:"Nom du template"`
+	TemplateBody
+}
+
+type CreateProjectTemplateRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	CreateTemplateRequest
+}
+
+// UpdateTemplateRequest publishes a new version, previous ones stay deployable.
+type UpdateTemplateRequest struct {
+	Name string `path:"name" desc:"Nom du template"`
+	TemplateBody
+}
+
+type UpdateProjectTemplateRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	UpdateTemplateRequest
+}
+
+type TemplateNameRequest struct {
+	Name    string `path:"name" desc:"Nom du template"`
+	Version int    `query:"version" desc:"Version, 0 = dernière"`
+}
+
+type ProjectTemplateNameRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	TemplateNameRequest
+}
+
+type ListTemplatesRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+}
+
+type DeployFromTemplateRequest struct {
+	ProjectID  string            `json:"project_id" binding:"required" desc:"ID du projet cible"`
+	Template   string            `json:"template" binding:"required" desc:"Nom du template (projet, sinon global)"`
+	Version    int               `json:"version" binding:"min=0" desc:"Version du template, 0 = dernière"`
+	Name       string            `json:"name" binding:"required,min=3,max=30" desc:"Nom de la release Helm"`
+	Parameters map[string]string `json:"parameters" desc:"Valeurs des paramètres du template"`
+}
+
+type TemplateResponse struct {
+	ID          string                 `json:"id"`
+	ProjectID   string                 `json:"project_id,omitempty"`
+	Scope       string                 `json:"scope"` // project | org
+	Name        string                 `json:"name"`
+	Version     int                    `json:"version"`
+	Description string                 `json:"description,omitempty"`
+	Spec        map[string]interface{} `json:"spec"`
+	Parameters  []TemplateParameter    `json:"parameters"`
+	CreatedBy   string                 `json:"created_by,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkloadTemplate is one immutable version of a partial workload spec. A new version
+// is stored on every update, ProjectID is nil for org-wide templates.
+type WorkloadTemplate struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID *uuid.UUID `gorm:"type:uuid;index"`
+
+	Name        string `gorm:"type:varchar(50);index;not null"`
+	Version     int    `gorm:"not null"`
+	Description string `gorm:"type:text"`
+
+	// champs partiels de CreateWorkloadRequest, les chaînes peuvent contenir des {{ parametres }}
+	Spec       map[string]interface{} `gorm:"type:jsonb;serializer:json;not null"`
+	Parameters []TemplateParameter    `gorm:"type:jsonb;serializer:json"`
+
+	CreatedBy string `gorm:"type:varchar(100)"`
+	CreatedAt time.Time
+}
+
+type TemplateParameter struct {
+	Name        string `json:"name" binding:"required,max=40" desc:"Nom utilisé dans le spec : {{ nom }}"`
+	Type        string `json:"type" default:"string" binding:"omitempty,oneof=string int bool" desc:"string, int ou bool"`
+	Description string `json:"description"`
+	Default     string `json:"default" desc:"Valeur par défaut, vide = paramètre requis"`
+	Required    bool   `json:"required"`
+}
+
+func (t *WorkloadTemplate) IsOrgWide() bool {
+	return t.ProjectID == nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/thekrauss/kubemanager/internal/modules/templates/domain"
+	"gorm.io/gorm"
+)
+
+// A nil projectID targets the org-wide templates.
+type TemplateRepository interface {
+	Create(ctx context.Context, tpl *domain.WorkloadTemplate) error
+	GetLatest(ctx context.Context, projectID *uuid.UUID, name string) (*domain.WorkloadTemplate, error)
+	GetVersion(ctx context.Context, projectID *uuid.UUID, name string, version int) (*domain.WorkloadTemplate, error)
+	ListLatest(ctx context.Context, projectID *uuid.UUID) ([]domain.WorkloadTemplate, error)
+	DeleteByName(ctx context.Context, projectID *uuid.UUID, name string) error
+}
+
+type templateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+func (r *templateRepository) scope(ctx context.Context, projectID *uuid.UUID) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&domain.WorkloadTemplate{})
+	if projectID == nil {
+		return q.Where("project_id IS NULL")
+	}
+	return q.Where("project_id = ?", *projectID)
+}
+
+func (r *templateRepository) Create(ctx context.Context, tpl *domain.WorkloadTemplate) error {
+	return r.db.WithContext(ctx).Create(tpl).Error
+}
+
+func (r *templateRepository) GetLatest(ctx context.Context, projectID *uuid.UUID, name string) (*domain.WorkloadTemplate, error) {
+	var tpl domain.WorkloadTemplate
+	err := r.scope(ctx, projectID).
+		Where("name = ?", name).
+		Order("version DESC").
+		First(&tpl).Error
+	return &tpl, err
+}
+
+func (r *templateRepository) GetVersion(ctx context.Context, projectID *uuid.UUID, name string, version int) (*domain.WorkloadTemplate, error) {
+	var tpl domain.WorkloadTemplate
+	err := r.scope(ctx, projectID).
+		Where("name = ? AND version = ?", name, version).
+		First(&tpl).Error
+	return &tpl, err
+}
+
+// ListLatest returns the last version of each template of the scope.
+func (r *templateRepository) ListLatest(ctx context.Context, projectID *uuid.UUID) ([]domain.WorkloadTemplate, error) {
+	var templates []domain.WorkloadTemplate
+	err := r.scope(ctx, projectID).
+		Select("DISTINCT ON (name) *").
+		Order("name ASC, version DESC").
+		Find(&templates).Error
+	return templates, err
+}
+
+func (r *templateRepository) DeleteByName(ctx context.Context, projectID *uuid.UUID, name string) error {
+	res := r.scope(ctx, projectID).Where("name = ?", name).Delete(&domain.WorkloadTemplate{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
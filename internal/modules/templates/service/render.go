@@ -0,0 +1,197 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/templates/domain"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+)
+
+var (
+	placeholderRegex   = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+	parameterNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// le projet et le nom de la release sont toujours fournis au déploiement
+var reservedSpecKeys = []string{"project_id", "name"}
+
+// validateTemplate checks the parameters and renders the spec with their default (or zero)
+// values, so that unknown fields, undeclared placeholders and wrong types fail at save time.
+func validateTemplate(spec map[string]interface{}, params []domain.TemplateParameter) error {
+	for _, key := range reservedSpecKeys {
+		if _, ok := spec[key]; ok {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "spec cannot set "+key)
+		}
+	}
+
+	sample := make(map[string]interface{}, len(params))
+	for i := range params {
+		p := &params[i]
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		if !parameterNameRegex.MatchString(p.Name) {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "invalid parameter name: "+p.Name)
+		}
+		if _, ok := sample[p.Name]; ok {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "duplicate parameter: "+p.Name)
+		}
+
+		raw := p.Default
+		if raw == "" {
+			raw = zeroValue(p.Type)
+		}
+		value, err := convertParameter(*p, raw)
+		if err != nil {
+			return err
+		}
+		sample[p.Name] = value
+	}
+
+	rendered, err := renderValue(spec, sample)
+	if err != nil {
+		return err
+	}
+	_, err = decodeSpec(rendered.(map[string]interface{}))
+	return err
+}
+
+// resolveParameters merges the user values over the defaults and converts them to the declared types.
+func resolveParameters(params []domain.TemplateParameter, input map[string]string) (map[string]interface{}, error) {
+	declared := make(map[string]bool, len(params))
+	values := make(map[string]interface{}, len(params))
+
+	for _, p := range params {
+		declared[p.Name] = true
+
+		raw, ok := input[p.Name]
+		if !ok {
+			if p.Required && p.Default == "" {
+				return nil, betoerrors.New(betoerrors.CodeInvalidInput, "missing template parameter: "+p.Name)
+			}
+			raw = p.Default
+			if raw == "" {
+				raw = zeroValue(p.Type)
+			}
+		}
+
+		value, err := convertParameter(p, raw)
+		if err != nil {
+			return nil, err
+		}
+		values[p.Name] = value
+	}
+
+	for name := range input {
+		if !declared[name] {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "unknown template parameter: "+name)
+		}
+	}
+
+	return values, nil
+}
+
+func convertParameter(p domain.TemplateParameter, raw string) (interface{}, error) {
+	switch p.Type {
+	case "", "string":
+		return raw, nil
+	case "int":
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "parameter "+p.Name+" must be an integer")
+		}
+		return v, nil
+	case "bool":
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "parameter "+p.Name+" must be a boolean")
+		}
+		return v, nil
+	}
+	return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid type for parameter "+p.Name)
+}
+
+func zeroValue(paramType string) string {
+	switch paramType {
+	case "int":
+		return "0"
+	case "bool":
+		return "false"
+	}
+	return ""
+}
+
+// renderValue substitutes the {{ parameters }} of the spec. A string made of a single
+// placeholder takes the typed value, so that "{{ replicas }}" can fill an integer field.
+func renderValue(v interface{}, values map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		if m := placeholderRegex.FindStringSubmatch(val); m != nil && m[0] == val {
+			value, ok := values[m[1]]
+			if !ok {
+				return nil, betoerrors.New(betoerrors.CodeInvalidInput, "undeclared template parameter: "+m[1])
+			}
+			return value, nil
+		}
+
+		var missing string
+		out := placeholderRegex.ReplaceAllStringFunc(val, func(match string) string {
+			name := placeholderRegex.FindStringSubmatch(match)[1]
+			value, ok := values[name]
+			if !ok {
+				missing = name
+				return match
+			}
+			return fmt.Sprint(value)
+		})
+		if missing != "" {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "undeclared template parameter: "+missing)
+		}
+		return out, nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			rendered, err := renderValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+		return out, nil
+
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			rendered, err := renderValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rendered)
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// decodeSpec maps a rendered spec onto the deployment request, unknown fields are rejected.
+func decodeSpec(spec map[string]interface{}) (*workloadDomain.CreateWorkloadRequest, error) {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInvalidInput, "invalid template spec")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	var req workloadDomain.CreateWorkloadRequest
+	if err := dec.Decode(&req); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid template spec: "+err.Error())
+	}
+	return &req, nil
+}
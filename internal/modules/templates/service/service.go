@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/templates/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/templates/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	workloadSvc "github.com/thekrauss/kubemanager/internal/modules/workloads/service"
+)
+
+// An empty projectID targets the org-wide templates, only platform admins can edit them.
+type ITemplateService interface {
+	CreateTemplate(ctx context.Context, session *cache.SessionData, projectID string, req domain.CreateTemplateRequest) (*domain.TemplateResponse, error)
+	UpdateTemplate(ctx context.Context, session *cache.SessionData, projectID string, req domain.UpdateTemplateRequest) (*domain.TemplateResponse, error)
+	ListTemplates(ctx context.Context, projectID string) ([]domain.TemplateResponse, error)
+	GetTemplate(ctx context.Context, projectID, name string, version int) (*domain.TemplateResponse, error)
+	DeleteTemplate(ctx context.Context, session *cache.SessionData, projectID, name string) error
+	DeployFromTemplate(ctx context.Context, req domain.DeployFromTemplateRequest) (*workloadDomain.WorkloadResponse, error)
+}
+
+var _ ITemplateService = (*TemplateService)(nil)
+
+type TemplateService struct {
+	Config          *configs.GlobalConfig
+	Logger          *zap.SugaredLogger
+	Repo            repository.TemplateRepository
+	ProjectRepo     projectRepo.ProjectRepository
+	WorkloadService *workloadSvc.WorkloadService
+}
+
+func NewTemplateService(
+	cfg *configs.GlobalConfig,
+	log *zap.SugaredLogger,
+	repo repository.TemplateRepository,
+	pRepo projectRepo.ProjectRepository,
+	wSvc *workloadSvc.WorkloadService,
+) ITemplateService {
+	return &TemplateService{
+		Config:          cfg,
+		Logger:          log.With("service", "TemplateService"),
+		Repo:            repo,
+		ProjectRepo:     pRepo,
+		WorkloadService: wSvc,
+	}
+}
+
+func (s *TemplateService) CreateTemplate(ctx context.Context, session *cache.SessionData, projectID string, req domain.CreateTemplateRequest) (*domain.TemplateResponse, error) {
+	pID, err := s.resolveScope(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWriteAccess(pID, session); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repo.GetLatest(ctx, pID, req.Name); err == nil {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "template already exists, update it to publish a new version")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to check template name")
+	}
+
+	return s.saveVersion(ctx, session, pID, req.Name, 1, req.TemplateBody)
+}
+
+func (s *TemplateService) UpdateTemplate(ctx context.Context, session *cache.SessionData, projectID string, req domain.UpdateTemplateRequest) (*domain.TemplateResponse, error) {
+	pID, err := s.resolveScope(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWriteAccess(pID, session); err != nil {
+		return nil, err
+	}
+
+	latest, err := s.Repo.GetLatest(ctx, pID, req.Name)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "template not found")
+	}
+
+	return s.saveVersion(ctx, session, pID, req.Name, latest.Version+1, req.TemplateBody)
+}
+
+// ListTemplates returns the last version of the project templates followed by the org-wide ones.
+func (s *TemplateService) ListTemplates(ctx context.Context, projectID string) ([]domain.TemplateResponse, error) {
+	pID, err := s.resolveScope(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []domain.WorkloadTemplate
+	if pID != nil {
+		templates, err = s.Repo.ListLatest(ctx, pID)
+		if err != nil {
+			return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list templates")
+		}
+	}
+
+	orgTemplates, err := s.Repo.ListLatest(ctx, nil)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list templates")
+	}
+	templates = append(templates, orgTemplates...)
+
+	resp := make([]domain.TemplateResponse, 0, len(templates))
+	for i := range templates {
+		resp = append(resp, *toTemplateResponse(&templates[i]))
+	}
+	return resp, nil
+}
+
+func (s *TemplateService) GetTemplate(ctx context.Context, projectID, name string, version int) (*domain.TemplateResponse, error) {
+	pID, err := s.resolveScope(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	tpl, err := s.getTemplate(ctx, pID, name, version)
+	if err != nil {
+		return nil, err
+	}
+	return toTemplateResponse(tpl), nil
+}
+
+func (s *TemplateService) DeleteTemplate(ctx context.Context, session *cache.SessionData, projectID, name string) error {
+	pID, err := s.resolveScope(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkWriteAccess(pID, session); err != nil {
+		return err
+	}
+
+	// les workloads déjà déployés gardent leur spec, seule la source est supprimée
+	if err := s.Repo.DeleteByName(ctx, pID, name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return betoerrors.New(betoerrors.CodeNotFound, "template not found")
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to delete template")
+	}
+	return nil
+}
+
+// DeployFromTemplate renders the template with the user parameters and goes through the
+// regular deployment path, so quota and network validation are the same as POST /workloads.
+func (s *TemplateService) DeployFromTemplate(ctx context.Context, req domain.DeployFromTemplateRequest) (*workloadDomain.WorkloadResponse, error) {
+	pID, err := s.resolveScope(ctx, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// un template du projet masque le template global de même nom
+	tpl, err := s.getTemplate(ctx, pID, req.Template, req.Version)
+	if err != nil {
+		if !betoerrors.Is(err, betoerrors.CodeNotFound) {
+			return nil, err
+		}
+		if tpl, err = s.getTemplate(ctx, nil, req.Template, req.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	values, err := resolveParameters(tpl.Parameters, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderValue(tpl.Spec, values)
+	if err != nil {
+		return nil, err
+	}
+
+	workloadReq, err := decodeSpec(rendered.(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	workloadReq.ProjectID = pID.String()
+	workloadReq.Name = req.Name
+
+	// mêmes règles de binding que le corps de POST /workloads
+	if err := binding.Validator.ValidateStruct(workloadReq); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "rendered template is invalid: "+err.Error())
+	}
+
+	workload, err := s.WorkloadService.DeployNewWorkload(ctx, &workloadSvc.WorkloadServiceRequest{
+		CreateWorkloadRequest: *workloadReq,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Logger.Infow("workload deployed from template",
+		"workload_id", workload.ID, "template", tpl.Name, "version", tpl.Version, "scope", templateScope(tpl))
+
+	return &workloadDomain.WorkloadResponse{
+		WorkloadID: workload.ID.String(),
+		WorkflowID: workload.LastWorkflowID,
+		Status:     workload.Status,
+		Namespace:  workload.Namespace,
+		Message:    "Deployment initiated from template " + tpl.Name,
+	}, nil
+}
+
+func (s *TemplateService) saveVersion(ctx context.Context, session *cache.SessionData, pID *uuid.UUID, name string, version int, body domain.TemplateBody) (*domain.TemplateResponse, error) {
+	if err := validateTemplate(body.Spec, body.Parameters); err != nil {
+		return nil, err
+	}
+
+	tpl := &domain.WorkloadTemplate{
+		ID:          uuid.New(),
+		ProjectID:   pID,
+		Name:        name,
+		Version:     version,
+		Description: body.Description,
+		Spec:        body.Spec,
+		Parameters:  body.Parameters,
+		CreatedBy:   session.UserID,
+	}
+
+	if err := s.Repo.Create(ctx, tpl); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save template")
+	}
+	return toTemplateResponse(tpl), nil
+}
+
+func (s *TemplateService) getTemplate(ctx context.Context, pID *uuid.UUID, name string, version int) (*domain.WorkloadTemplate, error) {
+	var (
+		tpl *domain.WorkloadTemplate
+		err error
+	)
+	if version > 0 {
+		tpl, err = s.Repo.GetVersion(ctx, pID, name, version)
+	} else {
+		tpl, err = s.Repo.GetLatest(ctx, pID, name)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, betoerrors.New(betoerrors.CodeNotFound, "template not found")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to get template")
+	}
+	return tpl, nil
+}
+
+// resolveScope returns nil for the org-wide scope, otherwise the ID of an existing project.
+func (s *TemplateService) resolveScope(ctx context.Context, projectID string) (*uuid.UUID, error) {
+	if projectID == "" {
+		return nil, nil
+	}
+
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	if _, err := s.ProjectRepo.GetProjectByID(ctx, pID.String()); err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+	return &pID, nil
+}
+
+func (s *TemplateService) checkWriteAccess(pID *uuid.UUID, session *cache.SessionData) error {
+	// une clé API n'agit jamais en admin plateforme
+	if pID == nil && (session.GlobalRole != s.Config.Roles.PlatformAdmin || session.APIKey != nil) {
+		return betoerrors.New(betoerrors.CodeForbidden, "only platform admins can edit org-wide templates")
+	}
+	return nil
+}
+
+func templateScope(t *domain.WorkloadTemplate) string {
+	if t.IsOrgWide() {
+		return utils.TemplateScopeOrg
+	}
+	return utils.TemplateScopeProject
+}
+
+func toTemplateResponse(t *domain.WorkloadTemplate) *domain.TemplateResponse {
+	resp := &domain.TemplateResponse{
+		ID:          t.ID.String(),
+		Scope:       templateScope(t),
+		Name:        t.Name,
+		Version:     t.Version,
+		Description: t.Description,
+		Spec:        t.Spec,
+		Parameters:  t.Parameters,
+		CreatedBy:   t.CreatedBy,
+		CreatedAt:   t.CreatedAt,
+	}
+	if t.ProjectID != nil {
+		resp.ProjectID = t.ProjectID.String()
+	}
+	return resp
+}
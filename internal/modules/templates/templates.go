@@ -0,0 +1,95 @@
+package templates
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/thekrauss/kubemanager/internal/core/cache"
+	"github.com/thekrauss/kubemanager/internal/middleware/security"
+	"github.com/thekrauss/kubemanager/internal/modules/templates/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/templates/service"
+	workloadDomain "github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+)
+
+type ITemplateController interface {
+	CreateProjectTemplate(c *gin.Context, in *domain.CreateProjectTemplateRequest) (*domain.TemplateResponse, error)
+	UpdateProjectTemplate(c *gin.Context, in *domain.UpdateProjectTemplateRequest) (*domain.TemplateResponse, error)
+	ListProjectTemplates(c *gin.Context, in *domain.ListTemplatesRequest) ([]domain.TemplateResponse, error)
+	GetProjectTemplate(c *gin.Context, in *domain.ProjectTemplateNameRequest) (*domain.TemplateResponse, error)
+	DeleteProjectTemplate(c *gin.Context, in *domain.ProjectTemplateNameRequest) (*domain.TemplateResponse, error)
+
+	CreateOrgTemplate(c *gin.Context, in *domain.CreateTemplateRequest) (*domain.TemplateResponse, error)
+	UpdateOrgTemplate(c *gin.Context, in *domain.UpdateTemplateRequest) (*domain.TemplateResponse, error)
+	ListOrgTemplates(c *gin.Context) ([]domain.TemplateResponse, error)
+	GetOrgTemplate(c *gin.Context, in *domain.TemplateNameRequest) (*domain.TemplateResponse, error)
+	DeleteOrgTemplate(c *gin.Context, in *domain.TemplateNameRequest) (*domain.TemplateResponse, error)
+
+	DeployFromTemplate(c *gin.Context, in *domain.DeployFromTemplateRequest) (*workloadDomain.WorkloadResponse, error)
+}
+
+type TemplateHandler struct {
+	TemplateService service.ITemplateService
+}
+
+func NewTemplateHandler(svc service.ITemplateService) *TemplateHandler {
+	return &TemplateHandler{TemplateService: svc}
+}
+
+func (h *TemplateHandler) CreateProjectTemplate(c *gin.Context, in *domain.CreateProjectTemplateRequest) (*domain.TemplateResponse, error) {
+	return h.TemplateService.CreateTemplate(c.Request.Context(), sessionFromCtx(c), in.ProjectID, in.CreateTemplateRequest)
+}
+
+func (h *TemplateHandler) UpdateProjectTemplate(c *gin.Context, in *domain.UpdateProjectTemplateRequest) (*domain.TemplateResponse, error) {
+	return h.TemplateService.UpdateTemplate(c.Request.Context(), sessionFromCtx(c), in.ProjectID, in.UpdateTemplateRequest)
+}
+
+func (h *TemplateHandler) ListProjectTemplates(c *gin.Context, in *domain.ListTemplatesRequest) ([]domain.TemplateResponse, error) {
+	return h.TemplateService.ListTemplates(c.Request.Context(), in.ProjectID)
+}
+
+func (h *TemplateHandler) GetProjectTemplate(c *gin.Context, in *domain.ProjectTemplateNameRequest) (*domain.TemplateResponse, error) {
+	return h.TemplateService.GetTemplate(c.Request.Context(), in.ProjectID, in.Name, in.Version)
+}
+
+func (h *TemplateHandler) DeleteProjectTemplate(c *gin.Context, in *domain.ProjectTemplateNameRequest) (*domain.TemplateResponse, error) {
+	if err := h.TemplateService.DeleteTemplate(c.Request.Context(), sessionFromCtx(c), in.ProjectID, in.Name); err != nil {
+		return nil, err
+	}
+	return &domain.TemplateResponse{ProjectID: in.ProjectID, Name: in.Name}, nil
+}
+
+func (h *TemplateHandler) CreateOrgTemplate(c *gin.Context, in *domain.CreateTemplateRequest) (*domain.TemplateResponse, error) {
+	return h.TemplateService.CreateTemplate(c.Request.Context(), sessionFromCtx(c), "", *in)
+}
+
+func (h *TemplateHandler) UpdateOrgTemplate(c *gin.Context, in *domain.UpdateTemplateRequest) (*domain.TemplateResponse, error) {
+	return h.TemplateService.UpdateTemplate(c.Request.Context(), sessionFromCtx(c), "", *in)
+}
+
+func (h *TemplateHandler) ListOrgTemplates(c *gin.Context) ([]domain.TemplateResponse, error) {
+	return h.TemplateService.ListTemplates(c.Request.Context(), "")
+}
+
+func (h *TemplateHandler) GetOrgTemplate(c *gin.Context, in *domain.TemplateNameRequest) (*domain.TemplateResponse, error) {
+	return h.TemplateService.GetTemplate(c.Request.Context(), "", in.Name, in.Version)
+}
+
+func (h *TemplateHandler) DeleteOrgTemplate(c *gin.Context, in *domain.TemplateNameRequest) (*domain.TemplateResponse, error) {
+	if err := h.TemplateService.DeleteTemplate(c.Request.Context(), sessionFromCtx(c), "", in.Name); err != nil {
+		return nil, err
+	}
+	return &domain.TemplateResponse{Name: in.Name}, nil
+}
+
+func (h *TemplateHandler) DeployFromTemplate(c *gin.Context, in *domain.DeployFromTemplateRequest) (*workloadDomain.WorkloadResponse, error) {
+	return h.TemplateService.DeployFromTemplate(c.Request.Context(), *in)
+}
+
+// sessionFromCtx returns an empty session (no global role) when the request is not authenticated.
+func sessionFromCtx(c *gin.Context) *cache.SessionData {
+	if val, ok := c.Get(security.UserSessionKey); ok {
+		if session, ok := val.(*cache.SessionData); ok {
+			return session
+		}
+	}
+	return &cache.SessionData{}
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "strings"
+
 const (
 	ProjectStatusPending      = "PENDING"
 	ProjectStatusProvisioning = "PROVISIONING"
@@ -23,11 +25,32 @@ const (
 )
 
 const (
-	WorkloadStarting = "STARTING"
-	WorkloadRunning  = "RUNNING"
-	WorkloadDegraded = "DEGRADED"
-	WorkloadFailed   = "FAILED"
-	WorkloadScaling  = "SCALING"
+	WorkloadStarting  = "STARTING"
+	WorkloadRunning   = "RUNNING"
+	WorkloadDegraded  = "DEGRADED"
+	WorkloadFailed    = "FAILED"
+	WorkloadScaling   = "SCALING"
+	WorkloadCanceled  = "CANCELED"
+	WorkloadRestoring = "RESTORING"
+	WorkloadSucceeded = "SUCCEEDED" // job terminé avec succès
+	WorkloadScheduled = "SCHEDULED" // cronjob installé, en attente de son planning
+	WorkloadDeleting  = "DELETING"
+)
+
+const (
+	WorkloadKindService = "service"
+	WorkloadKindWorker  = "worker" // Deployment sans Service ni Ingress
+	WorkloadKindJob     = "job"
+	WorkloadKindCronJob = "cronjob"
+)
+
+const (
+	VolumeTypePersistent = "persistent"
+	VolumeTypeEmptyDir   = "emptyDir"
+	VolumeTypeTmpfs      = "tmpfs" // emptyDir en mémoire, compté dans la limite mémoire du conteneur
+
+	// volume historique d'un workload (persistence_enabled), sa PVC reste "<release>-pvc"
+	PrimaryVolumeName = "data"
 )
 
 const (
@@ -37,6 +60,208 @@ const (
 	PhaseHelmReleaseSuccess    = "HELM_SUCCESS"
 )
 
+const (
+	PhaseHelmPreparing   = "HELM_PREPARING"
+	PhaseDeployed        = "DEPLOYED"
+	PhaseHelmError       = "HELM_ERROR"
+	PhaseSecretError     = "SECRET_CREATION_ERROR"
+	PhaseImageParseError = "IMAGE_PARSE_ERROR"
+)
+
+const (
+	PhaseJobRunning        = "JOB_RUNNING"
+	PhaseJobSucceeded      = "JOB_SUCCEEDED"
+	PhaseJobFailed         = "JOB_FAILED"
+	PhaseCronJobScheduled  = "CRONJOB_SCHEDULED"
+	PhaseCronJobTriggering = "CRONJOB_TRIGGERING"
+)
+
+const (
+	PhaseVolumeExpansionCheck = "VOLUME_EXPANSION_CHECK"
+	PhaseVolumeResizing       = "VOLUME_RESIZING"
+	PhaseFileSystemResizing   = "FILESYSTEM_RESIZING"
+	PhaseVolumeResized        = "VOLUME_RESIZED"
+	PhaseVolumeResizeFailed   = "VOLUME_RESIZE_FAILED"
+)
+
+const (
+	SnapshotPending = "PENDING"
+	SnapshotReady   = "READY"
+	SnapshotFailed  = "FAILED"
+)
+
+const (
+	SnapshotRestoreNewPVC  = "new-pvc"
+	SnapshotRestoreInPlace = "in-place"
+)
+
+const (
+	PhaseSnapshotCreating      = "SNAPSHOT_CREATING"
+	PhaseSnapshotReady         = "SNAPSHOT_READY"
+	PhaseSnapshotFailed        = "SNAPSHOT_FAILED"
+	PhaseWorkloadPausing       = "WORKLOAD_PAUSING"
+	PhaseSnapshotRestoring     = "SNAPSHOT_RESTORING"
+	PhaseSnapshotRestored      = "SNAPSHOT_RESTORED"
+	PhaseSnapshotRestoreFailed = "SNAPSHOT_RESTORE_FAILED"
+)
+
+const (
+	PhaseWorkloadUninstalling  = "WORKLOAD_UNINSTALLING"
+	PhaseWorkloadDeleteFailed  = "WORKLOAD_DELETE_FAILED"
+	PhaseWorkloadVolumesDelete = "WORKLOAD_VOLUMES_DELETING"
+)
+
+const (
+	AddonProvisioning = "PROVISIONING"
+	AddonReady        = "READY"
+	AddonFailed       = "FAILED"
+	AddonDeleting     = "DELETING"
+	AddonDeleted      = "DELETED"
+)
+
+const (
+	PhaseAddonSecretCreating = "ADDON_SECRET_CREATING"
+	PhaseAddonInstalling     = "ADDON_INSTALLING"
+	PhaseAddonReady          = "ADDON_READY"
+	PhaseAddonFailed         = "ADDON_FAILED"
+	PhaseAddonSnapshotting   = "ADDON_FINAL_SNAPSHOT"
+	PhaseAddonSnapshotFailed = "ADDON_FINAL_SNAPSHOT_FAILED"
+	PhaseAddonUninstalling   = "ADDON_UNINSTALLING"
+	PhaseAddonDeleted        = "ADDON_DELETED"
+)
+
+const (
+	TemplateScopeProject = "project"
+	TemplateScopeOrg     = "org"
+)
+
+// plan d'un manifeste de projet
+const (
+	ManifestActionCreate = "create"
+	ManifestActionUpdate = "update"
+	ManifestActionDelete = "delete"
+	ManifestActionOrphan = "orphan" // absent du manifeste, conservé sans ?prune=true
+)
+
+const (
+	ManifestResourceQuotas        = "quotas"
+	ManifestResourceMember        = "member"
+	ManifestResourceWorkload      = "workload"
+	ManifestResourceNetworkPolicy = "network_policy"
+)
+
+const (
+	PhaseManifestPruning         = "MANIFEST_PRUNING"
+	PhaseManifestQuotas          = "MANIFEST_QUOTAS_APPLYING"
+	PhaseManifestWorkloads       = "MANIFEST_WORKLOADS_APPLYING"
+	PhaseManifestNetworkPolicies = "MANIFEST_NETWORK_POLICIES_APPLYING"
+	PhaseManifestMembers         = "MANIFEST_MEMBERS_APPLYING"
+	PhaseManifestApplied         = "MANIFEST_APPLIED"
+	PhaseManifestPartial         = "MANIFEST_PARTIALLY_APPLIED"
+)
+
+const (
+	GitSyncPending = "PENDING"
+	GitSyncSynced  = "SYNCED"
+	GitSyncFailed  = "FAILED"
+)
+
+const (
+	PhaseGitFetching   = "GIT_FETCHING"
+	PhaseGitApplying   = "GIT_MANIFEST_APPLYING"
+	PhaseGitSynced     = "GIT_SYNCED"
+	PhaseGitUnchanged  = "GIT_UNCHANGED"
+	PhaseGitSyncFailed = "GIT_SYNC_FAILED"
+)
+
+const (
+	BuildPending   = "PENDING"
+	BuildRunning   = "RUNNING"
+	BuildSucceeded = "SUCCEEDED"
+	BuildFailed    = "FAILED"
+)
+
+const (
+	PhaseBuildResolving = "BUILD_REF_RESOLVING"
+	PhaseBuildRunning   = "BUILD_RUNNING"
+	PhaseBuildDeploying = "BUILD_DEPLOYING"
+	PhaseBuildSucceeded = "BUILD_SUCCEEDED"
+	PhaseBuildFailed    = "BUILD_FAILED"
+)
+
+// événements envoyés aux webhooks des projets
+const (
+	EventWorkloadDeployed = "workload.deployed"
+	EventWorkloadFailed   = "workload.failed"
+	EventProjectReady     = "project.ready"
+	EventMemberAdded      = "member.added"
+
+	EventAll = "*"
+)
+
+// événements publiés sur le bus RabbitMQ, le type sert de routing key
+const (
+	EventProjectCreated  = "project.created"
+	EventProjectDeleted  = "project.deleted"
+	EventWorkloadCreated = "workload.created"
+	EventWorkloadUpdated = "workload.updated"
+	EventWorkloadDeleted = "workload.deleted"
+	EventMemberUpdated   = "member.updated"
+	EventMemberRemoved   = "member.removed"
+	EventAPIKeyCreated   = "apikey.created"
+	EventAPIKeyRevoked   = "apikey.revoked"
+	EventAPIKeyRotated   = "apikey.rotated"
+	EventAPIKeyExpiring  = "apikey.expiring"
+
+	EventInvitationCreated  = "invitation.created"
+	EventInvitationAccepted = "invitation.accepted"
+	EventInvitationDeclined = "invitation.declined"
+	EventInvitationRevoked  = "invitation.revoked"
+
+	EventServiceAccountCreated = "serviceaccount.created"
+	EventServiceAccountDeleted = "serviceaccount.deleted"
+)
+
+// statuts d'une invitation à un projet
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationDeclined = "DECLINED"
+	InvitationRevoked  = "REVOKED"
+)
+
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+)
+
+// RegistrySecretName is the dockerconfigjson Secret of the build registry in a project namespace,
+// referenced as imagePullSecret by the workloads using a built image. Its login only pulls.
+const RegistrySecretName = "km-registry"
+
+// BuildGitSecretName and BuildRegistrySecretName are the Secrets of a Kaniko Job, owned by the Job:
+// the git token and the login pushing the image of the build.
+func BuildGitSecretName(jobName string) string {
+	return jobName + "-git"
+}
+
+func BuildRegistrySecretName(jobName string) string {
+	return jobName + "-registry"
+}
+
+// IsPlatformSecret reports the Secrets written by kubemanager in a project namespace that a
+// workload must not mount: registry logins, build tokens and Helm releases (values included).
+func IsPlatformSecret(name string) bool {
+	if name == RegistrySecretName || strings.HasPrefix(name, "sh.helm.release.") {
+		return true
+	}
+	return strings.HasPrefix(name, "build-") && (strings.HasSuffix(name, "-git") || strings.HasSuffix(name, "-registry"))
+}
+
+// QueryCurrentPhase is the Temporal query type exposing the phase of a running workflow.
+const QueryCurrentPhase = "current_phase"
+
 const (
 	HealthHealthy   = "HEALTHY"
 	HealthUnhealthy = "UNHEALTHY"
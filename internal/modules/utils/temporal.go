@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// LoadWorkflowInput decodes the input of the latest run of workflowID, as persisted by Temporal
+// in the WorkflowExecutionStarted event, so a failed run can be restarted with the same arguments.
+func LoadWorkflowInput(ctx context.Context, c client.Client, workflowID string, valuePtrs ...interface{}) error {
+	iter := c.GetWorkflowHistory(ctx, workflowID, "", false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	if !iter.HasNext() {
+		return fmt.Errorf("no history for workflow %s", workflowID)
+	}
+
+	event, err := iter.Next()
+	if err != nil {
+		return fmt.Errorf("failed to read workflow history: %w", err)
+	}
+
+	attrs := event.GetWorkflowExecutionStartedEventAttributes()
+	if attrs == nil {
+		return fmt.Errorf("first event of workflow %s is not a start event", workflowID)
+	}
+
+	return converter.GetDefaultDataConverter().FromPayloads(attrs.GetInput(), valuePtrs...)
+}
@@ -17,3 +17,9 @@ func ParseStorageToBytes(size string) int64 {
 	}
 	return res.Value()
 }
+
+// IsDeploymentKind reports whether the workload runs as a Deployment: its pods can be scaled
+// down and restarted, unlike the pods of a job or a cronjob.
+func IsDeploymentKind(kind string) bool {
+	return kind == "" || kind == WorkloadKindService || kind == WorkloadKindWorker
+}
@@ -0,0 +1,65 @@
+package activities
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsForbiddenAddress(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":       true,
+		"10.96.0.1":       true,
+		"172.20.3.4":      true,
+		"192.168.1.10":    true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fe80::1":         true,
+		"fd00::1":         true,
+		"::ffff:10.0.0.1": true,
+		"93.184.216.34":   false,
+		"2606:4700::1111": false,
+	}
+	for raw, want := range cases {
+		if got := IsForbiddenAddress(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("IsForbiddenAddress(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestIsInternalHost(t *testing.T) {
+	cases := map[string]bool{
+		"10.0.0.5":                      true,
+		"localhost":                     true,
+		"gitea":                         true,
+		"gitea.forge.svc":               true,
+		"metadata.google.internal":      true,
+		"printer.local.":                true,
+		"git.example.com":               false,
+		"hooks.slack.com":               false,
+		"93.184.216.34":                 false,
+		"gitea.forge.svc.cluster.local": true,
+	}
+	for host, want := range cases {
+		if got := IsInternalHost(host); got != want {
+			t.Errorf("IsInternalHost(%s) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestDeliveryClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request must not reach a loopback endpoint")
+	}))
+	defer srv.Close()
+
+	_, err := NewDeliveryClient(5 * time.Second).Get(srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/repository"
+)
+
+const (
+	// "sha256=<hex>" : HMAC-SHA256 du corps avec le secret du webhook
+	SignatureHeader = "X-Kubemanager-Signature-256"
+	EventHeader     = "X-Kubemanager-Event"
+	DeliveryHeader  = "X-Kubemanager-Delivery"
+
+	// réponse conservée dans le journal des livraisons
+	maxResponseBody = 2048
+)
+
+type DeliveryActivities struct {
+	Repo       repository.WebhookRepository
+	HTTPClient *http.Client
+	Logger     *zap.SugaredLogger
+}
+
+// SendWebhook makes one HTTP call, any answer other than 2xx is an error retried by the
+// workflow policy. The secret is read at each attempt so a rotated secret applies to pending deliveries.
+func (a *DeliveryActivities) SendWebhook(ctx context.Context, deliveryID string) error {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid delivery id", "InvalidDelivery", err)
+	}
+	delivery, err := a.Repo.GetDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return temporal.NewNonRetryableApplicationError("delivery not found", "InvalidDelivery", err)
+		}
+		return err
+	}
+	webhook, err := a.Repo.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return temporal.NewNonRetryableApplicationError("webhook deleted", "WebhookDeleted", err)
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid webhook url", "InvalidWebhook", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kubemanager-webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, []byte(delivery.Payload)))
+
+	start := time.Now()
+	resp, err := a.HTTPClient.Do(req)
+	result := repository.AttemptResult{
+		Status:     utils.DeliveryPending,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		a.record(ctx, id, result)
+		// l'hôte pointe vers une adresse interne, réessayer ne changera rien
+		if errors.Is(err, ErrForbiddenAddress) {
+			return temporal.NewNonRetryableApplicationError(result.Error, "ForbiddenAddress", err)
+		}
+		return fmt.Errorf("webhook call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result.ResponseCode = resp.StatusCode
+	result.ResponseBody = string(body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		result.Status = utils.DeliveryDelivered
+		a.record(ctx, id, result)
+		return nil
+	}
+
+	result.Error = fmt.Sprintf("endpoint answered %d", resp.StatusCode)
+	a.record(ctx, id, result)
+
+	// 410 : l'endpoint n'existe plus, inutile d'insister
+	if resp.StatusCode == http.StatusGone {
+		return temporal.NewNonRetryableApplicationError(result.Error, "WebhookGone", nil)
+	}
+	return errors.New(result.Error)
+}
+
+func (a *DeliveryActivities) MarkDeliveryFailed(ctx context.Context, deliveryID, reason string) error {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid delivery id", "InvalidDelivery", err)
+	}
+	return a.Repo.MarkDeliveryFailed(ctx, id, reason)
+}
+
+// record keeps the attempt in the delivery log, a failure to write it must not hide the HTTP outcome.
+func (a *DeliveryActivities) record(ctx context.Context, id uuid.UUID, result repository.AttemptResult) {
+	if err := a.Repo.RecordAttempt(ctx, id, result); err != nil {
+		a.Logger.Warnw("failed to record webhook attempt", "delivery_id", id, "error", err)
+	}
+}
+
+// Sign returns the value of the signature header, receivers recompute it on the raw body.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
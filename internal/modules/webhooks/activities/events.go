@@ -0,0 +1,17 @@
+package activities
+
+import (
+	"context"
+
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+)
+
+type EventActivities struct {
+	Emitter domain.EventEmitter
+}
+
+// EmitEvent records the deliveries of the event and starts them, an event emitted twice
+// by a retried activity keeps the same deliveries.
+func (a *EventActivities) EmitEvent(ctx context.Context, event domain.Event) error {
+	return a.Emitter.Emit(ctx, event)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+type CreateWebhookRequest struct {
+	ProjectID   string   `path:"id" desc:"ID du projet"`
+	URL         string   `json:"url" binding:"required,url" desc:"Endpoint HTTP(S) appelé en POST"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" binding:"required,min=1" desc:"workload.deployed, workload.failed, project.ready, member.added ou *"`
+	Secret      string   `json:"secret" desc:"Clé HMAC, générée si vide"`
+}
+
+type UpdateWebhookRequest struct {
+	ProjectID   string   `path:"id" desc:"ID du projet"`
+	WebhookID   string   `path:"webhookID" desc:"ID du webhook"`
+	URL         string   `json:"url" binding:"omitempty,url"`
+	Description *string  `json:"description"`
+	Events      []string `json:"events"`
+	Active      *bool    `json:"active"`
+}
+
+type ProjectWebhooksRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+}
+
+type WebhookPathRequest struct {
+	ProjectID string `path:"id" desc:"ID du projet"`
+	WebhookID string `path:"webhookID" desc:"ID du webhook"`
+}
+
+type DeliveryPathRequest struct {
+	ProjectID  string `path:"id" desc:"ID du projet"`
+	WebhookID  string `path:"webhookID" desc:"ID du webhook"`
+	DeliveryID string `path:"deliveryID" desc:"ID de la livraison"`
+}
+
+type WebhookResponse struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"secret,omitempty"` // uniquement à la création
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	ID           string     `json:"id"`
+	WebhookID    string     `json:"webhook_id"`
+	EventID      string     `json:"event_id"`
+	Event        string     `json:"event"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	ResponseCode int        `json:"response_code,omitempty"`
+	ResponseBody string     `json:"response_body,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	DurationMs   int64      `json:"duration_ms"`
+	RedeliveryOf string     `json:"redelivery_of,omitempty"`
+	WorkflowID   string     `json:"workflow_id,omitempty"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
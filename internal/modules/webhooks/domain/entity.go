@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is an HTTP endpoint of a project subscribed to a set of events.
+type Webhook struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	URL         string    `gorm:"type:text;not null"`
+	Description string    `gorm:"type:varchar(255)"`
+	Secret      string    `gorm:"type:varchar(128);not null"` // clé HMAC des signatures
+	Events      []string  `gorm:"type:jsonb;serializer:json"` // types d'événements, "*" pour tous
+	Active      bool      `gorm:"default:true"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Accepts tells whether the webhook is subscribed to the event type.
+func (w *Webhook) Accepts(eventType string) bool {
+	for _, e := range w.Events {
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one delivery of an event to a webhook, the payload is kept for redeliveries.
+type WebhookDelivery struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	WebhookID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ProjectID uuid.UUID `gorm:"type:uuid;index;not null"`
+	EventID   string    `gorm:"type:varchar(64);index;not null"`
+	Event     string    `gorm:"type:varchar(50);not null"`
+	Payload   string    `gorm:"type:text;not null"`
+
+	Status       string     `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	Attempts     int        `gorm:"default:0"`
+	ResponseCode int        `gorm:"default:0"`
+	ResponseBody string     `gorm:"type:text"` // tronquée
+	Error        string     `gorm:"type:text"`
+	DurationMs   int64      `gorm:"default:0"`
+	RedeliveryOf *uuid.UUID `gorm:"type:uuid"`
+	WorkflowID   string     `gorm:"type:varchar(100)"`
+	DeliveredAt  *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
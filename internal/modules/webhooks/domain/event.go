@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is the JSON body sent to the webhooks.
+type Event struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	ProjectID  string                 `json:"project_id"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// EventEmitter fans an event out to the webhooks of its project, it is implemented by the
+// webhook service and reached by the workflows through the EmitEvent activity.
+type EventEmitter interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+func NewEvent(eventType, projectID string, data map[string]interface{}) Event {
+	return Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		ProjectID:  projectID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
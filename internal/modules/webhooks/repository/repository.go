@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+)
+
+// deliveries listed per webhook, the most recent first
+const deliveryLogSize = 100
+
+// AttemptResult is the outcome of one HTTP call of a delivery.
+type AttemptResult struct {
+	Status       string
+	ResponseCode int
+	ResponseBody string
+	Error        string
+	DurationMs   int64
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	Save(ctx context.Context, webhook *domain.Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Webhook, error)
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Webhook, error)
+	ListActiveByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Webhook, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID) ([]domain.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, id uuid.UUID, result AttemptResult) error
+	MarkDeliveryFailed(ctx context.Context, id uuid.UUID, reason string) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepository) Save(ctx context.Context, webhook *domain.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	err := r.db.WithContext(ctx).First(&webhook, "id = ?", id).Error
+	return &webhook, err
+}
+
+func (r *webhookRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("created_at ASC").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) ListActiveByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND active = ?", projectID, true).
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+// Delete removes the webhook and its delivery log.
+func (r *webhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&domain.WebhookDelivery{}, "webhook_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Webhook{}, "id = ?", id).Error
+	})
+}
+
+// CreateDelivery returns false when the delivery already exists, an event emitted twice
+// (retried activity) keeps a single delivery per webhook.
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := r.db.WithContext(ctx).First(&delivery, "id = ?", id).Error
+	return &delivery, err
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Omit("payload").
+		Where("webhook_id = ?", webhookID).
+		Order("created_at DESC").
+		Limit(deliveryLogSize).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) RecordAttempt(ctx context.Context, id uuid.UUID, result AttemptResult) error {
+	updates := map[string]interface{}{
+		"status":        result.Status,
+		"attempts":      gorm.Expr("attempts + 1"),
+		"response_code": result.ResponseCode,
+		"response_body": result.ResponseBody,
+		"error":         result.Error,
+		"duration_ms":   result.DurationMs,
+	}
+	if result.Status == utils.DeliveryDelivered {
+		updates["delivered_at"] = time.Now()
+	}
+
+	return r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func (r *webhookRepository) MarkDeliveryFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).
+		Where("id = ? AND status <> ?", id, utils.DeliveryDelivered).
+		Updates(map[string]interface{}{
+			"status": utils.DeliveryFailed,
+			"error":  reason,
+		}).Error
+}
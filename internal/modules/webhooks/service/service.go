@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/workflows"
+)
+
+var supportedEvents = map[string]bool{
+	utils.EventWorkloadDeployed: true,
+	utils.EventWorkloadFailed:   true,
+	utils.EventProjectReady:     true,
+	utils.EventMemberAdded:      true,
+	utils.EventAll:              true,
+}
+
+type IWebhookService interface {
+	domain.EventEmitter
+
+	CreateWebhook(ctx context.Context, req domain.CreateWebhookRequest) (*domain.WebhookResponse, error)
+	ListWebhooks(ctx context.Context, projectID string) ([]domain.WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, req domain.UpdateWebhookRequest) (*domain.WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, projectID, webhookID string) error
+	ListDeliveries(ctx context.Context, projectID, webhookID string) ([]domain.DeliveryResponse, error)
+	Redeliver(ctx context.Context, projectID, webhookID, deliveryID string) (*domain.DeliveryResponse, error)
+}
+
+var _ IWebhookService = (*WebhookService)(nil)
+
+type WebhookService struct {
+	TemporalClient client.Client
+	Config         *configs.GlobalConfig
+	Logger         *zap.SugaredLogger
+	Repo           repository.WebhookRepository
+	ProjectRepo    projectRepo.ProjectRepository
+}
+
+func NewWebhookService(
+	tc client.Client,
+	cfg *configs.GlobalConfig,
+	log *zap.SugaredLogger,
+	repo repository.WebhookRepository,
+	pRepo projectRepo.ProjectRepository,
+) IWebhookService {
+	return &WebhookService{
+		TemporalClient: tc,
+		Config:         cfg,
+		Logger:         log.With("service", "WebhookService"),
+		Repo:           repo,
+		ProjectRepo:    pRepo,
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, req domain.CreateWebhookRequest) (*domain.WebhookResponse, error) {
+	pID, err := s.getProjectID(ctx, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEvents(req.Events); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to generate webhook secret")
+		}
+	}
+
+	webhook := &domain.Webhook{
+		ID:          uuid.New(),
+		ProjectID:   pID,
+		URL:         req.URL,
+		Description: req.Description,
+		Secret:      secret,
+		Events:      req.Events,
+		Active:      true,
+	}
+	if err := s.Repo.Create(ctx, webhook); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to create webhook")
+	}
+
+	s.Logger.Infow("webhook created", "project_id", pID, "webhook_id", webhook.ID, "events", webhook.Events)
+
+	resp := toWebhookResponse(webhook)
+	resp.Secret = webhook.Secret
+	return resp, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, projectID string) ([]domain.WebhookResponse, error) {
+	pID, err := s.getProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := s.Repo.ListByProject(ctx, pID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list webhooks")
+	}
+
+	resp := make([]domain.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		resp = append(resp, *toWebhookResponse(&webhooks[i]))
+	}
+	return resp, nil
+}
+
+// UpdateWebhook changes the fields sent, the secret is never returned again.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, req domain.UpdateWebhookRequest) (*domain.WebhookResponse, error) {
+	webhook, err := s.getWebhook(ctx, req.ProjectID, req.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != "" {
+		if err := validateURL(req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = req.URL
+	}
+	if req.Events != nil {
+		if err := validateEvents(req.Events); err != nil {
+			return nil, err
+		}
+		webhook.Events = req.Events
+	}
+	if req.Description != nil {
+		webhook.Description = *req.Description
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := s.Repo.Save(ctx, webhook); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to update webhook")
+	}
+	return toWebhookResponse(webhook), nil
+}
+
+// DeleteWebhook removes the webhook and its log, the deliveries still retrying fail on their next attempt.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, projectID, webhookID string) error {
+	webhook, err := s.getWebhook(ctx, projectID, webhookID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, webhook.ID); err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to delete webhook")
+	}
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, projectID, webhookID string) ([]domain.DeliveryResponse, error) {
+	webhook, err := s.getWebhook(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.Repo.ListDeliveries(ctx, webhook.ID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list deliveries")
+	}
+
+	resp := make([]domain.DeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		resp = append(resp, *toDeliveryResponse(&deliveries[i]))
+	}
+	return resp, nil
+}
+
+// Redeliver sends the payload of a past delivery again as a new delivery, with the current
+// secret and URL of the webhook. The original entry is left untouched in the log.
+func (s *WebhookService) Redeliver(ctx context.Context, projectID, webhookID, deliveryID string) (*domain.DeliveryResponse, error) {
+	webhook, err := s.getWebhook(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	dID, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid delivery id")
+	}
+	original, err := s.Repo.GetDelivery(ctx, dID)
+	if err != nil || original.WebhookID != webhook.ID {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "delivery not found")
+	}
+
+	delivery := &domain.WebhookDelivery{
+		ID:           uuid.New(),
+		WebhookID:    webhook.ID,
+		ProjectID:    webhook.ProjectID,
+		EventID:      original.EventID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		Status:       utils.DeliveryPending,
+		RedeliveryOf: &original.ID,
+	}
+	if err := s.deliver(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return toDeliveryResponse(delivery), nil
+}
+
+// Emit records one delivery per subscribed webhook and starts its workflow. The delivery ID
+// is derived from the webhook and the event so that an event emitted twice is sent once.
+func (s *WebhookService) Emit(ctx context.Context, event domain.Event) error {
+	pID, err := uuid.Parse(event.ProjectID)
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	webhooks, err := s.Repo.ListActiveByProject(ctx, pID)
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list webhooks")
+	}
+
+	var payload []byte
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Accepts(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to encode event")
+			}
+		}
+
+		delivery := &domain.WebhookDelivery{
+			ID:        uuid.NewSHA1(webhook.ID, []byte(event.ID)),
+			WebhookID: webhook.ID,
+			ProjectID: pID,
+			EventID:   event.ID,
+			Event:     event.Type,
+			Payload:   string(payload),
+			Status:    utils.DeliveryPending,
+		}
+		if err := s.deliver(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver saves the delivery if it does not exist yet and starts its workflow. The workflow ID
+// cannot be reused, a delivery already sent is not sent again by a retried emit.
+func (s *WebhookService) deliver(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	delivery.WorkflowID = deliveryWorkflowID(delivery.ID.String())
+	if _, err := s.Repo.CreateDelivery(ctx, delivery); err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to record delivery")
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                    delivery.WorkflowID,
+		TaskQueue:             s.Config.Temporal.TaskQueue,
+		WorkflowIDReusePolicy: enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+	}
+	if _, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.DeliverWebhookWorkflow, delivery.ID.String()); err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil
+		}
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start webhook delivery")
+	}
+	return nil
+}
+
+func (s *WebhookService) getProjectID(ctx context.Context, projectID string) (uuid.UUID, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return uuid.Nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	if _, err := s.ProjectRepo.GetProjectByID(ctx, projectID); err != nil {
+		return uuid.Nil, betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+	return pID, nil
+}
+
+// getWebhook only returns a webhook of the project given in the path.
+func (s *WebhookService) getWebhook(ctx context.Context, projectID, webhookID string) (*domain.Webhook, error) {
+	pID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid project id")
+	}
+	id, err := uuid.Parse(webhookID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid webhook id")
+	}
+	webhook, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, betoerrors.New(betoerrors.CodeNotFound, "webhook not found")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to get webhook")
+	}
+	if webhook.ProjectID != pID {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "webhook not found")
+	}
+	return webhook, nil
+}
+
+// validateURL rejects obviously internal endpoints early, the delivery client checks the
+// resolved address again at each call.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "url must be an http(s) endpoint")
+	}
+	if activities.IsInternalHost(u.Hostname()) {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "url must not target a private or internal host")
+	}
+	return nil
+}
+
+func validateEvents(events []string) error {
+	if len(events) == 0 {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "at least one event is required")
+	}
+	for _, e := range events {
+		if !supportedEvents[e] {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "unsupported event: "+e)
+		}
+	}
+	return nil
+}
+
+func deliveryWorkflowID(deliveryID string) string {
+	return "webhook-delivery-" + deliveryID
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func toWebhookResponse(w *domain.Webhook) *domain.WebhookResponse {
+	return &domain.WebhookResponse{
+		ID:          w.ID.String(),
+		ProjectID:   w.ProjectID.String(),
+		URL:         w.URL,
+		Description: w.Description,
+		Events:      w.Events,
+		Active:      w.Active,
+		CreatedAt:   w.CreatedAt,
+	}
+}
+
+func toDeliveryResponse(d *domain.WebhookDelivery) *domain.DeliveryResponse {
+	resp := &domain.DeliveryResponse{
+		ID:           d.ID.String(),
+		WebhookID:    d.WebhookID.String(),
+		EventID:      d.EventID,
+		Event:        d.Event,
+		Status:       d.Status,
+		Attempts:     d.Attempts,
+		ResponseCode: d.ResponseCode,
+		ResponseBody: d.ResponseBody,
+		Error:        d.Error,
+		DurationMs:   d.DurationMs,
+		WorkflowID:   d.WorkflowID,
+		DeliveredAt:  d.DeliveredAt,
+		CreatedAt:    d.CreatedAt,
+	}
+	if d.RedeliveryOf != nil {
+		resp.RedeliveryOf = d.RedeliveryOf.String()
+	}
+	return resp
+}
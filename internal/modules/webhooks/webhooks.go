@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/service"
+)
+
+type IWebhookController interface {
+	CreateWebhook(c *gin.Context, in *domain.CreateWebhookRequest) (*domain.WebhookResponse, error)
+	ListWebhooks(c *gin.Context, in *domain.ProjectWebhooksRequest) ([]domain.WebhookResponse, error)
+	UpdateWebhook(c *gin.Context, in *domain.UpdateWebhookRequest) (*domain.WebhookResponse, error)
+	DeleteWebhook(c *gin.Context, in *domain.WebhookPathRequest) error
+	ListDeliveries(c *gin.Context, in *domain.WebhookPathRequest) ([]domain.DeliveryResponse, error)
+	Redeliver(c *gin.Context, in *domain.DeliveryPathRequest) (*domain.DeliveryResponse, error)
+}
+
+type WebhookHandler struct {
+	WebhookService service.IWebhookService
+}
+
+func NewWebhookHandler(svc service.IWebhookService) *WebhookHandler {
+	return &WebhookHandler{WebhookService: svc}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context, in *domain.CreateWebhookRequest) (*domain.WebhookResponse, error) {
+	return h.WebhookService.CreateWebhook(c.Request.Context(), *in)
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context, in *domain.ProjectWebhooksRequest) ([]domain.WebhookResponse, error) {
+	return h.WebhookService.ListWebhooks(c.Request.Context(), in.ProjectID)
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context, in *domain.UpdateWebhookRequest) (*domain.WebhookResponse, error) {
+	return h.WebhookService.UpdateWebhook(c.Request.Context(), *in)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context, in *domain.WebhookPathRequest) error {
+	return h.WebhookService.DeleteWebhook(c.Request.Context(), in.ProjectID, in.WebhookID)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context, in *domain.WebhookPathRequest) ([]domain.DeliveryResponse, error) {
+	return h.WebhookService.ListDeliveries(c.Request.Context(), in.ProjectID, in.WebhookID)
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context, in *domain.DeliveryPathRequest) (*domain.DeliveryResponse, error) {
+	return h.WebhookService.Redeliver(c.Request.Context(), in.ProjectID, in.WebhookID, in.DeliveryID)
+}
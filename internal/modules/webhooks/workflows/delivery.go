@@ -0,0 +1,40 @@
+package workflows
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+)
+
+// DeliverWebhookWorkflow calls the webhook until it answers 2xx, with an exponential backoff
+// (10s, 20s, 40s ... capped at 30min) over about 3 hours.
+func DeliverWebhookWorkflow(ctx workflow.Context, deliveryID string) error {
+	sendCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    10 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Minute,
+			MaximumAttempts:    12,
+		},
+	})
+
+	var a *activities.DeliveryActivities
+	err := workflow.ExecuteActivity(sendCtx, a.SendWebhook, deliveryID).Get(ctx, nil)
+	if err == nil {
+		return nil
+	}
+
+	markCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 5,
+		},
+	})
+	_ = workflow.ExecuteActivity(markCtx, a.MarkDeliveryFailed, deliveryID, err.Error()).Get(ctx, nil)
+	return err
+}
@@ -0,0 +1,39 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/webhooks/domain"
+)
+
+// EmitEvent sends an event to the webhooks of the project from a workflow. The event ID is
+// derived from the run so a replay or a retry never doubles the deliveries; a failure is
+// only logged, a notification must not fail the workflow that emits it.
+func EmitEvent(ctx workflow.Context, eventType, projectID string, data map[string]interface{}) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 5,
+		},
+	})
+
+	info := workflow.GetInfo(ctx)
+	event := domain.Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(info.WorkflowExecution.RunID+"/"+eventType)).String(),
+		Type:       eventType,
+		ProjectID:  projectID,
+		OccurredAt: workflow.Now(ctx).UTC(),
+		Data:       data,
+	}
+
+	var a *activities.EventActivities
+	if err := workflow.ExecuteActivity(ctx, a.EmitEvent, event).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("failed to emit webhook event", "event", eventType, "error", err)
+	}
+}
@@ -2,17 +2,27 @@ package activities
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
-	"github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+	"go.temporal.io/sdk/temporal"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+
+	addonRepo "github.com/thekrauss/kubemanager/internal/modules/addons/repository"
+	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
 )
 
 type WorkloadDBActivities struct {
-	DB     *gorm.DB
-	Logger *zap.SugaredLogger
-	Repo   repository.WorkloadRepository
+	DB           *gorm.DB
+	Logger       *zap.SugaredLogger
+	Repo         repository.WorkloadRepository
+	ProjectRepo  projectRepo.ProjectRepository
+	SnapshotRepo repository.SnapshotRepository
+	AddonRepo    addonRepo.AddonRepository
 }
 
 func (a *WorkloadDBActivities) UpdateWorkloadStatus(ctx context.Context, workloadID string, status string, phase string) error {
@@ -21,3 +31,170 @@ func (a *WorkloadDBActivities) UpdateWorkloadStatus(ctx context.Context, workloa
 	uID, _ := uuid.Parse(workloadID)
 	return a.Repo.UpdateStatus(ctx, uID, status, phase)
 }
+
+// CheckStorageQuota re-checks the project StorageLimit right before the primary volume is grown,
+// its current size is replaced by the requested one in the project total.
+func (a *WorkloadDBActivities) CheckStorageQuota(ctx context.Context, workloadID string, newSize string) error {
+	uID, err := uuid.Parse(workloadID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid workload id", "InvalidInput", err)
+	}
+
+	workload, err := a.Repo.GetByID(ctx, uID)
+	if err != nil {
+		return fmt.Errorf("workload not found: %w", err)
+	}
+
+	project, err := a.ProjectRepo.GetProjectByID(ctx, workload.ProjectID.String())
+	if err != nil {
+		return fmt.Errorf("project not found: %w", err)
+	}
+
+	workloads, err := a.Repo.ListByProject(ctx, workload.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, w := range workloads {
+		if w.Status == utils.WorkloadFailed {
+			continue
+		}
+		for _, v := range w.PersistentVolumes() {
+			if w.ID == workload.ID && v.Name == utils.PrimaryVolumeName {
+				continue
+			}
+			total += utils.ParseStorageToBytes(v.Size)
+		}
+	}
+	total += utils.ParseStorageToBytes(newSize)
+
+	// les add-ons du projet partagent le même quota (en Mi)
+	_, _, addonStorage, err := a.AddonRepo.GetTotalUsageByProject(ctx, workload.ProjectID)
+	if err != nil {
+		return err
+	}
+	total += addonStorage * 1024 * 1024
+
+	if limit := utils.ParseStorageToBytes(project.StorageLimit); total > limit {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("quota exceeded: Storage limit reached (%d/%d bytes)", total, limit),
+			"QuotaExceeded", nil,
+		)
+	}
+	return nil
+}
+
+func (a *WorkloadDBActivities) UpdateWorkloadStorage(ctx context.Context, workloadID string, size string) error {
+	a.Logger.Infow("Updating workload storage size in DB", "id", workloadID, "size", size)
+
+	uID, _ := uuid.Parse(workloadID)
+	return a.Repo.UpdateStorageSize(ctx, uID, size)
+}
+
+// DeleteWorkloadRecord removes the workload, its volumes and its add-on bindings. The snapshots
+// are kept: they outlive the PVC and can still be restored into another workload.
+func (a *WorkloadDBActivities) DeleteWorkloadRecord(ctx context.Context, workloadID string) error {
+	uID, err := uuid.Parse(workloadID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid workload id", "InvalidInput", err)
+	}
+
+	if err := a.AddonRepo.DeleteBindingsByWorkload(ctx, uID); err != nil {
+		return err
+	}
+	return a.Repo.Delete(ctx, uID)
+}
+
+// SnapshotRef identifies a snapshot both in DB and in the cluster.
+type SnapshotRef struct {
+	SnapshotID    string
+	WorkloadID    string
+	Namespace     string
+	Name          string
+	PVCName       string
+	SnapshotClass string
+}
+
+func NewSnapshotRef(s *domain.VolumeSnapshot) SnapshotRef {
+	return SnapshotRef{
+		SnapshotID:    s.ID.String(),
+		WorkloadID:    s.WorkloadID.String(),
+		Namespace:     s.Namespace,
+		Name:          s.Name,
+		PVCName:       s.PVCName,
+		SnapshotClass: s.SnapshotClass,
+	}
+}
+
+func SnapshotName(releaseName string, id uuid.UUID) string {
+	return fmt.Sprintf("%s-snap-%s", releaseName, id.String()[:8])
+}
+
+func (a *WorkloadDBActivities) UpdateSnapshotStatus(ctx context.Context, snapshotID string, status string, restoreSize string) error {
+	sID, _ := uuid.Parse(snapshotID)
+	return a.SnapshotRepo.UpdateStatus(ctx, sID, status, restoreSize)
+}
+
+func (a *WorkloadDBActivities) DeleteSnapshotRecord(ctx context.Context, snapshotID string) error {
+	sID, _ := uuid.Parse(snapshotID)
+	return a.SnapshotRepo.Delete(ctx, sID)
+}
+
+// PrepareScheduledSnapshot records a PENDING snapshot for a scheduled run, the workload
+// is read at run time since its volume may have been resized since the schedule was set.
+func (a *WorkloadDBActivities) PrepareScheduledSnapshot(ctx context.Context, workloadID string, snapshotClass string) (SnapshotRef, error) {
+	uID, err := uuid.Parse(workloadID)
+	if err != nil {
+		return SnapshotRef{}, temporal.NewNonRetryableApplicationError("invalid workload id", "InvalidInput", err)
+	}
+
+	workload, err := a.Repo.GetByID(ctx, uID)
+	if err != nil {
+		return SnapshotRef{}, fmt.Errorf("workload not found: %w", err)
+	}
+	if !workload.PersistenceEnabled {
+		return SnapshotRef{}, temporal.NewNonRetryableApplicationError("workload has no persistent volume", "InvalidInput", nil)
+	}
+
+	snapshotID := uuid.New()
+	snapshot := &domain.VolumeSnapshot{
+		ID:            snapshotID,
+		WorkloadID:    workload.ID,
+		Name:          SnapshotName(workload.Name, snapshotID),
+		Namespace:     workload.Namespace,
+		PVCName:       PVCName(workload.Name),
+		SnapshotClass: snapshotClass,
+		Status:        utils.SnapshotPending,
+		Scheduled:     true,
+	}
+	if err := a.SnapshotRepo.Create(ctx, snapshot); err != nil {
+		return SnapshotRef{}, err
+	}
+	return NewSnapshotRef(snapshot), nil
+}
+
+// ListExpiredSnapshots returns the scheduled snapshots beyond the retention count of ready ones,
+// plus the failed ones. Manual snapshots are never pruned.
+func (a *WorkloadDBActivities) ListExpiredSnapshots(ctx context.Context, workloadID string, retention int) ([]SnapshotRef, error) {
+	uID, _ := uuid.Parse(workloadID)
+
+	snapshots, err := a.SnapshotRepo.ListByWorkload(ctx, uID)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []SnapshotRef
+	kept := 0
+	for i := range snapshots {
+		if !snapshots[i].Scheduled || snapshots[i].Status == utils.SnapshotPending {
+			continue
+		}
+		if snapshots[i].Status == utils.SnapshotReady && kept < retention {
+			kept++
+			continue
+		}
+		expired = append(expired, NewSnapshotRef(&snapshots[i]))
+	}
+	return expired, nil
+}
@@ -2,20 +2,35 @@ package activities
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"go.temporal.io/sdk/activity"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type WorkloadActivities struct {
-	K8sConfig *rest.Config
-	K8sClient *kubernetes.Clientset
+	K8sConfig     *rest.Config
+	K8sClient     *kubernetes.Clientset
+	DynamicClient dynamic.Interface
+}
+
+type EnvSecretRef struct {
+	Name   string
+	Prefix string
 }
 
 type InstallWorkloadInput struct {
@@ -23,6 +38,7 @@ type InstallWorkloadInput struct {
 	Namespace          string
 	ImageRepo          string
 	ImageTag           string
+	ImageDigest        string
 	ExternalURL        string
 	ServiceType        string //"ClusterIP" ou "LoadBalancer"
 	Env                map[string]string
@@ -30,18 +46,40 @@ type InstallWorkloadInput struct {
 	PersistenceEnabled bool
 	StorageSize        string
 	StorageClass       string
+	AccessMode         string
 	Replicas           int
 	TargetPort         int
 	MountPath          string
+	Volumes            []VolumeSpec
+
+	// secrets de connexion des add-ons liés (envFrom)
+	EnvSecrets []EnvSecretRef
+
+	Kind                    string
+	Schedule                string
+	ConcurrencyPolicy       string
+	BackoffLimit            *int
+	TTLSecondsAfterFinished int
 }
 
-func (a *WorkloadActivities) InstallChart(ctx context.Context, input InstallWorkloadInput) error {
+// heartbeat interval of long running helm actions, must stay below the workflow HeartbeatTimeout
+const helmHeartbeatInterval = 10 * time.Second
+
+func (a *WorkloadActivities) newActionConfig(namespace string) (*action.Configuration, error) {
 	settings := cli.New()
 
 	actionConfig := new(action.Configuration)
-	if err := actionConfig.Init(settings.RESTClientGetter(), input.Namespace, "secret", func(format string, v ...interface{}) {
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, "secret", func(format string, v ...interface{}) {
 		fmt.Printf(format, v...)
 	}); err != nil {
+		return nil, err
+	}
+	return actionConfig, nil
+}
+
+func (a *WorkloadActivities) InstallChart(ctx context.Context, input InstallWorkloadInput) error {
+	actionConfig, err := a.newActionConfig(input.Namespace)
+	if err != nil {
 		return err
 	}
 
@@ -49,7 +87,7 @@ func (a *WorkloadActivities) InstallChart(ctx context.Context, input InstallWork
 	client.Install = true
 	client.Namespace = input.Namespace
 	client.Wait = true
-	client.Timeout = 5 * 60
+	client.Timeout = 5 * time.Minute
 
 	chartPath := "/app/internal/infrastructure/helm/charts/standard-app"
 	chart, err := loader.Load(chartPath)
@@ -57,11 +95,15 @@ func (a *WorkloadActivities) InstallChart(ctx context.Context, input InstallWork
 		return fmt.Errorf("failed to load chart: %w", err)
 	}
 	vals := map[string]interface{}{
+		"kind":         workloadKind(input.Kind),
+		"batch":        batchValues(input),
 		"replicaCount": input.Replicas,
 		"image": map[string]interface{}{
 			"repository": input.ImageRepo,
 			"tag":        input.ImageTag,
+			"digest":     input.ImageDigest,
 		},
+		"imagePullSecrets": registryPullSecrets(input.ImageRepo),
 		"ingress": map[string]interface{}{
 			"host": input.ExternalURL,
 		},
@@ -78,12 +120,8 @@ func (a *WorkloadActivities) InstallChart(ctx context.Context, input InstallWork
 			"ingressNSLabelValue": "kube-system",
 		},
 
-		"persistence": map[string]interface{}{
-			"enabled":      input.PersistenceEnabled,
-			"size":         input.StorageSize,
-			"storageClass": input.StorageClass,
-			"mountPath":    input.MountPath,
-		},
+		"volumes":       volumeValues(input),
+		"envSecrets":    envSecretValues(input.EnvSecrets),
 		"envSecretName": input.ReleaseName + "-env",
 	}
 
@@ -91,9 +129,125 @@ func (a *WorkloadActivities) InstallChart(ctx context.Context, input InstallWork
 		vals["envVars"] = input.Env
 	}
 
-	_, err = client.Run(input.ReleaseName, chart, vals)
+	// helm bloque pendant le Wait : on heartbeat pour que Temporal puisse nous livrer une annulation
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.RunWithContext(ctx, input.ReleaseName, chart, vals)
+		done <- err
+	}()
+
+	ticker := time.NewTicker(helmHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("helm release failed: %w", err)
+			}
+			return nil
+		case <-ticker.C:
+			activity.RecordHeartbeat(ctx, input.ReleaseName)
+		case <-ctx.Done():
+			<-done
+			return ctx.Err()
+		}
+	}
+}
+
+func workloadKind(kind string) string {
+	if kind == "" {
+		return utils.WorkloadKindService
+	}
+	return kind
+}
+
+func batchValues(input InstallWorkloadInput) map[string]interface{} {
+	backoffLimit := 6
+	if input.BackoffLimit != nil {
+		backoffLimit = *input.BackoffLimit
+	}
+	concurrencyPolicy := input.ConcurrencyPolicy
+	if concurrencyPolicy == "" {
+		concurrencyPolicy = "Forbid"
+	}
+
+	return map[string]interface{}{
+		"schedule":                input.Schedule,
+		"concurrencyPolicy":       concurrencyPolicy,
+		"backoffLimit":            backoffLimit,
+		"ttlSecondsAfterFinished": input.TTLSecondsAfterFinished,
+	}
+}
+
+func envSecretValues(refs []EnvSecretRef) []interface{} {
+	vals := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		vals = append(vals, map[string]interface{}{
+			"name":   ref.Name,
+			"prefix": ref.Prefix,
+		})
+	}
+	return vals
+}
+
+// volumeValues renders the chart "volumes" list, inputs recorded before multi-volume
+// support only carry the legacy persistence fields.
+func volumeValues(input InstallWorkloadInput) []interface{} {
+	volumes := input.Volumes
+	if len(volumes) == 0 && input.PersistenceEnabled {
+		volumes = []VolumeSpec{{
+			Name:         utils.PrimaryVolumeName,
+			Type:         utils.VolumeTypePersistent,
+			Size:         input.StorageSize,
+			StorageClass: input.StorageClass,
+			AccessMode:   input.AccessMode,
+			MountPath:    input.MountPath,
+		}}
+	}
+
+	vals := make([]interface{}, 0, len(volumes))
+	for _, v := range volumes {
+		if v.MountPath == "" {
+			v.MountPath = "/data"
+		}
+		vol := map[string]interface{}{
+			"name":      v.Name,
+			"type":      v.Type,
+			"size":      v.Size,
+			"mountPath": v.MountPath,
+		}
+		if v.Type == utils.VolumeTypePersistent {
+			vol["claimName"] = VolumeClaimName(input.ReleaseName, v.Name)
+			vol["storageClass"] = v.StorageClass
+			vol["accessMode"] = v.AccessMode
+		}
+		vals = append(vals, vol)
+	}
+	return vals
+}
+
+// UninstallRelease removes a (possibly half-installed) release, a missing release is not an error.
+func (a *WorkloadActivities) UninstallRelease(ctx context.Context, namespace, releaseName string) error {
+	actionConfig, err := a.newActionConfig(namespace)
 	if err != nil {
-		return fmt.Errorf("helm release failed: %w", err)
+		return err
+	}
+
+	client := action.NewUninstall(actionConfig)
+	client.Wait = false
+
+	_, err = client.Run(releaseName)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return fmt.Errorf("helm uninstall failed: %w", err)
+	}
+	return nil
+}
+
+func (a *WorkloadActivities) DeleteEnvSecret(ctx context.Context, nsName, releaseName string) error {
+	err := a.K8sClient.CoreV1().Secrets(nsName).Delete(ctx, releaseName+"-env", metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret %s-env: %w", releaseName, err)
 	}
 	return nil
 }
@@ -114,3 +268,13 @@ func (a *WorkloadActivities) EnsureSecret(ctx context.Context, nsNam, releaseNam
 	}
 	return err
 }
+
+// registryPullSecrets returns the build registry Secret when the image was pushed by a build,
+// the Secret is written in the namespace by the build before its first push.
+func registryPullSecrets(repository string) []string {
+	build := configs.AppConfig.Build
+	if build.Registry == "" || !strings.HasPrefix(repository, strings.TrimSuffix(build.Registry, "/")+"/") {
+		return []string{}
+	}
+	return []string{utils.RegistrySecretName}
+}
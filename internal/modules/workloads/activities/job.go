@@ -0,0 +1,122 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"go.temporal.io/sdk/temporal"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrJobRunning is returned while the job has neither completed nor failed, the workflow retry policy polls on it.
+const ErrJobRunning = "JobRunning"
+
+// label set by the chart on the jobs of a release, including the ones spawned by its CronJob
+const releaseLabel = "kubemanager.io/release"
+
+type JobResult struct {
+	JobName string
+	Phase   string // utils.PhaseJobSucceeded | utils.PhaseJobFailed
+	Reason  string
+}
+
+// JobPhase maps the Job conditions to a workload phase.
+func JobPhase(job *batchv1.Job) (string, string) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete, batchv1.JobSuccessCriteriaMet:
+			return utils.PhaseJobSucceeded, c.Reason
+		case batchv1.JobFailed, batchv1.JobFailureTarget:
+			return utils.PhaseJobFailed, c.Message
+		}
+	}
+	return utils.PhaseJobRunning, ""
+}
+
+// WaitForJob polls a job until it completes or fails. Without jobName the latest job
+// installed by the release (not spawned by a CronJob) is used.
+func (a *WorkloadActivities) WaitForJob(ctx context.Context, namespace, releaseName, jobName string) (JobResult, error) {
+	job, err := a.findJob(ctx, namespace, releaseName, jobName)
+	if err != nil {
+		return JobResult{}, err
+	}
+
+	phase, reason := JobPhase(job)
+	if phase == utils.PhaseJobRunning {
+		return JobResult{}, temporal.NewApplicationError(
+			fmt.Sprintf("job %s still running (%d active)", job.Name, job.Status.Active),
+			ErrJobRunning,
+		)
+	}
+	return JobResult{JobName: job.Name, Phase: phase, Reason: reason}, nil
+}
+
+func (a *WorkloadActivities) findJob(ctx context.Context, namespace, releaseName, jobName string) (*batchv1.Job, error) {
+	if jobName != "" {
+		return a.K8sClient.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+	}
+
+	jobs, err := a.K8sClient.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: releaseLabel + "=" + releaseName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if metav1.GetControllerOf(job) != nil {
+			continue
+		}
+		if latest == nil || job.CreationTimestamp.After(latest.CreationTimestamp.Time) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no job found for release %s", releaseName)
+	}
+	return latest, nil
+}
+
+// TriggerCronJob creates a Job from the CronJob template, like `kubectl create job --from=cronjob/...`.
+func (a *WorkloadActivities) TriggerCronJob(ctx context.Context, namespace, releaseName, jobName string) error {
+	cronJob, err := a.K8sClient.BatchV1().CronJobs(namespace).Get(ctx, releaseName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return temporal.NewNonRetryableApplicationError("cronjob not found", "NotFound", err)
+		}
+		return err
+	}
+
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+
+	_, err = a.K8sClient.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
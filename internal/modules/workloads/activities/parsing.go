@@ -9,6 +9,7 @@ import (
 type ImageInfo struct {
 	Repository string
 	Tag        string
+	Digest     string // "sha256:...", prioritaire sur le tag (images construites par un build)
 }
 
 func (a *WorkloadActivities) ParseImage(ctx context.Context, fullImage string) (ImageInfo, error) {
@@ -19,15 +20,24 @@ func (a *WorkloadActivities) ParseImage(ctx context.Context, fullImage string) (
 	if info.Repository == "" {
 		return info, fmt.Errorf("image repository cannot be empty")
 	}
-	// ":" ---- nginx:1.21
-	if strings.Contains(fullImage, ":") {
-		parts := strings.Split(fullImage, ":")
-		if len(parts) != 2 {
-			return info, fmt.Errorf("invalid image format: %s", fullImage)
+
+	// "@" ---- registry.local/app@sha256:...
+	if repo, digest, ok := strings.Cut(fullImage, "@"); ok {
+		if repo == "" || !strings.HasPrefix(digest, "sha256:") {
+			return info, fmt.Errorf("invalid image digest: %s", fullImage)
 		}
+		info.Repository = repo
+		info.Digest = digest
+		return info, nil
+	}
 
-		info.Repository = parts[0]
-		info.Tag = parts[1]
+	// ":" ---- nginx:1.21, un ":" avant le dernier "/" est le port du registre (registry:5000/app)
+	if i := strings.LastIndex(fullImage, ":"); i > strings.LastIndex(fullImage, "/") {
+		info.Repository = fullImage[:i]
+		info.Tag = fullImage[i+1:]
+		if info.Tag == "" {
+			return info, fmt.Errorf("invalid image format: %s", fullImage)
+		}
 	}
 
 	return info, nil
@@ -0,0 +1,174 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VolumeSnapshot CRD, accessed through the dynamic client so no compiled snapshot types are needed
+var volumeSnapshotGVR = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshots",
+}
+
+// ErrSnapshotNotReady is returned while the snapshot is being cut, the workflow retry policy polls on it.
+const ErrSnapshotNotReady = "SnapshotNotReady"
+
+type RestorePVCInput struct {
+	Namespace    string
+	SnapshotName string
+	PVCName      string
+	Size         string
+	StorageClass string
+	AccessMode   string
+	ReleaseName  string // non vide : la PVC est rattachée à la release Helm (restauration en place)
+}
+
+func (a *WorkloadActivities) CreateVolumeSnapshot(ctx context.Context, namespace, name, pvcName, snapshotClass string) error {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": pvcName,
+		},
+	}
+	if snapshotClass != "" {
+		spec["volumeSnapshotClassName"] = snapshotClass
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"kubemanager.io/managed": "true",
+				"kubemanager.io/pvc":     pvcName,
+			},
+		},
+		"spec": spec,
+	}}
+
+	_, err := a.DynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create volume snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// WaitForSnapshotReady returns the restore size once the snapshot is ready to use.
+func (a *WorkloadActivities) WaitForSnapshotReady(ctx context.Context, namespace, name string) (string, error) {
+	obj, err := a.DynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get volume snapshot %s: %w", name, err)
+	}
+
+	if msg, found, _ := unstructured.NestedString(obj.Object, "status", "error", "message"); found && msg != "" {
+		return "", temporal.NewNonRetryableApplicationError(fmt.Sprintf("snapshot %s failed: %s", name, msg), "SnapshotFailed", nil)
+	}
+
+	ready, _, _ := unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	if !ready {
+		return "", temporal.NewApplicationError(fmt.Sprintf("snapshot %s not ready yet", name), ErrSnapshotNotReady)
+	}
+
+	size, _, _ := unstructured.NestedString(obj.Object, "status", "restoreSize")
+	return size, nil
+}
+
+func (a *WorkloadActivities) DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error {
+	err := a.DynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete volume snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *WorkloadActivities) RestorePVCFromSnapshot(ctx context.Context, input RestorePVCInput) error {
+	size, err := resource.ParseQuantity(input.Size)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("invalid restore size %s", input.Size), "InvalidSize", err)
+	}
+
+	accessMode := corev1.PersistentVolumeAccessMode(input.AccessMode)
+	if accessMode == "" {
+		accessMode = corev1.ReadWriteOnce
+	}
+
+	apiGroup := volumeSnapshotGVR.Group
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      input.PVCName,
+			Namespace: input.Namespace,
+			Labels: map[string]string{
+				"kubemanager.io/managed":           "true",
+				"kubemanager.io/restored-snapshot": input.SnapshotName,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     input.SnapshotName,
+			},
+		},
+	}
+	if input.StorageClass != "" {
+		pvc.Spec.StorageClassName = &input.StorageClass
+	}
+
+	// la PVC recréée doit rester adoptable par la release pour les prochains upgrades
+	if input.ReleaseName != "" {
+		pvc.Labels["app.kubernetes.io/managed-by"] = "Helm"
+		pvc.Annotations = map[string]string{
+			"meta.helm.sh/release-name":      input.ReleaseName,
+			"meta.helm.sh/release-namespace": input.Namespace,
+		}
+	}
+
+	_, err = a.K8sClient.CoreV1().PersistentVolumeClaims(input.Namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create pvc %s from snapshot: %w", input.PVCName, err)
+	}
+	return nil
+}
+
+// DeletePVC deletes the claim and reports an error until it is really gone, so it can be polled.
+func (a *WorkloadActivities) DeletePVC(ctx context.Context, namespace, pvcName string) error {
+	err := a.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvcName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pvc %s: %w", pvcName, err)
+	}
+
+	_, err = a.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return fmt.Errorf("pvc %s still terminating", pvcName)
+}
+
+func (a *WorkloadActivities) ScaleDeployment(ctx context.Context, namespace, releaseName string, replicas int32) error {
+	scale, err := a.K8sClient.AppsV1().Deployments(namespace).GetScale(ctx, releaseName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get scale of %s: %w", releaseName, err)
+	}
+
+	scale.Spec.Replicas = replicas
+	_, err = a.K8sClient.AppsV1().Deployments(namespace).UpdateScale(ctx, releaseName, scale, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to scale %s to %d: %w", releaseName, replicas, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"go.temporal.io/sdk/temporal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type ResizeStatus struct {
+	Capacity          string
+	FileSystemPending bool
+}
+
+// ErrVolumeResizePending is returned while the PVC has not reached the requested size yet,
+// the workflow retry policy turns it into polling.
+const ErrVolumeResizePending = "VolumeResizePending"
+
+// VolumeSpec is a workload volume as sent to the chart.
+type VolumeSpec struct {
+	Name         string
+	Type         string // utils.VolumeTypePersistent | utils.VolumeTypeEmptyDir | utils.VolumeTypeTmpfs
+	Size         string
+	StorageClass string
+	AccessMode   string
+	MountPath    string
+}
+
+func PVCName(releaseName string) string {
+	return releaseName + "-pvc"
+}
+
+// VolumeClaimName keeps "<release>-pvc" for the primary volume so releases
+// installed before multi-volume support keep their claim.
+func VolumeClaimName(releaseName, volumeName string) string {
+	if volumeName == utils.PrimaryVolumeName {
+		return PVCName(releaseName)
+	}
+	return fmt.Sprintf("%s-%s-pvc", releaseName, volumeName)
+}
+
+func (a *WorkloadActivities) CheckVolumeExpansion(ctx context.Context, namespace, pvcName string) error {
+	pvc, err := a.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pvc %s: %w", pvcName, err)
+	}
+
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return temporal.NewNonRetryableApplicationError("pvc has no storage class, expansion is not supported", "VolumeExpansionUnsupported", nil)
+	}
+
+	sc, err := a.K8sClient.StorageV1().StorageClasses().Get(ctx, *pvc.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get storage class %s: %w", *pvc.Spec.StorageClassName, err)
+	}
+
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("storage class %s does not allow volume expansion", sc.Name),
+			"VolumeExpansionUnsupported", nil,
+		)
+	}
+	return nil
+}
+
+func (a *WorkloadActivities) ExpandPVC(ctx context.Context, namespace, pvcName, newSize string) error {
+	qty, err := resource.ParseQuantity(newSize)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("invalid storage size %s", newSize), "InvalidSize", err)
+	}
+
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]string{
+					string(corev1.ResourceStorage): qty.String(),
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, pvcName, types.MergePatchType, body, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch pvc %s: %w", pvcName, err)
+	}
+	return nil
+}
+
+// WaitForPVCResize reports the PVC state once the controller side resize is done: either the
+// capacity already matches, or the filesystem resize waits for the pod to be restarted.
+func (a *WorkloadActivities) WaitForPVCResize(ctx context.Context, namespace, pvcName, newSize string) (ResizeStatus, error) {
+	var status ResizeStatus
+
+	pvc, err := a.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return status, fmt.Errorf("failed to get pvc %s: %w", pvcName, err)
+	}
+
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	status.Capacity = capacity.String()
+
+	for _, cond := range pvc.Status.Conditions {
+		if cond.Type == corev1.PersistentVolumeClaimFileSystemResizePending && cond.Status == corev1.ConditionTrue {
+			status.FileSystemPending = true
+			return status, nil
+		}
+	}
+
+	wanted, err := resource.ParseQuantity(newSize)
+	if err != nil {
+		return status, temporal.NewNonRetryableApplicationError(fmt.Sprintf("invalid storage size %s", newSize), "InvalidSize", err)
+	}
+	if capacity.Cmp(wanted) < 0 {
+		return status, temporal.NewApplicationError(
+			fmt.Sprintf("pvc %s capacity %s, waiting for %s", pvcName, capacity.String(), newSize),
+			ErrVolumeResizePending,
+		)
+	}
+	return status, nil
+}
+
+// RestartWorkloadPods triggers a rollout restart of the release deployment so the kubelet
+// can finish an offline filesystem resize.
+func (a *WorkloadActivities) RestartWorkloadPods(ctx context.Context, namespace, releaseName string) error {
+	patch := fmt.Sprintf(
+		`{"spec":{"template":{"metadata":{"annotations":{"kubemanager.io/restarted-at":"%s"}}}}}`,
+		time.Now().Format(time.RFC3339),
+	)
+
+	_, err := a.K8sClient.AppsV1().Deployments(namespace).Patch(ctx, releaseName, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to restart deployment %s: %w", releaseName, err)
+	}
+	return nil
+}
@@ -6,7 +6,14 @@ type CreateWorkloadRequest struct {
 	ProjectID string `json:"project_id" binding:"required" desc:"ID du projet parent"`
 	Name      string `json:"name" binding:"required,min=3,max=30" desc:"Nom de la release Helm"`
 	Image     string `json:"image" binding:"required" desc:"Image Docker (ex: nginx:latest)"`
-	Replicas  int    `json:"replicas" default:"1"`
+	Replicas  int    `json:"replicas" default:"1" desc:"Réplicas, ou parallélisme pour job/cronjob"`
+	Kind      string `json:"kind" default:"service" binding:"omitempty,oneof=service worker job cronjob" desc:"service, worker (sans Service ni Ingress), job ou cronjob"`
+
+	// Batch (job / cronjob)
+	Schedule                string `json:"schedule" desc:"Expression cron (cronjob)"`
+	ConcurrencyPolicy       string `json:"concurrency_policy" default:"Forbid" binding:"omitempty,oneof=Allow Forbid Replace" desc:"Politique de concurrence (cronjob)"`
+	BackoffLimit            *int   `json:"backoff_limit" binding:"omitempty,min=0" desc:"Nombre de tentatives avant échec du job"`
+	TTLSecondsAfterFinished int    `json:"ttl_seconds_after_finished" binding:"min=0" desc:"Suppression du job terminé après N secondes (0 = jamais)"`
 
 	CPULimit    string `json:"cpu_limit" default:"200m"`
 	MemoryLimit string `json:"memory_limit" default:"256Mi"`
@@ -18,13 +25,28 @@ type CreateWorkloadRequest struct {
 	PersistenceEnabled bool   `json:"persistence_enabled" default:"false"`
 	StorageSize        string `json:"storage_size" default:"1Gi"`
 	StorageClass       string `json:"storage_class" default:"local-path"`
+	AccessMode         string `json:"access_mode" default:"ReadWriteOnce" binding:"omitempty,oneof=ReadWriteOnce ReadOnlyMany ReadWriteMany ReadWriteOncePod"`
+
+	// volumes supplémentaires, le volume "data" est réservé à persistence_enabled
+	Volumes []VolumeRequest `json:"volumes" binding:"omitempty,dive"`
 
 	EnvVars    map[string]string `json:"env_vars"`
 	SecretData map[string]string `json:"secret_data"`
+	SecretRefs []string          `json:"secret_refs" desc:"Secrets existants du namespace injectés en variables d'environnement"`
+}
+
+type VolumeRequest struct {
+	Name         string `json:"name" binding:"required,max=40" desc:"Nom du volume (DNS label)"`
+	Type         string `json:"type" default:"persistent" binding:"omitempty,oneof=persistent emptyDir tmpfs" desc:"persistent, emptyDir ou tmpfs"`
+	Size         string `json:"size" desc:"Taille de la PVC, ou limite de l'emptyDir/tmpfs"`
+	StorageClass string `json:"storage_class" desc:"StorageClass de la PVC (persistent)"`
+	AccessMode   string `json:"access_mode" binding:"omitempty,oneof=ReadWriteOnce ReadOnlyMany ReadWriteMany ReadWriteOncePod" desc:"Mode d'accès de la PVC (persistent)"`
+	MountPath    string `json:"mount_path" binding:"required" desc:"Chemin de montage dans le conteneur"`
 }
 
 type WorkloadResponse struct {
 	WorkloadID string `json:"workload_id"`
+	WorkflowID string `json:"workflow_id,omitempty"`
 	Status     string `json:"status"`
 	Namespace  string `json:"namespace"`
 	Message    string `json:"message"`
@@ -45,3 +67,55 @@ type UpdateWorkloadRequest struct {
 	StorageSize string            `json:"storage_size" desc:"Nouvelle taille du disque (ex: 5Gi)"`
 	EnvVars     map[string]string `json:"env_vars"`
 }
+
+type ResizeVolumeRequest struct {
+	ID          string `path:"id" desc:"ID du workload"`
+	StorageSize string `json:"storage_size" binding:"required" desc:"Nouvelle taille du disque (ex: 5Gi)"`
+}
+
+type SnapshotRequest struct {
+	ID string `path:"id" desc:"ID du workload"`
+}
+
+type CreateSnapshotRequest struct {
+	ID     string `path:"id" desc:"ID du workload"`
+	Volume string `json:"volume" desc:"Volume persistant à capturer (data par défaut)"`
+}
+
+type SnapshotPathRequest struct {
+	ID         string `path:"id" desc:"ID du workload"`
+	SnapshotID string `path:"snapshotID" desc:"ID du snapshot"`
+}
+
+type RestoreSnapshotRequest struct {
+	ID         string `path:"id" desc:"ID du workload"`
+	SnapshotID string `path:"snapshotID" desc:"ID du snapshot"`
+	Mode       string `json:"mode" binding:"required,oneof=new-pvc in-place" desc:"new-pvc ou in-place (workload mis en pause)"`
+	TargetPVC  string `json:"target_pvc" desc:"Nom de la nouvelle PVC (mode new-pvc)"`
+	Volume     string `json:"volume" desc:"Volume du workload remplacé (in-place) ou dont la nouvelle PVC reprend la classe, par défaut celui du snapshot"`
+}
+
+type SnapshotScheduleRequest struct {
+	ID        string `path:"id" desc:"ID du workload"`
+	Schedule  string `json:"schedule" desc:"Expression cron (ex: 0 3 * * *), vide pour désactiver"`
+	Retention int    `json:"retention" binding:"min=0" desc:"Nombre de snapshots planifiés conservés"`
+}
+
+type SnapshotResponse struct {
+	ID          string    `json:"id"`
+	WorkloadID  string    `json:"workload_id"`
+	Name        string    `json:"name"`
+	PVCName     string    `json:"pvc_name"`
+	Status      string    `json:"status"`
+	RestoreSize string    `json:"restore_size,omitempty"`
+	Scheduled   bool      `json:"scheduled"`
+	WorkflowID  string    `json:"workflow_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SnapshotScheduleResponse struct {
+	WorkloadID string `json:"workload_id"`
+	Schedule   string `json:"schedule"`
+	Retention  int    `json:"retention"`
+	WorkflowID string `json:"workflow_id,omitempty"`
+}
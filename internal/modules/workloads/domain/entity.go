@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
 )
 
 type Workload struct {
@@ -18,6 +19,15 @@ type Workload struct {
 
 	Image string `gorm:"not null"` //  "nginx:latest"
 
+	// service | worker | job | cronjob
+	Kind string `gorm:"type:varchar(20);not null;default:'service'"`
+
+	// Batch (job / cronjob)
+	Schedule                string `gorm:"type:varchar(100)"`
+	ConcurrencyPolicy       string `gorm:"type:varchar(20);default:'Forbid'"`
+	BackoffLimit            *int   `gorm:"default:6"`
+	TTLSecondsAfterFinished int    `gorm:"default:0"`
+
 	// Engine State
 	Status       string `gorm:"not null;default:'STARTING'"`
 	CurrentPhase string `gorm:"not null;default:'HELM_CHART_LOADING'"`
@@ -52,6 +62,74 @@ type Workload struct {
 	StorageClass       string `gorm:"type:varchar(50);default:'local-path'"`
 	AccessMode         string `gorm:"type:varchar(20);default:'ReadWriteOnce'"`
 
+	Volumes []WorkloadVolume `gorm:"foreignKey:WorkloadID"`
+
+	// Secrets K8s existants du namespace, injectés en envFrom
+	SecretRefs []string `gorm:"type:jsonb;serializer:json"`
+
+	// Snapshots planifiés (cron) et nombre de snapshots conservés
+	SnapshotSchedule  string `gorm:"type:varchar(100)"`
+	SnapshotRetention int    `gorm:"default:0"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WorkloadVolume struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	WorkloadID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	Name         string `gorm:"type:varchar(63);not null"`
+	Type         string `gorm:"type:varchar(20);not null;default:'persistent'"` // persistent | emptyDir | tmpfs
+	Size         string `gorm:"type:varchar(20)"`                               // taille de la PVC, ou sizeLimit de l'emptyDir
+	StorageClass string `gorm:"type:varchar(50)"`
+	AccessMode   string `gorm:"type:varchar(20)"`
+	MountPath    string `gorm:"type:varchar(255);not null"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PersistentVolumes returns the volumes backed by a PVC, workloads created before
+// multi-volume support only have the legacy StorageSize/StorageClass columns.
+func (w *Workload) PersistentVolumes() []WorkloadVolume {
+	if len(w.Volumes) == 0 {
+		if !w.PersistenceEnabled {
+			return nil
+		}
+		return []WorkloadVolume{{
+			WorkloadID:   w.ID,
+			Name:         utils.PrimaryVolumeName,
+			Type:         utils.VolumeTypePersistent,
+			Size:         w.StorageSize,
+			StorageClass: w.StorageClass,
+			AccessMode:   w.AccessMode,
+		}}
+	}
+
+	var volumes []WorkloadVolume
+	for _, v := range w.Volumes {
+		if v.Type == utils.VolumeTypePersistent {
+			volumes = append(volumes, v)
+		}
+	}
+	return volumes
+}
+
+type VolumeSnapshot struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	WorkloadID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	// K8s identity (snapshot.storage.k8s.io/v1 VolumeSnapshot)
+	Name          string `gorm:"type:varchar(100);not null"`
+	Namespace     string `gorm:"not null"`
+	PVCName       string `gorm:"type:varchar(100);not null"`
+	SnapshotClass string `gorm:"type:varchar(100)"`
+
+	Status      string `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	RestoreSize string `gorm:"type:varchar(20)"`
+	Scheduled   bool   `gorm:"default:false"` // pris par le planning, soumis à la rétention
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
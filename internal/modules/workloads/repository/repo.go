@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
+	"github.com/thekrauss/kubemanager/internal/core/events"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
 	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
 	"gorm.io/gorm"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -14,6 +17,10 @@ type WorkloadRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Workload, error)
 	ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Workload, error)
 	UpdateStatus(ctx context.Context, id uuid.UUID, status string, phase string) error
+	SetLastWorkflowID(ctx context.Context, id uuid.UUID, workflowID string) error
+	UpdateStorageSize(ctx context.Context, id uuid.UUID, size string) error
+	UpdateSnapshotSchedule(ctx context.Context, id uuid.UUID, schedule string, retention int) error
+	UpdateSpec(ctx context.Context, workload *domain.Workload) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetTotalUsageByProject(ctx context.Context, projectID uuid.UUID) (totalCPU int64, totalMem int64, totalStorage int64, err error)
 }
@@ -27,18 +34,23 @@ func NewWorkloadRepository(db *gorm.DB) WorkloadRepository {
 }
 
 func (r *workloadRepository) Create(ctx context.Context, workload *domain.Workload) error {
-	return r.db.WithContext(ctx).Create(workload).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workload).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, workloadEvent(utils.EventWorkloadCreated, workload))
+	})
 }
 
 func (r *workloadRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Workload, error) {
 	var workload domain.Workload
-	err := r.db.WithContext(ctx).First(&workload, "id = ?", id).Error
+	err := r.db.WithContext(ctx).Preload("Volumes").First(&workload, "id = ?", id).Error
 	return &workload, err
 }
 
 func (r *workloadRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]domain.Workload, error) {
 	var workloads []domain.Workload
-	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&workloads).Error
+	err := r.db.WithContext(ctx).Preload("Volumes").Where("project_id = ?", projectID).Find(&workloads).Error
 	return workloads, err
 }
 
@@ -51,14 +63,81 @@ func (r *workloadRepository) UpdateStatus(ctx context.Context, id uuid.UUID, sta
 		}).Error
 }
 
+func (r *workloadRepository) SetLastWorkflowID(ctx context.Context, id uuid.UUID, workflowID string) error {
+	return r.db.WithContext(ctx).Model(&domain.Workload{}).
+		Where("id = ?", id).
+		Update("last_workflow_id", workflowID).Error
+}
+
+// UpdateStorageSize records the size of the primary volume, on the workload and on its volume row.
+func (r *workloadRepository) UpdateStorageSize(ctx context.Context, id uuid.UUID, size string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Workload{}).Where("id = ?", id).Update("storage_size", size).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.WorkloadVolume{}).
+			Where("workload_id = ? AND name = ?", id, utils.PrimaryVolumeName).
+			Update("size", size).Error
+	})
+}
+
+func (r *workloadRepository) UpdateSnapshotSchedule(ctx context.Context, id uuid.UUID, schedule string, retention int) error {
+	return r.db.WithContext(ctx).Model(&domain.Workload{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"snapshot_schedule":  schedule,
+			"snapshot_retention": retention,
+		}).Error
+}
+
+// UpdateSpec saves the fields that can change on a redeploy, volumes are left untouched.
+func (r *workloadRepository) UpdateSpec(ctx context.Context, workload *domain.Workload) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(workload).
+			Select("image", "replicas", "cpu_limit", "memory_limit", "target_port", "schedule",
+				"concurrency_policy", "backoff_limit", "ttl_seconds_after_finished", "secret_refs", "last_workflow_id").
+			Updates(workload).Error
+		if err != nil {
+			return err
+		}
+		return events.Record(tx, workloadEvent(utils.EventWorkloadUpdated, workload))
+	})
+}
+
 func (r *workloadRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&domain.Workload{}, "id = ?", id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var workload domain.Workload
+		if err := tx.First(&workload, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Delete(&domain.WorkloadVolume{}, "workload_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&domain.Workload{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, workloadEvent(utils.EventWorkloadDeleted, &workload))
+	})
+}
+
+func workloadEvent(eventType string, w *domain.Workload) events.Event {
+	return events.New(eventType, w.ProjectID.String(), map[string]interface{}{
+		"workload_id": w.ID.String(),
+		"name":        w.Name,
+		"namespace":   w.Namespace,
+		"kind":        w.Kind,
+		"image":       w.Image,
+	})
 }
 
 func (r *workloadRepository) GetTotalUsageByProject(ctx context.Context, projectID uuid.UUID) (totalCPU int64, totalMem int64, totalStorage int64, err error) {
 	var workloads []domain.Workload
 
 	err = r.db.WithContext(ctx).
+		Preload("Volumes").
 		Where("project_id = ? AND status NOT IN ('FAILED', 'DELETED')", projectID).
 		Find(&workloads).Error
 	if err != nil {
@@ -71,11 +150,14 @@ func (r *workloadRepository) GetTotalUsageByProject(ctx context.Context, project
 			replicas = 1
 		}
 
-		totalCPU += r.parseCPUToMilli(w.CPULimit) * replicas
-		totalMem += r.parseMemToMi(w.MemoryLimit) * replicas
+		// un job terminé ne consomme plus que son stockage
+		if w.Status != utils.WorkloadSucceeded {
+			totalCPU += r.parseCPUToMilli(w.CPULimit) * replicas
+			totalMem += r.parseMemToMi(w.MemoryLimit) * replicas
+		}
 
-		if w.PersistenceEnabled {
-			totalStorage += r.parseSizeToGi(w.StorageSize)
+		for _, v := range w.PersistentVolumes() {
+			totalStorage += r.parseSizeToMi(v.Size)
 		}
 	}
 
@@ -100,10 +182,11 @@ func (r *workloadRepository) parseMemToMi(mem string) int64 {
 	return q.Value() / (1024 * 1024)
 }
 
-func (r *workloadRepository) parseSizeToGi(size string) int64 {
+// en Mi, même unité que les quotas calculés par le service
+func (r *workloadRepository) parseSizeToMi(size string) int64 {
 	q, err := resource.ParseQuantity(size)
 	if err != nil {
 		return 0
 	}
-	return q.Value() / (1024 * 1024 * 1024)
+	return q.Value() / (1024 * 1024)
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"gorm.io/gorm"
+)
+
+type SnapshotRepository interface {
+	Create(ctx context.Context, snapshot *domain.VolumeSnapshot) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.VolumeSnapshot, error)
+	ListByWorkload(ctx context.Context, workloadID uuid.UUID) ([]domain.VolumeSnapshot, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string, restoreSize string) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type snapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSnapshotRepository(db *gorm.DB) SnapshotRepository {
+	return &snapshotRepository{db: db}
+}
+
+func (r *snapshotRepository) Create(ctx context.Context, snapshot *domain.VolumeSnapshot) error {
+	return r.db.WithContext(ctx).Create(snapshot).Error
+}
+
+func (r *snapshotRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.VolumeSnapshot, error) {
+	var snapshot domain.VolumeSnapshot
+	err := r.db.WithContext(ctx).First(&snapshot, "id = ?", id).Error
+	return &snapshot, err
+}
+
+// newest first
+func (r *snapshotRepository) ListByWorkload(ctx context.Context, workloadID uuid.UUID) ([]domain.VolumeSnapshot, error) {
+	var snapshots []domain.VolumeSnapshot
+	err := r.db.WithContext(ctx).
+		Where("workload_id = ?", workloadID).
+		Order("created_at DESC").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+func (r *snapshotRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string, restoreSize string) error {
+	updates := map[string]interface{}{"status": status}
+	if restoreSize != "" {
+		updates["restore_size"] = restoreSize
+	}
+	return r.db.WithContext(ctx).Model(&domain.VolumeSnapshot{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func (r *snapshotRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.VolumeSnapshot{}, "id = ?", id).Error
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
+	"go.temporal.io/api/serviceerror"
+)
+
+// DeleteWorkload uninstalls the release and deletes its volumes, the DB record is removed
+// by the workflow once the storage has been released.
+func (s *WorkloadService) DeleteWorkload(ctx context.Context, id string) (*domain.Workload, string, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	workload, err := s.Repo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+	if workload.Status == utils.WorkloadDeleting {
+		return nil, "", betoerrors.New(betoerrors.CodeConflict, "workload is already being deleted")
+	}
+
+	// le cron des snapshots planifiés échouerait sans PVC
+	if workload.SnapshotSchedule != "" {
+		if err := s.TemporalClient.TerminateWorkflow(ctx, snapshotScheduleWorkflowID(id), "", "workload deleted"); err != nil {
+			var notFound *serviceerror.NotFound
+			if !errors.As(err, &notFound) {
+				return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to stop snapshot schedule")
+			}
+		}
+	}
+
+	pvcNames := make([]string, 0, len(workload.Volumes))
+	for _, v := range workload.PersistentVolumes() {
+		pvcNames = append(pvcNames, activities.VolumeClaimName(workload.Name, v.Name))
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, deployWorkflowOptions("workload-delete-"+id), workflows.DeleteWorkloadWorkflow, workflows.DeleteWorkloadInput{
+		WorkloadID:  id,
+		Namespace:   workload.Namespace,
+		ReleaseName: workload.Name,
+		PVCNames:    pvcNames,
+	})
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, "", betoerrors.New(betoerrors.CodeConflict, "workload deletion already in progress")
+		}
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start delete workflow")
+	}
+
+	workload.Status = utils.WorkloadDeleting
+	return workload, we.GetID(), nil
+}
@@ -2,13 +2,22 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/robfig/cron"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/core/configs"
 	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	addonRepo "github.com/thekrauss/kubemanager/internal/modules/addons/repository"
 	projectRepo "github.com/thekrauss/kubemanager/internal/modules/projects/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
 	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
 	"github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
 	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
@@ -17,19 +26,28 @@ import (
 
 type WorkloadService struct {
 	TemporalClient client.Client
+	Config         *configs.GlobalConfig
 	Repo           repository.WorkloadRepository
 	ProjectRepo    projectRepo.ProjectRepository
+	SnapshotRepo   repository.SnapshotRepository
+	AddonRepo      addonRepo.AddonRepository
 }
 
 func NewWorkloadService(
 	temporal client.Client,
+	cfg *configs.GlobalConfig,
 	repo repository.WorkloadRepository,
 	pRepo projectRepo.ProjectRepository,
+	sRepo repository.SnapshotRepository,
+	aRepo addonRepo.AddonRepository,
 ) *WorkloadService {
 	return &WorkloadService{
 		TemporalClient: temporal,
+		Config:         cfg,
 		Repo:           repo,
 		ProjectRepo:    pRepo,
+		SnapshotRepo:   sRepo,
+		AddonRepo:      aRepo,
 	}
 }
 
@@ -42,6 +60,12 @@ func (s *WorkloadService) DeployNewWorkload(ctx context.Context, in *WorkloadSer
 
 	currentCPU, currentMem, currentStorage, _ := s.Repo.GetTotalUsageByProject(ctx, pID)
 
+	// les add-ons du projet partagent le même quota
+	addonCPU, addonMem, addonStorage, _ := s.AddonRepo.GetTotalUsageByProject(ctx, pID)
+	currentCPU += addonCPU
+	currentMem += addonMem
+	currentStorage += addonStorage
+
 	project, err := s.ProjectRepo.GetProjectByID(ctx, pID.String())
 	if err != nil {
 		return nil, fmt.Errorf("project not found: %w", err)
@@ -52,10 +76,26 @@ func (s *WorkloadService) DeployNewWorkload(ctx context.Context, in *WorkloadSer
 		replicas = 1
 	}
 
-	if err := s.validateNetwork(in.TargetPort); err != nil {
+	// un add-on et un workload du même nom se partageraient la release Helm et le Service
+	if taken, err := s.AddonRepo.ExistsByName(ctx, pID, in.Name); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "name already used by an add-on of this project")
+	}
+
+	if in.Kind == "" {
+		in.Kind = utils.WorkloadKindService
+	}
+	if err := s.validateKind(&in.CreateWorkloadRequest); err != nil {
 		return nil, err
 	}
 
+	if in.Kind == utils.WorkloadKindService {
+		if err := s.validateNetwork(in.TargetPort); err != nil {
+			return nil, err
+		}
+	}
+
 	requestedTotalCPU := s.parseCPU(in.CPULimit) * replicas
 	limitCPU := s.parseCPU(project.CpuLimit)
 	if (currentCPU + requestedTotalCPU) > limitCPU {
@@ -68,40 +108,63 @@ func (s *WorkloadService) DeployNewWorkload(ctx context.Context, in *WorkloadSer
 		return nil, fmt.Errorf("quota exceeded: Memory limit reached")
 	}
 
-	if in.PersistenceEnabled {
-		requestedStorage := s.parseStorage(in.StorageSize)
+	if err := s.validateSecretRefs(ctx, pID, in.SecretRefs); err != nil {
+		return nil, err
+	}
+
+	volumes, err := s.buildVolumes(&in.CreateWorkloadRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var requestedStorage int64
+	for _, v := range volumes {
+		if v.Type == utils.VolumeTypePersistent {
+			requestedStorage += s.parseStorage(v.Size)
+		}
+	}
+	if requestedStorage > 0 {
 		limitStorage := s.parseStorage(project.StorageLimit)
 		if (currentStorage + requestedStorage) > limitStorage {
 			return nil, fmt.Errorf("quota exceeded: Storage limit reached")
 		}
 	}
 
-	_ = currentStorage
-
 	targetNamespace := fmt.Sprintf("km-%s", project.Name)
 
+	workloadID := uuid.New()
+	workflowID := "workload-deploy-" + workloadID.String()
+
 	workload := &domain.Workload{
-		ID:                 uuid.New(),
+		ID:                 workloadID,
 		ProjectID:          pID,
 		Name:               in.Name,
 		Namespace:          targetNamespace,
 		Image:              in.Image,
+		Kind:               in.Kind,
 		Replicas:           in.Replicas,
 		CPULimit:           in.CPULimit,
 		MemoryLimit:        in.MemoryLimit,
 		TargetPort:         in.TargetPort,
 		PersistenceEnabled: in.PersistenceEnabled,
 		StorageSize:        in.StorageSize,
+		StorageClass:       in.StorageClass,
+		AccessMode:         in.AccessMode,
+		Volumes:            volumes,
+		SecretRefs:         in.SecretRefs,
 		Status:             "STARTING",
+		LastWorkflowID:     workflowID,
+
+		Schedule:                in.Schedule,
+		ConcurrencyPolicy:       in.ConcurrencyPolicy,
+		BackoffLimit:            in.BackoffLimit,
+		TTLSecondsAfterFinished: in.TTLSecondsAfterFinished,
 	}
 
 	if err := s.Repo.Create(ctx, workload); err != nil {
 		return nil, err
 	}
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        "workload-deploy-" + workload.ID.String(),
-		TaskQueue: "kubemanager-tasks",
-	}
+	workflowOptions := deployWorkflowOptions(workflowID)
 
 	_, err = s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, "DeployWorkloadWorkflow", workflows.DeployWorkloadInput{
 		WorkloadID:         workload.ID.String(),
@@ -114,8 +177,19 @@ func (s *WorkloadService) DeployNewWorkload(ctx context.Context, in *WorkloadSer
 		Replicas:           in.Replicas,
 		PersistenceEnabled: in.PersistenceEnabled,
 		StorageSize:        in.StorageSize,
+		StorageClass:       in.StorageClass,
+		AccessMode:         in.AccessMode,
+		MountPath:          in.MountPath,
+		Volumes:            toVolumeSpecs(volumes),
+		EnvSecrets:         secretRefSpecs(in.SecretRefs),
 		TargetPort:         in.TargetPort,
 		ServiceType:        in.ServiceType,
+
+		Kind:                    in.Kind,
+		Schedule:                in.Schedule,
+		ConcurrencyPolicy:       in.ConcurrencyPolicy,
+		BackoffLimit:            in.BackoffLimit,
+		TTLSecondsAfterFinished: in.TTLSecondsAfterFinished,
 	})
 
 	return workload, err
@@ -133,11 +207,28 @@ func (s *WorkloadService) UpdateWorkload(ctx context.Context, id string, req dom
 		}
 	}
 
+	// la spec d'une PVC est immuable pour Helm : l'agrandissement passe par le workflow dédié
+	if current.PersistenceEnabled && req.StorageSize != "" &&
+		utils.ParseStorageToBytes(req.StorageSize) > utils.ParseStorageToBytes(current.StorageSize) {
+		if _, _, err := s.ResizeVolume(ctx, id, req.StorageSize); err != nil {
+			return err
+		}
+	}
+
 	workflowOptions := client.StartWorkflowOptions{
 		ID:        "workload-update-" + id,
 		TaskQueue: "kubemanager-tasks",
 	}
 
+	if err := s.Repo.SetLastWorkflowID(ctx, current.ID, workflowOptions.ID); err != nil {
+		return err
+	}
+
+	envSecrets, err := s.envSecrets(ctx, current)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.DeployWorkloadWorkflow, workflows.DeployWorkloadInput{
 		WorkloadID:         id,
 		ProjectID:          current.ProjectID.String(),
@@ -146,12 +237,200 @@ func (s *WorkloadService) UpdateWorkload(ctx context.Context, id string, req dom
 		Image:              req.Image,
 		EnvVars:            req.EnvVars,
 		PersistenceEnabled: current.PersistenceEnabled,
-		StorageSize:        req.StorageSize,
+		StorageSize:        current.StorageSize,
+		StorageClass:       current.StorageClass,
+		AccessMode:         current.AccessMode,
+		Volumes:            toVolumeSpecs(current.Volumes),
+		EnvSecrets:         envSecrets,
+		Replicas:           current.Replicas,
+		TargetPort:         current.TargetPort,
+
+		Kind:                    current.Kind,
+		Schedule:                current.Schedule,
+		ConcurrencyPolicy:       current.ConcurrencyPolicy,
+		BackoffLimit:            current.BackoffLimit,
+		TTLSecondsAfterFinished: current.TTLSecondsAfterFinished,
 	})
 
 	return err
 }
 
+func (s *WorkloadService) ResizeVolume(ctx context.Context, id string, newSize string) (*domain.Workload, string, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	workload, err := s.Repo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+
+	if !workload.PersistenceEnabled {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "workload has no persistent volume")
+	}
+	if err := checkWorkloadIdle(workload); err != nil {
+		return nil, "", err
+	}
+
+	if _, err := resource.ParseQuantity(newSize); err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid storage size")
+	}
+	if utils.ParseStorageToBytes(newSize) <= utils.ParseStorageToBytes(workload.StorageSize) {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "reduction of storage size is not supported")
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       "workload-resize-" + id,
+		TaskQueue:                "kubemanager-tasks",
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.ResizeVolumeWorkflow, workflows.ResizeVolumeInput{
+		WorkloadID:  id,
+		Namespace:   workload.Namespace,
+		ReleaseName: workload.Name,
+		NewSize:     newSize,
+		Kind:        workload.Kind,
+		Status:      workload.Status,
+	})
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, "", betoerrors.New(betoerrors.CodeConflict, "a volume resize is already running for this workload")
+		}
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start resize workflow")
+	}
+
+	return workload, we.GetID(), nil
+}
+
+// deployWorkflowOptions reuses the workload workflow ID so only one run can be active at a time,
+// a new run is only accepted once the previous one failed, was canceled or terminated.
+func deployWorkflowOptions(workflowID string) client.StartWorkflowOptions {
+	return client.StartWorkflowOptions{
+		ID:                       workflowID,
+		TaskQueue:                "kubemanager-tasks",
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+}
+
+// checkWorkloadIdle refuses to start a deploy or a volume operation while another one or a
+// deletion runs: they would change the same release and volume, and the status each one restores.
+func checkWorkloadIdle(workload *domain.Workload) error {
+	switch workload.Status {
+	case utils.WorkloadStarting, utils.WorkloadScaling, utils.WorkloadRestoring, utils.WorkloadDeleting:
+		return betoerrors.New(betoerrors.CodeConflict, "workload is "+workload.Status+", retry once it is done")
+	}
+	return nil
+}
+
+// isDeployWorkflowID tells whether the workflow is a DeployWorkloadWorkflow, the only kind whose
+// input Retry and DeployImage can replay.
+func isDeployWorkflowID(workflowID string) bool {
+	for _, prefix := range []string{"workload-deploy-", "workload-update-", "workload-build-deploy-"} {
+		if strings.HasPrefix(workflowID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *WorkloadService) CancelWorkload(ctx context.Context, id string) (*domain.Workload, error) {
+	workload, err := s.getDeployedWorkload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// l'annulation est traitée par le workflow, qui désinstalle la release à moitié installée
+	if err := s.TemporalClient.CancelWorkflow(ctx, workload.LastWorkflowID, ""); err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "no running workflow to cancel for this workload")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to cancel workflow")
+	}
+
+	return workload, nil
+}
+
+func (s *WorkloadService) RetryWorkload(ctx context.Context, id string) (*domain.Workload, error) {
+	workload, err := s.getDeployedWorkload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if workload.Status == utils.WorkloadDeleting {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "workload is being deleted")
+	}
+
+	var input workflows.DeployWorkloadInput
+	if err := utils.LoadWorkflowInput(ctx, s.TemporalClient, workload.LastWorkflowID, &input); err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeNotFound, "last workflow input not found")
+	}
+
+	// les bindings ont pu changer depuis le premier essai
+	if input.EnvSecrets, err = s.envSecrets(ctx, workload); err != nil {
+		return nil, err
+	}
+
+	_, err = s.TemporalClient.ExecuteWorkflow(ctx, deployWorkflowOptions(workload.LastWorkflowID), workflows.DeployWorkloadWorkflow, input)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, betoerrors.New(betoerrors.CodeConflict, "a workflow is already running or has completed for this workload")
+		}
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to restart workflow")
+	}
+
+	workload.Status = utils.WorkloadStarting
+	return workload, nil
+}
+
+// envSecrets returns the Secrets referenced by the workload followed by the connection
+// Secrets of the add-ons bound to it.
+func (s *WorkloadService) envSecrets(ctx context.Context, workload *domain.Workload) ([]activities.EnvSecretRef, error) {
+	bindings, err := s.AddonRepo.ListBindingsByWorkload(ctx, workload.ID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to load add-on bindings")
+	}
+
+	refs := secretRefSpecs(workload.SecretRefs)
+	for _, b := range bindings {
+		refs = append(refs, activities.EnvSecretRef{Name: b.Addon.SecretName, Prefix: b.EnvPrefix})
+	}
+	return refs, nil
+}
+
+func (s *WorkloadService) getWorkloadWithWorkflow(ctx context.Context, id string) (*domain.Workload, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	workload, err := s.Repo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+
+	if workload.LastWorkflowID == "" {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "no workflow recorded for this workload")
+	}
+	return workload, nil
+}
+
+// getDeployedWorkload is getWorkloadWithWorkflow for the operations acting on the last deploy.
+func (s *WorkloadService) getDeployedWorkload(ctx context.Context, id string) (*domain.Workload, error) {
+	workload, err := s.getWorkloadWithWorkflow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isDeployWorkflowID(workload.LastWorkflowID) {
+		return nil, betoerrors.New(betoerrors.CodeConflict, "the last workflow of this workload is not a deploy")
+	}
+	return workload, nil
+}
+
 func (s *WorkloadService) parseCPU(cpu string) int64 {
 	res, err := resource.ParseQuantity(cpu)
 	if err != nil {
@@ -184,6 +463,25 @@ func (s *WorkloadService) parseStorageToBytes(storage string) int64 {
 	return res.Value()
 }
 
+func (s *WorkloadService) validateKind(in *domain.CreateWorkloadRequest) error {
+	switch in.Kind {
+	case utils.WorkloadKindCronJob:
+		if in.Schedule == "" {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "schedule is required for a cronjob")
+		}
+		if _, err := cron.ParseStandard(in.Schedule); err != nil {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "invalid cron schedule")
+		}
+	case utils.WorkloadKindService, utils.WorkloadKindWorker, utils.WorkloadKindJob:
+		if in.Schedule != "" {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "schedule is only supported for a cronjob")
+		}
+	default:
+		return betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload kind: "+in.Kind)
+	}
+	return nil
+}
+
 func (s *WorkloadService) validateNetwork(port int) error {
 
 	if port <= 0 || port > 65535 {
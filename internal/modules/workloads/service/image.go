@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
+	"go.temporal.io/api/serviceerror"
+)
+
+// DeployImage redeploys a workload with a new image, the rest of the spec is taken from the
+// last deploy input. The workflow ID is chosen by the caller so a retried call starts it only once.
+func (s *WorkloadService) DeployImage(ctx context.Context, id string, image string, workflowID string) (*domain.Workload, string, error) {
+	workload, err := s.getDeployedWorkload(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if workload.Status == utils.WorkloadDeleting {
+		return nil, "", betoerrors.New(betoerrors.CodeConflict, "workload is being deleted")
+	}
+
+	// appel rejoué par Temporal : le déploiement est déjà enregistré
+	if workload.LastWorkflowID == workflowID {
+		return workload, workflowID, nil
+	}
+
+	var input workflows.DeployWorkloadInput
+	if err := utils.LoadWorkflowInput(ctx, s.TemporalClient, workload.LastWorkflowID, &input); err != nil {
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeNotFound, "last workflow input not found")
+	}
+	if input.EnvSecrets, err = s.envSecrets(ctx, workload); err != nil {
+		return nil, "", err
+	}
+	input.Image = image
+	// le volume a déjà été agrandi, ou non, par le déploiement précédent
+	input.StorageSize, input.ResizeTo = workload.StorageSize, ""
+
+	_, err = s.TemporalClient.ExecuteWorkflow(ctx, deployWorkflowOptions(workflowID), workflows.DeployWorkloadWorkflow, input)
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if !errors.As(err, &started) {
+			return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start deploy workflow")
+		}
+	}
+
+	// enregistré après le démarrage : un essai rejoué relit encore l'input du déploiement précédent
+	workload.Image = image
+	workload.LastWorkflowID = workflowID
+	if err := s.Repo.UpdateSpec(ctx, workload); err != nil {
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save workload image")
+	}
+
+	workload.Status = utils.WorkloadStarting
+	return workload, workflowID, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
+	"go.temporal.io/sdk/client"
+)
+
+// RunWorkload triggers a cronjob workload outside of its schedule.
+func (s *WorkloadService) RunWorkload(ctx context.Context, id string) (*domain.Workload, string, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	workload, err := s.Repo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+
+	if workload.Kind != utils.WorkloadKindCronJob {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "only a cronjob workload can be run manually")
+	}
+	if workload.Status != utils.WorkloadScheduled {
+		return nil, "", betoerrors.New(betoerrors.CodeConflict, "cronjob is not deployed yet")
+	}
+
+	runID := uuid.New().String()[:8]
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("workload-run-%s-%s", id, runID),
+		TaskQueue: "kubemanager-tasks",
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.RunCronJobWorkflow, workflows.RunCronJobInput{
+		WorkloadID:  id,
+		Namespace:   workload.Namespace,
+		ReleaseName: workload.Name,
+		JobName:     fmt.Sprintf("%s-manual-%s", workload.Name, runID),
+	})
+	if err != nil {
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start cronjob run")
+	}
+
+	return workload, we.GetID(), nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// validateSecretRefs checks the names of the existing Secrets injected into the workload,
+// their presence is only checked by the kubelet (optional envFrom). The Secrets written by
+// kubemanager are refused: add-on credentials go through a binding, the others never reach a pod.
+func (s *WorkloadService) validateSecretRefs(ctx context.Context, projectID uuid.UUID, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	addons, err := s.AddonRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list add-ons")
+	}
+	addonSecrets := make(map[string]string, len(addons))
+	for _, a := range addons {
+		addonSecrets[a.SecretName] = a.Name
+	}
+
+	seen := make(map[string]bool, len(refs))
+	for _, name := range refs {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "invalid secret name: "+name)
+		}
+		if utils.IsPlatformSecret(name) {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "secret "+name+" is managed by kubemanager and cannot be injected")
+		}
+		if addon, ok := addonSecrets[name]; ok {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "secret "+name+" holds the credentials of add-on "+addon+", bind the add-on instead")
+		}
+		if seen[name] {
+			return betoerrors.New(betoerrors.CodeInvalidInput, "duplicate secret reference: "+name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func secretRefSpecs(refs []string) []activities.EnvSecretRef {
+	specs := make([]activities.EnvSecretRef, 0, len(refs))
+	for _, name := range refs {
+		specs = append(specs, activities.EnvSecretRef{Name: name})
+	}
+	return specs
+}
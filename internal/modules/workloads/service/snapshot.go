@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+func snapshotScheduleWorkflowID(workloadID string) string {
+	return "workload-snapshot-schedule-" + workloadID
+}
+
+// CreateSnapshot captures one persistent volume of the workload, the primary one by default.
+func (s *WorkloadService) CreateSnapshot(ctx context.Context, id string, volumeName string) (*domain.VolumeSnapshot, string, error) {
+	workload, err := s.getPersistentWorkload(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	volume, err := persistentVolume(workload, volumeName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	snapshotID := uuid.New()
+	snapshot := &domain.VolumeSnapshot{
+		ID:            snapshotID,
+		WorkloadID:    workload.ID,
+		Name:          activities.SnapshotName(workload.Name, snapshotID),
+		Namespace:     workload.Namespace,
+		PVCName:       activities.VolumeClaimName(workload.Name, volume.Name),
+		SnapshotClass: s.Config.Kubernetes.SnapshotClass,
+		Status:        utils.SnapshotPending,
+	}
+
+	if err := s.SnapshotRepo.Create(ctx, snapshot); err != nil {
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save snapshot")
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        "workload-snapshot-" + snapshotID.String(),
+		TaskQueue: "kubemanager-tasks",
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.CreateSnapshotWorkflow, activities.NewSnapshotRef(snapshot))
+	if err != nil {
+		_ = s.SnapshotRepo.UpdateStatus(ctx, snapshotID, utils.SnapshotFailed, "")
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start snapshot workflow")
+	}
+
+	return snapshot, we.GetID(), nil
+}
+
+func (s *WorkloadService) ListSnapshots(ctx context.Context, id string) ([]domain.VolumeSnapshot, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	snapshots, err := s.SnapshotRepo.ListByWorkload(ctx, wID)
+	if err != nil {
+		return nil, betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to list snapshots")
+	}
+	return snapshots, nil
+}
+
+func (s *WorkloadService) DeleteSnapshot(ctx context.Context, id string, snapshotID string) (string, error) {
+	snapshot, err := s.getWorkloadSnapshot(ctx, id, snapshotID)
+	if err != nil {
+		return "", err
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       "workload-snapshot-delete-" + snapshotID,
+		TaskQueue:                "kubemanager-tasks",
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.DeleteSnapshotWorkflow, activities.NewSnapshotRef(snapshot))
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return "", betoerrors.New(betoerrors.CodeConflict, "snapshot deletion already in progress")
+		}
+		return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start snapshot deletion")
+	}
+
+	return we.GetID(), nil
+}
+
+// RestoreSnapshot restores into the volume the snapshot was taken from unless another persistent
+// volume of the workload is named: in place it is replaced, otherwise the new PVC takes its class.
+func (s *WorkloadService) RestoreSnapshot(ctx context.Context, id string, snapshotID string, mode string, targetPVC string, volumeName string) (*domain.VolumeSnapshot, string, error) {
+	workload, err := s.getPersistentWorkload(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	snapshot, err := s.getWorkloadSnapshot(ctx, id, snapshotID)
+	if err != nil {
+		return nil, "", err
+	}
+	if snapshot.Status != utils.SnapshotReady {
+		return nil, "", betoerrors.New(betoerrors.CodeConflict, "snapshot is not ready")
+	}
+
+	if volumeName == "" {
+		if volumeName = snapshotVolumeName(workload, snapshot); volumeName == "" {
+			return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "the volume of the snapshot is not mounted by the workload, name the target volume")
+		}
+	}
+	volume, err := persistentVolume(workload, volumeName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch mode {
+	case utils.SnapshotRestoreInPlace:
+		// la restauration en place met le Deployment à 0 réplica le temps de remplacer la PVC
+		if !utils.IsDeploymentKind(workload.Kind) {
+			return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "in-place restore needs a service or worker workload, restore to a new pvc instead")
+		}
+		if err := checkWorkloadIdle(workload); err != nil {
+			return nil, "", err
+		}
+		targetPVC = activities.VolumeClaimName(workload.Name, volume.Name)
+	case utils.SnapshotRestoreNewPVC:
+		if targetPVC == "" {
+			targetPVC = fmt.Sprintf("%s-restore-%s", snapshot.PVCName, uuid.New().String()[:8])
+		}
+		for _, v := range workload.PersistentVolumes() {
+			if targetPVC == activities.VolumeClaimName(workload.Name, v.Name) {
+				return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "target pvc is the workload volume "+v.Name+", use in-place mode")
+			}
+		}
+	default:
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid restore mode")
+	}
+
+	// la taille restaurée ne peut pas être inférieure à celle du snapshot
+	size := volume.Size
+	if snapshot.RestoreSize != "" && utils.ParseStorageToBytes(snapshot.RestoreSize) > utils.ParseStorageToBytes(size) {
+		size = snapshot.RestoreSize
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       "workload-restore-" + id,
+		TaskQueue:                "kubemanager-tasks",
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+
+	we, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.RestoreSnapshotWorkflow, workflows.RestoreSnapshotInput{
+		WorkloadID:   id,
+		Snapshot:     activities.NewSnapshotRef(snapshot),
+		Mode:         mode,
+		TargetPVC:    targetPVC,
+		ReleaseName:  workload.Name,
+		Replicas:     workload.Replicas,
+		Size:         size,
+		StorageClass: volume.StorageClass,
+		AccessMode:   volume.AccessMode,
+		Status:       workload.Status,
+	})
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &started) {
+			return nil, "", betoerrors.New(betoerrors.CodeConflict, "a restore is already running for this workload")
+		}
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start restore workflow")
+	}
+
+	snapshot.PVCName = targetPVC
+	return snapshot, we.GetID(), nil
+}
+
+func (s *WorkloadService) SetSnapshotSchedule(ctx context.Context, id string, schedule string, retention int) (string, error) {
+	workload, err := s.getPersistentWorkload(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	// le planning capture le volume principal
+	if _, err := persistentVolume(workload, utils.PrimaryVolumeName); err != nil {
+		return "", err
+	}
+
+	if schedule != "" {
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			return "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid cron schedule")
+		}
+		if retention <= 0 {
+			return "", betoerrors.New(betoerrors.CodeInvalidInput, "retention must be greater than 0")
+		}
+	}
+
+	workflowID := snapshotScheduleWorkflowID(id)
+
+	// un cron Temporal ne se modifie pas : l'ancien planning est arrêté avant d'en démarrer un nouveau
+	if err := s.TemporalClient.TerminateWorkflow(ctx, workflowID, "", "snapshot schedule updated"); err != nil {
+		var notFound *serviceerror.NotFound
+		if !errors.As(err, &notFound) {
+			return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to stop previous snapshot schedule")
+		}
+	}
+
+	if schedule != "" {
+		workflowOptions := client.StartWorkflowOptions{
+			ID:           workflowID,
+			TaskQueue:    "kubemanager-tasks",
+			CronSchedule: schedule,
+		}
+
+		_, err := s.TemporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.ScheduledSnapshotWorkflow, workflows.ScheduledSnapshotInput{
+			WorkloadID:    id,
+			SnapshotClass: s.Config.Kubernetes.SnapshotClass,
+			Retention:     retention,
+		})
+		if err != nil {
+			return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start snapshot schedule")
+		}
+	} else {
+		workflowID = ""
+		retention = 0
+	}
+
+	if err := s.Repo.UpdateSnapshotSchedule(ctx, workload.ID, schedule, retention); err != nil {
+		return "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save snapshot schedule")
+	}
+
+	return workflowID, nil
+}
+
+func (s *WorkloadService) getPersistentWorkload(ctx context.Context, id string) (*domain.Workload, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	workload, err := s.Repo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+
+	if len(workload.PersistentVolumes()) == 0 {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "workload has no persistent volume")
+	}
+	return workload, nil
+}
+
+// persistentVolume returns the persistent volume of the workload with this name, the primary
+// one when the name is empty.
+func persistentVolume(workload *domain.Workload, name string) (*domain.WorkloadVolume, error) {
+	if name == "" {
+		name = utils.PrimaryVolumeName
+	}
+	for _, v := range workload.PersistentVolumes() {
+		if v.Name == name {
+			return &v, nil
+		}
+	}
+	return nil, betoerrors.New(betoerrors.CodeInvalidInput, "workload has no persistent volume named "+name)
+}
+
+// snapshotVolumeName finds the volume a snapshot was taken from by its claim.
+func snapshotVolumeName(workload *domain.Workload, snapshot *domain.VolumeSnapshot) string {
+	for _, v := range workload.PersistentVolumes() {
+		if activities.VolumeClaimName(workload.Name, v.Name) == snapshot.PVCName {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+func (s *WorkloadService) getWorkloadSnapshot(ctx context.Context, id string, snapshotID string) (*domain.VolumeSnapshot, error) {
+	sID, err := uuid.Parse(snapshotID)
+	if err != nil {
+		return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid snapshot id")
+	}
+
+	snapshot, err := s.SnapshotRepo.GetByID(ctx, sID)
+	if err != nil || snapshot.WorkloadID.String() != id {
+		return nil, betoerrors.New(betoerrors.CodeNotFound, "snapshot not found")
+	}
+	return snapshot, nil
+}
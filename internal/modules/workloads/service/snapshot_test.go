@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"go.temporal.io/sdk/client"
+	"gorm.io/gorm"
+
+	"github.com/thekrauss/kubemanager/internal/core/configs"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/repository"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
+)
+
+type memWorkloadRepo struct {
+	repository.WorkloadRepository
+	workloads map[uuid.UUID]*domain.Workload
+}
+
+func (r *memWorkloadRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Workload, error) {
+	if w, ok := r.workloads[id]; ok {
+		return w, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type memSnapshotRepo struct {
+	repository.SnapshotRepository
+	snapshots map[uuid.UUID]*domain.VolumeSnapshot
+}
+
+func (r *memSnapshotRepo) Create(_ context.Context, snapshot *domain.VolumeSnapshot) error {
+	r.snapshots[snapshot.ID] = snapshot
+	return nil
+}
+
+func (r *memSnapshotRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.VolumeSnapshot, error) {
+	if s, ok := r.snapshots[id]; ok {
+		return s, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// startRecorder keeps the arguments of the started workflows.
+type startRecorder struct {
+	client.Client
+	started []interface{}
+}
+
+func (c *startRecorder) ExecuteWorkflow(_ context.Context, options client.StartWorkflowOptions, _ interface{}, args ...interface{}) (client.WorkflowRun, error) {
+	c.started = append(c.started, args...)
+	return startedRun{id: options.ID}, nil
+}
+
+type startedRun struct {
+	client.WorkflowRun
+	id string
+}
+
+func (r startedRun) GetID() string { return r.id }
+
+func newSnapshotTestService() (*WorkloadService, *domain.Workload, *memSnapshotRepo, *startRecorder) {
+	workload := &domain.Workload{
+		ID:     uuid.New(),
+		Name:   "shop",
+		Kind:   utils.WorkloadKindService,
+		Status: utils.WorkloadRunning,
+		Volumes: []domain.WorkloadVolume{
+			{Name: "data", Type: utils.VolumeTypePersistent, Size: "2Gi", StorageClass: "fast", AccessMode: "ReadWriteOnce", MountPath: "/data"},
+			{Name: "uploads", Type: utils.VolumeTypePersistent, Size: "5Gi", StorageClass: "shared", AccessMode: "ReadWriteMany", MountPath: "/uploads"},
+			{Name: "cache", Type: "emptyDir", MountPath: "/cache"},
+		},
+	}
+	snapshots := &memSnapshotRepo{snapshots: map[uuid.UUID]*domain.VolumeSnapshot{}}
+	temporal := &startRecorder{}
+	return &WorkloadService{
+		TemporalClient: temporal,
+		Config:         &configs.GlobalConfig{},
+		Repo:           &memWorkloadRepo{workloads: map[uuid.UUID]*domain.Workload{workload.ID: workload}},
+		SnapshotRepo:   snapshots,
+	}, workload, snapshots, temporal
+}
+
+func TestSnapshotTargetsTheNamedVolume(t *testing.T) {
+	s, workload, _, _ := newSnapshotTestService()
+	ctx := context.Background()
+
+	snapshot, _, err := s.CreateSnapshot(ctx, workload.ID.String(), "uploads")
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if snapshot.PVCName != "shop-uploads-pvc" {
+		t.Errorf("PVCName = %q, want the claim of uploads", snapshot.PVCName)
+	}
+
+	for _, name := range []string{"cache", "logs"} {
+		if _, _, err := s.CreateSnapshot(ctx, workload.ID.String(), name); !betoerrors.Is(err, betoerrors.CodeInvalidInput) {
+			t.Errorf("snapshot of %s: err = %v, want invalid input", name, err)
+		}
+	}
+}
+
+func TestRestoreUsesTheVolumeOfTheSnapshot(t *testing.T) {
+	s, workload, snapshots, temporal := newSnapshotTestService()
+	ctx := context.Background()
+	snapshot := &domain.VolumeSnapshot{ID: uuid.New(), WorkloadID: workload.ID, PVCName: "shop-uploads-pvc", Status: utils.SnapshotReady, RestoreSize: "4Gi"}
+	snapshots.snapshots[snapshot.ID] = snapshot
+
+	if _, _, err := s.RestoreSnapshot(ctx, workload.ID.String(), snapshot.ID.String(), utils.SnapshotRestoreInPlace, "", ""); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	input := temporal.started[0].(workflows.RestoreSnapshotInput)
+	if input.TargetPVC != "shop-uploads-pvc" || input.Size != "5Gi" || input.StorageClass != "shared" || input.AccessMode != "ReadWriteMany" {
+		t.Errorf("restore input = %+v, want the uploads volume", input)
+	}
+
+	// la PVC d'un autre volume du workload ne se remplace qu'en place
+	if _, _, err := s.RestoreSnapshot(ctx, workload.ID.String(), snapshot.ID.String(), utils.SnapshotRestoreNewPVC, "shop-pvc", ""); !betoerrors.Is(err, betoerrors.CodeInvalidInput) {
+		t.Errorf("new pvc over the data claim: err = %v, want invalid input", err)
+	}
+	if _, _, err := s.RestoreSnapshot(ctx, workload.ID.String(), snapshot.ID.String(), utils.SnapshotRestoreInPlace, "", "cache"); !betoerrors.Is(err, betoerrors.CodeInvalidInput) {
+		t.Errorf("restore into an emptyDir: err = %v, want invalid input", err)
+	}
+}
+
+func TestRestoreOfAnUnmountedClaimNeedsAVolume(t *testing.T) {
+	s, workload, snapshots, temporal := newSnapshotTestService()
+	ctx := context.Background()
+	snapshot := &domain.VolumeSnapshot{ID: uuid.New(), WorkloadID: workload.ID, PVCName: "shop-old-pvc", Status: utils.SnapshotReady}
+	snapshots.snapshots[snapshot.ID] = snapshot
+
+	if _, _, err := s.RestoreSnapshot(ctx, workload.ID.String(), snapshot.ID.String(), utils.SnapshotRestoreInPlace, "", ""); !betoerrors.Is(err, betoerrors.CodeInvalidInput) {
+		t.Fatalf("err = %v, want invalid input", err)
+	}
+	if _, _, err := s.RestoreSnapshot(ctx, workload.ID.String(), snapshot.ID.String(), utils.SnapshotRestoreInPlace, "", "data"); err != nil {
+		t.Fatalf("RestoreSnapshot into data: %v", err)
+	}
+	if input := temporal.started[0].(workflows.RestoreSnapshotInput); input.TargetPVC != "shop-pvc" {
+		t.Errorf("TargetPVC = %q, want the claim of data", input.TargetPVC)
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/workflows"
+	"go.temporal.io/api/serviceerror"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ApplyWorkloadSpec redeploys an existing workload with a full spec (image, resources, batch
+// settings, secret references). The kind, the persistence flag and the extra volumes are fixed
+// at creation; the primary volume can only grow, the deploy workflow resizes it before installing
+// the release. The workflow ID is chosen by the caller so a retried call starts it only once.
+func (s *WorkloadService) ApplyWorkloadSpec(ctx context.Context, id string, in *domain.CreateWorkloadRequest, workflowID string) (*domain.Workload, string, error) {
+	wID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid workload id")
+	}
+
+	current, err := s.Repo.GetByID(ctx, wID)
+	if err != nil {
+		return nil, "", betoerrors.New(betoerrors.CodeNotFound, "workload not found")
+	}
+	if current.Status == utils.WorkloadDeleting {
+		return nil, "", betoerrors.New(betoerrors.CodeConflict, "workload is being deleted")
+	}
+
+	// appel rejoué par Temporal : la mise à jour est déjà lancée
+	if current.LastWorkflowID == workflowID {
+		return current, workflowID, nil
+	}
+	if err := checkWorkloadIdle(current); err != nil {
+		return nil, "", err
+	}
+
+	if in.Kind == "" {
+		in.Kind = utils.WorkloadKindService
+	}
+	if in.Kind != current.Kind {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "kind cannot be changed, delete and recreate the workload")
+	}
+	if in.PersistenceEnabled != current.PersistenceEnabled {
+		return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "persistence cannot be toggled on an existing workload")
+	}
+	if err := s.validateKind(in); err != nil {
+		return nil, "", err
+	}
+	if in.Kind == utils.WorkloadKindService {
+		if err := s.validateNetwork(in.TargetPort); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := s.validateSecretRefs(ctx, current.ProjectID, in.SecretRefs); err != nil {
+		return nil, "", err
+	}
+	if err := s.checkComputeQuota(ctx, current, in); err != nil {
+		return nil, "", err
+	}
+
+	storageSize, resizeTo := current.StorageSize, ""
+	if current.PersistenceEnabled && in.StorageSize != "" && in.StorageSize != current.StorageSize {
+		if _, err := resource.ParseQuantity(in.StorageSize); err != nil {
+			return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "invalid storage size")
+		}
+		if utils.ParseStorageToBytes(in.StorageSize) < utils.ParseStorageToBytes(current.StorageSize) {
+			return nil, "", betoerrors.New(betoerrors.CodeInvalidInput, "reduction of storage size is not supported")
+		}
+		// agrandi par le workflow de déploiement avant l'installation, jamais en parallèle de celle-ci
+		storageSize, resizeTo = in.StorageSize, in.StorageSize
+	}
+
+	current.Image = in.Image
+	current.Replicas = in.Replicas
+	current.CPULimit = in.CPULimit
+	current.MemoryLimit = in.MemoryLimit
+	current.TargetPort = in.TargetPort
+	current.Schedule = in.Schedule
+	current.ConcurrencyPolicy = in.ConcurrencyPolicy
+	current.BackoffLimit = in.BackoffLimit
+	current.TTLSecondsAfterFinished = in.TTLSecondsAfterFinished
+	current.SecretRefs = in.SecretRefs
+
+	envSecrets, err := s.envSecrets(ctx, current)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = s.TemporalClient.ExecuteWorkflow(ctx, deployWorkflowOptions(workflowID), workflows.DeployWorkloadWorkflow, workflows.DeployWorkloadInput{
+		WorkloadID:         id,
+		ProjectID:          current.ProjectID.String(),
+		Namespace:          current.Namespace,
+		ReleaseName:        current.Name,
+		Image:              current.Image,
+		EnvVars:            in.EnvVars,
+		Secrets:            in.SecretData,
+		PersistenceEnabled: current.PersistenceEnabled,
+		StorageSize:        storageSize,
+		ResizeTo:           resizeTo,
+		StorageClass:       current.StorageClass,
+		AccessMode:         current.AccessMode,
+		MountPath:          in.MountPath,
+		Volumes:            toVolumeSpecs(current.Volumes),
+		EnvSecrets:         envSecrets,
+		Replicas:           current.Replicas,
+		ServiceType:        in.ServiceType,
+		TargetPort:         current.TargetPort,
+
+		Kind:                    current.Kind,
+		Schedule:                current.Schedule,
+		ConcurrencyPolicy:       current.ConcurrencyPolicy,
+		BackoffLimit:            current.BackoffLimit,
+		TTLSecondsAfterFinished: current.TTLSecondsAfterFinished,
+	})
+	if err != nil {
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		if !errors.As(err, &started) {
+			return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to start update workflow")
+		}
+	}
+
+	// enregistré après le démarrage, comme DeployImage : un essai rejoué relance le même workflow
+	current.LastWorkflowID = workflowID
+	if err := s.Repo.UpdateSpec(ctx, current); err != nil {
+		return nil, "", betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to save workload spec")
+	}
+
+	current.Status = utils.WorkloadStarting
+	return current, workflowID, nil
+}
+
+// checkComputeQuota replaces the current CPU/memory reservation of the workload by the requested one.
+func (s *WorkloadService) checkComputeQuota(ctx context.Context, current *domain.Workload, in *domain.CreateWorkloadRequest) error {
+	project, err := s.ProjectRepo.GetProjectByID(ctx, current.ProjectID.String())
+	if err != nil {
+		return betoerrors.New(betoerrors.CodeNotFound, "project not found")
+	}
+
+	usedCPU, usedMem, _, err := s.Repo.GetTotalUsageByProject(ctx, current.ProjectID)
+	if err != nil {
+		return betoerrors.Wrap(err, betoerrors.CodeInternal, "failed to compute project usage")
+	}
+	addonCPU, addonMem, _, _ := s.AddonRepo.GetTotalUsageByProject(ctx, current.ProjectID)
+	usedCPU += addonCPU
+	usedMem += addonMem
+
+	if current.Status != utils.WorkloadSucceeded && current.Status != utils.WorkloadFailed {
+		replicas := int64(max(current.Replicas, 1))
+		usedCPU -= s.parseCPU(current.CPULimit) * replicas
+		usedMem -= s.parseMemory(current.MemoryLimit) * replicas
+	}
+
+	replicas := int64(max(in.Replicas, 1))
+	requestedCPU := usedCPU + s.parseCPU(in.CPULimit)*replicas
+	if limit := s.parseCPU(project.CpuLimit); requestedCPU > limit {
+		return betoerrors.New(betoerrors.CodeInvalidInput, fmt.Sprintf("quota exceeded: CPU limit reached (%d/%d m)", requestedCPU, limit))
+	}
+	if requestedMem := usedMem + s.parseMemory(in.MemoryLimit)*replicas; requestedMem > s.parseMemory(project.MemoryLimit) {
+		return betoerrors.New(betoerrors.CodeInvalidInput, "quota exceeded: Memory limit reached")
+	}
+	return nil
+}
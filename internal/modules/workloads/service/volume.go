@@ -0,0 +1,113 @@
+package service
+
+import (
+	"path"
+	"regexp"
+
+	"github.com/google/uuid"
+	betoerrors "github.com/thekrauss/beto-shared/pkg/errors"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/domain"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var volumeNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// buildVolumes merges the legacy persistence fields (primary "data" volume) with the
+// requested volumes, and validates names, mount paths and sizes.
+func (s *WorkloadService) buildVolumes(in *domain.CreateWorkloadRequest) ([]domain.WorkloadVolume, error) {
+	requests := make([]domain.VolumeRequest, 0, len(in.Volumes)+1)
+	if in.PersistenceEnabled {
+		requests = append(requests, domain.VolumeRequest{
+			Name:         utils.PrimaryVolumeName,
+			Type:         utils.VolumeTypePersistent,
+			Size:         in.StorageSize,
+			StorageClass: in.StorageClass,
+			AccessMode:   in.AccessMode,
+			MountPath:    in.MountPath,
+		})
+	}
+	requests = append(requests, in.Volumes...)
+
+	names := make(map[string]bool, len(requests))
+	mountPaths := make(map[string]bool, len(requests))
+	volumes := make([]domain.WorkloadVolume, 0, len(requests))
+
+	for _, v := range requests {
+		if v.Type == "" {
+			v.Type = utils.VolumeTypePersistent
+		}
+		if v.MountPath == "" {
+			v.MountPath = "/data"
+		}
+
+		if !volumeNameRegex.MatchString(v.Name) {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid volume name: "+v.Name)
+		}
+		if names[v.Name] {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "duplicate volume name: "+v.Name)
+		}
+		names[v.Name] = true
+
+		if !path.IsAbs(v.MountPath) {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "mount path must be absolute: "+v.MountPath)
+		}
+		mountPath := path.Clean(v.MountPath)
+		if mountPaths[mountPath] {
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "duplicate mount path: "+mountPath)
+		}
+		mountPaths[mountPath] = true
+
+		if v.Size != "" {
+			if _, err := resource.ParseQuantity(v.Size); err != nil {
+				return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid size for volume "+v.Name)
+			}
+		}
+
+		volume := domain.WorkloadVolume{
+			ID:        uuid.New(),
+			Name:      v.Name,
+			Type:      v.Type,
+			Size:      v.Size,
+			MountPath: mountPath,
+		}
+
+		switch v.Type {
+		case utils.VolumeTypePersistent:
+			if v.Size == "" {
+				return nil, betoerrors.New(betoerrors.CodeInvalidInput, "size is required for persistent volume "+v.Name)
+			}
+			volume.StorageClass = v.StorageClass
+			if volume.StorageClass == "" {
+				volume.StorageClass = in.StorageClass
+			}
+			volume.AccessMode = v.AccessMode
+			if volume.AccessMode == "" {
+				volume.AccessMode = "ReadWriteOnce"
+			}
+		case utils.VolumeTypeEmptyDir, utils.VolumeTypeTmpfs:
+		default:
+			return nil, betoerrors.New(betoerrors.CodeInvalidInput, "invalid volume type: "+v.Type)
+		}
+
+		volumes = append(volumes, volume)
+	}
+
+	return volumes, nil
+}
+
+func toVolumeSpecs(volumes []domain.WorkloadVolume) []activities.VolumeSpec {
+	specs := make([]activities.VolumeSpec, 0, len(volumes))
+	for _, v := range volumes {
+		specs = append(specs, activities.VolumeSpec{
+			Name:         v.Name,
+			Type:         v.Type,
+			Size:         v.Size,
+			StorageClass: v.StorageClass,
+			AccessMode:   v.AccessMode,
+			MountPath:    v.MountPath,
+		})
+	}
+	return specs
+}
@@ -0,0 +1,76 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type DeleteWorkloadInput struct {
+	WorkloadID  string
+	Namespace   string
+	ReleaseName string
+	PVCNames    []string
+}
+
+func DeleteWorkloadWorkflow(ctx workflow.Context, input DeleteWorkloadInput) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	// polling : une PVC reste en Terminating tant qu'un pod la monte
+	pollCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    1 * time.Minute,
+			MaximumAttempts:    40,
+		},
+	})
+
+	var dbActs *activities.WorkloadDBActivities
+	var helmActs *activities.WorkloadActivities
+
+	phase := utils.PhaseWorkloadUninstalling
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
+	fail := func(err error) error {
+		phase = utils.PhaseWorkloadDeleteFailed
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadFailed, phase).Get(ctx, nil)
+		return err
+	}
+
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadDeleting, phase).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, helmActs.UninstallRelease, input.Namespace, input.ReleaseName).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	if err := workflow.ExecuteActivity(ctx, helmActs.DeleteEnvSecret, input.Namespace, input.ReleaseName).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	// helm supprime les PVC du chart sans attendre : on attend qu'elles aient disparu avant de libérer le quota
+	phase = utils.PhaseWorkloadVolumesDelete
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadDeleting, phase).Get(ctx, nil)
+
+	for _, pvcName := range input.PVCNames {
+		if err := workflow.ExecuteActivity(pollCtx, helmActs.DeletePVC, input.Namespace, pvcName).Get(ctx, nil); err != nil {
+			return fail(err)
+		}
+	}
+
+	return workflow.ExecuteActivity(ctx, dbActs.DeleteWorkloadRecord, input.WorkloadID).Get(ctx, nil)
+}
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/thekrauss/kubemanager/internal/core/configs"
+	mailWorkflows "github.com/thekrauss/kubemanager/internal/modules/mailer/workflows"
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	webhookWorkflows "github.com/thekrauss/kubemanager/internal/modules/webhooks/workflows"
 	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
@@ -20,13 +23,52 @@ type DeployWorkloadInput struct {
 	Secrets            map[string]string
 	PersistenceEnabled bool
 	StorageSize        string
+	ResizeTo           string // nouvelle taille du volume principal, agrandi avant l'installation
 	StorageClass       string
+	AccessMode         string
+	MountPath          string
+	Volumes            []activities.VolumeSpec
+	EnvSecrets         []activities.EnvSecretRef
 	Replicas           int
 	ServiceType        string //"ClusterIP" ou "LoadBalancer"
 	TargetPort         int
+
+	Kind                    string // utils.WorkloadKind*, vide = service
+	Schedule                string
+	ConcurrencyPolicy       string
+	BackoffLimit            *int
+	TTLSecondsAfterFinished int
 }
 
+// DeployWorkloadWorkflow installs the release and notifies the project webhooks of the outcome,
+// the owners also get an email on failure. A canceled deploy is rolled back without event.
 func DeployWorkloadWorkflow(ctx workflow.Context, input DeployWorkloadInput) error {
+	err := deployWorkload(ctx, input)
+
+	data := map[string]interface{}{
+		"workload_id": input.WorkloadID,
+		"name":        input.ReleaseName,
+		"namespace":   input.Namespace,
+		"image":       input.Image,
+		"kind":        input.Kind,
+	}
+	switch {
+	case err == nil:
+		webhookWorkflows.EmitEvent(ctx, utils.EventWorkloadDeployed, input.ProjectID, data)
+	case !temporal.IsCanceledError(err):
+		data["error"] = err.Error()
+		webhookWorkflows.EmitEvent(ctx, utils.EventWorkloadFailed, input.ProjectID, data)
+		mailWorkflows.NotifyDeployFailure(ctx, input.ProjectID, input.WorkloadID, map[string]interface{}{
+			"Workload":  input.ReleaseName,
+			"Namespace": input.Namespace,
+			"Image":     input.Image,
+			"Error":     err.Error(),
+		})
+	}
+	return err
+}
+
+func deployWorkload(ctx workflow.Context, input DeployWorkloadInput) error {
 	options := workflow.ActivityOptions{
 		StartToCloseTimeout: 5 * time.Minute,
 		RetryPolicy: &temporal.RetryPolicy{
@@ -44,53 +86,170 @@ func DeployWorkloadWorkflow(ctx workflow.Context, input DeployWorkloadInput) err
 	var dbActs *activities.WorkloadDBActivities
 	var helmActs *activities.WorkloadActivities
 
+	phase := utils.PhaseHelmPreparing
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
 	//STATUT -> STARTING
-	err := workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "STARTING", "HELM_PREPARING").Get(ctx, nil)
+	err := workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "STARTING", phase).Get(ctx, nil)
 	if err != nil {
 		return err
 	}
 
+	if input.ResizeTo != "" {
+		// même ID que POST /resize : un seul redimensionnement à la fois pour le workload
+		phase = utils.PhaseVolumeResizing
+		resizeCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID: "workload-resize-" + input.WorkloadID,
+		})
+		err = workflow.ExecuteChildWorkflow(resizeCtx, ResizeVolumeWorkflow, ResizeVolumeInput{
+			WorkloadID:  input.WorkloadID,
+			Namespace:   input.Namespace,
+			ReleaseName: input.ReleaseName,
+			NewSize:     input.ResizeTo,
+			Kind:        input.Kind,
+			Status:      utils.WorkloadStarting,
+		}).Get(ctx, nil)
+		if err != nil && temporal.IsCanceledError(err) {
+			phase = utils.PhaseRollbackInitiated
+			return compensateCanceledDeploy(ctx, input, &phase)
+		}
+		if err != nil {
+			phase = utils.PhaseVolumeResizeFailed
+			workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadFailed, phase).Get(ctx, nil)
+			return err
+		}
+		phase = utils.PhaseHelmPreparing
+	}
+
 	var imgInfo activities.ImageInfo
 	err = workflow.ExecuteLocalActivity(ctx, helmActs.ParseImage, input.Image).Get(ctx, &imgInfo)
 	if err != nil {
+		phase = utils.PhaseImageParseError
 		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "FAILED", "SECRET_CREATION_ERROR")
 		return err
 	}
 
 	err = workflow.ExecuteActivity(ctx, helmActs.EnsureSecret, input.Namespace, input.ReleaseName, input.EnvVars).Get(ctx, nil)
+	if err != nil && temporal.IsCanceledError(err) {
+		phase = utils.PhaseRollbackInitiated
+		return compensateCanceledDeploy(ctx, input, &phase)
+	}
 	if err != nil {
+		phase = utils.PhaseSecretError
 		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "FAILED", "SECRET_CREATION_ERROR")
 		return err
 	}
 
-	vpsIP := configs.AppConfig.Vps.AdressIp
-	shortID := input.ProjectID[:6]
-	externalURL := fmt.Sprintf("%s-%s.%s.sslip.io", input.ReleaseName, shortID, vpsIP)
-	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "STARTING", "HELM_INSTALLING").Get(ctx, nil)
+	// seuls les workloads "service" sont exposés par un Ingress
+	var externalURL string
+	if input.Kind == "" || input.Kind == utils.WorkloadKindService {
+		vpsIP := configs.AppConfig.Vps.AdressIp
+		shortID := input.ProjectID[:6]
+		externalURL = fmt.Sprintf("%s-%s.%s.sslip.io", input.ReleaseName, shortID, vpsIP)
+	}
+	phase = utils.PhaseHelmReleaseInstalling
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "STARTING", phase).Get(ctx, nil)
 
 	helmInput := activities.InstallWorkloadInput{
 		ReleaseName:        input.ReleaseName,
 		Namespace:          input.Namespace,
 		ImageRepo:          imgInfo.Repository,
 		ImageTag:           imgInfo.Tag,
+		ImageDigest:        imgInfo.Digest,
 		ExternalURL:        externalURL,
 		Env:                input.EnvVars,
 		PersistenceEnabled: input.PersistenceEnabled,
 		StorageSize:        input.StorageSize,
 		StorageClass:       input.StorageClass,
+		AccessMode:         input.AccessMode,
+		MountPath:          input.MountPath,
+		Volumes:            input.Volumes,
+		EnvSecrets:         input.EnvSecrets,
 		Replicas:           input.Replicas,
 		ServiceType:        input.ServiceType,
 		Secrets:            input.Secrets,
 		TargetPort:         input.TargetPort,
+
+		Kind:                    input.Kind,
+		Schedule:                input.Schedule,
+		ConcurrencyPolicy:       input.ConcurrencyPolicy,
+		BackoffLimit:            input.BackoffLimit,
+		TTLSecondsAfterFinished: input.TTLSecondsAfterFinished,
+	}
+	installCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		HeartbeatTimeout:    30 * time.Second,
+		WaitForCancellation: true,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+	err = workflow.ExecuteActivity(installCtx, helmActs.InstallChart, helmInput).Get(ctx, nil)
+	if err != nil && temporal.IsCanceledError(err) {
+		phase = utils.PhaseRollbackInitiated
+		return compensateCanceledDeploy(ctx, input, &phase)
 	}
-	err = workflow.ExecuteActivity(ctx, helmActs.InstallChart, helmInput).Get(ctx, nil)
 	if err != nil {
+		phase = utils.PhaseHelmError
 		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "FAILED", "HELM_ERROR")
 		return err
 	}
 
+	switch input.Kind {
+	case utils.WorkloadKindCronJob:
+		phase = utils.PhaseCronJobScheduled
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadScheduled, phase).Get(ctx, nil)
+		return nil
+
+	case utils.WorkloadKindJob:
+		phase = utils.PhaseJobRunning
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadRunning, phase).Get(ctx, nil)
+
+		if _, err := waitForJob(ctx, input.Namespace, input.ReleaseName, ""); err != nil {
+			phase = utils.PhaseJobFailed
+			workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadFailed, phase).Get(ctx, nil)
+			return err
+		}
+
+		phase = utils.PhaseJobSucceeded
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadSucceeded, phase).Get(ctx, nil)
+		return nil
+	}
+
 	//FINITION -> RUNNING
+	phase = utils.PhaseDeployed
 	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, "RUNNING", "DEPLOYED").Get(ctx, nil)
 
 	return nil
 }
+
+// compensateCanceledDeploy uninstalls a half-installed release once the deploy workflow
+// has been canceled, on a disconnected context so the cleanup itself is not canceled.
+func compensateCanceledDeploy(ctx workflow.Context, input DeployWorkloadInput, phase *string) error {
+	var dbActs *activities.WorkloadDBActivities
+	var helmActs *activities.WorkloadActivities
+
+	dCtx, _ := workflow.NewDisconnectedContext(ctx)
+	dCtx = workflow.WithActivityOptions(dCtx, workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	if err := workflow.ExecuteActivity(dCtx, helmActs.UninstallRelease, input.Namespace, input.ReleaseName).Get(dCtx, nil); err != nil {
+		workflow.ExecuteActivity(dCtx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadFailed, utils.PhaseHelmError).Get(dCtx, nil)
+		return err
+	}
+
+	*phase = utils.PhaseRollbackCompleted
+	workflow.ExecuteActivity(dCtx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadCanceled, *phase).Get(dCtx, nil)
+
+	return temporal.NewCanceledError()
+}
@@ -0,0 +1,92 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	webhookActivities "github.com/thekrauss/kubemanager/internal/modules/webhooks/activities"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+)
+
+// TestDeployResizesTheVolumeBeforeInstalling checks that a grown volume is resized by the
+// deploy itself, before the release asks for the new size, and that a failed resize stops it.
+func TestDeployResizesTheVolumeBeforeInstalling(t *testing.T) {
+	for _, resizeErr := range []error{nil, errors.New("quota exceeded: storage limit reached")} {
+		var suite testsuite.WorkflowTestSuite
+		suite.SetLogger(log.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		env := suite.NewTestWorkflowEnvironment()
+
+		dbActs := &activities.WorkloadDBActivities{}
+		k8sActs := &activities.WorkloadActivities{}
+		eventActs := &webhookActivities.EventActivities{}
+		env.RegisterActivity(dbActs)
+		env.RegisterActivity(k8sActs)
+		env.RegisterActivity(eventActs)
+		env.RegisterWorkflow(ResizeVolumeWorkflow)
+
+		var steps []string
+		var statuses []string
+		env.OnActivity(dbActs.UpdateWorkloadStatus, mock.Anything, "w1", mock.Anything, mock.Anything).
+			Return(func(_ context.Context, _, status, _ string) error {
+				statuses = append(statuses, status)
+				return nil
+			})
+		env.OnWorkflow(ResizeVolumeWorkflow, mock.Anything, mock.Anything).
+			Return(func(_ workflow.Context, input ResizeVolumeInput) error {
+				if input.NewSize != "2Gi" || input.Kind != utils.WorkloadKindWorker {
+					t.Errorf("resize input = %+v", input)
+				}
+				steps = append(steps, "resize")
+				return resizeErr
+			})
+		env.OnActivity(k8sActs.ParseImage, mock.Anything, "nginx:1.27").Return(activities.ImageInfo{Repository: "nginx", Tag: "1.27"}, nil)
+		env.OnActivity(k8sActs.EnsureSecret, mock.Anything, "ns", "app", mock.Anything).Return(nil)
+		env.OnActivity(k8sActs.InstallChart, mock.Anything, mock.Anything).
+			Return(func(_ context.Context, input activities.InstallWorkloadInput) error {
+				if input.StorageSize != "2Gi" {
+					t.Errorf("release installed with %s, want the new size", input.StorageSize)
+				}
+				steps = append(steps, "install")
+				return nil
+			})
+		env.OnActivity(eventActs.EmitEvent, mock.Anything, mock.Anything).Return(nil)
+
+		env.ExecuteWorkflow(DeployWorkloadWorkflow, DeployWorkloadInput{
+			WorkloadID:         "w1",
+			ProjectID:          "p1",
+			Namespace:          "ns",
+			ReleaseName:        "app",
+			Image:              "nginx:1.27",
+			PersistenceEnabled: true,
+			StorageSize:        "2Gi",
+			ResizeTo:           "2Gi",
+			Kind:               utils.WorkloadKindWorker,
+		})
+
+		err := env.GetWorkflowError()
+		if resizeErr == nil {
+			if err != nil {
+				t.Fatalf("deploy: %v", err)
+			}
+			if len(steps) != 2 || steps[0] != "resize" || steps[1] != "install" {
+				t.Errorf("steps = %v, want the resize before the install", steps)
+			}
+			continue
+		}
+		if err == nil || len(steps) != 1 {
+			t.Errorf("failed resize: err = %v, steps = %v, want no install", err, steps)
+		}
+		if last := statuses[len(statuses)-1]; last != utils.WorkloadFailed {
+			t.Errorf("failed resize: status = %s, want FAILED", last)
+		}
+	}
+}
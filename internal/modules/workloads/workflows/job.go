@@ -0,0 +1,83 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type RunCronJobInput struct {
+	WorkloadID  string
+	Namespace   string
+	ReleaseName string
+	JobName     string
+}
+
+// jobPollContext polls a job until it finishes, a batch run can last for hours
+func jobPollContext(ctx workflow.Context) workflow.Context {
+	return workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout:    30 * time.Second,
+		ScheduleToCloseTimeout: 24 * time.Hour,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    1 * time.Minute,
+		},
+	})
+}
+
+// waitForJob blocks until the job finishes and returns an error if it failed.
+func waitForJob(ctx workflow.Context, namespace, releaseName, jobName string) (activities.JobResult, error) {
+	var k8sActs *activities.WorkloadActivities
+
+	var result activities.JobResult
+	if err := workflow.ExecuteActivity(jobPollContext(ctx), k8sActs.WaitForJob, namespace, releaseName, jobName).Get(ctx, &result); err != nil {
+		return result, err
+	}
+	if result.Phase == utils.PhaseJobFailed {
+		return result, temporal.NewApplicationError("job "+result.JobName+" failed: "+result.Reason, "JobFailed")
+	}
+	return result, nil
+}
+
+// RunCronJobWorkflow triggers a CronJob outside of its schedule and waits for the run,
+// the workload stays SCHEDULED, only its phase reflects the manual run.
+func RunCronJobWorkflow(ctx workflow.Context, input RunCronJobInput) (activities.JobResult, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	var dbActs *activities.WorkloadDBActivities
+	var k8sActs *activities.WorkloadActivities
+
+	phase := utils.PhaseCronJobTriggering
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return activities.JobResult{}, err
+	}
+
+	if err := workflow.ExecuteActivity(ctx, k8sActs.TriggerCronJob, input.Namespace, input.ReleaseName, input.JobName).Get(ctx, nil); err != nil {
+		return activities.JobResult{}, err
+	}
+
+	phase = utils.PhaseJobRunning
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadScheduled, phase).Get(ctx, nil)
+
+	result, err := waitForJob(ctx, input.Namespace, input.ReleaseName, input.JobName)
+	if err != nil {
+		phase = utils.PhaseJobFailed
+	} else {
+		phase = utils.PhaseJobSucceeded
+	}
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadScheduled, phase).Get(ctx, nil)
+
+	return result, err
+}
@@ -0,0 +1,124 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// maxFileSystemResizeChecks bounds the wait for the kubelet once the pods have been restarted
+const maxFileSystemResizeChecks = 30
+
+type ResizeVolumeInput struct {
+	WorkloadID  string
+	Namespace   string
+	ReleaseName string
+	NewSize     string
+	Kind        string // utils.WorkloadKind*, vide = service
+	Status      string // statut remis en place à la fin, vide = RUNNING
+}
+
+func ResizeVolumeWorkflow(ctx workflow.Context, input ResizeVolumeInput) error {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, options)
+
+	// polling de la PVC : chaque tentative échouée = la taille n'est pas encore atteinte
+	pollCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    30,
+		},
+	})
+
+	var dbActs *activities.WorkloadDBActivities
+	var k8sActs *activities.WorkloadActivities
+
+	pvcName := activities.PVCName(input.ReleaseName)
+
+	phase := utils.PhaseVolumeExpansionCheck
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
+	// un job terminé ou un cronjob en attente garde son statut une fois le volume traité
+	status := input.Status
+	if status == "" {
+		status = utils.WorkloadRunning
+	}
+
+	fail := func(err error) error {
+		phase = utils.PhaseVolumeResizeFailed
+		// le volume n'a pas changé, le workload continue de tourner
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, status, phase).Get(ctx, nil)
+		return err
+	}
+
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadScaling, phase).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, k8sActs.CheckVolumeExpansion, input.Namespace, pvcName).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	if err := workflow.ExecuteActivity(ctx, dbActs.CheckStorageQuota, input.WorkloadID, input.NewSize).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	phase = utils.PhaseVolumeResizing
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadScaling, phase).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, k8sActs.ExpandPVC, input.Namespace, pvcName, input.NewSize).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	var resize activities.ResizeStatus
+	if err := workflow.ExecuteActivity(pollCtx, k8sActs.WaitForPVCResize, input.Namespace, pvcName, input.NewSize).Get(ctx, &resize); err != nil {
+		return fail(err)
+	}
+
+	// les pods d'un job ou d'un cronjob ne se redémarrent pas : le kubelet termine le
+	// redimensionnement au montage du volume par le prochain pod
+	if resize.FileSystemPending && utils.IsDeploymentKind(input.Kind) {
+		phase = utils.PhaseFileSystemResizing
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadScaling, phase).Get(ctx, nil)
+
+		if err := workflow.ExecuteActivity(ctx, k8sActs.RestartWorkloadPods, input.Namespace, input.ReleaseName).Get(ctx, nil); err != nil {
+			return fail(err)
+		}
+
+		for attempt := 0; resize.FileSystemPending; attempt++ {
+			if attempt >= maxFileSystemResizeChecks {
+				return fail(temporal.NewApplicationError("filesystem resize still pending after pod restart", "FileSystemResizeTimeout"))
+			}
+			if err := workflow.Sleep(ctx, 10*time.Second); err != nil {
+				return fail(err)
+			}
+			if err := workflow.ExecuteActivity(pollCtx, k8sActs.WaitForPVCResize, input.Namespace, pvcName, input.NewSize).Get(ctx, &resize); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	// la taille n'est enregistrée qu'une fois le redimensionnement terminé
+	if err := workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStorage, input.WorkloadID, input.NewSize).Get(ctx, nil); err != nil {
+		return fail(err)
+	}
+
+	phase = utils.PhaseVolumeResized
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, status, phase).Get(ctx, nil)
+
+	return nil
+}
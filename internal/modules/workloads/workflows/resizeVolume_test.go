@@ -0,0 +1,75 @@
+package workflows
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+)
+
+// TestResizeVolumeKeepsJobPods checks that the pods of a job are never restarted as a
+// Deployment, and that the job gets its own status back once the volume is resized.
+func TestResizeVolumeKeepsJobPods(t *testing.T) {
+	for _, kind := range []string{utils.WorkloadKindJob, utils.WorkloadKindCronJob, utils.WorkloadKindService} {
+		var suite testsuite.WorkflowTestSuite
+		suite.SetLogger(log.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		env := suite.NewTestWorkflowEnvironment()
+
+		dbActs := &activities.WorkloadDBActivities{}
+		k8sActs := &activities.WorkloadActivities{}
+		env.RegisterActivity(dbActs)
+		env.RegisterActivity(k8sActs)
+
+		var statuses []string
+		env.OnActivity(dbActs.UpdateWorkloadStatus, mock.Anything, "w1", mock.Anything, mock.Anything).
+			Return(func(_ context.Context, _, status, _ string) error {
+				statuses = append(statuses, status)
+				return nil
+			})
+		env.OnActivity(k8sActs.CheckVolumeExpansion, mock.Anything, "ns", mock.Anything).Return(nil)
+		env.OnActivity(dbActs.CheckStorageQuota, mock.Anything, "w1", "2Gi").Return(nil)
+		env.OnActivity(k8sActs.ExpandPVC, mock.Anything, "ns", mock.Anything, "2Gi").Return(nil)
+		env.OnActivity(dbActs.UpdateWorkloadStorage, mock.Anything, "w1", "2Gi").Return(nil)
+
+		// le système de fichiers attend un redémarrage, terminé au second passage
+		pending := true
+		env.OnActivity(k8sActs.WaitForPVCResize, mock.Anything, "ns", mock.Anything, "2Gi").
+			Return(func(_ context.Context, _, _, _ string) (activities.ResizeStatus, error) {
+				status := activities.ResizeStatus{Capacity: "2Gi", FileSystemPending: pending}
+				pending = false
+				return status, nil
+			})
+		restarted := false
+		env.OnActivity(k8sActs.RestartWorkloadPods, mock.Anything, "ns", "app").
+			Return(func(_ context.Context, _, _ string) error {
+				restarted = true
+				return nil
+			})
+
+		env.ExecuteWorkflow(ResizeVolumeWorkflow, ResizeVolumeInput{
+			WorkloadID:  "w1",
+			Namespace:   "ns",
+			ReleaseName: "app",
+			NewSize:     "2Gi",
+			Kind:        kind,
+			Status:      utils.WorkloadSucceeded,
+		})
+		if err := env.GetWorkflowError(); err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+
+		if want := utils.IsDeploymentKind(kind); restarted != want {
+			t.Errorf("%s: restarted = %v, want %v", kind, restarted, want)
+		}
+		if last := statuses[len(statuses)-1]; last != utils.WorkloadSucceeded {
+			t.Errorf("%s: final status = %s, want the previous status back", kind, last)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/thekrauss/kubemanager/internal/modules/utils"
+	"github.com/thekrauss/kubemanager/internal/modules/workloads/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type RestoreSnapshotInput struct {
+	WorkloadID   string
+	Snapshot     activities.SnapshotRef
+	Mode         string // utils.SnapshotRestoreNewPVC | utils.SnapshotRestoreInPlace
+	TargetPVC    string
+	ReleaseName  string
+	Replicas     int
+	Size         string
+	StorageClass string
+	AccessMode   string
+	Status       string // statut remis en place si la mise en pause échoue, vide = RUNNING
+}
+
+type ScheduledSnapshotInput struct {
+	WorkloadID    string
+	SnapshotClass string
+	Retention     int
+}
+
+func snapshotActivityContexts(ctx workflow.Context) (workflow.Context, workflow.Context) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval: time.Second,
+			MaximumAttempts: 3,
+		},
+	})
+
+	// polling : un snapshot ou une suppression de PVC peut prendre plusieurs minutes
+	pollCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    1 * time.Minute,
+			MaximumAttempts:    40,
+		},
+	})
+	return ctx, pollCtx
+}
+
+func CreateSnapshotWorkflow(ctx workflow.Context, input activities.SnapshotRef) error {
+	ctx, pollCtx := snapshotActivityContexts(ctx)
+
+	phase := utils.PhaseSnapshotCreating
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := takeSnapshot(ctx, pollCtx, input); err != nil {
+		phase = utils.PhaseSnapshotFailed
+		return err
+	}
+
+	phase = utils.PhaseSnapshotReady
+	return nil
+}
+
+func takeSnapshot(ctx, pollCtx workflow.Context, input activities.SnapshotRef) error {
+	var dbActs *activities.WorkloadDBActivities
+	var k8sActs *activities.WorkloadActivities
+
+	err := workflow.ExecuteActivity(ctx, k8sActs.CreateVolumeSnapshot, input.Namespace, input.Name, input.PVCName, input.SnapshotClass).Get(ctx, nil)
+	if err != nil {
+		workflow.ExecuteActivity(ctx, dbActs.UpdateSnapshotStatus, input.SnapshotID, utils.SnapshotFailed, "").Get(ctx, nil)
+		return err
+	}
+
+	var restoreSize string
+	err = workflow.ExecuteActivity(pollCtx, k8sActs.WaitForSnapshotReady, input.Namespace, input.Name).Get(ctx, &restoreSize)
+	if err != nil {
+		workflow.ExecuteActivity(ctx, dbActs.UpdateSnapshotStatus, input.SnapshotID, utils.SnapshotFailed, "").Get(ctx, nil)
+		return err
+	}
+
+	return workflow.ExecuteActivity(ctx, dbActs.UpdateSnapshotStatus, input.SnapshotID, utils.SnapshotReady, restoreSize).Get(ctx, nil)
+}
+
+func DeleteSnapshotWorkflow(ctx workflow.Context, input activities.SnapshotRef) error {
+	ctx, _ = snapshotActivityContexts(ctx)
+	return deleteSnapshot(ctx, input)
+}
+
+func deleteSnapshot(ctx workflow.Context, input activities.SnapshotRef) error {
+	var dbActs *activities.WorkloadDBActivities
+	var k8sActs *activities.WorkloadActivities
+
+	if err := workflow.ExecuteActivity(ctx, k8sActs.DeleteVolumeSnapshot, input.Namespace, input.Name).Get(ctx, nil); err != nil {
+		return err
+	}
+	return workflow.ExecuteActivity(ctx, dbActs.DeleteSnapshotRecord, input.SnapshotID).Get(ctx, nil)
+}
+
+// ScheduledSnapshotWorkflow runs as a Temporal cron workflow: one snapshot per run,
+// then the scheduled snapshots beyond the retention count are pruned.
+func ScheduledSnapshotWorkflow(ctx workflow.Context, input ScheduledSnapshotInput) error {
+	ctx, pollCtx := snapshotActivityContexts(ctx)
+
+	var dbActs *activities.WorkloadDBActivities
+
+	var ref activities.SnapshotRef
+	if err := workflow.ExecuteActivity(ctx, dbActs.PrepareScheduledSnapshot, input.WorkloadID, input.SnapshotClass).Get(ctx, &ref); err != nil {
+		return err
+	}
+
+	// un snapshot raté ne doit pas bloquer la rotation des précédents
+	snapErr := takeSnapshot(ctx, pollCtx, ref)
+
+	var expired []activities.SnapshotRef
+	if err := workflow.ExecuteActivity(ctx, dbActs.ListExpiredSnapshots, input.WorkloadID, input.Retention).Get(ctx, &expired); err != nil {
+		return err
+	}
+	for _, old := range expired {
+		if err := deleteSnapshot(ctx, old); err != nil {
+			workflow.GetLogger(ctx).Warn("failed to prune snapshot", "snapshot", old.Name, "error", err)
+		}
+	}
+
+	return snapErr
+}
+
+func RestoreSnapshotWorkflow(ctx workflow.Context, input RestoreSnapshotInput) error {
+	ctx, pollCtx := snapshotActivityContexts(ctx)
+
+	var dbActs *activities.WorkloadDBActivities
+	var k8sActs *activities.WorkloadActivities
+
+	phase := utils.PhaseSnapshotRestoring
+	if err := workflow.SetQueryHandler(ctx, utils.QueryCurrentPhase, func() (string, error) {
+		return phase, nil
+	}); err != nil {
+		return err
+	}
+
+	restore := activities.RestorePVCInput{
+		Namespace:    input.Snapshot.Namespace,
+		SnapshotName: input.Snapshot.Name,
+		PVCName:      input.TargetPVC,
+		Size:         input.Size,
+		StorageClass: input.StorageClass,
+		AccessMode:   input.AccessMode,
+	}
+
+	if input.Mode == utils.SnapshotRestoreNewPVC {
+		if err := workflow.ExecuteActivity(ctx, k8sActs.RestorePVCFromSnapshot, restore).Get(ctx, nil); err != nil {
+			phase = utils.PhaseSnapshotRestoreFailed
+			return err
+		}
+		phase = utils.PhaseSnapshotRestored
+		return nil
+	}
+
+	// restauration en place : le workload est mis en pause le temps de remplacer sa PVC
+	restore.ReleaseName = input.ReleaseName
+
+	phase = utils.PhaseWorkloadPausing
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadRestoring, phase).Get(ctx, nil)
+
+	if err := workflow.ExecuteActivity(ctx, k8sActs.ScaleDeployment, input.Snapshot.Namespace, input.ReleaseName, int32(0)).Get(ctx, nil); err != nil {
+		// rien n'a changé : le workload reprend son statut d'avant la restauration
+		status := input.Status
+		if status == "" {
+			status = utils.WorkloadRunning
+		}
+		phase = utils.PhaseSnapshotRestoreFailed
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, status, phase).Get(ctx, nil)
+		return err
+	}
+
+	phase = utils.PhaseSnapshotRestoring
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadRestoring, phase).Get(ctx, nil)
+
+	err := workflow.ExecuteActivity(pollCtx, k8sActs.DeletePVC, input.Snapshot.Namespace, input.TargetPVC).Get(ctx, nil)
+	if err == nil {
+		err = workflow.ExecuteActivity(ctx, k8sActs.RestorePVCFromSnapshot, restore).Get(ctx, nil)
+	}
+	if err != nil {
+		// le workload reste à 0 réplica : sa PVC d'origine a peut-être déjà disparu
+		phase = utils.PhaseSnapshotRestoreFailed
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadFailed, phase).Get(ctx, nil)
+		return err
+	}
+
+	replicas := input.Replicas
+	if replicas <= 0 {
+		replicas = 1
+	}
+	if err := workflow.ExecuteActivity(ctx, k8sActs.ScaleDeployment, input.Snapshot.Namespace, input.ReleaseName, int32(replicas)).Get(ctx, nil); err != nil {
+		phase = utils.PhaseSnapshotRestoreFailed
+		workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadFailed, phase).Get(ctx, nil)
+		return err
+	}
+
+	phase = utils.PhaseSnapshotRestored
+	workflow.ExecuteActivity(ctx, dbActs.UpdateWorkloadStatus, input.WorkloadID, utils.WorkloadRunning, phase).Get(ctx, nil)
+	return nil
+}
@@ -9,6 +9,16 @@ import (
 type IWorkloadController interface {
 	CreateWorkload(c *gin.Context, in *domain.CreateWorkloadRequest) (*domain.WorkloadResponse, error)
 	GetWorkloadStatus(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadStatusResponse, error)
+	CancelWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error)
+	RetryWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error)
+	ResizeVolume(c *gin.Context, in *domain.ResizeVolumeRequest) (*domain.WorkloadResponse, error)
+	RunWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error)
+	DeleteWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error)
+	CreateSnapshot(c *gin.Context, in *domain.CreateSnapshotRequest) (*domain.SnapshotResponse, error)
+	ListSnapshots(c *gin.Context, in *domain.SnapshotRequest) ([]domain.SnapshotResponse, error)
+	DeleteSnapshot(c *gin.Context, in *domain.SnapshotPathRequest) (*domain.SnapshotResponse, error)
+	RestoreSnapshot(c *gin.Context, in *domain.RestoreSnapshotRequest) (*domain.SnapshotResponse, error)
+	SetSnapshotSchedule(c *gin.Context, in *domain.SnapshotScheduleRequest) (*domain.SnapshotScheduleResponse, error)
 }
 type WorkloadController struct {
 	WorkloadService *service.WorkloadService
@@ -49,3 +59,151 @@ func (h *WorkloadController) GetWorkloadStatus(c *gin.Context, in *GetWorkloadRe
 
 	return nil, nil
 }
+
+func (h *WorkloadController) CancelWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error) {
+	workload, err := h.WorkloadService.CancelWorkload(c.Request.Context(), in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WorkloadResponse{
+		WorkloadID: workload.ID.String(),
+		Status:     workload.Status,
+		Namespace:  workload.Namespace,
+		Message:    "Cancellation requested, the release will be rolled back",
+	}, nil
+}
+
+func (h *WorkloadController) RetryWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error) {
+	workload, err := h.WorkloadService.RetryWorkload(c.Request.Context(), in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WorkloadResponse{
+		WorkloadID: workload.ID.String(),
+		Status:     workload.Status,
+		Namespace:  workload.Namespace,
+		Message:    "Deployment restarted from the last input",
+	}, nil
+}
+
+func (h *WorkloadController) ResizeVolume(c *gin.Context, in *domain.ResizeVolumeRequest) (*domain.WorkloadResponse, error) {
+	workload, workflowID, err := h.WorkloadService.ResizeVolume(c.Request.Context(), in.ID, in.StorageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WorkloadResponse{
+		WorkloadID: workload.ID.String(),
+		WorkflowID: workflowID,
+		Status:     workload.Status,
+		Namespace:  workload.Namespace,
+		Message:    "Volume expansion initiated",
+	}, nil
+}
+
+func (h *WorkloadController) RunWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error) {
+	workload, workflowID, err := h.WorkloadService.RunWorkload(c.Request.Context(), in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WorkloadResponse{
+		WorkloadID: workload.ID.String(),
+		WorkflowID: workflowID,
+		Status:     workload.Status,
+		Namespace:  workload.Namespace,
+		Message:    "Manual run of the cronjob triggered",
+	}, nil
+}
+
+func (h *WorkloadController) DeleteWorkload(c *gin.Context, in *GetWorkloadRequest) (*domain.WorkloadResponse, error) {
+	workload, workflowID, err := h.WorkloadService.DeleteWorkload(c.Request.Context(), in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WorkloadResponse{
+		WorkloadID: workload.ID.String(),
+		WorkflowID: workflowID,
+		Status:     workload.Status,
+		Namespace:  workload.Namespace,
+		Message:    "Uninstall and volume cleanup initiated",
+	}, nil
+}
+
+func (h *WorkloadController) CreateSnapshot(c *gin.Context, in *domain.CreateSnapshotRequest) (*domain.SnapshotResponse, error) {
+	snapshot, workflowID, err := h.WorkloadService.CreateSnapshot(c.Request.Context(), in.ID, in.Volume)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toSnapshotResponse(snapshot)
+	resp.WorkflowID = workflowID
+	return resp, nil
+}
+
+func (h *WorkloadController) ListSnapshots(c *gin.Context, in *domain.SnapshotRequest) ([]domain.SnapshotResponse, error) {
+	snapshots, err := h.WorkloadService.ListSnapshots(c.Request.Context(), in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]domain.SnapshotResponse, 0, len(snapshots))
+	for i := range snapshots {
+		resp = append(resp, *toSnapshotResponse(&snapshots[i]))
+	}
+	return resp, nil
+}
+
+func (h *WorkloadController) DeleteSnapshot(c *gin.Context, in *domain.SnapshotPathRequest) (*domain.SnapshotResponse, error) {
+	workflowID, err := h.WorkloadService.DeleteSnapshot(c.Request.Context(), in.ID, in.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SnapshotResponse{
+		ID:         in.SnapshotID,
+		WorkloadID: in.ID,
+		WorkflowID: workflowID,
+	}, nil
+}
+
+func (h *WorkloadController) RestoreSnapshot(c *gin.Context, in *domain.RestoreSnapshotRequest) (*domain.SnapshotResponse, error) {
+	snapshot, workflowID, err := h.WorkloadService.RestoreSnapshot(c.Request.Context(), in.ID, in.SnapshotID, in.Mode, in.TargetPVC, in.Volume)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toSnapshotResponse(snapshot)
+	resp.WorkflowID = workflowID
+	return resp, nil
+}
+
+func (h *WorkloadController) SetSnapshotSchedule(c *gin.Context, in *domain.SnapshotScheduleRequest) (*domain.SnapshotScheduleResponse, error) {
+	workflowID, err := h.WorkloadService.SetSnapshotSchedule(c.Request.Context(), in.ID, in.Schedule, in.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SnapshotScheduleResponse{
+		WorkloadID: in.ID,
+		Schedule:   in.Schedule,
+		Retention:  in.Retention,
+		WorkflowID: workflowID,
+	}, nil
+}
+
+func toSnapshotResponse(s *domain.VolumeSnapshot) *domain.SnapshotResponse {
+	return &domain.SnapshotResponse{
+		ID:          s.ID.String(),
+		WorkloadID:  s.WorkloadID.String(),
+		Name:        s.Name,
+		PVCName:     s.PVCName,
+		Status:      s.Status,
+		RestoreSize: s.RestoreSize,
+		Scheduled:   s.Scheduled,
+		CreatedAt:   s.CreatedAt,
+	}
+}